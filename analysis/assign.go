@@ -0,0 +1,193 @@
+package analysis
+
+import (
+	"lang/parser"
+	"lang/scanner"
+)
+
+// checkAssignments reports the reads of local variables of f that are
+// declared without a value and may not have been assigned one on every path
+// to the read, and reports whether there were none. Parameters and module
+// variables always have a value, which is the zero value of their type for
+// module variables declared without one.
+func checkAssignments(env Env, f parser.FunctionStmt) bool {
+	a := &assignment{env: env, assigned: map[int]bool{}, ok: true}
+	a.block(f.Body)
+	return a.ok
+}
+
+type assignment struct {
+	env Env
+	// The numbers of the local variables declared in each enclosing block,
+	// innermost last, and how many have been declared.
+	scopes []map[string]int
+	vars   int
+	// The numbers of the variables that are assigned on every path to the
+	// statement being checked.
+	assigned map[int]bool
+	ok       bool
+}
+
+func (a *assignment) lookup(name string) int {
+	for i := len(a.scopes) - 1; i >= 0; i-- {
+		if v, ok := a.scopes[i][name]; ok {
+			return v
+		}
+	}
+	return 0
+}
+
+func (a *assignment) read(name scanner.Token) {
+	if v := a.lookup(name.Lexeme); v != 0 && !a.assigned[v] {
+		a.env.errorf(name, "variable %s is used before it is assigned", name.Lexeme)
+		a.ok = false
+		// Report each variable once on each path.
+		a.assigned[v] = true
+	}
+}
+
+func (a *assignment) assign(name scanner.Token) {
+	if v := a.lookup(name.Lexeme); v != 0 {
+		a.assigned[v] = true
+	}
+}
+
+// branch checks b starting from the variables assigned before it, and
+// returns those assigned after it, or nil if it terminates.
+func (a *assignment) branch(before map[int]bool, b parser.Block) map[int]bool {
+	a.assigned = map[int]bool{}
+	for v, assigned := range before {
+		a.assigned[v] = assigned
+	}
+	a.block(b)
+	if Terminates(b) {
+		return nil
+	}
+	return a.assigned
+}
+
+// join returns the variables assigned on every path that continues past a
+// statement, which are all of them if none does.
+func (a *assignment) join(paths []map[int]bool) map[int]bool {
+	joined := map[int]bool{}
+	for v := 1; v <= a.vars; v++ {
+		joined[v] = true
+		for _, assigned := range paths {
+			if assigned != nil && !assigned[v] {
+				delete(joined, v)
+			}
+		}
+	}
+	return joined
+}
+
+func (a *assignment) block(b parser.Block) {
+	a.scopes = append(a.scopes, map[string]int{})
+	a.stmts(b.Stmts)
+	a.scopes = a.scopes[:len(a.scopes)-1]
+}
+
+func (a *assignment) stmts(stmts []parser.Stmt) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case parser.VarStmt:
+			if s.Expr != nil {
+				a.expr(s.Expr)
+			}
+			a.vars++
+			a.scopes[len(a.scopes)-1][s.Name.Lexeme] = a.vars
+			a.assigned[a.vars] = s.Expr != nil
+		case parser.AssignStmt:
+			a.expr(s.Expr)
+			a.assign(s.Target.(scanner.Token))
+		case parser.CompoundAssignStmt:
+			a.read(s.Target)
+			a.expr(s.Expr)
+		case parser.IncDecStmt:
+			a.read(s.Target)
+		case parser.ReturnStmt:
+			if s.Expr != nil {
+				a.expr(s.Expr)
+			}
+		case parser.IfStmt:
+			a.expr(s.Cond)
+			before := a.assigned
+			then, els := a.branch(before, s.Then), a.branch(before, s.Els)
+			switch cond, _ := Constant(s.Cond); cond {
+			case true:
+				els = nil
+			case false:
+				then = nil
+			}
+			a.assigned = a.join([]map[int]bool{then, els})
+		case parser.WhileStmt:
+			a.expr(s.Cond)
+			before := a.assigned
+			a.branch(before, s.Body)
+			a.assigned = before
+			if Terminates(s) {
+				a.assigned = a.join(nil)
+			}
+		case parser.SwitchStmt:
+			a.switchStmt(s)
+		case parser.Block:
+			a.block(s)
+		case parser.FallthroughStmt, parser.Comment, parser.BlankLine:
+		default:
+			a.expr(s)
+		}
+	}
+}
+
+// switchStmt checks each case of s starting from the variables assigned
+// before s, since a case that falls through assigns at least as many.
+func (a *assignment) switchStmt(s parser.SwitchStmt) {
+	a.expr(s.Expr)
+	before := a.assigned
+	var paths []map[int]bool
+	hasDefault := false
+	for _, c := range s.Cases {
+		hasDefault = hasDefault || c.Default
+		for _, v := range c.Values {
+			a.expr(v)
+		}
+		after := a.branch(before, c.Body)
+		if n := len(c.Body.Stmts); n > 0 {
+			if _, ok := c.Body.Stmts[n-1].(parser.FallthroughStmt); ok {
+				continue
+			}
+		}
+		paths = append(paths, after)
+	}
+	if !hasDefault {
+		paths = append(paths, before)
+	}
+	a.assigned = a.join(paths)
+}
+
+func (a *assignment) expr(e parser.Expr) {
+	switch n := e.(type) {
+	case parser.IdentExpr:
+		a.read(n.Name)
+	case parser.MemberAccess:
+		a.expr(n.Parent)
+	case parser.FunctionCall:
+		a.expr(n.Callee)
+		for _, arg := range n.Args {
+			a.expr(arg)
+		}
+	case parser.TernaryExpr:
+		a.expr(n.Cond)
+		a.expr(n.Then)
+		a.expr(n.Els)
+	case parser.UnaryOp:
+		a.expr(n.Expr)
+	case parser.BinaryOp:
+		a.expr(n.Left)
+		a.expr(n.Right)
+	case parser.InterpolatedStr:
+		for _, part := range n.Parts {
+			a.expr(part)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package analysis
+
+import (
+	"lang/parser"
+	"lang/scanner"
+	"strconv"
+	"strings"
+)
+
+// Constant returns the value of e, as an int64, float64, bool or string, if
+// it is a constant expression: one made of literals and operators, whose
+// value is the same whenever it is evaluated. Expressions that would fail
+// at run time, like integer division by zero, and those whose value the
+// backends do not agree on, like shifts by 64 or more, are not constant,
+// and neither are literals out of range.
+func Constant(e parser.Expr) (interface{}, bool) {
+	switch n := e.(type) {
+	case parser.LiteralNum:
+		if strings.ContainsRune(n.Value, '.') {
+			f, err := strconv.ParseFloat(n.Value, 64)
+			return f, err == nil
+		}
+		i, err := strconv.ParseInt(n.Value, 10, 64)
+		return i, err == nil
+	case parser.LiteralBool:
+		return n.Value, true
+	case parser.LiteralStr:
+		return n.Value, true
+	case parser.UnaryOp:
+		x, ok := Constant(n.Expr)
+		if !ok {
+			return nil, false
+		}
+		switch x := x.(type) {
+		case int64:
+			switch n.Op.Kind {
+			case scanner.Minus:
+				return -x, true
+			case scanner.BNot:
+				return ^x, true
+			}
+		case float64:
+			if n.Op.Kind == scanner.Minus {
+				return -x, true
+			}
+		case bool:
+			if n.Op.Kind == scanner.LNot {
+				return !x, true
+			}
+		}
+	case parser.BinaryOp:
+		l, ok := Constant(n.Left)
+		if !ok {
+			return nil, false
+		}
+		if n.Op.Kind == scanner.LAnd || n.Op.Kind == scanner.LOr {
+			if b, isBool := l.(bool); isBool && b == (n.Op.Kind == scanner.LOr) {
+				return b, true
+			}
+			r, ok := Constant(n.Right)
+			_, isBool := r.(bool)
+			return r, ok && isBool
+		}
+		r, ok := Constant(n.Right)
+		if !ok {
+			return nil, false
+		}
+		return binaryConstant(n.Op.Kind, l, r)
+	case parser.TernaryExpr:
+		cond, ok := Constant(n.Cond)
+		if b, isBool := cond.(bool); ok && isBool {
+			if b {
+				return Constant(n.Then)
+			}
+			return Constant(n.Els)
+		}
+	}
+	return nil, false
+}
+
+func binaryConstant(op scanner.TokenKind, left, right interface{}) (interface{}, bool) {
+	switch l := left.(type) {
+	case int64:
+		r, ok := right.(int64)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case scanner.Plus:
+			return l + r, true
+		case scanner.Minus:
+			return l - r, true
+		case scanner.Star:
+			return l * r, true
+		case scanner.Slash:
+			if r == 0 {
+				return nil, false
+			}
+			return l / r, true
+		case scanner.Percent:
+			if r == 0 {
+				return nil, false
+			}
+			return l % r, true
+		case scanner.BAnd:
+			return l & r, true
+		case scanner.BOr:
+			return l | r, true
+		case scanner.BXor:
+			return l ^ r, true
+		case scanner.Shl:
+			if r < 0 || r >= 64 {
+				return nil, false
+			}
+			return l << uint64(r), true
+		case scanner.Shr:
+			if r < 0 || r >= 64 {
+				return nil, false
+			}
+			return l >> uint64(r), true
+		case scanner.EqEq:
+			return l == r, true
+		case scanner.Ne:
+			return l != r, true
+		case scanner.Gt:
+			return l > r, true
+		case scanner.Gte:
+			return l >= r, true
+		case scanner.Lt:
+			return l < r, true
+		case scanner.Lte:
+			return l <= r, true
+		}
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case scanner.Plus:
+			return l + r, true
+		case scanner.Minus:
+			return l - r, true
+		case scanner.Star:
+			return l * r, true
+		case scanner.Slash:
+			return l / r, true
+		case scanner.EqEq:
+			return l == r, true
+		case scanner.Ne:
+			return l != r, true
+		case scanner.Gt:
+			return l > r, true
+		case scanner.Gte:
+			return l >= r, true
+		case scanner.Lt:
+			return l < r, true
+		case scanner.Lte:
+			return l <= r, true
+		}
+	case bool:
+		if r, ok := right.(bool); ok {
+			return equality(op, l == r)
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return equality(op, l == r)
+		}
+	}
+	return nil, false
+}
+
+// equality returns the value of == or != on operands for which == is eq.
+func equality(op scanner.TokenKind, eq bool) (interface{}, bool) {
+	switch op {
+	case scanner.EqEq:
+		return eq, true
+	case scanner.Ne:
+		return !eq, true
+	}
+	return nil, false
+}
+
+// divisionByZero reports whether n divides an int by a constant zero.
+func divisionByZero(n parser.BinaryOp) bool {
+	if n.Op.Kind != scanner.Slash && n.Op.Kind != scanner.Percent {
+		return false
+	}
+	r, ok := Constant(n.Right)
+	return ok && r == int64(0)
+}
@@ -0,0 +1,108 @@
+package analysis
+
+import (
+	"lang/loader"
+	"lang/parser"
+)
+
+// Terminates reports whether control never continues past stmt, because
+// it returns or loops forever on every path through it.
+func Terminates(stmt parser.Stmt) bool {
+	switch s := stmt.(type) {
+	case parser.ReturnStmt:
+		return true
+	case parser.Block:
+		// The statements after one that terminates are unreachable.
+		for _, stmt := range s.Stmts {
+			if Terminates(stmt) {
+				return true
+			}
+		}
+	case parser.IfStmt:
+		switch cond, _ := Constant(s.Cond); cond {
+		case true:
+			return Terminates(s.Then)
+		case false:
+			return Terminates(s.Els)
+		}
+		return Terminates(s.Then) && Terminates(s.Els)
+	case parser.WhileStmt:
+		cond, _ := Constant(s.Cond)
+		return cond == true
+	case parser.SwitchStmt:
+		hasDefault := false
+		for _, c := range s.Cases {
+			hasDefault = hasDefault || c.Default
+			n := len(c.Body.Stmts)
+			if n > 0 {
+				if _, ok := c.Body.Stmts[n-1].(parser.FallthroughStmt); ok {
+					continue
+				}
+			}
+			if !Terminates(c.Body) {
+				return false
+			}
+		}
+		return hasDefault
+	}
+	return false
+}
+
+// unreachable reports the first of stmts that cannot run because one
+// before it terminates.
+func unreachable(env Env, stmts []parser.Stmt) {
+	terminated := false
+	for _, stmt := range stmts {
+		switch stmt.(type) {
+		case parser.Comment, parser.BlankLine:
+			continue
+		}
+		if terminated {
+			env.warnf(parser.Pos(stmt), "unreachable code")
+			return
+		}
+		terminated = Terminates(stmt)
+	}
+}
+
+// deadBranch reports the first statement of b, which never runs because of
+// a constant condition.
+func deadBranch(env Env, b parser.Block) {
+	for _, stmt := range b.Stmts {
+		switch stmt.(type) {
+		case parser.Comment, parser.BlankLine:
+			continue
+		}
+		env.warnf(parser.Pos(stmt), "unreachable code")
+		return
+	}
+}
+
+// unusedFunctions reports the functions of each of mods that are not used
+// by its main function, its exported functions or the initializers of its
+// variables.
+func unusedFunctions(universe Env, envs []Env, mods []*loader.Module) {
+	ix := NewIndex(universe, mods)
+	for i, m := range mods {
+		var roots []*Decl
+		var functions []*Decl
+		for _, d := range ix.Module(m.Path) {
+			switch s := d.Stmt.(type) {
+			case parser.FunctionStmt:
+				if s.Exported || s.Name.Lexeme == "main" {
+					roots = append(roots, d)
+				} else {
+					functions = append(functions, d)
+				}
+			case parser.VarStmt:
+				roots = append(roots, d)
+			}
+		}
+		reached := ix.Reachable(roots)
+		for _, d := range functions {
+			if !reached[d] {
+				envs[i].warnf(d.Name, "function %s is unused", d.Name.Lexeme)
+			}
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package analysis
+
+import (
+	"fmt"
+	"lang/parser"
+	"lang/scanner"
+	"strings"
+)
+
+// Diagnostic is an error or warning found while checking, reported at the
+// token it is about.
+type Diagnostic struct {
+	Path    string
+	Pos     scanner.Token
+	Msg     string
+	Warning bool
+}
+
+func (d Diagnostic) String() string {
+	msg := d.Msg
+	if d.Warning {
+		msg = "warning: " + msg
+	}
+	pos := fmt.Sprintf("%d:%d", d.Pos.Row+1, d.Pos.Col+1)
+	if d.Path != "" {
+		pos = d.Path + ":" + pos
+	}
+	return pos + ": " + msg
+}
+
+// Diagnostics returns everything reported so far while checking within e
+// or any environment enclosed by it.
+func (e Env) Diagnostics() []Diagnostic {
+	return *e.diags
+}
+
+func (e Env) report(d Diagnostic) {
+	*e.diags = append(*e.diags, d)
+	fmt.Fprintln(e.out, d)
+}
+
+func (e Env) errorf(pos scanner.Token, format string, args ...interface{}) {
+	e.report(Diagnostic{Path: e.path, Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (e Env) warnf(pos scanner.Token, format string, args ...interface{}) {
+	if e.nowarn[pos.Row] {
+		return
+	}
+	e.report(Diagnostic{Path: e.path, Pos: pos, Msg: fmt.Sprintf(format, args...), Warning: true})
+}
+
+// nowarnRows returns the rows of the comments that are "// nowarn",
+// optionally followed by a colon and a reason, which suppress the warnings
+// about the code on their row.
+func nowarnRows(comments []scanner.Token) map[int]bool {
+	rows := map[int]bool{}
+	for _, c := range comments {
+		if fields := strings.Fields(c.Lexeme); len(fields) > 0 && strings.TrimSuffix(fields[0], ":") == "nowarn" {
+			rows[c.Row] = true
+		}
+	}
+	return rows
+}
+
+func (e Env) errors() int {
+	n := 0
+	for _, d := range *e.diags {
+		if !d.Warning {
+			n++
+		}
+	}
+	return n
+}
+
+// checkStmt checks stmt like IsType, and explains why it does not type
+// check unless a more precise error was already reported from within it.
+func checkStmt(env Env, stmt parser.Stmt, expected Type) bool {
+	errors := env.errors()
+	if IsType(env, stmt, expected) {
+		return true
+	}
+	if env.errors() == errors {
+		pos, msg := stmtError(env, stmt, expected)
+		env.errorf(pos, "%s", msg)
+	}
+	return false
+}
+
+func stmtError(env Env, stmt parser.Stmt, expected Type) (scanner.Token, string) {
+	switch s := stmt.(type) {
+	case parser.VarStmt:
+		return exprError(env, s.Expr, env.Vars.find(Symbol(s.Name.Lexeme)), "in declaration of "+s.Name.Lexeme)
+	case parser.ReturnStmt:
+		if s.Expr == nil {
+			return s.Keyword, "missing return value"
+		}
+		return exprError(env, s.Expr, expected, "in return statement")
+	case parser.AssignStmt:
+		target := s.Target.(scanner.Token)
+		t := env.Vars.find(Symbol(target.Lexeme))
+		if t == nil {
+			return target, "undefined: " + target.Lexeme
+		}
+		return exprError(env, s.Expr, t, "in assignment to "+target.Lexeme)
+	case parser.CompoundAssignStmt:
+		t := env.Vars.find(Symbol(s.Target.Lexeme))
+		if t == nil {
+			return s.Target, "undefined: " + s.Target.Lexeme
+		}
+		if pos, msg, ok := invalidExpr(env, s.Expr); ok {
+			return pos, msg
+		}
+		op := s.Op
+		op.Kind = parser.CompoundAssignOps[s.Op.Kind]
+		if divisionByZero(parser.BinaryOp{Op: op, Left: parser.IdentExpr{Name: s.Target}, Right: s.Expr}) {
+			return s.Op, "invalid operation: division by zero"
+		}
+		return s.Op, fmt.Sprintf("invalid operation: %s %s %v", s.Target.Lexeme, scanner.Operators[s.Op.Kind], describe(env, s.Expr))
+	case parser.IncDecStmt:
+		t := env.Vars.find(Symbol(s.Target.Lexeme))
+		if t == nil {
+			return s.Target, "undefined: " + s.Target.Lexeme
+		}
+		return s.Op, fmt.Sprintf("invalid operation: %s%s on %v", s.Target.Lexeme, scanner.Operators[s.Op.Kind], t)
+	case parser.IfStmt:
+		return exprError(env, s.Cond, Bool, "as if condition")
+	case parser.WhileStmt:
+		return exprError(env, s.Cond, Bool, "as while condition")
+	case parser.SwitchStmt, parser.FunctionStmt, parser.Block:
+		return parser.Pos(s), "invalid statement"
+	default:
+		if pos, msg, ok := invalidExpr(env, s); ok {
+			return pos, msg
+		}
+		return parser.Pos(s), "invalid statement"
+	}
+}
+
+// exprError explains why e is not of type expected, where context says how
+// e is used.
+func exprError(env Env, e parser.Expr, expected Type, context string) (scanner.Token, string) {
+	if pos, msg, ok := invalidExpr(env, e); ok {
+		return pos, msg
+	}
+	return parser.Pos(e), fmt.Sprintf("cannot use %s as %v %s", describe(env, e), expected, context)
+}
+
+// describe names the type of e for use in a message.
+func describe(env Env, e parser.Expr) string {
+	if t := TypeOf(env, e); t != nil {
+		return fmt.Sprintf("%v value", t)
+	}
+	return "expression"
+}
+
+// invalidExpr finds the innermost part of e that has no type at all.
+func invalidExpr(env Env, e parser.Expr) (scanner.Token, string, bool) {
+	switch n := e.(type) {
+	case parser.LiteralNum:
+		if _, ok := Constant(n); !ok {
+			t := Int
+			if strings.ContainsRune(n.Value, '.') {
+				t = Float
+			}
+			return n.Token, fmt.Sprintf("constant %s overflows %v", n.Value, t), true
+		}
+	case parser.IdentExpr:
+		if !env.Vars.contains(Symbol(n.Name.Lexeme)) && !env.Types.contains(Symbol(n.Name.Lexeme)) {
+			return n.Name, "undefined: " + n.Name.Lexeme, true
+		}
+	case parser.MemberAccess:
+		if enum, isEnum := TypeNamed(env, n.Parent).(*EnumType); isEnum {
+			if !enum.has(Symbol(n.Name.Lexeme)) {
+				return n.Name, fmt.Sprintf("%s has no member %s", enum.Name, n.Name.Lexeme), true
+			}
+			return scanner.Token{}, "", false
+		}
+		if pos, msg, ok := invalidExpr(env, n.Parent); ok {
+			return pos, msg, true
+		}
+		switch parent := TypeOf(env, n.Parent).(type) {
+		case *ModuleType:
+			if parent.Vars[Symbol(n.Name.Lexeme)] == nil {
+				return n.Name, fmt.Sprintf("module %s has no exported %s", parent.Name, n.Name.Lexeme), true
+			}
+		case CompoundType:
+			if parent[Symbol(n.Name.Lexeme)] == nil {
+				return n.Name, "no member " + n.Name.Lexeme, true
+			}
+		default:
+			return n.Name, fmt.Sprintf("%s has no members", describe(env, n.Parent)), true
+		}
+	case parser.FunctionCall:
+		for _, arg := range n.Args {
+			if pos, msg, ok := invalidExpr(env, arg); ok {
+				return pos, msg, true
+			}
+		}
+		if t := conversionType(env, n); t != nil {
+			if !isConvertible(env, n.Args, t) {
+				return parser.Pos(n), fmt.Sprintf("cannot convert to %v", t), true
+			}
+			return scanner.Token{}, "", false
+		}
+		if pos, msg, ok := invalidExpr(env, n.Callee); ok {
+			return pos, msg, true
+		}
+		f, isFunction := getType(env, n.Callee).(FunctionType)
+		if !isFunction {
+			return parser.Pos(n), fmt.Sprintf("cannot call %s", describe(env, n.Callee)), true
+		}
+		if len(n.Args) < len(f.Params) || !f.Variadic && len(n.Args) != len(f.Params) {
+			return parser.Pos(n), fmt.Sprintf("wrong number of arguments in call: have %d, want %d", len(n.Args), len(f.Params)), true
+		}
+		for i, arg := range n.Args {
+			if i >= len(f.Params) {
+				if !isStringable(env, arg) {
+					return parser.Pos(arg), fmt.Sprintf("cannot use %s as variadic argument", describe(env, arg)), true
+				}
+			} else if !IsType(env, arg, f.Params[i]) {
+				return parser.Pos(arg), fmt.Sprintf("cannot use %s as %v argument", describe(env, arg), f.Params[i]), true
+			}
+		}
+	case parser.BinaryOp:
+		if pos, msg, ok := invalidExpr(env, n.Left); ok {
+			return pos, msg, true
+		}
+		if pos, msg, ok := invalidExpr(env, n.Right); ok {
+			return pos, msg, true
+		}
+		if divisionByZero(n) {
+			return n.Op, "invalid operation: division by zero", true
+		}
+		if TypeOf(env, n) == nil {
+			return n.Op, fmt.Sprintf("invalid operation: %s %s %s", describe(env, n.Left), scanner.Operators[n.Op.Kind], describe(env, n.Right)), true
+		}
+	case parser.UnaryOp:
+		if pos, msg, ok := invalidExpr(env, n.Expr); ok {
+			return pos, msg, true
+		}
+		if TypeOf(env, n) == nil {
+			return n.Op, fmt.Sprintf("invalid operation: %s%s", scanner.Operators[n.Op.Kind], describe(env, n.Expr)), true
+		}
+	case parser.TernaryExpr:
+		for _, part := range []parser.Expr{n.Cond, n.Then, n.Els} {
+			if pos, msg, ok := invalidExpr(env, part); ok {
+				return pos, msg, true
+			}
+		}
+		if !IsType(env, n.Cond, Bool) {
+			return parser.Pos(n.Cond), fmt.Sprintf("cannot use %s as bool condition", describe(env, n.Cond)), true
+		}
+		if TypeOf(env, n) == nil {
+			return parser.Pos(n.Els), fmt.Sprintf("mismatched types %s and %s in ternary expression", describe(env, n.Then), describe(env, n.Els)), true
+		}
+	case parser.InterpolatedStr:
+		for _, part := range n.Parts {
+			if pos, msg, ok := invalidExpr(env, part); ok {
+				return pos, msg, true
+			}
+			if !isStringable(env, part) {
+				return parser.Pos(part), fmt.Sprintf("cannot interpolate %s", describe(env, part)), true
+			}
+		}
+	}
+	return scanner.Token{}, "", false
+}
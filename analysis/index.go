@@ -0,0 +1,508 @@
+package analysis
+
+import (
+	"fmt"
+	"io/ioutil"
+	"lang/loader"
+	"lang/parser"
+	"lang/prelude"
+	"lang/scanner"
+	"strings"
+)
+
+type DeclKind int
+
+const (
+	VarDecl DeclKind = iota
+	ParamDecl
+	FunctionDecl
+	EnumDecl
+	MemberDecl
+	ModuleDecl
+)
+
+// Decl is a declared name. Names declared by the prelude or the host have
+// no Path.
+type Decl struct {
+	Name scanner.Token
+	Path string
+	Kind DeclKind
+	Type Type
+	// Stmt is the declaring statement, if there is one.
+	Stmt parser.Stmt
+	// Members are the members of an enum or the exports of a module.
+	Members []*Decl
+}
+
+func (d *Decl) String() string {
+	switch d.Kind {
+	case FunctionDecl:
+		f, ok := d.Stmt.(parser.FunctionStmt)
+		if !ok {
+			return fmt.Sprintf("%s %v", d.Name.Lexeme, d.Type)
+		}
+		var params []string
+		for _, param := range f.Params {
+			params = append(params, param.Kind.Lexeme+" "+param.Name.Lexeme)
+		}
+		if f.Variadic {
+			params = append(params, "...")
+		}
+		return fmt.Sprintf("%s %s(%s)", f.ReturnKind.Lexeme, d.Name.Lexeme, strings.Join(params, ", "))
+	case EnumDecl:
+		var members []string
+		for _, m := range d.Members {
+			members = append(members, m.Name.Lexeme)
+		}
+		return fmt.Sprintf("enum %s { %s }", d.Name.Lexeme, strings.Join(members, ", "))
+	case MemberDecl:
+		return fmt.Sprintf("%v.%s", d.Type, d.Name.Lexeme)
+	case ModuleDecl:
+		return "module " + d.Name.Lexeme
+	default:
+		return fmt.Sprintf("%v %s", d.Type, d.Name.Lexeme)
+	}
+}
+
+// Ref is an occurrence of a declared name, including the one in its
+// declaration.
+type Ref struct {
+	Name scanner.Token
+	Path string
+	Decl *Decl
+	// In is the top-level function or variable whose declaration the
+	// reference is in, if any.
+	In *Decl
+	// Assigned is set if the reference is the target of an assignment,
+	// which does not use the value of the name.
+	Assigned bool
+}
+
+// Scope is a module, or a block within one, along with the names declared
+// directly in it.
+type Scope struct {
+	Path   string
+	Parent *Scope
+	// The braces of a block. They are missing from a module and from the
+	// blocks the parser makes up.
+	Open   scanner.Token
+	Close  scanner.Token
+	Decls  []*Decl
+	module bool
+}
+
+func (s *Scope) lookup(name string) *Decl {
+	for ; s != nil; s = s.Parent {
+		for i := len(s.Decls) - 1; i >= 0; i-- {
+			if s.Decls[i].Name.Lexeme == name {
+				return s.Decls[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Index resolves every name used in a set of modules to its declaration.
+type Index struct {
+	Refs   []Ref
+	Scopes []*Scope
+}
+
+// NewIndex indexes mods, ordered as for Check, within universe. Unlike
+// Check it does not report errors, and it indexes whatever names it can
+// resolve in modules that do not type check.
+func NewIndex(universe Env, mods []*loader.Module) *Index {
+	universe.out = ioutil.Discard
+	universe.diags = &[]Diagnostic{}
+	ix := &Index{}
+	top := &Scope{}
+	builtins := map[string]parser.FunctionStmt{}
+	for _, stmt := range prelude.Stmts() {
+		f := stmt.(parser.FunctionStmt)
+		builtins[f.Name.Lexeme] = f
+	}
+	for sym, t := range universe.Vars.Symbols {
+		d := &Decl{Name: scanner.Token{Kind: scanner.Ident, Lexeme: string(sym)}, Kind: VarDecl, Type: t}
+		if _, isFunction := t.(FunctionType); isFunction {
+			d.Kind = FunctionDecl
+		}
+		if f, ok := builtins[string(sym)]; ok {
+			d.Name, d.Stmt = f.Name, f
+		}
+		top.Decls = append(top.Decls, d)
+	}
+
+	modules := map[*loader.Module]*Decl{}
+	exports := map[*loader.Module]*ModuleType{}
+	for _, m := range mods {
+		env := newEnv(universe)
+		env.path = m.Path
+		idx := &indexer{ix: ix, path: m.Path}
+		scope := idx.newScope(top, parser.Block{})
+		scope.module = true
+
+		self := &Decl{Name: scanner.Token{Kind: scanner.Ident, Lexeme: m.Name}, Path: m.Path, Kind: ModuleDecl}
+		imports := 0
+		for _, stmt := range m.Stmts {
+			switch s := stmt.(type) {
+			case parser.ModuleStmt:
+				self.Name, self.Stmt = s.Name, s
+				idx.ref(s.Name, self)
+			case parser.ImportStmt:
+				if imports >= len(m.Imports) {
+					break
+				}
+				imported := m.Imports[imports]
+				imports++
+				d := modules[imported]
+				mt := exports[imported]
+				scope.Decls = append(scope.Decls, d)
+				idx.ref(s.Path, d)
+				env.Vars.Symbols[mt.Name] = mt
+				for sym, t := range mt.Types {
+					env.Types.Symbols[mt.Name+"."+sym] = t
+				}
+			}
+		}
+
+		for _, stmt := range m.Stmts {
+			if s, isEnum := stmt.(parser.EnumStmt); isEnum {
+				env.addEnum(s)
+				d := &Decl{Name: s.Name, Path: m.Path, Kind: EnumDecl, Type: env.Types.Symbols[Symbol(s.Name.Lexeme)], Stmt: s}
+				for _, member := range s.Members {
+					d.Members = append(d.Members, &Decl{Name: member, Path: m.Path, Kind: MemberDecl, Type: d.Type})
+				}
+				scope.Decls = append(scope.Decls, d)
+			}
+		}
+		for _, stmt := range m.Stmts {
+			switch s := stmt.(type) {
+			case parser.FunctionStmt:
+				env.addFunction(s)
+				scope.Decls = append(scope.Decls, &Decl{Name: s.Name, Path: m.Path, Kind: FunctionDecl, Type: env.Vars.Symbols[Symbol(s.Name.Lexeme)], Stmt: s})
+			case parser.VarStmt:
+				env.addVar(s)
+				scope.Decls = append(scope.Decls, &Decl{Name: s.Name, Path: m.Path, Kind: VarDecl, Type: env.Vars.Symbols[Symbol(s.Name.Lexeme)], Stmt: s})
+			}
+		}
+		for _, stmt := range m.Stmts {
+			idx.topLevelStmt(env, scope, stmt)
+		}
+
+		for _, d := range scope.Decls {
+			switch s := d.Stmt.(type) {
+			case parser.FunctionStmt:
+				if s.Exported {
+					self.Members = append(self.Members, d)
+				}
+			case parser.VarStmt:
+				if s.Exported {
+					self.Members = append(self.Members, d)
+				}
+			case parser.EnumStmt:
+				if s.Exported {
+					self.Members = append(self.Members, d)
+				}
+			}
+		}
+		mt := moduleExports(env, m)
+		self.Type = mt
+		modules[m] = self
+		exports[m] = mt
+	}
+	return ix
+}
+
+// RefAt returns the reference at a position of the module at path, if
+// there is one.
+func (ix *Index) RefAt(path string, row, col int) *Ref {
+	for i, ref := range ix.Refs {
+		if ref.Path == path && ref.Name.Row == row && ref.Name.Col <= col && col <= ref.Name.Col+len(ref.Name.Lexeme) {
+			return &ix.Refs[i]
+		}
+	}
+	return nil
+}
+
+// RefsTo returns every reference to d, including its declaration.
+func (ix *Index) RefsTo(d *Decl) []Ref {
+	var refs []Ref
+	for _, ref := range ix.Refs {
+		if ref.Decl == d {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// Reachable returns the declarations that roots refer to, directly or
+// through the top-level declarations they refer to, along with roots.
+func (ix *Index) Reachable(roots []*Decl) map[*Decl]bool {
+	uses := map[*Decl][]*Decl{}
+	for _, ref := range ix.Refs {
+		if ref.In != nil && ref.Decl != ref.In {
+			uses[ref.In] = append(uses[ref.In], ref.Decl)
+		}
+	}
+	reached := map[*Decl]bool{}
+	var visit func(d *Decl)
+	visit = func(d *Decl) {
+		if reached[d] {
+			return
+		}
+		reached[d] = true
+		for _, u := range uses[d] {
+			visit(u)
+		}
+	}
+	for _, d := range roots {
+		visit(d)
+	}
+	return reached
+}
+
+// Module returns the top-level declarations of the module at path.
+func (ix *Index) Module(path string) []*Decl {
+	for _, s := range ix.Scopes {
+		if s.Path == path && s.module {
+			return s.Decls
+		}
+	}
+	return nil
+}
+
+// Visible returns the names visible at a position of the module at path,
+// innermost first.
+func (ix *Index) Visible(path string, row, col int) []*Decl {
+	var innermost *Scope
+	for _, s := range ix.Scopes {
+		if s.Path != path {
+			continue
+		}
+		if s.module && innermost == nil {
+			innermost = s
+		}
+		if s.Open.Kind == scanner.LBrace && before(s.Open, row, col) && !before(s.Close, row, col) &&
+			(innermost.module || before(innermost.Open, s.Open.Row, s.Open.Col)) {
+			innermost = s
+		}
+	}
+	var decls []*Decl
+	seen := map[string]bool{}
+	for s := innermost; s != nil; s = s.Parent {
+		for i := len(s.Decls) - 1; i >= 0; i-- {
+			d := s.Decls[i]
+			if seen[d.Name.Lexeme] || !s.module && s.Parent != nil && !declared(d, row, col) {
+				continue
+			}
+			seen[d.Name.Lexeme] = true
+			decls = append(decls, d)
+		}
+	}
+	return decls
+}
+
+// declared reports whether the local d is declared at a position: after
+// its name, and after its initializer if it has one.
+func declared(d *Decl, row, col int) bool {
+	if s, ok := d.Stmt.(parser.VarStmt); ok && s.Expr != nil {
+		end := parser.End(s.Expr)
+		end.Col += end.Len()
+		return before(end, row, col)
+	}
+	return before(d.Name, row, col)
+}
+
+// before reports whether t starts before a position.
+func before(t scanner.Token, row, col int) bool {
+	return t.Row < row || t.Row == row && t.Col < col
+}
+
+type indexer struct {
+	ix   *Index
+	path string
+	// The top-level declaration being indexed.
+	in *Decl
+}
+
+func (idx *indexer) newScope(parent *Scope, blk parser.Block) *Scope {
+	s := &Scope{Path: idx.path, Parent: parent, Open: blk.Open, Close: blk.Close}
+	idx.ix.Scopes = append(idx.ix.Scopes, s)
+	return s
+}
+
+func (idx *indexer) ref(name scanner.Token, d *Decl) {
+	if d != nil {
+		idx.ix.Refs = append(idx.ix.Refs, Ref{Name: name, Path: idx.path, Decl: d, In: idx.in})
+	}
+}
+
+// assign references d as the target of an assignment.
+func (idx *indexer) assign(name scanner.Token, d *Decl) {
+	if d != nil {
+		idx.ix.Refs = append(idx.ix.Refs, Ref{Name: name, Path: idx.path, Decl: d, In: idx.in, Assigned: true})
+	}
+}
+
+// typeName references the declarations named by a type, which may be
+// qualified by a module name.
+func (idx *indexer) typeName(scope *Scope, t scanner.Token) {
+	parts := strings.SplitN(t.Lexeme, ".", 2)
+	d := scope.lookup(parts[0])
+	if d == nil || d.Kind != EnumDecl && d.Kind != ModuleDecl {
+		return
+	}
+	idx.ref(scanner.Token{Kind: scanner.Ident, Lexeme: parts[0], Row: t.Row, Col: t.Col}, d)
+	if len(parts) == 2 {
+		idx.ref(scanner.Token{Kind: scanner.Ident, Lexeme: parts[1], Row: t.Row, Col: t.Col + len(parts[0]) + 1}, member(d, parts[1]))
+	}
+}
+
+func member(d *Decl, name string) *Decl {
+	for _, m := range d.Members {
+		if m.Name.Lexeme == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func (idx *indexer) topLevelStmt(env Env, scope *Scope, stmt parser.Stmt) {
+	switch s := stmt.(type) {
+	case parser.EnumStmt:
+		d := scope.lookup(s.Name.Lexeme)
+		idx.ref(s.Name, d)
+		for i, m := range s.Members {
+			if i < len(d.Members) {
+				idx.ref(m, d.Members[i])
+			}
+		}
+	case parser.FunctionStmt:
+		idx.in = scope.lookup(s.Name.Lexeme)
+		defer func() { idx.in = nil }()
+		idx.ref(s.Name, idx.in)
+		idx.typeName(scope, s.ReturnKind)
+		for _, param := range s.Params {
+			idx.typeName(scope, param.Kind)
+		}
+		if s.Extern {
+			return
+		}
+		e := newEnv(env)
+		body := idx.newScope(scope, s.Body)
+		for _, param := range s.Params {
+			t := e.Types.find(Symbol(param.Kind.Lexeme))
+			e.Vars.Symbols[Symbol(param.Name.Lexeme)] = t
+			d := &Decl{Name: param.Name, Path: idx.path, Kind: ParamDecl, Type: t}
+			body.Decls = append(body.Decls, d)
+			idx.ref(param.Name, d)
+		}
+		idx.stmts(e, body, s.Body.Stmts)
+	case parser.VarStmt:
+		idx.in = scope.lookup(s.Name.Lexeme)
+		defer func() { idx.in = nil }()
+		idx.typeName(scope, s.Kind)
+		idx.ref(s.Name, idx.in)
+		if s.Expr != nil {
+			idx.expr(scope, s.Expr)
+		}
+	}
+}
+
+func (idx *indexer) block(env Env, scope *Scope, blk parser.Block) {
+	idx.stmts(newEnv(env), idx.newScope(scope, blk), blk.Stmts)
+}
+
+func (idx *indexer) stmts(env Env, scope *Scope, stmts []parser.Stmt) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case parser.VarStmt:
+			idx.typeName(scope, s.Kind)
+			t := env.Types.find(Symbol(s.Kind.Lexeme))
+			env.Vars.Symbols[Symbol(s.Name.Lexeme)] = t
+			// The initializer refers to the names that the variable
+			// shadows.
+			if s.Expr != nil {
+				idx.expr(scope, s.Expr)
+			}
+			d := &Decl{Name: s.Name, Path: idx.path, Kind: VarDecl, Type: t, Stmt: s}
+			scope.Decls = append(scope.Decls, d)
+			idx.ref(s.Name, d)
+		case parser.ReturnStmt:
+			if s.Expr != nil {
+				idx.expr(scope, s.Expr)
+			}
+		case parser.AssignStmt:
+			target := s.Target.(scanner.Token)
+			idx.assign(target, scope.lookup(target.Lexeme))
+			idx.expr(scope, s.Expr)
+		case parser.CompoundAssignStmt:
+			idx.assign(s.Target, scope.lookup(s.Target.Lexeme))
+			idx.expr(scope, s.Expr)
+		case parser.IncDecStmt:
+			idx.assign(s.Target, scope.lookup(s.Target.Lexeme))
+		case parser.IfStmt:
+			idx.expr(scope, s.Cond)
+			idx.block(env, scope, s.Then)
+			idx.block(env, scope, s.Els)
+		case parser.WhileStmt:
+			idx.expr(scope, s.Cond)
+			idx.block(env, scope, s.Body)
+		case parser.SwitchStmt:
+			idx.expr(scope, s.Expr)
+			for _, c := range s.Cases {
+				for _, v := range c.Values {
+					idx.expr(scope, v)
+				}
+				idx.block(env, scope, c.Body)
+			}
+		case parser.Block:
+			idx.block(env, scope, s)
+		case parser.FallthroughStmt:
+		default:
+			idx.expr(scope, s)
+		}
+	}
+}
+
+// expr references the names used in e, and returns the declaration e
+// names, if it is a name.
+func (idx *indexer) expr(scope *Scope, e parser.Expr) *Decl {
+	switch n := e.(type) {
+	case parser.IdentExpr:
+		d := scope.lookup(n.Name.Lexeme)
+		idx.ref(n.Name, d)
+		return d
+	case parser.MemberAccess:
+		parent := idx.expr(scope, n.Parent)
+		if parent == nil {
+			return nil
+		}
+		var d *Decl
+		switch parent.Kind {
+		case EnumDecl, ModuleDecl:
+			d = member(parent, n.Name.Lexeme)
+		}
+		idx.ref(n.Name, d)
+		return d
+	case parser.FunctionCall:
+		idx.expr(scope, n.Callee)
+		for _, arg := range n.Args {
+			idx.expr(scope, arg)
+		}
+	case parser.TernaryExpr:
+		idx.expr(scope, n.Cond)
+		idx.expr(scope, n.Then)
+		idx.expr(scope, n.Els)
+	case parser.UnaryOp:
+		idx.expr(scope, n.Expr)
+	case parser.BinaryOp:
+		idx.expr(scope, n.Left)
+		idx.expr(scope, n.Right)
+	case parser.InterpolatedStr:
+		for _, part := range n.Parts {
+			idx.expr(scope, part)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package analysis
+
+import (
+	"io/ioutil"
+	"lang/loader"
+	"path/filepath"
+	"testing"
+)
+
+const indexSrc = `int total = 0;
+
+int add(int a, int b) {
+  int sum = a + b;
+  if (sum > 10) {
+    int sum = sum - 10;
+    total += sum;
+  }
+  total = total + sum;
+  return sum;
+}
+
+enum Color { Red, Green }
+
+Color pick() {
+  return Color.Green;
+}
+`
+
+func newTestIndex(t *testing.T) *Index {
+	t.Helper()
+	mods, err := loader.LoadSource("main.c", indexSrc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewIndex(NewUniverse(ioutil.Discard), mods)
+}
+
+// names returns the names of decls.
+func names(decls []*Decl) []string {
+	var names []string
+	for _, d := range decls {
+		names = append(names, d.Name.Lexeme)
+	}
+	return names
+}
+
+func equalNames(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestIndexDecls(t *testing.T) {
+	ix := newTestIndex(t)
+	decls := ix.Module("main.c")
+	if got, want := names(decls), []string{"Color", "total", "add", "pick"}; !equalNames(got, want) {
+		t.Fatalf("module declares %v, want %v", got, want)
+	}
+	for i, kind := range []DeclKind{EnumDecl, VarDecl, FunctionDecl, FunctionDecl} {
+		if decls[i].Kind != kind || decls[i].Path != "main.c" {
+			t.Errorf("%s has kind %v in %q, want %v in main.c", decls[i].Name.Lexeme, decls[i].Kind, decls[i].Path, kind)
+		}
+	}
+	if got, want := names(decls[0].Members), []string{"Red", "Green"}; !equalNames(got, want) {
+		t.Errorf("Color has members %v, want %v", got, want)
+	}
+	if got, want := decls[2].String(), "int add(int a, int b)"; got != want {
+		t.Errorf("add is %q, want %q", got, want)
+	}
+	if ft, ok := decls[3].Type.(FunctionType); decls[1].Type != Int || !ok || ft.Return != decls[0].Type {
+		t.Errorf("total is %v and pick is %v", decls[1].Type, decls[3].Type)
+	}
+}
+
+func TestIndexRefs(t *testing.T) {
+	ix := newTestIndex(t)
+	decls := ix.Module("main.c")
+	color, total, add := decls[0], decls[1], decls[2]
+
+	type pos struct {
+		row, col int
+		assigned bool
+	}
+	var got []pos
+	for _, ref := range ix.RefsTo(total) {
+		got = append(got, pos{ref.Name.Row, ref.Name.Col, ref.Assigned})
+		if ref.Name.Row > 0 && ref.In != add {
+			t.Errorf("reference to total at %d:%d is in %v, want add", ref.Name.Row+1, ref.Name.Col+1, ref.In)
+		}
+	}
+	want := []pos{{0, 4, false}, {6, 4, true}, {8, 2, true}, {8, 10, false}}
+	if len(got) != len(want) {
+		t.Fatalf("references to total are %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("references to total are %v, want %v", got, want)
+			break
+		}
+	}
+
+	for _, tt := range []struct {
+		row, col int
+		// The row the declaration referred to is on, or -1 if there is no
+		// reference.
+		declRow int
+		decl    *Decl
+	}{
+		{row: 6, col: 13, declRow: 5},
+		// The initializer of the inner sum refers to the outer one.
+		{row: 5, col: 14, declRow: 3},
+		{row: 8, col: 18, declRow: 3},
+		{row: 9, col: 11, declRow: 3},
+		{row: 3, col: 12, declRow: 2},
+		{row: 15, col: 9, decl: color},
+		{row: 15, col: 15, decl: color.Members[1]},
+		{row: 9, col: 4, declRow: -1},
+	} {
+		ref := ix.RefAt("main.c", tt.row, tt.col)
+		switch {
+		case tt.declRow < 0:
+			if ref != nil {
+				t.Errorf("RefAt(%d, %d) = %s, want none", tt.row, tt.col, ref.Name.Lexeme)
+			}
+		case ref == nil:
+			t.Errorf("RefAt(%d, %d) is missing", tt.row, tt.col)
+		case tt.decl != nil && ref.Decl != tt.decl:
+			t.Errorf("RefAt(%d, %d) refers to %v, want %v", tt.row, tt.col, ref.Decl, tt.decl)
+		case tt.decl == nil && ref.Decl.Name.Row != tt.declRow:
+			t.Errorf("RefAt(%d, %d) refers to %v declared on row %d, want row %d", tt.row, tt.col, ref.Decl, ref.Decl.Name.Row, tt.declRow)
+		}
+	}
+}
+
+func TestIndexScopes(t *testing.T) {
+	ix := newTestIndex(t)
+	var inner *Scope
+	for _, s := range ix.Scopes {
+		if len(s.Decls) == 1 && s.Decls[0].Name.Lexeme == "sum" {
+			inner = s
+		}
+	}
+	if inner == nil {
+		t.Fatal("no scope declares only the inner sum")
+	}
+	body := inner.Parent
+	if body == nil || !equalNames(names(body.Decls), []string{"a", "b", "sum"}) {
+		t.Fatalf("the if block's parent declares %v, want a, b and sum", names(body.Decls))
+	}
+	if inner.Open.Row != 4 || inner.Close.Row != 7 || body.Open.Row != 2 || body.Close.Row != 10 {
+		t.Errorf("blocks span rows %d-%d and %d-%d, want 4-7 and 2-10", inner.Open.Row, inner.Close.Row, body.Open.Row, body.Close.Row)
+	}
+	module := body.Parent
+	if module == nil || !module.module || module.Path != "main.c" {
+		t.Fatalf("the function body's parent is not the module")
+	}
+	if module.Parent == nil || module.Parent.Parent != nil || module.Parent.lookup("println") == nil {
+		t.Errorf("the module's parent is not the universe")
+	}
+}
+
+func TestIndexVisible(t *testing.T) {
+	ix := newTestIndex(t)
+	for _, tt := range []struct {
+		row, col int
+		want     []string
+		// The row the first name is declared on.
+		firstRow int
+	}{
+		// Before the outer sum is declared.
+		{row: 3, col: 2, want: []string{"b", "a", "pick", "add", "total", "Color"}, firstRow: 2},
+		// The inner sum is not declared in its own initializer.
+		{row: 5, col: 15, want: []string{"sum", "b", "a", "pick", "add", "total", "Color"}, firstRow: 3},
+		// The inner sum shadows the outer one.
+		{row: 6, col: 4, want: []string{"sum", "b", "a", "pick", "add", "total", "Color"}, firstRow: 5},
+		// After the if block ends.
+		{row: 8, col: 2, want: []string{"sum", "b", "a", "pick", "add", "total", "Color"}, firstRow: 3},
+		{row: 15, col: 2, want: []string{"pick", "add", "total", "Color"}, firstRow: 14},
+		{row: 11, col: 0, want: []string{"pick", "add", "total", "Color"}, firstRow: 14},
+	} {
+		visible := ix.Visible("main.c", tt.row, tt.col)
+		got := names(visible)
+		if len(got) > len(tt.want) {
+			got = got[:len(tt.want)]
+		}
+		if !equalNames(got, tt.want) {
+			t.Errorf("Visible(%d, %d) starts with %v, want %v", tt.row, tt.col, got, tt.want)
+		} else if visible[0].Name.Row != tt.firstRow {
+			t.Errorf("Visible(%d, %d) starts with %s declared on row %d, want row %d", tt.row, tt.col, got[0], visible[0].Name.Row, tt.firstRow)
+		}
+	}
+}
+
+func TestIndexImports(t *testing.T) {
+	path := filepath.Join("..", "testdata", "imports.c")
+	mods, err := loader.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix := NewIndex(NewUniverse(ioutil.Discard), mods)
+	geo := filepath.Join("..", "testdata", "modules", "geo.c")
+	for _, tt := range []struct {
+		row, col int
+		name     string
+		declRow  int
+	}{
+		{row: 7, col: 9, name: "geo", declRow: 0},
+		{row: 7, col: 13, name: "area", declRow: 6},
+		{row: 7, col: 22, name: "Shape", declRow: 2},
+		{row: 7, col: 28, name: "Square", declRow: 2},
+	} {
+		ref := ix.RefAt(path, tt.row, tt.col)
+		if ref == nil {
+			t.Errorf("RefAt(%d, %d) is missing", tt.row, tt.col)
+		} else if d := ref.Decl; d.Name.Lexeme != tt.name || d.Path != geo || d.Name.Row != tt.declRow {
+			t.Errorf("RefAt(%d, %d) refers to %s in %s on row %d, want %s in %s on row %d", tt.row, tt.col, d.Name.Lexeme, d.Path, d.Name.Row, tt.name, geo, tt.declRow)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package analysis
+
+import (
+	"lang/loader"
+	"lang/parser"
+)
+
+// redeclarations reports the names of mods declared more than once in the
+// same scope, at each declaration after the first and at the first, and
+// reports whether there were none. The parameters of a function are in the
+// same scope as the variables declared directly in its body. If
+// WarnShadowing is set in universe, local variables and parameters that
+// hide a variable or parameter of an enclosing scope are reported too.
+func redeclarations(universe Env, envs []Env, mods []*loader.Module) bool {
+	ix := NewIndex(universe, mods)
+	ok := true
+	for i, m := range mods {
+		env := envs[i]
+		for _, s := range ix.Scopes {
+			if s.Path != m.Path {
+				continue
+			}
+			where := "block"
+			if s.module {
+				where = "module"
+			}
+			first := map[string]*Decl{}
+			for _, d := range s.Decls {
+				key := d.Name.Lexeme
+				if d.Kind == EnumDecl {
+					// Types are named apart from values.
+					key = "type " + key
+				}
+				prev, seen := first[key]
+				if !seen {
+					first[key] = d
+					continue
+				}
+				if prev == d {
+					// A module imported more than once, which the checker
+					// reports.
+					continue
+				}
+				env.errorf(d.Name, "%s redeclared in this %s", d.Name.Lexeme, where)
+				env.errorf(prev.Name, "other declaration of %s", d.Name.Lexeme)
+				ok = false
+			}
+			if s.module || !universe.WarnShadowing {
+				continue
+			}
+			for _, d := range s.Decls {
+				outer := shadowed(s, d)
+				if outer == nil {
+					continue
+				}
+				if outer.Path == "" {
+					// Declared in the environment the statements are
+					// checked in, which has no position.
+					env.warnf(d.Name, "%s shadows an earlier %s", d.Name.Lexeme, kindName(outer))
+				} else {
+					env.warnf(d.Name, "%s shadows the %s declared at %d:%d", d.Name.Lexeme, kindName(outer), outer.Name.Row+1, outer.Name.Col+1)
+				}
+			}
+		}
+	}
+	return ok
+}
+
+// stmtRedeclarations is redeclarations for a statement that CheckStmt
+// checks within env, whose variables may be shadowed by those stmt
+// declares.
+func stmtRedeclarations(env Env, stmt parser.Stmt) bool {
+	switch stmt.(type) {
+	case parser.EnumStmt, parser.FunctionStmt, parser.VarStmt:
+	default:
+		// Other statements are indexed as the body of a function.
+		stmt = parser.FunctionStmt{Body: parser.Block{Stmts: []parser.Stmt{stmt}}}
+	}
+	// The path tells the declarations of stmt from those of env, which
+	// have none.
+	m := &loader.Module{Path: "stmt", Stmts: []parser.Stmt{stmt}}
+	return redeclarations(env, []Env{env}, []*loader.Module{m})
+}
+
+// shadowed returns the variable or parameter of a scope enclosing s that
+// d, declared in s, hides.
+func shadowed(s *Scope, d *Decl) *Decl {
+	if d.Kind != VarDecl && d.Kind != ParamDecl {
+		return nil
+	}
+	for p := s.Parent; p != nil; p = p.Parent {
+		for i := len(p.Decls) - 1; i >= 0; i-- {
+			outer := p.Decls[i]
+			if outer.Name.Lexeme == d.Name.Lexeme && (outer.Kind == VarDecl || outer.Kind == ParamDecl) &&
+				(p.module || before(outer.Name, d.Name.Row, d.Name.Col)) {
+				return outer
+			}
+		}
+	}
+	return nil
+}
+
+func kindName(d *Decl) string {
+	if d.Kind == ParamDecl {
+		return "parameter"
+	}
+	return "variable"
+}
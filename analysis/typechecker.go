@@ -2,8 +2,13 @@ package analysis
 
 import (
 	"fmt"
+	"io"
+	"lang/loader"
 	"lang/parser"
+	"lang/prelude"
 	"lang/scanner"
+	"os"
+	"strconv"
 	"strings"
 )
 
@@ -18,15 +23,73 @@ const (
 	Float
 	Int
 	String
+	Void
 )
 
+func (t PrimitiveType) String() string {
+	switch t {
+	case Bool:
+		return "bool"
+	case Float:
+		return "float"
+	case Int:
+		return "int"
+	case String:
+		return "string"
+	case Void:
+		return "void"
+	default:
+		return "PrimitiveType(" + strconv.Itoa(int(t)) + ")"
+	}
+}
+
 type FunctionType struct {
-	Return Type
-	Params []Type
+	Return   Type
+	Params   []Type
+	Variadic bool
+}
+
+func (t FunctionType) String() string {
+	var params []string
+	for _, param := range t.Params {
+		params = append(params, fmt.Sprint(param))
+	}
+	if t.Variadic {
+		params = append(params, "...")
+	}
+	return fmt.Sprintf("%v(%s)", t.Return, strings.Join(params, ", "))
 }
 
 type CompoundType map[Symbol]Type
 
+type EnumType struct {
+	Name    Symbol
+	Members []Symbol
+}
+
+func (t *EnumType) String() string {
+	return string(t.Name)
+}
+
+func (t *EnumType) has(member Symbol) bool {
+	for _, m := range t.Members {
+		if m == member {
+			return true
+		}
+	}
+	return false
+}
+
+type ModuleType struct {
+	Name  Symbol
+	Vars  map[Symbol]Type
+	Types map[Symbol]Type
+}
+
+func (t *ModuleType) String() string {
+	return "module " + string(t.Name)
+}
+
 type SymbolTypesTable struct {
 	Parent  *SymbolTypesTable
 	Symbols map[Symbol]Type
@@ -49,6 +112,15 @@ func (t *SymbolTypesTable) contains(name Symbol) bool {
 type Env struct {
 	Vars  SymbolTypesTable
 	Types SymbolTypesTable
+	out   io.Writer
+	diags *[]Diagnostic
+	// Path of the module being checked, for diagnostics.
+	path string
+	// Rows of the module being checked whose warnings are suppressed.
+	nowarn map[int]bool
+	// WarnShadowing enables warnings about local variables and parameters
+	// that hide a variable of an enclosing scope.
+	WarnShadowing bool
 }
 
 func (e *Env) addFunction(f parser.FunctionStmt) error {
@@ -66,9 +138,47 @@ func (e *Env) addFunction(f parser.FunctionStmt) error {
 		paramTypes = append(paramTypes, e.Types.find(sym))
 	}
 	e.Vars.Symbols[nameSym] = FunctionType{
-		Return: e.Types.find(retSym),
-		Params: paramTypes,
+		Return:   e.Types.find(retSym),
+		Params:   paramTypes,
+		Variadic: f.Variadic,
+	}
+	return nil
+}
+
+// checkAnnotations reports the annotations of f that are unknown, repeated
+// or contradict another.
+func checkAnnotations(env Env, f parser.FunctionStmt) bool {
+	ok := true
+	seen := map[string]bool{}
+	for _, a := range f.Annotations {
+		switch {
+		case a.Lexeme != "inline" && a.Lexeme != "noinline":
+			env.errorf(a, "unknown annotation @%s", a.Lexeme)
+		case seen[a.Lexeme]:
+			env.errorf(a, "duplicate annotation @%s", a.Lexeme)
+		case seen["inline"] || seen["noinline"]:
+			env.errorf(a, "function %s cannot be both @inline and @noinline", f.Name.Lexeme)
+		case f.Extern:
+			env.errorf(a, "extern function %s cannot be annotated @%s", f.Name.Lexeme, a.Lexeme)
+		default:
+			seen[a.Lexeme] = true
+			continue
+		}
+		ok = false
+	}
+	return ok
+}
+
+func (e *Env) addEnum(en parser.EnumStmt) error {
+	t := &EnumType{Name: Symbol(en.Name.Lexeme)}
+	for _, member := range en.Members {
+		sym := Symbol(member.Lexeme)
+		if t.has(sym) {
+			return fmt.Errorf("duplicate member %q in enum %q", sym, t.Name)
+		}
+		t.Members = append(t.Members, sym)
 	}
+	e.Types.Symbols[t.Name] = t
 	return nil
 }
 
@@ -84,20 +194,27 @@ func (e *Env) addVar(v parser.VarStmt) error {
 
 func newEnv(env Env) Env {
 	return Env{
-		Vars:  SymbolTypesTable{Parent: &env.Vars, Symbols: map[Symbol]Type{}},
-		Types: SymbolTypesTable{Parent: &env.Types, Symbols: map[Symbol]Type{}},
+		Vars:   SymbolTypesTable{Parent: &env.Vars, Symbols: map[Symbol]Type{}},
+		Types:  SymbolTypesTable{Parent: &env.Types, Symbols: map[Symbol]Type{}},
+		out:    env.out,
+		diags:  env.diags,
+		path:   env.path,
+		nowarn: env.nowarn,
+
+		WarnShadowing: env.WarnShadowing,
 	}
 }
 
-func Check(stmts []parser.Stmt) bool {
+// NewUniverse returns the environment enclosing every module, which holds
+// the builtin types and the functions declared by the prelude. Callers may
+// add their own predeclared names to it before passing it to CheckIn.
+// Diagnostics found while checking within it are written to out.
+func NewUniverse(out io.Writer) Env {
 	env := Env{
+		out:   out,
+		diags: &[]Diagnostic{},
 		Vars: SymbolTypesTable{
-			Symbols: map[Symbol]Type{
-				"testVars": CompoundType{
-					"someBool": Bool,
-					"someInt":  Int,
-				},
-			},
+			Symbols: map[Symbol]Type{},
 		},
 		Types: SymbolTypesTable{
 			Symbols: map[Symbol]Type{
@@ -105,39 +222,241 @@ func Check(stmts []parser.Stmt) bool {
 				"float":  Float,
 				"int":    Int,
 				"string": String,
+				"void":   Void,
 			},
 		},
 	}
+	for _, stmt := range prelude.Stmts() {
+		if err := env.addFunction(stmt.(parser.FunctionStmt)); err != nil {
+			panic(err)
+		}
+	}
+	return env
+}
+
+// Check type checks mods, which must be ordered so that every module comes
+// after the modules it imports, as returned by loader.Load. Each module gets
+// its own symbol tables, and sees an imported module's exported names only
+// qualified by that module's name. Warnings about a line that ends with a
+// "// nowarn" comment are not reported.
+func Check(mods []*loader.Module) bool {
+	return CheckIn(NewUniverse(os.Stdout), mods)
+}
+
+// CheckIn is like Check, but checks mods within universe.
+func CheckIn(universe Env, mods []*loader.Module) bool {
+	_, ok := CheckModules(universe, mods)
+	return ok
+}
+
+// CheckModules is like CheckIn, but also returns the environment that each
+// of mods was checked in, where the types of its expressions can be found
+// with TypeOf.
+func CheckModules(universe Env, mods []*loader.Module) ([]Env, bool) {
+	exports := map[*loader.Module]*ModuleType{}
+	var envs []Env
+	ok := true
+	for _, m := range mods {
+		env := newEnv(universe)
+		env.path = m.Path
+		env.nowarn = nowarnRows(m.Comments)
+		for i, imported := range m.Imports {
+			mt := exports[imported]
+			if env.Vars.Symbols[mt.Name] != nil {
+				env.errorf(importPos(m, i), "module %s imported more than once", mt.Name)
+				ok = false
+				continue
+			}
+			env.Vars.Symbols[mt.Name] = mt
+			for sym, t := range mt.Types {
+				env.Types.Symbols[mt.Name+"."+sym] = t
+			}
+		}
+		if !checkModule(env, m.Stmts) {
+			ok = false
+		}
+		exports[m] = moduleExports(env, m)
+		envs = append(envs, env)
+	}
+	if !redeclarations(universe, envs, mods) {
+		ok = false
+	}
+	unusedFunctions(universe, envs, mods)
+	unusedNames(universe, envs, mods)
+	return envs, ok
+}
+
+// NewModuleEnv returns an empty module environment enclosed by universe, to
+// which declarations can be added one at a time with CheckStmt.
+func NewModuleEnv(universe Env) Env {
+	return newEnv(universe)
+}
+
+// NewBlockEnv returns an environment for a block enclosed by env, in which
+// the variables the block declares can be added with Declare.
+func NewBlockEnv(env Env) Env {
+	return newEnv(env)
+}
+
+// Declare adds a variable of type t to env.
+func (e Env) Declare(name string, t Type) {
+	e.Vars.Symbols[Symbol(name)] = t
+}
+
+// LookupType returns the type with the given name, which may be qualified
+// by a module name, or nil if there is none.
+func (e Env) LookupType(name string) Type {
+	return e.Types.find(Symbol(name))
+}
+
+// CheckStmt type checks stmt within env, where stmt may be either a
+// top-level declaration or a block statement, and adds whatever it declares
+// to env, even if it does not type check. Names declared more than once in
+// the same scope, and with WarnShadowing those that shadow another, are
+// reported as CheckIn does.
+func CheckStmt(env Env, stmt parser.Stmt) bool {
+	switch s := stmt.(type) {
+	case parser.ModuleStmt, parser.ImportStmt:
+		env.errorf(parser.Pos(s), "modules can only be declared and imported at the top of a file")
+		return false
+	case parser.ReturnStmt:
+		env.errorf(s.Keyword, "return statement outside of a function")
+		return false
+	}
+	ok := stmtRedeclarations(env, stmt)
+	switch s := stmt.(type) {
+	case parser.EnumStmt:
+		if err := env.addEnum(s); err != nil {
+			env.errorf(s.Name, "%v", err)
+			return false
+		}
+		return ok
+	case parser.FunctionStmt:
+		if err := env.addFunction(s); err != nil {
+			env.errorf(s.Name, "%v", err)
+			return false
+		}
+		return checkStmt(env, s, nil) && ok
+	case parser.VarStmt:
+		if err := env.addVar(s); err != nil {
+			env.errorf(s.Kind, "%v", err)
+			return false
+		}
+		return checkStmt(env, s, nil) && ok
+	default:
+		return checkStmt(env, s, nil) && ok
+	}
+}
+
+// TypeOf returns the type of expr within env, or nil if it does not type
+// check.
+func TypeOf(env Env, expr parser.Expr) Type {
+	if t := getType(env, expr); t != nil {
+		switch expr.(type) {
+		case parser.IdentExpr, parser.MemberAccess:
+			return t
+		}
+		if IsType(env, expr, t) {
+			return t
+		}
+		return nil
+	}
+	for _, t := range []Type{Bool, Float, Int, String} {
+		if IsType(env, expr, t) {
+			return t
+		}
+	}
+	return nil
+}
+
+func checkModule(env Env, stmts []parser.Stmt) bool {
 	ok := true
+	for _, stmt := range stmts {
+		if s, isEnum := stmt.(parser.EnumStmt); isEnum {
+			if err := env.addEnum(s); err != nil {
+				env.errorf(s.Name, "%v", err)
+				ok = false
+			}
+		}
+	}
 	for _, stmt := range stmts {
 		switch s := stmt.(type) {
 		case parser.FunctionStmt:
 			if err := env.addFunction(s); err != nil {
-				fmt.Println(err)
+				env.errorf(s.Name, "%v", err)
 				ok = false
 			}
 		case parser.VarStmt:
 			if err := env.addVar(s); err != nil {
-				fmt.Println(err)
+				env.errorf(s.Kind, "%v", err)
 				ok = false
 			}
 		}
 	}
 	for _, stmt := range stmts {
-		if !IsType(env, stmt, nil) {
+		if !checkStmt(env, stmt, nil) {
 			ok = false
 		}
 	}
 	return ok
 }
 
+// importPos returns the position of the i-th import of m.
+func importPos(m *loader.Module, i int) scanner.Token {
+	for _, stmt := range m.Stmts {
+		if imp, ok := stmt.(parser.ImportStmt); ok {
+			if i == 0 {
+				return imp.Path
+			}
+			i--
+		}
+	}
+	return scanner.Token{}
+}
+
+func moduleExports(env Env, m *loader.Module) *ModuleType {
+	mt := &ModuleType{
+		Name:  Symbol(m.Name),
+		Vars:  map[Symbol]Type{},
+		Types: map[Symbol]Type{},
+	}
+	for _, stmt := range m.Stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			if s.Exported {
+				mt.Vars[Symbol(s.Name.Lexeme)] = env.Vars.Symbols[Symbol(s.Name.Lexeme)]
+			}
+		case parser.VarStmt:
+			if s.Exported {
+				mt.Vars[Symbol(s.Name.Lexeme)] = env.Vars.Symbols[Symbol(s.Name.Lexeme)]
+			}
+		case parser.EnumStmt:
+			if s.Exported {
+				mt.Types[Symbol(s.Name.Lexeme)] = env.Types.Symbols[Symbol(s.Name.Lexeme)]
+			}
+		}
+	}
+	return mt
+}
+
 func IsType(env Env, node interface{}, expected Type) bool {
 	switch n := node.(type) {
 	case parser.LiteralBool:
 		return expected == Bool
 	case parser.LiteralStr:
 		return expected == String
+	case parser.InterpolatedStr:
+		ok := expected == String
+		for _, part := range n.Parts {
+			if !isStringable(env, part) {
+				ok = false
+			}
+		}
+		return ok
 	case parser.LiteralNum:
+		if _, ok := Constant(n); !ok {
+			return false
+		}
 		if strings.ContainsRune(n.Value, '.') {
 			return expected == Float
 		}
@@ -146,30 +465,73 @@ func IsType(env Env, node interface{}, expected Type) bool {
 		sym := Symbol(n.Name.Lexeme)
 		return env.Vars.contains(sym) && env.Vars.find(sym) == expected
 	case parser.BinaryOp:
+		if divisionByZero(n) {
+			return false
+		}
 		switch n.Op.Kind {
-		case scanner.Plus, scanner.Minus, scanner.Star, scanner.Slash, scanner.Gt, scanner.Gte, scanner.Lt, scanner.Lte:
-			return IsType(env, n.Left, Int) && IsType(env, n.Right, Int) ||
-				IsType(env, n.Left, Float) && IsType(env, n.Right, Float)
-		case scanner.EqEq:
+		case scanner.Plus, scanner.Minus, scanner.Star, scanner.Slash:
+			return (expected == Int || expected == Float) &&
+				IsType(env, n.Left, expected) && IsType(env, n.Right, expected)
+		case scanner.Gt, scanner.Gte, scanner.Lt, scanner.Lte:
+			return expected == Bool && (IsType(env, n.Left, Int) && IsType(env, n.Right, Int) ||
+				IsType(env, n.Left, Float) && IsType(env, n.Right, Float))
+		case scanner.EqEq, scanner.Ne:
+			if expected != Bool {
+				return false
+			}
+			if enum, isEnum := getType(env, n.Left).(*EnumType); isEnum {
+				return IsType(env, n.Right, enum)
+			}
 			return IsType(env, n.Left, Int) && IsType(env, n.Right, Int) ||
 				IsType(env, n.Left, Float) && IsType(env, n.Right, Float) ||
 				IsType(env, n.Left, Bool) && IsType(env, n.Right, Bool) ||
 				IsType(env, n.Left, String) && IsType(env, n.Right, String)
 		case scanner.LAnd, scanner.LOr:
-			return IsType(env, n.Left, Bool) && IsType(env, n.Right, Bool)
+			return expected == Bool && IsType(env, n.Left, Bool) && IsType(env, n.Right, Bool)
+		case scanner.Percent, scanner.BAnd, scanner.BOr, scanner.BXor, scanner.Shl, scanner.Shr:
+			return expected == Int && IsType(env, n.Left, Int) && IsType(env, n.Right, Int)
 		default:
 			panic("Unknown binary op")
 		}
 	case parser.UnaryOp:
 		switch n.Op.Kind {
 		case scanner.LNot:
-			return IsType(env, n.Expr, Bool)
+			return expected == Bool && IsType(env, n.Expr, Bool)
 		case scanner.Minus:
-			return IsType(env, n.Expr, Int) || IsType(env, n.Expr, Float)
+			return (expected == Int || expected == Float) && IsType(env, n.Expr, expected)
+		case scanner.BNot:
+			return expected == Int && IsType(env, n.Expr, Int)
 		default:
 			panic("Unknown unary op")
 		}
+	case parser.TernaryExpr:
+		return IsType(env, n.Cond, Bool) &&
+			IsType(env, n.Then, expected) &&
+			IsType(env, n.Els, expected)
+	case parser.AssignStmt:
+		target := n.Target.(scanner.Token)
+		t := env.Vars.find(Symbol(target.Lexeme))
+		return t != nil && IsType(env, n.Expr, t)
+	case parser.CompoundAssignStmt:
+		t := env.Vars.find(Symbol(n.Target.Lexeme))
+		op := n.Op
+		op.Kind = parser.CompoundAssignOps[n.Op.Kind]
+		return t != nil && IsType(env, parser.BinaryOp{Op: op, Left: parser.IdentExpr{Name: n.Target}, Right: n.Expr}, t)
+	case parser.IncDecStmt:
+		t := env.Vars.find(Symbol(n.Target.Lexeme))
+		return t == Int || t == Float
+	case parser.ModuleStmt, parser.ImportStmt, parser.EnumStmt:
+		return true
+	case parser.VarStmt:
+		t := env.Vars.find(Symbol(n.Name.Lexeme))
+		return t != nil && (n.Expr == nil || IsType(env, n.Expr, t))
+	case parser.ReturnStmt:
+		if n.Expr == nil {
+			return expected == Void
+		}
+		return IsType(env, n.Expr, expected)
 	case parser.FunctionStmt:
+		ok := checkAnnotations(env, n)
 		e := newEnv(env)
 		retType := e.Types.find(Symbol(n.ReturnKind.Lexeme))
 		for _, param := range n.Params {
@@ -177,31 +539,39 @@ func IsType(env Env, node interface{}, expected Type) bool {
 			nameSym := Symbol(param.Name.Lexeme)
 			e.Vars.Symbols[nameSym] = e.Types.find(typeSym)
 		}
-		return IsType(e, n.Body, retType)
+		if !IsType(e, n.Body, retType) {
+			ok = false
+		}
+		if retType != nil && retType != Void && !n.Extern && !Terminates(n.Body) {
+			env.errorf(n.Body.Close, "missing return")
+			ok = false
+		}
+		return checkAssignments(env, n) && ok
 	case parser.Block:
 		ok := true
 		e := newEnv(env)
+		unreachable(e, n.Stmts)
 		for _, stmt := range n.Stmts {
 			switch s := stmt.(type) {
 			case parser.VarStmt:
 				typeSym := Symbol(s.Kind.Lexeme)
 				nameSym := Symbol(s.Name.Lexeme)
 				if !e.Types.contains(typeSym) {
-					fmt.Printf("unknown variable type %q\n", typeSym)
+					e.errorf(s.Kind, "unknown variable type %q", typeSym)
 					ok = false
 					continue
 				}
 				t := e.Types.find(typeSym)
 				e.Vars.Symbols[nameSym] = t
-				if !IsType(e, s.Expr, t) {
+				if !checkStmt(e, s, nil) {
 					ok = false
 				}
-			case parser.ReturnStmt:
-				if !IsType(e, s.Expr, expected) {
+			case parser.FunctionCall:
+				if !checkStmt(e, s, nil) {
 					ok = false
 				}
 			default:
-				if !IsType(e, s, expected) {
+				if !checkStmt(e, s, expected) {
 					ok = false
 				}
 			}
@@ -209,6 +579,12 @@ func IsType(env Env, node interface{}, expected Type) bool {
 		return ok
 	case parser.IfStmt:
 		ok := IsType(env, n.Cond, Bool)
+		switch cond, _ := Constant(n.Cond); cond {
+		case true:
+			deadBranch(env, n.Els)
+		case false:
+			deadBranch(env, n.Then)
+		}
 		if !IsType(env, n.Then, expected) {
 			ok = false
 		}
@@ -216,8 +592,16 @@ func IsType(env Env, node interface{}, expected Type) bool {
 			ok = false
 		}
 		return ok
+	case parser.SwitchStmt:
+		return checkSwitch(env, n, expected)
+	case parser.FallthroughStmt:
+		env.errorf(n.Keyword, "fallthrough statement out of place")
+		return false
 	case parser.WhileStmt:
 		ok := IsType(env, n.Cond, Bool)
+		if cond, _ := Constant(n.Cond); cond == false {
+			deadBranch(env, n.Body)
+		}
 		if !IsType(env, n.Body, expected) {
 			ok = false
 		}
@@ -230,13 +614,23 @@ func IsType(env Env, node interface{}, expected Type) bool {
 			return true
 		}
 	case parser.FunctionCall:
+		if t := conversionType(env, n); t != nil {
+			return (expected == nil || expected == t) && isConvertible(env, n.Args, t)
+		}
 		functionType := getType(env, n.Callee)
 		if functionIdent, ok := functionType.(FunctionType); ok {
-			if len(functionIdent.Params) != len(n.Args) {
+			if len(n.Args) < len(functionIdent.Params) ||
+				!functionIdent.Variadic && len(n.Args) != len(functionIdent.Params) {
 				return false
 			}
 			for i := range n.Args {
 				arg := n.Args[i]
+				if i >= len(functionIdent.Params) {
+					if !isStringable(env, arg) {
+						return false
+					}
+					continue
+				}
 				param := functionIdent.Params[i]
 				if !IsType(env, arg, param) {
 					return false
@@ -254,19 +648,182 @@ func IsType(env Env, node interface{}, expected Type) bool {
 	}
 }
 
+func checkSwitch(env Env, n parser.SwitchStmt, expected Type) bool {
+	var t Type
+	if enum, isEnum := getType(env, n.Expr).(*EnumType); isEnum {
+		t = enum
+	} else if IsType(env, n.Expr, Int) {
+		t = Int
+	} else if IsType(env, n.Expr, String) {
+		t = String
+	} else {
+		env.errorf(parser.Pos(n.Expr), "can only switch on an int, string or enum")
+		return false
+	}
+	ok := true
+	seen := map[string]bool{}
+	hasDefault := false
+	for i, c := range n.Cases {
+		if c.Default {
+			if hasDefault {
+				env.errorf(n.Keyword, "multiple defaults in switch")
+				ok = false
+			}
+			hasDefault = true
+		}
+		for _, v := range c.Values {
+			if !IsType(env, v, t) {
+				pos, msg := exprError(env, v, t, "in switch case")
+				env.errorf(pos, "%s", msg)
+				ok = false
+				continue
+			}
+			key, isConst := constKey(env, v)
+			if !isConst {
+				env.errorf(parser.Pos(v), "switch case must be a constant")
+				ok = false
+			} else if seen[key] {
+				env.errorf(parser.Pos(v), "duplicate case %s in switch", key)
+				ok = false
+			}
+			seen[key] = true
+		}
+		body := c.Body
+		if len(body.Stmts) > 0 {
+			if _, isFallthrough := body.Stmts[len(body.Stmts)-1].(parser.FallthroughStmt); isFallthrough {
+				if i == len(n.Cases)-1 {
+					env.errorf(parser.Pos(body.Stmts[len(body.Stmts)-1]), "cannot fallthrough final case in switch")
+					ok = false
+				}
+				body.Stmts = body.Stmts[:len(body.Stmts)-1]
+			}
+		}
+		if !IsType(env, body, expected) {
+			ok = false
+		}
+	}
+	if enum, isEnum := t.(*EnumType); isEnum && !hasDefault {
+		var missing []string
+		for _, member := range enum.Members {
+			if !seen[string(member)] {
+				missing = append(missing, string(member))
+			}
+		}
+		if len(missing) > 0 {
+			env.warnf(n.Keyword, "switch on %s is missing cases %s", enum.Name, strings.Join(missing, ", "))
+		}
+	}
+	return ok
+}
+
+func constKey(env Env, e parser.Expr) (string, bool) {
+	switch n := e.(type) {
+	case parser.LiteralNum:
+		if i, err := strconv.ParseInt(n.Value, 10, 64); err == nil {
+			return strconv.FormatInt(i, 10), true
+		}
+	case parser.LiteralStr:
+		return strconv.Quote(n.Value), true
+	case parser.MemberAccess:
+		if _, isEnum := TypeNamed(env, n.Parent).(*EnumType); isEnum {
+			return n.Name.Lexeme, true
+		}
+	case parser.UnaryOp:
+		if lit, ok := n.Expr.(parser.LiteralNum); ok && n.Op.Kind == scanner.Minus {
+			if i, err := strconv.ParseInt(lit.Value, 10, 64); err == nil {
+				return strconv.FormatInt(-i, 10), true
+			}
+		}
+	}
+	return "", false
+}
+
+// TypeNamed returns the type that e names if it is a type name rather than
+// a value, as the callee of a conversion or the parent of an enum member.
+func TypeNamed(env Env, e parser.Expr) Type {
+	switch n := e.(type) {
+	case parser.IdentExpr:
+		sym := Symbol(n.Name.Lexeme)
+		if !env.Vars.contains(sym) {
+			return env.Types.find(sym)
+		}
+	case parser.MemberAccess:
+		if parent, ok := n.Parent.(parser.IdentExpr); ok {
+			if _, isModule := env.Vars.find(Symbol(parent.Name.Lexeme)).(*ModuleType); isModule {
+				return env.Types.find(Symbol(parent.Name.Lexeme + "." + n.Name.Lexeme))
+			}
+		}
+	}
+	return nil
+}
+
+func conversionType(env Env, call parser.FunctionCall) Type {
+	return TypeNamed(env, call.Callee)
+}
+
+func isConvertible(env Env, args []parser.Expr, t Type) bool {
+	if len(args) != 1 {
+		return false
+	}
+	if IsType(env, args[0], t) {
+		return true
+	}
+	if _, isEnum := t.(*EnumType); isEnum {
+		return IsType(env, args[0], Int)
+	}
+	_, fromEnum := getType(env, args[0]).(*EnumType)
+	switch t {
+	case Int:
+		return fromEnum || IsType(env, args[0], Float)
+	case Float:
+		return IsType(env, args[0], Int)
+	case String:
+		return isStringable(env, args[0])
+	default:
+		return false
+	}
+}
+
+func isStringable(env Env, e parser.Expr) bool {
+	if _, isEnum := getType(env, e).(*EnumType); isEnum {
+		return true
+	}
+	return IsType(env, e, String) ||
+		IsType(env, e, Int) ||
+		IsType(env, e, Float) ||
+		IsType(env, e, Bool)
+}
+
 func getType(env Env, node interface{}) Type {
 	switch n := node.(type) {
 	case parser.IdentExpr:
 		sym := Symbol(n.Name.Lexeme)
 		return env.Vars.find(sym)
 	case parser.MemberAccess:
-		parentType := getType(env, n.Parent)
 		memberSym := Symbol(n.Name.Lexeme)
-		if comp, ok := parentType.(CompoundType); ok {
-			return comp[memberSym]
-		} else {
-			panic("Tried to access a member of a non-compound type")
+		if enum, isEnum := TypeNamed(env, n.Parent).(*EnumType); isEnum {
+			if enum.has(memberSym) {
+				return enum
+			}
+			return nil
 		}
+		switch parent := getType(env, n.Parent).(type) {
+		case CompoundType:
+			return parent[memberSym]
+		case *ModuleType:
+			return parent.Vars[memberSym]
+		default:
+			return nil
+		}
+	case parser.FunctionCall:
+		if t := conversionType(env, n); t != nil {
+			return t
+		}
+		if f, ok := getType(env, n.Callee).(FunctionType); ok {
+			return f.Return
+		}
+	case parser.TernaryExpr:
+		return getType(env, n.Then)
 	}
-	panic("Tried to get type of non-member access")
+	return nil
 }
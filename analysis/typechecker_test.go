@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"bytes"
+	"io/ioutil"
+	"lang/parser"
+	"lang/scanner"
+	"testing"
+)
+
+func TestOperatorTypes(t *testing.T) {
+	op := func(kind scanner.TokenKind) scanner.Token {
+		return scanner.Token{Kind: kind}
+	}
+	binary := func(kind scanner.TokenKind, left, right parser.Expr) parser.Expr {
+		return parser.BinaryOp{Op: op(kind), Left: left, Right: right}
+	}
+	unary := func(kind scanner.TokenKind, e parser.Expr) parser.Expr {
+		return parser.UnaryOp{Op: op(kind), Expr: e}
+	}
+	one, two := parser.LiteralNum{Value: "1"}, parser.LiteralNum{Value: "2"}
+	half := parser.LiteralNum{Value: "0.5"}
+	yes := parser.LiteralBool{Value: true}
+
+	env := newEnv(NewUniverse(ioutil.Discard))
+	for _, tt := range []struct {
+		expr parser.Expr
+		want Type
+	}{
+		{expr: binary(scanner.Plus, one, two), want: Int},
+		{expr: binary(scanner.Slash, half, half), want: Float},
+		{expr: binary(scanner.Lt, one, two), want: Bool},
+		{expr: binary(scanner.Gte, half, half), want: Bool},
+		{expr: binary(scanner.EqEq, one, two), want: Bool},
+		{expr: binary(scanner.LAnd, yes, yes), want: Bool},
+		{expr: binary(scanner.Percent, one, two), want: Int},
+		{expr: unary(scanner.LNot, yes), want: Bool},
+		{expr: unary(scanner.Minus, one), want: Int},
+		{expr: unary(scanner.Minus, half), want: Float},
+	} {
+		for _, typ := range []Type{Bool, Float, Int, String} {
+			if got := IsType(env, tt.expr, typ); got != (typ == tt.want) {
+				t.Errorf("IsType(%#v, %v) = %v, want %v", tt.expr, typ, got, !got)
+			}
+		}
+	}
+}
+
+// TestCheckStmtShadowing checks statements one at a time, as the REPL
+// does, with shadowing warnings enabled.
+func TestCheckStmtShadowing(t *testing.T) {
+	var out bytes.Buffer
+	universe := NewUniverse(&out)
+	universe.WarnShadowing = true
+	env := NewModuleEnv(universe)
+	for _, tt := range []struct {
+		src  string
+		ok   bool
+		want string
+	}{
+		{src: "int x = 1;", ok: true},
+		{src: "int f(int x) { return x; }", ok: true, want: "1:11: warning: x shadows an earlier variable\n"},
+		{src: "void g(int a) { if (true) { int a = 1; } }", ok: true, want: "1:33: warning: a shadows the parameter declared at 1:12\n"},
+		{src: "if (true) { int y = 1; int y = 2; }", ok: false, want: "1:28: y redeclared in this block\n1:17: other declaration of y\n"},
+	} {
+		out.Reset()
+		p := parser.Parser{Tokens: scanner.Scan(tt.src)}
+		ok := true
+		for _, stmt := range p.ConsumeStmts() {
+			ok = CheckStmt(env, stmt) && ok
+		}
+		if ok != tt.ok || out.String() != tt.want {
+			t.Errorf("%s: got %v and %q, want %v and %q", tt.src, ok, out.String(), tt.ok, tt.want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package analysis
+
+import (
+	"lang/loader"
+	"lang/scanner"
+	"sort"
+	"strings"
+)
+
+// unusedNames reports the local variables, parameters and imports of each
+// of mods that are never used. Assigning to a variable does not use it.
+// Names starting with an underscore are never reported.
+func unusedNames(universe Env, envs []Env, mods []*loader.Module) {
+	ix := NewIndex(universe, mods)
+	// The paths of the modules that use each declaration, and the paths of
+	// the imports of each module, which are its references as strings.
+	used := map[*Decl]map[string]bool{}
+	var imports []Ref
+	for _, ref := range ix.Refs {
+		switch {
+		case ref.Name.Kind == scanner.Str:
+			imports = append(imports, ref)
+		case ref.Assigned, ref.Path == ref.Decl.Path && ref.Name == ref.Decl.Name:
+		default:
+			if used[ref.Decl] == nil {
+				used[ref.Decl] = map[string]bool{}
+			}
+			used[ref.Decl][ref.Path] = true
+		}
+	}
+	for i, m := range mods {
+		var unused []Ref
+		for _, s := range ix.Scopes {
+			if s.Path != m.Path || s.module {
+				continue
+			}
+			for _, d := range s.Decls {
+				if !used[d][m.Path] {
+					unused = append(unused, Ref{Name: d.Name, Decl: d})
+				}
+			}
+		}
+		for _, ref := range imports {
+			if ref.Path == m.Path && !used[ref.Decl][m.Path] {
+				unused = append(unused, ref)
+			}
+		}
+		sort.SliceStable(unused, func(i, j int) bool {
+			return before(unused[i].Name, unused[j].Name.Row, unused[j].Name.Col)
+		})
+		for _, ref := range unused {
+			name := ref.Decl.Name.Lexeme
+			switch {
+			case strings.HasPrefix(name, "_"):
+			case ref.Decl.Kind == ModuleDecl:
+				envs[i].warnf(ref.Name, "import %s is unused", name)
+			case ref.Decl.Kind == ParamDecl:
+				envs[i].warnf(ref.Name, "parameter %s is unused", name)
+			default:
+				envs[i].warnf(ref.Name, "variable %s is unused", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package astjson converts token streams and syntax trees to and from JSON,
+// so that tools not written in Go can read and produce them.
+//
+// Every token and node is an object whose "kind" is the name of its Go type,
+// or of its scanner.TokenKind for a token, and whose "span" gives the
+// 0-based row and byte column where it starts and just past where it ends.
+// The remaining keys are the node's fields in lower camel case, except
+// that the type of a declaration is "type" and the else branch of an if
+// statement or ternary expression is "else". Tokens that a node lacks,
+// such as the braces of the block of an else if, are left out, as are the
+// annotations of a function without any.
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"lang/parser"
+	"lang/scanner"
+)
+
+// Version is the version of the schema that is written, which changes
+// whenever a change to it could break a reader.
+const Version = 1
+
+type position struct {
+	Row int `json:"row"`
+	Col int `json:"col"`
+}
+
+type span struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type token struct {
+	Kind   string `json:"kind"`
+	Lexeme string `json:"lexeme,omitempty"`
+	Span   span   `json:"span"`
+}
+
+type tokensFile struct {
+	Version int     `json:"version"`
+	Tokens  []token `json:"tokens"`
+}
+
+type stmtsFile struct {
+	Version int               `json:"version"`
+	Stmts   []json.RawMessage `json:"stmts"`
+}
+
+var tokenKinds = map[string]scanner.TokenKind{}
+
+func init() {
+	for k := scanner.Ident; k <= scanner.Eof; k++ {
+		tokenKinds[k.String()] = k
+	}
+}
+
+// MarshalTokens returns the JSON form of a token stream.
+func MarshalTokens(tokens []scanner.Token) ([]byte, error) {
+	f := tokensFile{Version, []token{}}
+	for _, t := range tokens {
+		f.Tokens = append(f.Tokens, encodeToken(t))
+	}
+	return marshal(f)
+}
+
+// UnmarshalTokens parses the JSON form of a token stream.
+func UnmarshalTokens(data []byte) ([]scanner.Token, error) {
+	var f tokensFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("unsupported version %d", f.Version)
+	}
+	var tokens []scanner.Token
+	for _, t := range f.Tokens {
+		kind, ok := tokenKinds[t.Kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown token kind %q", t.Kind)
+		}
+		tokens = append(tokens, scanner.Token{Kind: kind, Lexeme: t.Lexeme, Row: t.Span.Start.Row, Col: t.Span.Start.Col})
+	}
+	return tokens, nil
+}
+
+// Marshal returns the JSON form of the statements of a module.
+func Marshal(stmts []parser.Stmt) ([]byte, error) {
+	nodes := []interface{}{}
+	for _, stmt := range stmts {
+		nodes = append(nodes, encode(stmt))
+	}
+	return marshal(object{{"version", Version}, {"stmts", nodes}})
+}
+
+// Unmarshal parses the JSON form of the statements of a module.
+func Unmarshal(data []byte) (stmts []parser.Stmt, err error) {
+	var f stmtsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("unsupported version %d", f.Version)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			msg, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			stmts, err = nil, fmt.Errorf("%s", msg)
+		}
+	}()
+	for _, raw := range f.Stmts {
+		stmts = append(stmts, decode(raw))
+	}
+	return stmts, nil
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// object is a JSON object that keeps its keys in order.
+type object []field
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.key)
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
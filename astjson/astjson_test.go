@@ -0,0 +1,27 @@
+package astjson
+
+import "testing"
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, tt := range []struct {
+		data   string
+		tokens bool
+		want   string
+	}{
+		{data: `{"version": 2, "tokens": []}`, tokens: true, want: "unsupported version 2"},
+		{data: `{"tokens": []}`, tokens: true, want: "unsupported version 0"},
+		{data: `{"version": 1, "tokens": [{"kind": "Nope"}]}`, tokens: true, want: `unknown token kind "Nope"`},
+		{data: `{"version": 2, "stmts": []}`, want: "unsupported version 2"},
+		{data: `{"version": 1, "stmts": [{"kind": "Nope"}]}`, want: `unknown node kind "Nope"`},
+	} {
+		var err error
+		if tt.tokens {
+			_, err = UnmarshalTokens([]byte(tt.data))
+		} else {
+			_, err = Unmarshal([]byte(tt.data))
+		}
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("decoding %s: got error %v, want %s", tt.data, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"lang/parser"
+	"lang/scanner"
+)
+
+// fields are the keys of a JSON object being decoded. Decoding panics with
+// a message if one does not hold what it should.
+type fields map[string]json.RawMessage
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
+
+func decodeFields(raw json.RawMessage) fields {
+	var f fields
+	if err := json.Unmarshal(raw, &f); err != nil {
+		panic(err.Error())
+	}
+	return f
+}
+
+func (f fields) get(key string, v interface{}) {
+	if isNull(f[key]) {
+		return
+	}
+	if err := json.Unmarshal(f[key], v); err != nil {
+		panic(fmt.Sprintf("invalid %s: %v", key, err))
+	}
+}
+
+func (f fields) string(key string) string {
+	var s string
+	f.get(key, &s)
+	return s
+}
+
+func (f fields) bool(key string) bool {
+	var b bool
+	f.get(key, &b)
+	return b
+}
+
+func (f fields) token(key string) scanner.Token {
+	if isNull(f[key]) {
+		return scanner.Token{}
+	}
+	return decodeToken(f[key])
+}
+
+func decodeToken(raw json.RawMessage) scanner.Token {
+	var t token
+	if err := json.Unmarshal(raw, &t); err != nil {
+		panic(fmt.Sprintf("invalid token: %v", err))
+	}
+	kind, ok := tokenKinds[t.Kind]
+	if !ok {
+		panic(fmt.Sprintf("unknown token kind %q", t.Kind))
+	}
+	return scanner.Token{Kind: kind, Lexeme: t.Lexeme, Row: t.Span.Start.Row, Col: t.Span.Start.Col}
+}
+
+func (f fields) tokens(key string) []scanner.Token {
+	var raws []json.RawMessage
+	f.get(key, &raws)
+	var tokens []scanner.Token
+	for _, raw := range raws {
+		tokens = append(tokens, decodeToken(raw))
+	}
+	return tokens
+}
+
+func (f fields) node(key string) interface{} {
+	return decode(f[key])
+}
+
+func (f fields) stmts(key string) []parser.Stmt {
+	var raws []json.RawMessage
+	f.get(key, &raws)
+	var stmts []parser.Stmt
+	for _, raw := range raws {
+		stmts = append(stmts, decode(raw))
+	}
+	return stmts
+}
+
+func (f fields) exprs(key string) []parser.Expr {
+	var raws []json.RawMessage
+	f.get(key, &raws)
+	var exprs []parser.Expr
+	for _, raw := range raws {
+		exprs = append(exprs, decode(raw))
+	}
+	return exprs
+}
+
+// commentLists decodes the comments before each element of a list, which
+// are nil if key is missing.
+func (f fields) commentLists(key string) [][]parser.Comment {
+	var raws [][]json.RawMessage
+	f.get(key, &raws)
+	var lists [][]parser.Comment
+	for _, list := range raws {
+		var comments []parser.Comment
+		for _, raw := range list {
+			c, ok := decode(raw).(parser.Comment)
+			if !ok {
+				panic(fmt.Sprintf("%s holds something other than comments", key))
+			}
+			comments = append(comments, c)
+		}
+		lists = append(lists, comments)
+	}
+	return lists
+}
+
+func (f fields) block(key string) parser.Block {
+	if isNull(f[key]) {
+		return parser.Block{}
+	}
+	b, ok := decode(f[key]).(parser.Block)
+	if !ok {
+		panic(fmt.Sprintf("%s is not a Block", key))
+	}
+	return b
+}
+
+func decode(raw json.RawMessage) interface{} {
+	if isNull(raw) {
+		return nil
+	}
+	f := decodeFields(raw)
+	switch kind := f.string("kind"); kind {
+	case "ModuleStmt":
+		return parser.ModuleStmt{Name: f.token("name")}
+	case "ImportStmt":
+		return parser.ImportStmt{Path: f.token("path")}
+	case "Block":
+		return parser.Block{Stmts: f.stmts("stmts"), Open: f.token("open"), Close: f.token("close")}
+	case "FunctionStmt":
+		var raws []json.RawMessage
+		f.get("params", &raws)
+		var params []parser.FunctionParam
+		for _, raw := range raws {
+			p := decodeFields(raw)
+			params = append(params, parser.FunctionParam{Kind: p.token("type"), Name: p.token("name")})
+		}
+		return parser.FunctionStmt{
+			ReturnKind:  f.token("returnType"),
+			Name:        f.token("name"),
+			Params:      params,
+			Variadic:    f.bool("variadic"),
+			Body:        f.block("body"),
+			Exported:    f.bool("exported"),
+			Extern:      f.bool("extern"),
+			Annotations: f.tokens("annotations"),
+		}
+	case "EnumStmt":
+		return parser.EnumStmt{Name: f.token("name"), Members: f.tokens("members"), Exported: f.bool("exported"), Comments: f.commentLists("comments")}
+	case "ReturnStmt":
+		return parser.ReturnStmt{Keyword: f.token("keyword"), Expr: f.node("expr")}
+	case "AssignStmt":
+		return parser.AssignStmt{Target: f.token("target"), Expr: f.node("expr")}
+	case "CompoundAssignStmt":
+		return parser.CompoundAssignStmt{Op: f.token("op"), Target: f.token("target"), Expr: f.node("expr")}
+	case "IncDecStmt":
+		return parser.IncDecStmt{Op: f.token("op"), Target: f.token("target")}
+	case "VarStmt":
+		return parser.VarStmt{Kind: f.token("type"), Name: f.token("name"), Expr: f.node("expr"), Exported: f.bool("exported")}
+	case "IfStmt":
+		return parser.IfStmt{Keyword: f.token("keyword"), Cond: f.node("cond"), Then: f.block("then"), Els: f.block("else")}
+	case "SwitchStmt":
+		var raws []json.RawMessage
+		f.get("cases", &raws)
+		var cases []parser.SwitchCase
+		for _, raw := range raws {
+			c := decodeFields(raw)
+			cases = append(cases, parser.SwitchCase{Comments: c.stmts("comments"), Values: c.exprs("values"), Default: c.bool("default"), Body: c.block("body")})
+		}
+		return parser.SwitchStmt{Keyword: f.token("keyword"), Expr: f.node("expr"), Cases: cases}
+	case "FallthroughStmt":
+		return parser.FallthroughStmt{Keyword: f.token("keyword")}
+	case "WhileStmt":
+		return parser.WhileStmt{Keyword: f.token("keyword"), Cond: f.node("cond"), Body: f.block("body")}
+	case "MemberAccess":
+		return parser.MemberAccess{Parent: f.node("parent"), Name: f.token("name")}
+	case "FunctionCall":
+		return parser.FunctionCall{Callee: f.node("callee"), Args: f.exprs("args"), Close: f.token("close"), Comments: f.commentLists("comments")}
+	case "TernaryExpr":
+		return parser.TernaryExpr{Cond: f.node("cond"), Then: f.node("then"), Els: f.node("else")}
+	case "UnaryOp":
+		return parser.UnaryOp{Op: f.token("op"), Expr: f.node("expr")}
+	case "BinaryOp":
+		return parser.BinaryOp{Op: f.token("op"), Left: f.node("left"), Right: f.node("right")}
+	case "LiteralStr":
+		return parser.LiteralStr{Value: f.string("value"), Token: f.token("token")}
+	case "InterpolatedStr":
+		return parser.InterpolatedStr{Parts: f.exprs("parts")}
+	case "LiteralNum":
+		return parser.LiteralNum{Value: f.string("value"), Token: f.token("token")}
+	case "LiteralBool":
+		return parser.LiteralBool{Value: f.bool("value"), Token: f.token("token")}
+	case "LiteralNull":
+		return parser.LiteralNull{Token: f.token("token")}
+	case "IdentExpr":
+		return parser.IdentExpr{Name: f.token("name")}
+	case "Comment":
+		return parser.Comment{Text: f.token("text"), Trailing: f.bool("trailing")}
+	case "BlankLine":
+		return parser.BlankLine{}
+	default:
+		panic(fmt.Sprintf("unknown node kind %q", kind))
+	}
+}
@@ -0,0 +1,172 @@
+package astjson
+
+import (
+	"lang/parser"
+	"lang/scanner"
+	"reflect"
+)
+
+func encodeToken(t scanner.Token) token {
+	return token{t.Kind.String(), t.Lexeme, span{start(t), end(t)}}
+}
+
+func start(t scanner.Token) position {
+	return position{t.Row, t.Col}
+}
+
+func end(t scanner.Token) position {
+	return position{t.Row, t.Col + t.Len()}
+}
+
+func (o object) with(key string, value interface{}) object {
+	return append(o, field{key, value})
+}
+
+// withToken adds t unless the node lacks it.
+func (o object) withToken(key string, t scanner.Token) object {
+	if t == (scanner.Token{}) {
+		return o
+	}
+	return o.with(key, encodeToken(t))
+}
+
+func encode(node interface{}) interface{} {
+	if node == nil {
+		return nil
+	}
+	o := object{{"kind", reflect.TypeOf(node).Name()}}
+	if _, ok := node.(parser.BlankLine); !ok {
+		o = o.with("span", span{start(parser.Pos(node)), end(parser.End(node))})
+	}
+	switch n := node.(type) {
+	case parser.ModuleStmt:
+		return o.withToken("name", n.Name)
+	case parser.ImportStmt:
+		return o.withToken("path", n.Path)
+	case parser.Block:
+		return o.withToken("open", n.Open).with("stmts", encodeStmts(n.Stmts)).withToken("close", n.Close)
+	case parser.FunctionStmt:
+		params := []interface{}{}
+		for _, p := range n.Params {
+			params = append(params, object{}.withToken("type", p.Kind).withToken("name", p.Name))
+		}
+		o = o.withToken("returnType", n.ReturnKind).withToken("name", n.Name).with("params", params).with("variadic", n.Variadic)
+		if !n.Extern {
+			o = o.with("body", encode(n.Body))
+		}
+		o = o.with("exported", n.Exported).with("extern", n.Extern)
+		if len(n.Annotations) > 0 {
+			annotations := []token{}
+			for _, a := range n.Annotations {
+				annotations = append(annotations, encodeToken(a))
+			}
+			o = o.with("annotations", annotations)
+		}
+		return o
+	case parser.EnumStmt:
+		members := []token{}
+		for _, m := range n.Members {
+			members = append(members, encodeToken(m))
+		}
+		o = o.withToken("name", n.Name).with("members", members).with("exported", n.Exported)
+		if n.Comments != nil {
+			o = o.with("comments", encodeCommentLists(n.Comments))
+		}
+		return o
+	case parser.ReturnStmt:
+		return o.withToken("keyword", n.Keyword).with("expr", encode(n.Expr))
+	case parser.AssignStmt:
+		return o.withToken("target", n.Target.(scanner.Token)).with("expr", encode(n.Expr))
+	case parser.CompoundAssignStmt:
+		return o.withToken("op", n.Op).withToken("target", n.Target).with("expr", encode(n.Expr))
+	case parser.IncDecStmt:
+		return o.withToken("op", n.Op).withToken("target", n.Target)
+	case parser.VarStmt:
+		return o.withToken("type", n.Kind).withToken("name", n.Name).with("expr", encode(n.Expr)).with("exported", n.Exported)
+	case parser.IfStmt:
+		o = o.withToken("keyword", n.Keyword).with("cond", encode(n.Cond)).with("then", encode(n.Then))
+		if isEmpty(n.Els) {
+			return o.with("else", nil)
+		}
+		return o.with("else", encode(n.Els))
+	case parser.SwitchStmt:
+		cases := []interface{}{}
+		for _, c := range n.Cases {
+			co := object{}
+			if c.Comments != nil {
+				co = co.with("comments", encodeStmts(c.Comments))
+			}
+			cases = append(cases, co.with("values", encodeExprs(c.Values)).with("default", c.Default).with("body", encode(c.Body)))
+		}
+		return o.withToken("keyword", n.Keyword).with("expr", encode(n.Expr)).with("cases", cases)
+	case parser.FallthroughStmt:
+		return o.withToken("keyword", n.Keyword)
+	case parser.WhileStmt:
+		return o.withToken("keyword", n.Keyword).with("cond", encode(n.Cond)).with("body", encode(n.Body))
+	case parser.MemberAccess:
+		return o.with("parent", encode(n.Parent)).withToken("name", n.Name)
+	case parser.FunctionCall:
+		o = o.with("callee", encode(n.Callee)).with("args", encodeExprs(n.Args))
+		if n.Comments != nil {
+			o = o.with("comments", encodeCommentLists(n.Comments))
+		}
+		return o.withToken("close", n.Close)
+	case parser.TernaryExpr:
+		return o.with("cond", encode(n.Cond)).with("then", encode(n.Then)).with("else", encode(n.Els))
+	case parser.UnaryOp:
+		return o.withToken("op", n.Op).with("expr", encode(n.Expr))
+	case parser.BinaryOp:
+		return o.withToken("op", n.Op).with("left", encode(n.Left)).with("right", encode(n.Right))
+	case parser.LiteralStr:
+		return o.with("value", n.Value).withToken("token", n.Token)
+	case parser.InterpolatedStr:
+		return o.with("parts", encodeExprs(n.Parts))
+	case parser.LiteralNum:
+		return o.with("value", n.Value).withToken("token", n.Token)
+	case parser.LiteralBool:
+		return o.with("value", n.Value).withToken("token", n.Token)
+	case parser.LiteralNull:
+		return o.withToken("token", n.Token)
+	case parser.IdentExpr:
+		return o.withToken("name", n.Name)
+	case parser.Comment:
+		return o.withToken("text", n.Text).with("trailing", n.Trailing)
+	case parser.BlankLine:
+		return o
+	default:
+		panic("unknown node " + reflect.TypeOf(node).String())
+	}
+}
+
+func encodeStmts(stmts []parser.Stmt) []interface{} {
+	nodes := []interface{}{}
+	for _, s := range stmts {
+		nodes = append(nodes, encode(s))
+	}
+	return nodes
+}
+
+func encodeExprs(exprs []parser.Expr) []interface{} {
+	nodes := []interface{}{}
+	for _, e := range exprs {
+		nodes = append(nodes, encode(e))
+	}
+	return nodes
+}
+
+// encodeCommentLists encodes the comments before each element of a list.
+func encodeCommentLists(lists [][]parser.Comment) []interface{} {
+	nodes := []interface{}{}
+	for _, comments := range lists {
+		list := []interface{}{}
+		for _, c := range comments {
+			list = append(list, encode(c))
+		}
+		nodes = append(nodes, list)
+	}
+	return nodes
+}
+
+func isEmpty(b parser.Block) bool {
+	return len(b.Stmts) == 0 && b.Open == (scanner.Token{})
+}
@@ -0,0 +1,639 @@
+// Package amd64 translates type checked programs to x86-64 assembly for
+// Linux, in the syntax of the GNU assembler, and builds executables from
+// them with the system's C compiler.
+//
+// Functions follow the System V ABI, so ints, bools, enums and strings are
+// passed and returned in general purpose registers, and floats in SSE
+// registers. A string is the address of a lang_string, as defined by the
+// runtime of programs compiled to C, which is linked with the program.
+// Extern functions are called the same way, so in C they take and return
+// strings as const lang_string * and bools as int64_t.
+package amd64
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/codegen/c"
+	"lang/loader"
+	"lang/parser"
+	"lang/prelude"
+	"lang/scanner"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed runtime/runtime.c
+var runtime string
+
+var (
+	intRegs   = []string{"%rdi", "%rsi", "%rdx", "%rcx", "%r8", "%r9"}
+	floatRegs = []string{"%xmm0", "%xmm1", "%xmm2", "%xmm3", "%xmm4", "%xmm5", "%xmm6", "%xmm7"}
+)
+
+type generator struct {
+	out  *bytes.Buffer
+	mods []*loader.Module
+	// Names of the builtin functions, which the runtime implements.
+	builtins map[string]bool
+	// Constants, and the labels of those already written.
+	rodata   *bytes.Buffer
+	relro    *bytes.Buffer
+	strings  map[string]string
+	cstrings map[string]string
+	floats   map[uint64]string
+	// Labels of the tables of each enum's member names.
+	enums  map[*analysis.EnumType]string
+	labels int
+
+	mod     *loader.Module
+	env     analysis.Env
+	globals map[string]bool
+	// Offsets from %rbp of the variables declared in each enclosing block
+	// of the function being generated, innermost last.
+	locals []map[string]int
+	slots  int
+	// The number of bytes pushed onto the stack since the frame of the
+	// function being generated was set up.
+	depth int
+	ret   string
+}
+
+// Generate writes mods, ordered as returned by loader.Load, as an assembly
+// program whose main function calls the main function of the last module
+// with its command line arguments. If that returns an int, it is the exit
+// status.
+func Generate(w io.Writer, mods []*loader.Module) error {
+	envs, ok := analysis.CheckModules(analysis.NewUniverse(ioutil.Discard), mods)
+	if !ok {
+		return errors.New("program does not type check")
+	}
+	g := &generator{
+		out:      &bytes.Buffer{},
+		mods:     mods,
+		builtins: map[string]bool{},
+		rodata:   &bytes.Buffer{},
+		relro:    &bytes.Buffer{},
+		strings:  map[string]string{},
+		cstrings: map[string]string{},
+		floats:   map[uint64]string{},
+		enums:    map[*analysis.EnumType]string{},
+	}
+	for _, stmt := range prelude.Stmts() {
+		g.builtins[stmt.(parser.FunctionStmt).Name.Lexeme] = true
+	}
+	entry := mods[len(mods)-1]
+	main := findFunction(entry, "main")
+	if main == nil {
+		return fmt.Errorf("%s: no main function", entry.Path)
+	}
+
+	g.out.WriteString("# Code generated by lang build. DO NOT EDIT.\n")
+	for i, m := range mods {
+		g.enter(m, envs[i])
+		g.module()
+	}
+	g.entry(entry, *main)
+	g.out.WriteString("\n\t.section .rodata\n")
+	g.out.Write(g.rodata.Bytes())
+	g.out.WriteString("\n\t.section .data.rel.ro\n")
+	g.out.Write(g.relro.Bytes())
+	g.out.WriteString("\n\t.section .note.GNU-stack,\"\",@progbits\n")
+	_, err := w.Write(g.out.Bytes())
+	return err
+}
+
+// Build writes an executable for mods to path. The program is assembled
+// and linked with its runtime by the C compiler named by $CC, or cc.
+func Build(path string, mods []*loader.Module) error {
+	var asm bytes.Buffer
+	if err := Generate(&asm, mods); err != nil {
+		return err
+	}
+	dir, err := ioutil.TempDir("", "lang")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"program.s": asm.String(),
+		"runtime.h": c.Runtime,
+		"runtime.c": runtime,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "cc"
+	}
+	cmd := exec.Command(cc, "-O2", "-o", path, filepath.Join(dir, "program.s"), filepath.Join(dir, "runtime.c"), "-lm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v\n%s", cc, err, out)
+	}
+	return nil
+}
+
+func findFunction(m *loader.Module, name string) *parser.FunctionStmt {
+	for _, stmt := range m.Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && f.Name.Lexeme == name {
+			return &f
+		}
+	}
+	return nil
+}
+
+func (g *generator) enter(m *loader.Module, env analysis.Env) {
+	g.mod = m
+	g.env = env
+	g.globals = map[string]bool{}
+	for _, stmt := range m.Stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			g.globals[s.Name.Lexeme] = true
+		case parser.VarStmt:
+			g.globals[s.Name.Lexeme] = true
+		}
+	}
+}
+
+// ins writes an instruction.
+func (g *generator) ins(format string, args ...interface{}) {
+	g.out.WriteByte('\t')
+	fmt.Fprintf(g.out, format, args...)
+	g.out.WriteByte('\n')
+}
+
+func (g *generator) label() string {
+	g.labels++
+	return fmt.Sprintf(".L%d", g.labels)
+}
+
+func (g *generator) place(label string) {
+	fmt.Fprintf(g.out, "%s:\n", label)
+}
+
+// str returns the label of a lang_string holding s.
+func (g *generator) str(s string) string {
+	if label, ok := g.strings[s]; ok {
+		return label
+	}
+	label := g.label()
+	fmt.Fprintf(g.rodata, "%s.bytes:\n\t.ascii %s\n", label, asmString(s))
+	fmt.Fprintf(g.relro, "\t.align 8\n%s:\n\t.quad %s.bytes\n\t.quad %d\n", label, label, len(s))
+	g.strings[s] = label
+	return label
+}
+
+// cstr returns the label of s as a NUL-terminated C string.
+func (g *generator) cstr(s string) string {
+	if label, ok := g.cstrings[s]; ok {
+		return label
+	}
+	label := g.label()
+	fmt.Fprintf(g.rodata, "%s:\n\t.asciz %s\n", label, asmString(s))
+	g.cstrings[s] = label
+	return label
+}
+
+func (g *generator) float(f float64) string {
+	bits := math.Float64bits(f)
+	if label, ok := g.floats[bits]; ok {
+		return label
+	}
+	label := g.label()
+	fmt.Fprintf(g.rodata, "\t.align 8\n%s:\n\t.quad %#x # %v\n", label, bits, f)
+	g.floats[bits] = label
+	return label
+}
+
+// asmString returns s as a string for the GNU assembler.
+func asmString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// module writes the current module's variables, enums and functions, and
+// a function that initializes its variables.
+func (g *generator) module() {
+	fmt.Fprintf(g.out, "\n# module %s\n", g.mod.Name)
+	for _, stmt := range g.mod.Stmts {
+		switch s := stmt.(type) {
+		case parser.EnumStmt:
+			table := g.global(s.Name.Lexeme) + "_names"
+			var entries string
+			for _, m := range s.Members {
+				// A copy of the lang_string of each name, so that the
+				// table can be indexed.
+				entries += fmt.Sprintf("\t.quad %s.bytes\n\t.quad %d\n", g.str(m.Lexeme), len(m.Lexeme))
+			}
+			fmt.Fprintf(g.relro, "\t.align 8\n%s:\n%s", table, entries)
+			g.enums[g.env.LookupType(s.Name.Lexeme).(*analysis.EnumType)] = table
+		case parser.VarStmt:
+			fmt.Fprintf(g.out, "\n\t.bss\n\t.align 8\n%s:\n\t.zero 8\n", g.global(s.Name.Lexeme))
+		}
+	}
+
+	g.function("lang_init_"+cName(g.mod.Name), nil, func() {
+		for _, stmt := range g.mod.Stmts {
+			if s, ok := stmt.(parser.VarStmt); ok {
+				t := g.env.LookupType(s.Kind.Lexeme)
+				g.value(s, t)
+				g.store(g.global(s.Name.Lexeme)+"(%rip)", t)
+			}
+		}
+	})
+
+	for _, stmt := range g.mod.Stmts {
+		f, ok := stmt.(parser.FunctionStmt)
+		if !ok || f.Extern {
+			continue
+		}
+		env := g.env
+		g.env = analysis.NewBlockEnv(env)
+		g.function(g.global(f.Name.Lexeme), f.Params, func() {
+			ret := g.env.LookupType(f.ReturnKind.Lexeme)
+			g.stmts(f.Body.Stmts, ret)
+			if ret != analysis.Void && !analysis.Terminates(f.Body) {
+				g.zero(ret)
+			}
+		})
+		g.env = env
+	}
+}
+
+// function writes a function called name, whose body is written by body.
+// Its parameters are stored in the stack frame, along with its variables.
+func (g *generator) function(name string, params []parser.FunctionParam, body func()) {
+	out := g.out
+	g.out = &bytes.Buffer{}
+	g.locals = []map[string]int{{}}
+	g.slots = 0
+	g.depth = 0
+	g.ret = g.label()
+	var types []analysis.Type
+	for _, p := range params {
+		types = append(types, g.env.LookupType(p.Kind.Lexeme))
+	}
+	regs, stack := classify(types)
+	for i, p := range params {
+		if i < len(regs) && regs[i] != "" {
+			offset := g.declare(p.Name.Lexeme, types[i])
+			if types[i] == analysis.Float {
+				g.ins("movsd %s, %d(%%rbp)", regs[i], offset)
+			} else {
+				g.ins("movq %s, %d(%%rbp)", regs[i], offset)
+			}
+		}
+	}
+	for k, i := range stack {
+		// Arguments passed on the stack are above the return address.
+		g.env.Declare(params[i].Name.Lexeme, types[i])
+		g.locals[0][params[i].Name.Lexeme] = 16 + 8*k
+	}
+	body()
+	code := g.out
+	g.out = out
+
+	fmt.Fprintf(g.out, "\n\t.text\n%s:\n", name)
+	g.ins("pushq %%rbp")
+	g.ins("movq %%rsp, %%rbp")
+	if frame := (g.slots*8 + 15) / 16 * 16; frame > 0 {
+		g.ins("subq $%d, %%rsp", frame)
+	}
+	g.out.Write(code.Bytes())
+	g.place(g.ret)
+	g.ins("leave")
+	g.ins("ret")
+}
+
+// classify returns the register that each argument of the given types is
+// passed in, or "" for those passed on the stack, whose indexes are also
+// returned in order.
+func classify(types []analysis.Type) (regs []string, stack []int) {
+	ints, floats := 0, 0
+	for i, t := range types {
+		switch {
+		case t == analysis.Float && floats < len(floatRegs):
+			regs = append(regs, floatRegs[floats])
+			floats++
+		case t != analysis.Float && ints < len(intRegs):
+			regs = append(regs, intRegs[ints])
+			ints++
+		default:
+			regs = append(regs, "")
+			stack = append(stack, i)
+		}
+	}
+	return regs, stack
+}
+
+// entry writes the C main function, which checks and parses the command
+// line arguments and passes them to the main function of m.
+func (g *generator) entry(m *loader.Module, main parser.FunctionStmt) {
+	g.enter(m, g.env)
+	var params string
+	var args []arg
+	for i, p := range main.Params {
+		params += " " + p.Name.Lexeme
+		t := g.env.LookupType(p.Kind.Lexeme)
+		i, kind := i, p.Kind.Lexeme
+		args = append(args, arg{t, func() {
+			g.ins("movq %d(%%rbx), %%rdi", 8*(i+1))
+			g.runtime("lang_asm_arg_" + kind)
+		}})
+	}
+	usage := g.cstr(params)
+	ok := g.label()
+
+	fmt.Fprintf(g.out, "\n# entry\n\n\t.text\n\t.globl main\nmain:\n")
+	g.ins("pushq %%rbp")
+	g.ins("movq %%rsp, %%rbp")
+	g.ins("pushq %%rbx")
+	g.ins("subq $8, %%rsp")
+	g.depth = 0
+	g.ins("movq %%rsi, %%rbx")
+	g.ins("cmpl $%d, %%edi", len(main.Params)+1)
+	g.ins("je %s", ok)
+	g.ins("movq (%%rsi), %%rdi")
+	g.ins("leaq %s(%%rip), %%rsi", usage)
+	g.runtime("lang_asm_usage")
+	g.place(ok)
+	for _, mod := range g.mods {
+		g.ins("call lang_init_%s", cName(mod.Name))
+	}
+	g.callArgs(g.global("main"), args)
+	if g.env.LookupType(main.ReturnKind.Lexeme) != analysis.Int {
+		g.ins("xorl %%eax, %%eax")
+	}
+	g.ins("movq -8(%%rbp), %%rbx")
+	g.ins("leave")
+	g.ins("ret")
+}
+
+// global returns the symbol of a top-level name in the current module.
+func (g *generator) global(name string) string {
+	return cName(g.mod.Name) + "__" + name
+}
+
+// cName makes a module name usable in a symbol.
+func cName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// zero loads the zero value of type t.
+func (g *generator) zero(t analysis.Type) {
+	switch t {
+	case analysis.Float:
+		g.ins("xorpd %%xmm0, %%xmm0")
+	case analysis.String:
+		g.ins("leaq %s(%%rip), %%rax", g.str(""))
+	default:
+		g.ins("xorl %%eax, %%eax")
+	}
+}
+
+// value loads the initial value of a variable of type t.
+func (g *generator) value(s parser.VarStmt, t analysis.Type) {
+	if s.Expr == nil {
+		g.zero(t)
+	} else {
+		g.expr(s.Expr)
+	}
+}
+
+// declare gives a variable of type t a slot in the stack frame, and
+// returns its offset from %rbp.
+func (g *generator) declare(name string, t analysis.Type) int {
+	g.env.Declare(name, t)
+	g.slots++
+	offset := -8 * g.slots
+	g.locals[len(g.locals)-1][name] = offset
+	return offset
+}
+
+// variable returns the address of the variable called name.
+func (g *generator) variable(name string) string {
+	for i := len(g.locals) - 1; i >= 0; i-- {
+		if offset, ok := g.locals[i][name]; ok {
+			return fmt.Sprintf("%d(%%rbp)", offset)
+		}
+	}
+	return g.global(name) + "(%rip)"
+}
+
+func (g *generator) isLocal(name string) bool {
+	for _, scope := range g.locals {
+		if _, ok := scope[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// load loads the value of type t at addr into %rax, or %xmm0 if it is a
+// float, which is where expressions leave their values.
+func (g *generator) load(addr string, t analysis.Type) {
+	if t == analysis.Float {
+		g.ins("movsd %s, %%xmm0", addr)
+	} else {
+		g.ins("movq %s, %%rax", addr)
+	}
+}
+
+func (g *generator) store(addr string, t analysis.Type) {
+	if t == analysis.Float {
+		g.ins("movsd %%xmm0, %s", addr)
+	} else {
+		g.ins("movq %%rax, %s", addr)
+	}
+}
+
+// push pushes the value of an expression of type t.
+func (g *generator) push(t analysis.Type) {
+	if t == analysis.Float {
+		g.ins("subq $8, %%rsp")
+		g.ins("movsd %%xmm0, (%%rsp)")
+	} else {
+		g.ins("pushq %%rax")
+	}
+	g.depth += 8
+}
+
+// pop pops a value into reg, which is an SSE register if t is float.
+func (g *generator) pop(t analysis.Type, reg string) {
+	if t == analysis.Float {
+		g.ins("movsd (%%rsp), %s", reg)
+		g.ins("addq $8, %%rsp")
+	} else {
+		g.ins("popq %s", reg)
+	}
+	g.depth -= 8
+}
+
+// runtime calls a function whose arguments are already in registers,
+// aligning the stack as the ABI requires.
+func (g *generator) runtime(name string) {
+	if g.depth%16 != 0 {
+		g.ins("subq $8, %%rsp")
+		g.ins("call %s@PLT", name)
+		g.ins("addq $8, %%rsp")
+	} else {
+		g.ins("call %s@PLT", name)
+	}
+}
+
+func (g *generator) block(b parser.Block, ret analysis.Type) {
+	env := g.env
+	g.env = analysis.NewBlockEnv(env)
+	g.locals = append(g.locals, map[string]int{})
+	g.stmts(b.Stmts, ret)
+	g.locals = g.locals[:len(g.locals)-1]
+	g.env = env
+}
+
+func (g *generator) stmts(stmts []parser.Stmt, ret analysis.Type) {
+	for _, stmt := range stmts {
+		g.stmt(stmt, ret)
+	}
+}
+
+func (g *generator) stmt(stmt parser.Stmt, ret analysis.Type) {
+	switch s := stmt.(type) {
+	case parser.VarStmt:
+		t := g.env.LookupType(s.Kind.Lexeme)
+		g.value(s, t)
+		g.store(fmt.Sprintf("%d(%%rbp)", g.declare(s.Name.Lexeme, t)), t)
+	case parser.AssignStmt:
+		name := s.Target.(scanner.Token).Lexeme
+		g.expr(s.Expr)
+		g.store(g.variable(name), analysis.TypeOf(g.env, s.Expr))
+	case parser.CompoundAssignStmt:
+		op := s.Op
+		op.Kind = parser.CompoundAssignOps[s.Op.Kind]
+		value := parser.BinaryOp{Op: op, Left: parser.IdentExpr{Name: s.Target}, Right: s.Expr}
+		g.expr(value)
+		g.store(g.variable(s.Target.Lexeme), analysis.TypeOf(g.env, value))
+	case parser.IncDecStmt:
+		addr := g.variable(s.Target.Lexeme)
+		op := "add"
+		if s.Op.Kind == scanner.Dec {
+			op = "sub"
+		}
+		if analysis.TypeOf(g.env, parser.IdentExpr{Name: s.Target}) == analysis.Float {
+			g.ins("movsd %s, %%xmm0", addr)
+			g.ins("%ssd %s(%%rip), %%xmm0", op, g.float(1))
+			g.ins("movsd %%xmm0, %s", addr)
+		} else {
+			g.ins("%sq $1, %s", op, addr)
+		}
+	case parser.ReturnStmt:
+		if call, ok := s.Expr.(parser.FunctionCall); ok && g.tailCall(call) {
+			break
+		}
+		if s.Expr != nil {
+			g.expr(s.Expr)
+		}
+		g.ins("jmp %s", g.ret)
+	case parser.IfStmt:
+		els, end := g.label(), g.label()
+		g.expr(s.Cond)
+		g.ins("testq %%rax, %%rax")
+		g.ins("je %s", els)
+		g.block(s.Then, ret)
+		if len(s.Els.Stmts) > 0 {
+			g.ins("jmp %s", end)
+			g.place(els)
+			g.block(s.Els, ret)
+			g.place(end)
+		} else {
+			g.place(els)
+		}
+	case parser.WhileStmt:
+		top, end := g.label(), g.label()
+		g.place(top)
+		g.expr(s.Cond)
+		g.ins("testq %%rax, %%rax")
+		g.ins("je %s", end)
+		g.block(s.Body, ret)
+		g.ins("jmp %s", top)
+		g.place(end)
+	case parser.SwitchStmt:
+		g.switchStmt(s, ret)
+	case parser.Block:
+		g.block(s, ret)
+	default:
+		g.expr(s)
+	}
+}
+
+// switchStmt writes a switch as jumps to the bodies of its cases, which
+// end by jumping past the others unless they fall through.
+func (g *generator) switchStmt(s parser.SwitchStmt, ret analysis.Type) {
+	t := analysis.TypeOf(g.env, s.Expr)
+	g.expr(s.Expr)
+	g.slots++
+	value := fmt.Sprintf("%d(%%rbp)", -8*g.slots)
+	g.store(value, t)
+	end := g.label()
+	otherwise := end
+	var bodies []string
+	for _, c := range s.Cases {
+		body := g.label()
+		bodies = append(bodies, body)
+		for _, v := range c.Values {
+			g.expr(v)
+			g.equal(t, value)
+			g.ins("testq %%rax, %%rax")
+			g.ins("jne %s", body)
+		}
+		if c.Default {
+			otherwise = body
+		}
+	}
+	g.ins("jmp %s", otherwise)
+	for i, c := range s.Cases {
+		g.place(bodies[i])
+		body := c.Body
+		fallsThrough := false
+		if n := len(body.Stmts); n > 0 {
+			_, fallsThrough = body.Stmts[n-1].(parser.FallthroughStmt)
+			if fallsThrough {
+				body.Stmts = body.Stmts[:n-1]
+			}
+		}
+		g.block(body, ret)
+		if !fallsThrough && i < len(s.Cases)-1 && !analysis.Terminates(body) {
+			g.ins("jmp %s", end)
+		}
+	}
+	g.place(end)
+}
@@ -0,0 +1,35 @@
+package amd64
+
+import (
+	"lang/codegen/codegentest"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"testing"
+)
+
+// TestGenerate compares the assembly generated for each of the backends'
+// test programs against the golden file with the same name and a .s
+// extension.
+func TestGenerate(t *testing.T) {
+	codegentest.Golden(t, ".s", nil, Generate)
+}
+
+// TestRun builds an executable for each program that the interpreter
+// runs, as lang build -target=elf does, and checks that it behaves as it
+// does in the interpreter.
+func TestRun(t *testing.T) {
+	if goruntime.GOOS != "linux" || goruntime.GOARCH != "amd64" {
+		t.Skip("not an x86-64 Linux system")
+	}
+	cc := os.Getenv("CC")
+	if cc == "" {
+		cc = "cc"
+	}
+	for _, tool := range []string{"as", cc} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	codegentest.Run(t, nil, Build)
+}
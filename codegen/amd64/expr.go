@@ -0,0 +1,443 @@
+package amd64
+
+import (
+	"fmt"
+	"lang/analysis"
+	"lang/parser"
+	"lang/scanner"
+	"math"
+	"strconv"
+)
+
+var (
+	// intOps are the instructions for binary operators on ints, which take
+	// the left operand in %rax and the right in %rcx. Those that can fail
+	// are runtime functions.
+	intOps = map[scanner.TokenKind]string{
+		scanner.Plus:    "addq %rcx, %rax",
+		scanner.Minus:   "subq %rcx, %rax",
+		scanner.Star:    "imulq %rcx, %rax",
+		scanner.BAnd:    "andq %rcx, %rax",
+		scanner.BOr:     "orq %rcx, %rax",
+		scanner.BXor:    "xorq %rcx, %rax",
+		scanner.Slash:   "lang_asm_div",
+		scanner.Percent: "lang_asm_mod",
+		scanner.Shl:     "lang_asm_shl",
+		scanner.Shr:     "lang_asm_shr",
+	}
+	intCompares = map[scanner.TokenKind]string{
+		scanner.Lt:   "setl",
+		scanner.Lte:  "setle",
+		scanner.Gt:   "setg",
+		scanner.Gte:  "setge",
+		scanner.EqEq: "sete",
+		scanner.Ne:   "setne",
+	}
+	// floatOps are the instructions for binary operators on floats, which
+	// take the left operand in %xmm1 and the right in %xmm0.
+	floatOps = map[scanner.TokenKind]string{
+		scanner.Plus:  "addsd",
+		scanner.Minus: "subsd",
+		scanner.Star:  "mulsd",
+		scanner.Slash: "divsd",
+	}
+)
+
+// kinds are the lang_kind of each type of argument to lang_asm_printf.
+var kinds = map[analysis.Type]int{
+	analysis.Int:    0,
+	analysis.Float:  1,
+	analysis.Bool:   2,
+	analysis.String: 3,
+}
+
+// arg is an argument to a function, whose value is loaded by code.
+type arg struct {
+	t    analysis.Type
+	code func()
+}
+
+// expr writes code that leaves the value of e in %xmm0 if it is a float,
+// and otherwise in %rax.
+func (g *generator) expr(e parser.Expr) {
+	switch n := e.(type) {
+	case parser.LiteralNum:
+		if analysis.TypeOf(g.env, n) == analysis.Float {
+			f, _ := strconv.ParseFloat(n.Value, 64)
+			g.ins("movsd %s(%%rip), %%xmm0", g.float(f))
+			return
+		}
+		i, _ := strconv.ParseInt(n.Value, 10, 64)
+		g.integer(i)
+	case parser.LiteralStr:
+		g.ins("leaq %s(%%rip), %%rax", g.str(n.Value))
+	case parser.LiteralBool:
+		if n.Value {
+			g.ins("movl $1, %%eax")
+		} else {
+			g.ins("xorl %%eax, %%eax")
+		}
+	case parser.IdentExpr:
+		g.load(g.variable(n.Name.Lexeme), analysis.TypeOf(g.env, n))
+	case parser.MemberAccess:
+		if enum, isEnum := analysis.TypeNamed(g.env, n.Parent).(*analysis.EnumType); isEnum {
+			for i, m := range enum.Members {
+				if string(m) == n.Name.Lexeme {
+					g.integer(int64(i))
+				}
+			}
+			return
+		}
+		mt := analysis.TypeOf(g.env, n.Parent).(*analysis.ModuleType)
+		g.load(cName(string(mt.Name))+"__"+n.Name.Lexeme+"(%rip)", analysis.TypeOf(g.env, n))
+	case parser.FunctionCall:
+		g.call(n)
+	case parser.UnaryOp:
+		g.expr(n.Expr)
+		switch n.Op.Kind {
+		case scanner.Minus:
+			if analysis.TypeOf(g.env, n) == analysis.Float {
+				g.ins("movq %%xmm0, %%rax")
+				g.ins("btcq $63, %%rax")
+				g.ins("movq %%rax, %%xmm0")
+			} else {
+				g.ins("negq %%rax")
+			}
+		case scanner.LNot:
+			g.ins("xorq $1, %%rax")
+		default:
+			g.ins("notq %%rax")
+		}
+	case parser.BinaryOp:
+		g.binary(n)
+	case parser.TernaryExpr:
+		els, end := g.label(), g.label()
+		g.expr(n.Cond)
+		g.ins("testq %%rax, %%rax")
+		g.ins("je %s", els)
+		g.expr(n.Then)
+		g.ins("jmp %s", end)
+		g.place(els)
+		g.expr(n.Els)
+		g.place(end)
+	case parser.InterpolatedStr:
+		first := true
+		for _, part := range n.Parts {
+			if lit, ok := part.(parser.LiteralStr); ok && lit.Value == "" {
+				continue
+			}
+			if !first {
+				g.push(analysis.String)
+			}
+			g.expr(part)
+			g.toString(analysis.TypeOf(g.env, part))
+			if !first {
+				g.ins("movq %%rax, %%rsi")
+				g.pop(analysis.String, "%rdi")
+				g.runtime("lang_asm_concat")
+			}
+			first = false
+		}
+		if first {
+			g.ins("leaq %s(%%rip), %%rax", g.str(""))
+		}
+	default:
+		panic(fmt.Sprintf("cannot generate assembly for %T", e))
+	}
+}
+
+// integer loads the constant i.
+func (g *generator) integer(i int64) {
+	switch {
+	case i == 0:
+		g.ins("xorl %%eax, %%eax")
+	case i < math.MinInt32 || i > math.MaxInt32:
+		g.ins("movabsq $%d, %%rax", i)
+	default:
+		g.ins("movq $%d, %%rax", i)
+	}
+}
+
+func (g *generator) binary(n parser.BinaryOp) {
+	if n.Op.Kind == scanner.LAnd || n.Op.Kind == scanner.LOr {
+		end := g.label()
+		g.expr(n.Left)
+		g.ins("testq %%rax, %%rax")
+		if n.Op.Kind == scanner.LAnd {
+			g.ins("je %s", end)
+		} else {
+			g.ins("jne %s", end)
+		}
+		g.expr(n.Right)
+		g.place(end)
+		return
+	}
+	t := analysis.TypeOf(g.env, n.Left)
+	g.expr(n.Left)
+	g.push(t)
+	g.expr(n.Right)
+	switch t {
+	case analysis.Float:
+		g.pop(t, "%xmm1")
+		if op, ok := floatOps[n.Op.Kind]; ok {
+			g.ins("%s %%xmm0, %%xmm1", op)
+			g.ins("movapd %%xmm1, %%xmm0")
+		} else {
+			g.compareFloats(n.Op.Kind)
+		}
+	case analysis.String:
+		g.ins("movq %%rax, %%rsi")
+		g.pop(t, "%rdi")
+		g.runtime("lang_asm_streq")
+		if n.Op.Kind == scanner.Ne {
+			g.ins("xorq $1, %%rax")
+		}
+	default:
+		g.ins("movq %%rax, %%rcx")
+		g.pop(t, "%rax")
+		if set, ok := intCompares[n.Op.Kind]; ok {
+			g.ins("cmpq %%rcx, %%rax")
+			g.ins("%s %%al", set)
+			g.ins("movzbq %%al, %%rax")
+		} else if op := intOps[n.Op.Kind]; op[0] == 'l' {
+			g.ins("movq %%rax, %%rdi")
+			g.ins("movq %%rcx, %%rsi")
+			g.runtime(op)
+		} else {
+			g.ins("%s", op)
+		}
+	}
+}
+
+// compareFloats compares %xmm1 with %xmm0, leaving the result in %rax.
+// Comparisons with NaN are false, except for !=.
+func (g *generator) compareFloats(op scanner.TokenKind) {
+	switch op {
+	case scanner.Lt:
+		g.ins("ucomisd %%xmm1, %%xmm0")
+		g.ins("seta %%al")
+	case scanner.Lte:
+		g.ins("ucomisd %%xmm1, %%xmm0")
+		g.ins("setae %%al")
+	case scanner.Gt:
+		g.ins("ucomisd %%xmm0, %%xmm1")
+		g.ins("seta %%al")
+	case scanner.Gte:
+		g.ins("ucomisd %%xmm0, %%xmm1")
+		g.ins("setae %%al")
+	case scanner.EqEq:
+		g.ins("ucomisd %%xmm0, %%xmm1")
+		g.ins("sete %%al")
+		g.ins("setnp %%cl")
+		g.ins("andb %%cl, %%al")
+	case scanner.Ne:
+		g.ins("ucomisd %%xmm0, %%xmm1")
+		g.ins("setne %%al")
+		g.ins("setp %%cl")
+		g.ins("orb %%cl, %%al")
+	}
+	g.ins("movzbq %%al, %%rax")
+}
+
+// equal compares the value of a case, of type t, with the value being
+// switched on at addr, leaving 1 in %rax if they are equal and 0 otherwise.
+func (g *generator) equal(t analysis.Type, addr string) {
+	if t == analysis.String {
+		g.ins("movq %%rax, %%rsi")
+		g.ins("movq %s, %%rdi", addr)
+		g.runtime("lang_asm_streq")
+		return
+	}
+	g.ins("cmpq %%rax, %s", addr)
+	g.ins("sete %%al")
+	g.ins("movzbq %%al, %%rax")
+}
+
+func (g *generator) call(n parser.FunctionCall) {
+	if t := analysis.TypeNamed(g.env, n.Callee); t != nil {
+		g.convert(t, n.Args[0])
+		return
+	}
+	var args []arg
+	for _, e := range n.Args {
+		e := e
+		args = append(args, arg{analysis.TypeOf(g.env, e), func() { g.expr(e) }})
+	}
+	var name string
+	switch callee := n.Callee.(type) {
+	case parser.IdentExpr:
+		name = callee.Name.Lexeme
+		if g.builtins[name] && !g.isLocal(name) && !g.globals[name] {
+			g.builtin(name, n.Args, args)
+			return
+		} else if g.mod.Extern(name) {
+			name += "@PLT"
+		} else {
+			name = g.global(name)
+		}
+	case parser.MemberAccess:
+		mt := analysis.TypeOf(g.env, callee.Parent).(*analysis.ModuleType)
+		name = cName(string(mt.Name)) + "__" + callee.Name.Lexeme
+		for _, m := range g.mods {
+			if m.Name == string(mt.Name) && m.Extern(callee.Name.Lexeme) {
+				name = callee.Name.Lexeme + "@PLT"
+			}
+		}
+	}
+	g.callArgs(name, args)
+}
+
+// tailCall writes n, which is returned by the function being written, as
+// a jump to the function it calls that reuses its stack frame, and reports
+// whether it could. Only calls to functions of the current module whose
+// arguments are all passed in registers can be written so.
+func (g *generator) tailCall(n parser.FunctionCall) bool {
+	callee, ok := n.Callee.(parser.IdentExpr)
+	if !ok || analysis.TypeNamed(g.env, n.Callee) != nil {
+		return false
+	}
+	name := callee.Name.Lexeme
+	if g.builtins[name] && !g.isLocal(name) && !g.globals[name] || g.mod.Extern(name) {
+		return false
+	}
+	var types []analysis.Type
+	for _, e := range n.Args {
+		types = append(types, analysis.TypeOf(g.env, e))
+	}
+	regs, stack := classify(types)
+	if len(stack) > 0 {
+		return false
+	}
+	for i, e := range n.Args {
+		g.expr(e)
+		g.push(types[i])
+	}
+	for i := len(regs) - 1; i >= 0; i-- {
+		g.pop(types[i], regs[i])
+	}
+	g.ins("leave")
+	g.ins("jmp %s", g.global(name))
+	return true
+}
+
+// callArgs calls a function with args. They are evaluated from left to
+// right onto the stack, then those passed in registers are loaded and
+// those passed on the stack are copied below them.
+func (g *generator) callArgs(name string, args []arg) {
+	var types []analysis.Type
+	for _, a := range args {
+		a.code()
+		g.push(a.t)
+		types = append(types, a.t)
+	}
+	regs, stack := classify(types)
+	size := 8 * len(args)
+	if (g.depth+8*len(stack))%16 != 0 {
+		g.ins("subq $8, %%rsp")
+		size += 8
+	}
+	for k := len(stack) - 1; k >= 0; k-- {
+		g.ins("pushq %d(%%rsp)", size-8*(stack[k]+1))
+		size += 8
+	}
+	for i, reg := range regs {
+		if reg == "" {
+			continue
+		}
+		offset := size - 8*(i+1)
+		if types[i] == analysis.Float {
+			g.ins("movsd %d(%%rsp), %s", offset, reg)
+		} else {
+			g.ins("movq %d(%%rsp), %s", offset, reg)
+		}
+	}
+	g.ins("call %s", name)
+	if size > 0 {
+		g.ins("addq $%d, %%rsp", size)
+	}
+	g.depth -= 8 * len(args)
+}
+
+func (g *generator) builtin(name string, exprs []parser.Expr, args []arg) {
+	switch name {
+	case "printf":
+		g.printfCall(exprs)
+	case "sqrt":
+		g.expr(exprs[0])
+		g.ins("sqrtsd %%xmm0, %%xmm0")
+	default:
+		g.callArgs("lang_asm_"+name+"@PLT", args)
+	}
+}
+
+// printfCall pushes the format, then the lang_kind and value of each
+// argument, and passes their address to lang_asm_printf.
+func (g *generator) printfCall(exprs []parser.Expr) {
+	g.expr(exprs[0])
+	g.push(analysis.String)
+	for _, e := range exprs[1:] {
+		t := analysis.TypeOf(g.env, e)
+		g.expr(e)
+		if _, isEnum := t.(*analysis.EnumType); isEnum {
+			g.toString(t)
+			t = analysis.String
+		}
+		g.push(t)
+		g.ins("pushq $%d", kinds[t])
+		g.depth += 8
+	}
+	n := len(exprs) - 1
+	g.ins("movq %d(%%rsp), %%rdi", 16*n)
+	g.ins("movq $%d, %%rsi", n)
+	g.ins("movq %%rsp, %%rdx")
+	g.runtime("lang_asm_printf")
+	g.ins("addq $%d, %%rsp", 16*n+8)
+	g.depth -= 16*n + 8
+}
+
+// toString converts a value of type t to a string.
+func (g *generator) toString(t analysis.Type) {
+	switch t := t.(type) {
+	case *analysis.EnumType:
+		g.ins("shlq $4, %%rax")
+		g.ins("leaq %s(%%rip), %%rcx", g.enums[t])
+		g.ins("addq %%rcx, %%rax")
+	default:
+		switch t {
+		case analysis.Int:
+			g.ins("movq %%rax, %%rdi")
+			g.runtime("lang_asm_int_string")
+		case analysis.Float:
+			g.runtime("lang_asm_float_string")
+		case analysis.Bool:
+			g.ins("movq %%rax, %%rdi")
+			g.runtime("lang_asm_bool_string")
+		}
+	}
+}
+
+// convert converts e to type t.
+func (g *generator) convert(t analysis.Type, e parser.Expr) {
+	g.expr(e)
+	from := analysis.TypeOf(g.env, e)
+	if from == t {
+		return
+	}
+	if enum, isEnum := t.(*analysis.EnumType); isEnum {
+		g.ins("movq %%rax, %%rdi")
+		g.ins("movq $%d, %%rsi", len(enum.Members))
+		g.ins("leaq %s(%%rip), %%rdx", g.cstr(string(enum.Name)))
+		g.runtime("lang_asm_enum")
+		return
+	}
+	switch t {
+	case analysis.Int:
+		if from == analysis.Float {
+			g.runtime("lang_asm_float_to_int")
+		}
+	case analysis.Float:
+		g.ins("cvtsi2sdq %%rax, %%xmm0")
+	default:
+		g.toString(from)
+	}
+}
@@ -0,0 +1,150 @@
+/* Runtime support for programs compiled to x86-64 assembly, which calls
+ * the runtime of programs compiled to C through these functions. Strings
+ * are passed as pointers to lang_string so that every value fits in a
+ * register, and bools as int64_t. */
+#include "runtime.h"
+
+static const lang_string *lang_box(lang_string s) {
+  lang_string *p = (lang_string *)lang_alloc(sizeof *p);
+  *p = s;
+  return p;
+}
+
+int64_t lang_asm_div(int64_t a, int64_t b) {
+  return lang_div(a, b);
+}
+
+int64_t lang_asm_mod(int64_t a, int64_t b) {
+  return lang_mod(a, b);
+}
+
+int64_t lang_asm_shl(int64_t a, int64_t b) {
+  return lang_shl(a, b);
+}
+
+int64_t lang_asm_shr(int64_t a, int64_t b) {
+  return lang_shr(a, b);
+}
+
+int64_t lang_asm_float_to_int(double f) {
+  return lang_float_to_int(f);
+}
+
+int64_t lang_asm_enum(int64_t i, int64_t n, const char *name) {
+  return lang_enum(i, n, name);
+}
+
+const lang_string *lang_asm_int_string(int64_t i) {
+  return lang_box(lang_int_string(i));
+}
+
+const lang_string *lang_asm_float_string(double f) {
+  return lang_box(lang_float_string(f));
+}
+
+const lang_string *lang_asm_bool_string(int64_t b) {
+  return lang_box(lang_bool_string(b != 0));
+}
+
+const lang_string *lang_asm_concat(const lang_string *a, const lang_string *b) {
+  lang_string parts[2];
+  parts[0] = *a;
+  parts[1] = *b;
+  return lang_box(lang_concat(2, parts));
+}
+
+int64_t lang_asm_streq(const lang_string *a, const lang_string *b) {
+  return lang_streq(*a, *b);
+}
+
+void lang_asm_print(const lang_string *s) {
+  lang_print(*s);
+}
+
+void lang_asm_println(const lang_string *s) {
+  lang_println(*s);
+}
+
+int64_t lang_asm_len(const lang_string *s) {
+  return lang_len(*s);
+}
+
+const lang_string *lang_asm_substr(const lang_string *s, int64_t start, int64_t end) {
+  return lang_box(lang_substr(*s, start, end));
+}
+
+int64_t lang_asm_indexOf(const lang_string *s, const lang_string *sub) {
+  return lang_indexOf(*s, *sub);
+}
+
+int64_t lang_asm_splitCount(const lang_string *s, const lang_string *sep) {
+  return lang_splitCount(*s, *sep);
+}
+
+const lang_string *lang_asm_split(const lang_string *s, const lang_string *sep, int64_t i) {
+  return lang_box(lang_split(*s, *sep, i));
+}
+
+int64_t lang_asm_abs(int64_t n) {
+  return lang_abs(n);
+}
+
+double lang_asm_pow(double x, double y) {
+  return lang_pow(x, y);
+}
+
+int64_t lang_asm_parseInt(const lang_string *s) {
+  return lang_parseInt(*s);
+}
+
+double lang_asm_parseFloat(const lang_string *s) {
+  return lang_parseFloat(*s);
+}
+
+/* lang_asm_printf is passed a pair of words for each argument, the last
+ * first, which are its lang_kind and its value. */
+void lang_asm_printf(const lang_string *format, int64_t n, const int64_t *pairs) {
+  lang_value *args = (lang_value *)lang_alloc(sizeof(lang_value) * (size_t)n);
+  int64_t i;
+  for (i = 0; i < n; i++) {
+    const int64_t *pair = pairs + 2 * (n - 1 - i);
+    double f;
+    switch (pair[0]) {
+    case LANG_INT:
+      args[i] = lang_int_value(pair[1]);
+      break;
+    case LANG_FLOAT:
+      memcpy(&f, &pair[1], sizeof f);
+      args[i] = lang_float_value(f);
+      break;
+    case LANG_BOOL:
+      args[i] = lang_bool_value(pair[1] != 0);
+      break;
+    default:
+      args[i] = lang_string_value(*(const lang_string *)(intptr_t)pair[1]);
+    }
+  }
+  lang_printf(*format, (int)n, args);
+}
+
+void lang_asm_usage(const char *program, const char *params) {
+  fprintf(stderr, "usage: %s%s\n", program, params);
+  exit(2);
+}
+
+int64_t lang_asm_arg_int(const char *s) {
+  return lang_parseInt(lang_arg(s));
+}
+
+double lang_asm_arg_float(const char *s) {
+  return lang_parseFloat(lang_arg(s));
+}
+
+int64_t lang_asm_arg_bool(const char *s) {
+  return lang_parse_bool(s);
+}
+
+const lang_string *lang_asm_arg_string(const char *s) {
+  return lang_box(lang_arg(s));
+}
+
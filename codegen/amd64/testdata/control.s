@@ -0,0 +1,313 @@
+# Code generated by lang build. DO NOT EDIT.
+
+# module control
+
+	.text
+lang_init_control:
+	pushq %rbp
+	movq %rsp, %rbp
+.L4:
+	leave
+	ret
+
+	.text
+control__describe:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq %rdi, -8(%rbp)
+	movq -8(%rbp), %rax
+	movq %rax, -16(%rbp)
+	xorl %eax, %eax
+	cmpq %rax, -16(%rbp)
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	jne .L7
+	movq $1, %rax
+	cmpq %rax, -16(%rbp)
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	jne .L8
+	movq $2, %rax
+	cmpq %rax, -16(%rbp)
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	jne .L8
+	jmp .L6
+.L7:
+	leaq .L9(%rip), %rax
+	jmp .L5
+.L8:
+	leaq .L10(%rip), %rax
+	jmp .L5
+.L6:
+	leaq .L11(%rip), %rax
+	jmp .L5
+.L5:
+	leave
+	ret
+
+	.text
+control__main:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $48, %rsp
+	xorl %eax, %eax
+	movq %rax, -8(%rbp)
+	xorl %eax, %eax
+	movq %rax, -16(%rbp)
+.L13:
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $5, %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setl %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L14
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq -8(%rbp), %rax
+	movq %rax, %rcx
+	popq %rax
+	imulq %rcx, %rax
+	movq %rax, -24(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $2, %rax
+	movq %rax, %rcx
+	popq %rax
+	movq %rax, %rdi
+	movq %rcx, %rsi
+	call lang_asm_mod@PLT
+	pushq %rax
+	xorl %eax, %eax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L15
+	movq -16(%rbp), %rax
+	pushq %rax
+	movq -24(%rbp), %rax
+	movq %rax, %rcx
+	popq %rax
+	addq %rcx, %rax
+	movq %rax, -16(%rbp)
+	jmp .L16
+.L15:
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $3, %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L17
+	movq $100, %rax
+	movq %rax, -32(%rbp)
+	movq -32(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	movq %rax, -32(%rbp)
+	jmp .L18
+.L17:
+	addq $1, -16(%rbp)
+.L18:
+.L16:
+	addq $1, -8(%rbp)
+	jmp .L13
+.L14:
+	movq -16(%rbp), %rax
+	movq %rax, -40(%rbp)
+	xorl %eax, %eax
+	cmpq %rax, -40(%rbp)
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	jne .L20
+	movq $21, %rax
+	cmpq %rax, -40(%rbp)
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	jne .L21
+	jmp .L22
+.L20:
+	leaq .L23(%rip), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	jmp .L19
+.L21:
+	leaq .L24(%rip), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+.L22:
+	leaq .L25(%rip), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+.L19:
+	movq $1, %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call control__describe
+	addq $16, %rsp
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	movq $2, %rax
+	movq %rax, %rdi
+	movq $3, %rsi
+	leaq .L26(%rip), %rdx
+	call lang_asm_enum@PLT
+	shlq $4, %rax
+	leaq control__Color_names(%rip), %rcx
+	addq %rcx, %rax
+	pushq %rax
+	leaq .L27(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq $2, %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	movq -16(%rbp), %rax
+	jmp .L12
+.L12:
+	leave
+	ret
+
+# entry
+
+	.text
+	.globl main
+main:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rsi, %rbx
+	cmpl $1, %edi
+	je .L29
+	movq (%rsi), %rdi
+	leaq .L28(%rip), %rsi
+	call lang_asm_usage@PLT
+.L29:
+	call lang_init_control
+	call control__main
+	movq -8(%rbp), %rbx
+	leave
+	ret
+
+	.section .rodata
+.L1.bytes:
+	.ascii "Red"
+.L2.bytes:
+	.ascii "Green"
+.L3.bytes:
+	.ascii "Blue"
+.L9.bytes:
+	.ascii "red"
+.L10.bytes:
+	.ascii "not red"
+.L11.bytes:
+	.ascii "unreachable"
+.L23.bytes:
+	.ascii "zero"
+.L24.bytes:
+	.ascii "twenty-one"
+.L25.bytes:
+	.ascii "default"
+.L26:
+	.asciz "Color"
+.L27.bytes:
+	.ascii " "
+.L28:
+	.asciz ""
+
+	.section .data.rel.ro
+	.align 8
+.L1:
+	.quad .L1.bytes
+	.quad 3
+	.align 8
+.L2:
+	.quad .L2.bytes
+	.quad 5
+	.align 8
+.L3:
+	.quad .L3.bytes
+	.quad 4
+	.align 8
+control__Color_names:
+	.quad .L1.bytes
+	.quad 3
+	.quad .L2.bytes
+	.quad 5
+	.quad .L3.bytes
+	.quad 4
+	.align 8
+.L9:
+	.quad .L9.bytes
+	.quad 3
+	.align 8
+.L10:
+	.quad .L10.bytes
+	.quad 7
+	.align 8
+.L11:
+	.quad .L11.bytes
+	.quad 11
+	.align 8
+.L23:
+	.quad .L23.bytes
+	.quad 4
+	.align 8
+.L24:
+	.quad .L24.bytes
+	.quad 10
+	.align 8
+.L25:
+	.quad .L25.bytes
+	.quad 7
+	.align 8
+.L27:
+	.quad .L27.bytes
+	.quad 1
+
+	.section .note.GNU-stack,"",@progbits
@@ -0,0 +1,507 @@
+# Code generated by lang build. DO NOT EDIT.
+
+# module functions
+
+	.bss
+	.align 8
+functions__calls:
+	.zero 8
+
+	.bss
+	.align 8
+functions__scale:
+	.zero 8
+
+	.text
+lang_init_functions:
+	pushq %rbp
+	movq %rsp, %rbp
+	xorl %eax, %eax
+	movq %rax, functions__calls(%rip)
+	movsd .L2(%rip), %xmm0
+	movsd %xmm0, functions__scale(%rip)
+.L1:
+	leave
+	ret
+
+	.text
+functions__fib:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq %rdi, -8(%rbp)
+	addq $1, functions__calls(%rip)
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $2, %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setl %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L4
+	movq -8(%rbp), %rax
+	jmp .L3
+.L4:
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call functions__fib
+	addq $16, %rsp
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $2, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	pushq %rax
+	movq 0(%rsp), %rdi
+	call functions__fib
+	addq $8, %rsp
+	movq %rax, %rcx
+	popq %rax
+	addq %rcx, %rax
+	jmp .L3
+.L3:
+	leave
+	ret
+
+	.text
+functions__area:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movsd %xmm0, -8(%rbp)
+	movsd .L7(%rip), %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movsd -8(%rbp), %xmm0
+	movsd (%rsp), %xmm1
+	addq $8, %rsp
+	mulsd %xmm0, %xmm1
+	movapd %xmm1, %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movsd -8(%rbp), %xmm0
+	movsd (%rsp), %xmm1
+	addq $8, %rsp
+	mulsd %xmm0, %xmm1
+	movapd %xmm1, %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movsd functions__scale(%rip), %xmm0
+	movsd (%rsp), %xmm1
+	addq $8, %rsp
+	mulsd %xmm0, %xmm1
+	movapd %xmm1, %xmm0
+	jmp .L6
+.L6:
+	leave
+	ret
+
+	.text
+functions__between:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $32, %rsp
+	movq %rdi, -8(%rbp)
+	movq %rsi, -16(%rbp)
+	movq %rdx, -24(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq -16(%rbp), %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setge %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L10
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq -24(%rbp), %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setle %al
+	movzbq %al, %rax
+.L10:
+	testq %rax, %rax
+	jne .L9
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	negq %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+.L9:
+	jmp .L8
+.L8:
+	leave
+	ret
+
+	.text
+functions__main:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq $10, %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call functions__fib
+	addq $16, %rsp
+	movq %rax, -8(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $2, %rax
+	movq %rax, %rcx
+	popq %rax
+	imulq %rcx, %rax
+	movq %rax, -8(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	movq %rax, -8(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	movq %rax, %rdi
+	movq %rcx, %rsi
+	call lang_asm_shl@PLT
+	movq %rax, -8(%rbp)
+	movsd .L12(%rip), %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	subq $8, %rsp
+	movsd 8(%rsp), %xmm0
+	call functions__area
+	addq $16, %rsp
+	movsd %xmm0, -16(%rbp)
+	movsd -16(%rbp), %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movsd .L12(%rip), %xmm0
+	movsd (%rsp), %xmm1
+	addq $8, %rsp
+	divsd %xmm0, %xmm1
+	movapd %xmm1, %xmm0
+	movsd %xmm0, -16(%rbp)
+	movsd -16(%rbp), %xmm0
+	subsd .L13(%rip), %xmm0
+	movsd %xmm0, -16(%rbp)
+	movq -8(%rbp), %rax
+	movq %rax, %rdi
+	call lang_asm_int_string@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq functions__calls(%rip), %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movsd -16(%rbp), %xmm0
+	subq $8, %rsp
+	call lang_asm_float_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	xorl %eax, %eax
+	pushq %rax
+	movq $100, %rax
+	pushq %rax
+	movq 16(%rsp), %rdi
+	movq 8(%rsp), %rsi
+	movq 0(%rsp), %rdx
+	call functions__between
+	addq $24, %rsp
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_bool_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq $5, %rax
+	pushq %rax
+	xorl %eax, %eax
+	pushq %rax
+	movq $10, %rax
+	pushq %rax
+	movq 16(%rsp), %rdi
+	movq 8(%rsp), %rsi
+	movq 0(%rsp), %rdx
+	call functions__between
+	addq $24, %rsp
+	xorq $1, %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_bool_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	movsd -16(%rbp), %xmm0
+	call lang_asm_float_to_int@PLT
+	movq %rax, %rdi
+	call lang_asm_int_string@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -8(%rbp), %rax
+	cvtsi2sdq %rax, %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movsd .L15(%rip), %xmm0
+	movsd (%rsp), %xmm1
+	addq $8, %rsp
+	divsd %xmm0, %xmm1
+	movapd %xmm1, %xmm0
+	subq $8, %rsp
+	call lang_asm_float_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -8(%rbp), %rax
+	negq %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -8(%rbp), %rax
+	notq %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $7, %rax
+	movq %rax, %rcx
+	popq %rax
+	movq %rax, %rdi
+	movq %rcx, %rsi
+	subq $8, %rsp
+	call lang_asm_mod@PLT
+	addq $8, %rsp
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $6, %rax
+	movq %rax, %rcx
+	popq %rax
+	andq %rcx, %rax
+	pushq %rax
+	movq $1, %rax
+	pushq %rax
+	movq $8, %rax
+	movq %rax, %rcx
+	popq %rax
+	xorq %rcx, %rax
+	movq %rax, %rcx
+	popq %rax
+	orq %rcx, %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L14(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $2, %rax
+	movq %rax, %rcx
+	popq %rax
+	movq %rax, %rdi
+	movq %rcx, %rsi
+	subq $8, %rsp
+	call lang_asm_shr@PLT
+	addq $8, %rsp
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $100, %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setg %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L16
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $200, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_abs@PLT
+	addq $16, %rsp
+	jmp .L17
+.L16:
+	xorl %eax, %eax
+.L17:
+	jmp .L11
+.L11:
+	leave
+	ret
+
+# entry
+
+	.text
+	.globl main
+main:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rsi, %rbx
+	cmpl $1, %edi
+	je .L19
+	movq (%rsi), %rdi
+	leaq .L18(%rip), %rsi
+	call lang_asm_usage@PLT
+.L19:
+	call lang_init_functions
+	call functions__main
+	movq -8(%rbp), %rbx
+	leave
+	ret
+
+	.section .rodata
+	.align 8
+.L2:
+	.quad 0x3ff8000000000000 # 1.5
+	.align 8
+.L7:
+	.quad 0x400921f9f01b866e # 3.14159
+	.align 8
+.L12:
+	.quad 0x4000000000000000 # 2
+	.align 8
+.L13:
+	.quad 0x3ff0000000000000 # 1
+.L14.bytes:
+	.ascii " "
+	.align 8
+.L15:
+	.quad 0x4010000000000000 # 4
+.L18:
+	.asciz ""
+
+	.section .data.rel.ro
+	.align 8
+.L14:
+	.quad .L14.bytes
+	.quad 1
+
+	.section .note.GNU-stack,"",@progbits
@@ -0,0 +1,189 @@
+# Code generated by lang build. DO NOT EDIT.
+
+# module loops
+
+	.bss
+	.align 8
+loops__counter:
+	.zero 8
+
+	.text
+lang_init_loops:
+	pushq %rbp
+	movq %rsp, %rbp
+	xorl %eax, %eax
+	movq %rax, loops__counter(%rip)
+.L1:
+	leave
+	ret
+
+	.text
+loops__next:
+	pushq %rbp
+	movq %rsp, %rbp
+	addq $1, loops__counter(%rip)
+	movq loops__counter(%rip), %rax
+	jmp .L2
+.L2:
+	leave
+	ret
+
+	.text
+loops__main:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $48, %rsp
+	movq %rdi, -8(%rbp)
+	movq %rsi, -16(%rbp)
+	xorl %eax, %eax
+	movq %rax, -24(%rbp)
+.L4:
+	movq -24(%rbp), %rax
+	pushq %rax
+	movq -8(%rbp), %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setl %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L6
+	call loops__next
+	pushq %rax
+	movq $100, %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setl %al
+	movzbq %al, %rax
+.L6:
+	testq %rax, %rax
+	je .L5
+	movq -24(%rbp), %rax
+	pushq %rax
+	movq $2, %rax
+	movq %rax, %rcx
+	popq %rax
+	addq %rcx, %rax
+	movq %rax, -24(%rbp)
+	movq -24(%rbp), %rax
+	pushq %rax
+	movq $4, %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L7
+	addq $1, -24(%rbp)
+.L7:
+	jmp .L4
+.L5:
+	movq -24(%rbp), %rax
+	pushq %rax
+	movq $3, %rax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setg %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L9
+	call loops__next
+	pushq %rax
+	xorl %eax, %eax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	setg %al
+	movzbq %al, %rax
+	jmp .L10
+.L9:
+	xorl %eax, %eax
+.L10:
+	movq %rax, -32(%rbp)
+	movq -16(%rbp), %rax
+	movq %rax, -40(%rbp)
+	leaq .L13(%rip), %rax
+	movq %rax, %rsi
+	movq -40(%rbp), %rdi
+	call lang_asm_streq@PLT
+	testq %rax, %rax
+	jne .L12
+	jmp .L11
+.L12:
+	leaq .L14(%rip), %rax
+	pushq %rax
+	movq -32(%rbp), %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_bool_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+.L11:
+	movq -24(%rbp), %rax
+	jmp .L3
+.L3:
+	leave
+	ret
+
+# entry
+
+	.text
+	.globl main
+main:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rsi, %rbx
+	cmpl $3, %edi
+	je .L16
+	movq (%rsi), %rdi
+	leaq .L15(%rip), %rsi
+	call lang_asm_usage@PLT
+.L16:
+	call lang_init_loops
+	movq 8(%rbx), %rdi
+	call lang_asm_arg_int@PLT
+	pushq %rax
+	movq 16(%rbx), %rdi
+	subq $8, %rsp
+	call lang_asm_arg_string@PLT
+	addq $8, %rsp
+	pushq %rax
+	movq 8(%rsp), %rdi
+	movq 0(%rsp), %rsi
+	call loops__main
+	addq $16, %rsp
+	movq -8(%rbp), %rbx
+	leave
+	ret
+
+	.section .rodata
+.L13.bytes:
+	.ascii "a"
+.L14.bytes:
+	.ascii "a "
+.L15:
+	.asciz " n s"
+
+	.section .data.rel.ro
+	.align 8
+.L13:
+	.quad .L13.bytes
+	.quad 1
+	.align 8
+.L14:
+	.quad .L14.bytes
+	.quad 2
+
+	.section .note.GNU-stack,"",@progbits
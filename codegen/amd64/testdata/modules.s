@@ -0,0 +1,201 @@
+# Code generated by lang build. DO NOT EDIT.
+
+# module geo
+
+	.bss
+	.align 8
+geo__sides:
+	.zero 8
+
+	.text
+lang_init_geo:
+	pushq %rbp
+	movq %rsp, %rbp
+	movq $4, %rax
+	movq %rax, geo__sides(%rip)
+.L3:
+	leave
+	ret
+
+	.text
+geo__area:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $32, %rsp
+	movq %rdi, -8(%rbp)
+	movq %rsi, -16(%rbp)
+	movq -8(%rbp), %rax
+	movq %rax, -24(%rbp)
+	xorl %eax, %eax
+	cmpq %rax, -24(%rbp)
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	jne .L6
+	movq $1, %rax
+	cmpq %rax, -24(%rbp)
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	jne .L7
+	jmp .L5
+.L6:
+	movq -16(%rbp), %rax
+	pushq %rax
+	movq -16(%rbp), %rax
+	movq %rax, %rcx
+	popq %rax
+	imulq %rcx, %rax
+	jmp .L4
+.L7:
+	movq -16(%rbp), %rax
+	pushq %rax
+	movq -16(%rbp), %rax
+	movq %rax, %rcx
+	popq %rax
+	imulq %rcx, %rax
+	pushq %rax
+	movq $2, %rax
+	movq %rax, %rcx
+	popq %rax
+	movq %rax, %rdi
+	movq %rcx, %rsi
+	call lang_asm_div@PLT
+	jmp .L4
+.L5:
+	xorl %eax, %eax
+	jmp .L4
+.L4:
+	leave
+	ret
+
+# module main
+
+	.text
+lang_init_main:
+	pushq %rbp
+	movq %rsp, %rbp
+.L8:
+	leave
+	ret
+
+	.text
+main__main:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq $1, %rax
+	movq %rax, -8(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $4, %rax
+	pushq %rax
+	movq 8(%rsp), %rdi
+	movq 0(%rsp), %rsi
+	call geo__area
+	addq $16, %rsp
+	movq %rax, %rdi
+	call lang_asm_int_string@PLT
+	pushq %rax
+	leaq .L10(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq geo__sides(%rip), %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L10(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -8(%rbp), %rax
+	shlq $4, %rax
+	leaq geo__Shape_names(%rip), %rcx
+	addq %rcx, %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	xorl %eax, %eax
+	pushq %rax
+	movq $3, %rax
+	pushq %rax
+	movq 0(%rsp), %rdi
+	call random@PLT
+	addq $8, %rsp
+	pushq %rax
+	movq 8(%rsp), %rdi
+	movq 0(%rsp), %rsi
+	call geo__area
+	addq $16, %rsp
+	jmp .L9
+.L9:
+	leave
+	ret
+
+# entry
+
+	.text
+	.globl main
+main:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rsi, %rbx
+	cmpl $1, %edi
+	je .L12
+	movq (%rsi), %rdi
+	leaq .L11(%rip), %rsi
+	call lang_asm_usage@PLT
+.L12:
+	call lang_init_geo
+	call lang_init_main
+	call main__main
+	movq -8(%rbp), %rbx
+	leave
+	ret
+
+	.section .rodata
+.L1.bytes:
+	.ascii "Square"
+.L2.bytes:
+	.ascii "Triangle"
+.L10.bytes:
+	.ascii " "
+.L11:
+	.asciz ""
+
+	.section .data.rel.ro
+	.align 8
+.L1:
+	.quad .L1.bytes
+	.quad 6
+	.align 8
+.L2:
+	.quad .L2.bytes
+	.quad 8
+	.align 8
+geo__Shape_names:
+	.quad .L1.bytes
+	.quad 6
+	.quad .L2.bytes
+	.quad 8
+	.align 8
+.L10:
+	.quad .L10.bytes
+	.quad 1
+
+	.section .note.GNU-stack,"",@progbits
@@ -0,0 +1,160 @@
+# Code generated by lang build. DO NOT EDIT.
+
+# module printf
+
+	.text
+lang_init_printf:
+	pushq %rbp
+	movq %rsp, %rbp
+.L3:
+	leave
+	ret
+
+	.text
+printf__main:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $32, %rsp
+	movq %rdi, -8(%rbp)
+	movq %rsi, -16(%rbp)
+	movq -8(%rbp), %rax
+	cvtsi2sdq %rax, %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movsd .L5(%rip), %xmm0
+	movsd (%rsp), %xmm1
+	addq $8, %rsp
+	divsd %xmm0, %xmm1
+	movapd %xmm1, %xmm0
+	movsd %xmm0, -24(%rbp)
+	leaq .L6(%rip), %rax
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	pushq $0
+	movsd -24(%rbp), %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	pushq $1
+	movq -16(%rbp), %rax
+	pushq %rax
+	pushq $2
+	leaq .L7(%rip), %rax
+	pushq %rax
+	pushq $3
+	movq $1, %rax
+	shlq $4, %rax
+	leaq printf__Suit_names(%rip), %rcx
+	addq %rcx, %rax
+	pushq %rax
+	pushq $3
+	leaq .L8(%rip), %rax
+	pushq %rax
+	pushq $3
+	movq 96(%rsp), %rdi
+	movq $6, %rsi
+	movq %rsp, %rdx
+	subq $8, %rsp
+	call lang_asm_printf@PLT
+	addq $8, %rsp
+	addq $104, %rsp
+	leaq .L9(%rip), %rax
+	pushq %rax
+	movq 0(%rsp), %rdi
+	movq $0, %rsi
+	movq %rsp, %rdx
+	subq $8, %rsp
+	call lang_asm_printf@PLT
+	addq $8, %rsp
+	addq $8, %rsp
+	xorl %eax, %eax
+	jmp .L4
+.L4:
+	leave
+	ret
+
+# entry
+
+	.text
+	.globl main
+main:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rsi, %rbx
+	cmpl $3, %edi
+	je .L11
+	movq (%rsi), %rdi
+	leaq .L10(%rip), %rsi
+	call lang_asm_usage@PLT
+.L11:
+	call lang_init_printf
+	movq 8(%rbx), %rdi
+	call lang_asm_arg_int@PLT
+	pushq %rax
+	movq 16(%rbx), %rdi
+	subq $8, %rsp
+	call lang_asm_arg_bool@PLT
+	addq $8, %rsp
+	pushq %rax
+	movq 8(%rsp), %rdi
+	movq 0(%rsp), %rsi
+	call printf__main
+	addq $16, %rsp
+	movq -8(%rbp), %rbx
+	leave
+	ret
+
+	.section .rodata
+.L1.bytes:
+	.ascii "Hearts"
+.L2.bytes:
+	.ascii "Spades"
+	.align 8
+.L5:
+	.quad 0x4008000000000000 # 3
+.L6.bytes:
+	.ascii "%d %5.2f %t %s %v|%-6q|\012"
+.L7.bytes:
+	.ascii "cards"
+.L8.bytes:
+	.ascii "x"
+.L9.bytes:
+	.ascii "done\012"
+.L10:
+	.asciz " n verbose"
+
+	.section .data.rel.ro
+	.align 8
+.L1:
+	.quad .L1.bytes
+	.quad 6
+	.align 8
+.L2:
+	.quad .L2.bytes
+	.quad 6
+	.align 8
+printf__Suit_names:
+	.quad .L1.bytes
+	.quad 6
+	.quad .L2.bytes
+	.quad 6
+	.align 8
+.L6:
+	.quad .L6.bytes
+	.quad 24
+	.align 8
+.L7:
+	.quad .L7.bytes
+	.quad 5
+	.align 8
+.L8:
+	.quad .L8.bytes
+	.quad 1
+	.align 8
+.L9:
+	.quad .L9.bytes
+	.quad 5
+
+	.section .note.GNU-stack,"",@progbits
@@ -0,0 +1,376 @@
+# Code generated by lang build. DO NOT EDIT.
+
+# module strings
+
+	.text
+lang_init_strings:
+	pushq %rbp
+	movq %rsp, %rbp
+.L1:
+	leave
+	ret
+
+	.text
+strings__greet:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq %rdi, -8(%rbp)
+	leaq .L3(%rip), %rax
+	pushq %rax
+	movq -8(%rbp), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L4(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	jmp .L2
+.L2:
+	leave
+	ret
+
+	.text
+strings__main:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $48, %rsp
+	movq %rdi, -8(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call strings__greet
+	addq $16, %rsp
+	movq %rax, -16(%rbp)
+	movq -16(%rbp), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	movq -16(%rbp), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_len@PLT
+	addq $16, %rsp
+	movq %rax, %rdi
+	call lang_asm_int_string@PLT
+	pushq %rax
+	leaq .L6(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -16(%rbp), %rax
+	pushq %rax
+	xorl %eax, %eax
+	pushq %rax
+	movq $5, %rax
+	pushq %rax
+	movq 16(%rsp), %rdi
+	movq 8(%rsp), %rsi
+	movq 0(%rsp), %rdx
+	call lang_asm_substr@PLT
+	addq $24, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L6(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -16(%rbp), %rax
+	pushq %rax
+	leaq .L4(%rip), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 16(%rsp), %rdi
+	movq 8(%rsp), %rsi
+	call lang_asm_indexOf@PLT
+	addq $24, %rsp
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L7(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_print@PLT
+	addq $16, %rsp
+	leaq .L8(%rip), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_parseInt@PLT
+	addq $16, %rsp
+	pushq %rax
+	leaq .L9(%rip), %rax
+	pushq %rax
+	movq 0(%rsp), %rdi
+	call lang_asm_parseFloat@PLT
+	addq $8, %rsp
+	subq $8, %rsp
+	call lang_asm_float_to_int@PLT
+	addq $8, %rsp
+	movq %rax, %rcx
+	popq %rax
+	addq %rcx, %rax
+	movq %rax, -24(%rbp)
+	movq -16(%rbp), %rax
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq 0(%rsp), %rdi
+	call strings__greet
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_streq@PLT
+	movq %rax, -32(%rbp)
+	movq -24(%rbp), %rax
+	movq %rax, %rdi
+	call lang_asm_int_string@PLT
+	pushq %rax
+	leaq .L6(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -32(%rbp), %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_bool_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L6(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq -16(%rbp), %rax
+	pushq %rax
+	leaq .L10(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	subq $8, %rsp
+	call lang_asm_streq@PLT
+	addq $8, %rsp
+	xorq $1, %rax
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_bool_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L6(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movsd .L11(%rip), %xmm0
+	sqrtsd %xmm0, %xmm0
+	subq $8, %rsp
+	call lang_asm_float_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L6(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movsd .L12(%rip), %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movsd .L13(%rip), %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	subq $8, %rsp
+	movsd 16(%rsp), %xmm0
+	movsd 8(%rsp), %xmm1
+	call lang_asm_pow@PLT
+	addq $24, %rsp
+	subq $8, %rsp
+	call lang_asm_float_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	movq -8(%rbp), %rax
+	movq %rax, -40(%rbp)
+	leaq .L16(%rip), %rax
+	movq %rax, %rsi
+	movq -40(%rbp), %rdi
+	call lang_asm_streq@PLT
+	testq %rax, %rax
+	jne .L15
+	leaq .L17(%rip), %rax
+	movq %rax, %rsi
+	movq -40(%rbp), %rdi
+	call lang_asm_streq@PLT
+	testq %rax, %rax
+	jne .L15
+	jmp .L18
+.L15:
+	leaq .L19(%rip), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	jmp .L14
+.L18:
+	leaq .L20(%rip), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+.L14:
+	movq -8(%rbp), %rax
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_len@PLT
+	addq $16, %rsp
+	jmp .L5
+.L5:
+	leave
+	ret
+
+# entry
+
+	.text
+	.globl main
+main:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rsi, %rbx
+	cmpl $2, %edi
+	je .L22
+	movq (%rsi), %rdi
+	leaq .L21(%rip), %rsi
+	call lang_asm_usage@PLT
+.L22:
+	call lang_init_strings
+	movq 8(%rbx), %rdi
+	call lang_asm_arg_string@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call strings__main
+	addq $16, %rsp
+	movq -8(%rbp), %rbx
+	leave
+	ret
+
+	.section .rodata
+.L3.bytes:
+	.ascii "h\303\251llo, "
+.L4.bytes:
+	.ascii "!"
+.L6.bytes:
+	.ascii " "
+.L7.bytes:
+	.ascii "\012"
+.L8.bytes:
+	.ascii "-42"
+.L9.bytes:
+	.ascii "2.5"
+.L10.bytes:
+	.ascii "x"
+	.align 8
+.L11:
+	.quad 0x4030000000000000 # 16
+	.align 8
+.L12:
+	.quad 0x4000000000000000 # 2
+	.align 8
+.L13:
+	.quad 0x4020000000000000 # 8
+.L16.bytes:
+	.ascii ""
+.L17.bytes:
+	.ascii "nobody"
+.L19.bytes:
+	.ascii "who?"
+.L20.bytes:
+	.ascii "hi"
+.L21:
+	.asciz " name"
+
+	.section .data.rel.ro
+	.align 8
+.L3:
+	.quad .L3.bytes
+	.quad 8
+	.align 8
+.L4:
+	.quad .L4.bytes
+	.quad 1
+	.align 8
+.L6:
+	.quad .L6.bytes
+	.quad 1
+	.align 8
+.L7:
+	.quad .L7.bytes
+	.quad 1
+	.align 8
+.L8:
+	.quad .L8.bytes
+	.quad 3
+	.align 8
+.L9:
+	.quad .L9.bytes
+	.quad 3
+	.align 8
+.L10:
+	.quad .L10.bytes
+	.quad 1
+	.align 8
+.L16:
+	.quad .L16.bytes
+	.quad 0
+	.align 8
+.L17:
+	.quad .L17.bytes
+	.quad 6
+	.align 8
+.L19:
+	.quad .L19.bytes
+	.quad 4
+	.align 8
+.L20:
+	.quad .L20.bytes
+	.quad 2
+
+	.section .note.GNU-stack,"",@progbits
@@ -0,0 +1,343 @@
+# Code generated by lang build. DO NOT EDIT.
+
+# module tailcall
+
+	.text
+lang_init_tailcall:
+	pushq %rbp
+	movq %rsp, %rbp
+.L1:
+	leave
+	ret
+
+	.text
+tailcall__count:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq %rdi, -8(%rbp)
+	movq %rsi, -16(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	xorl %eax, %eax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L3
+	movq -16(%rbp), %rax
+	jmp .L2
+.L3:
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	pushq %rax
+	movq -16(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	addq %rcx, %rax
+	pushq %rax
+	popq %rsi
+	popq %rdi
+	leave
+	jmp tailcall__count
+.L2:
+	leave
+	ret
+
+	.text
+tailcall__isEven:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq %rdi, -8(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	xorl %eax, %eax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L6
+	movl $1, %eax
+	jmp .L5
+.L6:
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	pushq %rax
+	popq %rdi
+	leave
+	jmp tailcall__isOdd
+.L5:
+	leave
+	ret
+
+	.text
+tailcall__isOdd:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq %rdi, -8(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	xorl %eax, %eax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L9
+	xorl %eax, %eax
+	jmp .L8
+.L9:
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	pushq %rax
+	popq %rdi
+	leave
+	jmp tailcall__isEven
+.L8:
+	leave
+	ret
+
+	.text
+tailcall__halve:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movsd %xmm0, -8(%rbp)
+	movq %rdi, -16(%rbp)
+	movq -16(%rbp), %rax
+	pushq %rax
+	xorl %eax, %eax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L12
+	movsd -8(%rbp), %xmm0
+	jmp .L11
+.L12:
+	movsd -8(%rbp), %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movsd .L14(%rip), %xmm0
+	movsd (%rsp), %xmm1
+	addq $8, %rsp
+	divsd %xmm0, %xmm1
+	movapd %xmm1, %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movq -16(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	pushq %rax
+	popq %rdi
+	movsd (%rsp), %xmm0
+	addq $8, %rsp
+	leave
+	jmp tailcall__halve
+.L11:
+	leave
+	ret
+
+	.text
+tailcall__depth:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq %rdi, -8(%rbp)
+	movq -8(%rbp), %rax
+	pushq %rax
+	xorl %eax, %eax
+	movq %rax, %rcx
+	popq %rax
+	cmpq %rcx, %rax
+	sete %al
+	movzbq %al, %rax
+	testq %rax, %rax
+	je .L16
+	xorl %eax, %eax
+	jmp .L15
+.L16:
+	movq $1, %rax
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq $1, %rax
+	movq %rax, %rcx
+	popq %rax
+	subq %rcx, %rax
+	pushq %rax
+	movq 0(%rsp), %rdi
+	call tailcall__depth
+	addq $8, %rsp
+	movq %rax, %rcx
+	popq %rax
+	addq %rcx, %rax
+	jmp .L15
+.L15:
+	leave
+	ret
+
+	.text
+tailcall__main:
+	pushq %rbp
+	movq %rsp, %rbp
+	subq $16, %rsp
+	movq %rdi, -8(%rbp)
+	movq $100000, %rax
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	movq 8(%rsp), %rdi
+	movq 0(%rsp), %rsi
+	call tailcall__count
+	addq $16, %rsp
+	movq %rax, %rdi
+	call lang_asm_int_string@PLT
+	pushq %rax
+	leaq .L19(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq $100001, %rax
+	pushq %rax
+	movq 0(%rsp), %rdi
+	call tailcall__isEven
+	addq $8, %rsp
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_bool_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L19(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movsd .L20(%rip), %xmm0
+	subq $8, %rsp
+	movsd %xmm0, (%rsp)
+	movq $20, %rax
+	pushq %rax
+	subq $8, %rsp
+	movsd 16(%rsp), %xmm0
+	movq 8(%rsp), %rdi
+	call tailcall__halve
+	addq $24, %rsp
+	subq $8, %rsp
+	call lang_asm_float_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	leaq .L19(%rip), %rax
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	movq $100, %rax
+	pushq %rax
+	movq 0(%rsp), %rdi
+	call tailcall__depth
+	addq $8, %rsp
+	movq %rax, %rdi
+	subq $8, %rsp
+	call lang_asm_int_string@PLT
+	addq $8, %rsp
+	movq %rax, %rsi
+	popq %rdi
+	call lang_asm_concat@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call lang_asm_println@PLT
+	addq $16, %rsp
+	movq $3, %rax
+	pushq %rax
+	movq -8(%rbp), %rax
+	pushq %rax
+	popq %rsi
+	popq %rdi
+	leave
+	jmp tailcall__count
+.L18:
+	leave
+	ret
+
+# entry
+
+	.text
+	.globl main
+main:
+	pushq %rbp
+	movq %rsp, %rbp
+	pushq %rbx
+	subq $8, %rsp
+	movq %rsi, %rbx
+	cmpl $2, %edi
+	je .L22
+	movq (%rsi), %rdi
+	leaq .L21(%rip), %rsi
+	call lang_asm_usage@PLT
+.L22:
+	call lang_init_tailcall
+	movq 8(%rbx), %rdi
+	call lang_asm_arg_int@PLT
+	pushq %rax
+	subq $8, %rsp
+	movq 8(%rsp), %rdi
+	call tailcall__main
+	addq $16, %rsp
+	movq -8(%rbp), %rbx
+	leave
+	ret
+
+	.section .rodata
+	.align 8
+.L14:
+	.quad 0x4000000000000000 # 2
+.L19.bytes:
+	.ascii " "
+	.align 8
+.L20:
+	.quad 0x4090000000000000 # 1024
+.L21:
+	.asciz " n"
+
+	.section .data.rel.ro
+	.align 8
+.L19:
+	.quad .L19.bytes
+	.quad 1
+
+	.section .note.GNU-stack,"",@progbits
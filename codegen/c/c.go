@@ -0,0 +1,720 @@
+// Package c translates type checked programs to C99, so that they can be
+// compiled to native executables by any C compiler.
+package c
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/loader"
+	"lang/parser"
+	"lang/prelude"
+	"lang/scanner"
+	"strings"
+)
+
+// Runtime is the header that generated programs start with, which
+// implements strings and the builtin functions.
+//
+//go:embed runtime.h
+var Runtime string
+
+type generator struct {
+	out  *bytes.Buffer
+	mods []*loader.Module
+	// Names of the builtin functions, which the runtime implements.
+	builtins map[string]bool
+	// C names of the arrays holding the names of each enum's members.
+	enums map[*analysis.EnumType]string
+
+	mod     *loader.Module
+	env     analysis.Env
+	globals map[string]bool
+	// The function being generated.
+	fn parser.FunctionStmt
+	// The group of functions that call each other in tail position that
+	// fn is in, if any.
+	group *tailGroup
+	// Variables declared in each enclosing block of the function being
+	// generated, innermost last.
+	locals []map[string]bool
+	indent int
+	temps  int
+	labels int
+}
+
+// Generate writes mods, ordered as returned by loader.Load, as a C program
+// whose main function calls the main function of the last module with its
+// command line arguments. If that returns an int, it is the exit status.
+func Generate(w io.Writer, mods []*loader.Module) error {
+	envs, ok := analysis.CheckModules(analysis.NewUniverse(ioutil.Discard), mods)
+	if !ok {
+		return errors.New("program does not type check")
+	}
+	g := &generator{
+		out:      &bytes.Buffer{},
+		mods:     mods,
+		builtins: map[string]bool{},
+		enums:    map[*analysis.EnumType]string{},
+	}
+	for _, stmt := range prelude.Stmts() {
+		g.builtins[stmt.(parser.FunctionStmt).Name.Lexeme] = true
+	}
+	entry := mods[len(mods)-1]
+	main := findFunction(entry, "main")
+	if main == nil {
+		return fmt.Errorf("%s: no main function", entry.Path)
+	}
+
+	g.printf("/* Code generated by lang build. DO NOT EDIT. */\n\n")
+	g.out.WriteString(Runtime)
+	for i, m := range mods {
+		g.enter(m, envs[i])
+		g.declarations()
+	}
+	for i, m := range mods {
+		g.enter(m, envs[i])
+		g.definitions()
+	}
+	g.entry(entry, *main)
+	_, err := w.Write(g.out.Bytes())
+	return err
+}
+
+func findFunction(m *loader.Module, name string) *parser.FunctionStmt {
+	for _, stmt := range m.Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && f.Name.Lexeme == name {
+			return &f
+		}
+	}
+	return nil
+}
+
+func (g *generator) enter(m *loader.Module, env analysis.Env) {
+	g.mod = m
+	g.env = env
+	g.globals = map[string]bool{}
+	for _, stmt := range m.Stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			g.globals[s.Name.Lexeme] = true
+		case parser.VarStmt:
+			g.globals[s.Name.Lexeme] = true
+		}
+	}
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.out, format, args...)
+}
+
+// line writes a line of a function body at the current indentation.
+func (g *generator) line(format string, args ...interface{}) {
+	g.out.WriteString(strings.Repeat("  ", g.indent))
+	g.printf(format, args...)
+	g.out.WriteByte('\n')
+}
+
+// declarations declares the enums, variables and functions of the current
+// module, so that they can be used before they are defined.
+func (g *generator) declarations() {
+	g.printf("\n/* module %s */\n", g.mod.Name)
+	for _, stmt := range g.mod.Stmts {
+		switch s := stmt.(type) {
+		case parser.EnumStmt:
+			var names []string
+			for _, m := range s.Members {
+				names = append(names, fmt.Sprintf("{%s, %d}", cString(m.Lexeme), len(m.Lexeme)))
+			}
+			array := g.global(s.Name.Lexeme) + "_names"
+			g.enums[g.env.LookupType(s.Name.Lexeme).(*analysis.EnumType)] = array
+			g.printf("static const lang_string %s[] = {%s};\n", array, strings.Join(names, ", "))
+		case parser.VarStmt:
+			g.printf("static %s %s;\n", g.ctype(g.env.LookupType(s.Kind.Lexeme)), g.global(s.Name.Lexeme))
+		case parser.FunctionStmt:
+			g.printf("%s;\n", g.signature(s))
+		}
+	}
+}
+
+func (g *generator) signature(f parser.FunctionStmt) string {
+	var params []string
+	for _, p := range f.Params {
+		params = append(params, g.ctype(g.env.LookupType(p.Kind.Lexeme))+" v_"+p.Name.Lexeme)
+	}
+	if len(params) == 0 {
+		params = []string{"void"}
+	}
+	ret := g.ctype(g.env.LookupType(f.ReturnKind.Lexeme))
+	if f.Extern {
+		return fmt.Sprintf("extern %s %s(%s)", ret, f.Name.Lexeme, strings.Join(params, ", "))
+	}
+	return fmt.Sprintf("static %s %s(%s)", ret, g.global(f.Name.Lexeme), strings.Join(params, ", "))
+}
+
+// definitions defines the functions of the current module, and a function
+// that initializes its variables.
+func (g *generator) definitions() {
+	g.printf("\nstatic void lang_init_%s(void) {\n", cName(g.mod.Name))
+	g.indent = 1
+	g.locals = nil
+	g.temps = 0
+	for _, stmt := range g.mod.Stmts {
+		if s, ok := stmt.(parser.VarStmt); ok {
+			g.line("%s = %s;", g.global(s.Name.Lexeme), g.value(s))
+		}
+	}
+	g.printf("}\n")
+
+	groups := tailGroups(g.mod)
+	for _, stmt := range g.mod.Stmts {
+		f, ok := stmt.(parser.FunctionStmt)
+		if !ok || f.Extern {
+			continue
+		}
+		if group := groups[f.Name.Lexeme]; group != nil {
+			if group.funcs[0].Name.Lexeme == f.Name.Lexeme {
+				g.tailGroup(group)
+			}
+			g.printf("\n%s {\n", g.signature(f))
+			call := fmt.Sprintf("%s(%s)", group.name, strings.Join(g.args(group, f), ", "))
+			if f.ReturnKind.Lexeme == "void" {
+				g.printf("  %s;\n", call)
+			} else {
+				g.printf("  return %s;\n", call)
+			}
+			g.printf("}\n")
+			continue
+		}
+		g.printf("\n%s {\n", g.signature(f))
+		if tailCalls(f, f.Body.Stmts) {
+			g.printf("lang_tail:;\n")
+		}
+		g.indent = 1
+		g.body(f, nil)
+		g.printf("}\n")
+	}
+}
+
+// body writes the statements of f, at the current indentation and with its
+// parameters in variables of the same name, and a return of the zero value
+// if control can reach its end.
+func (g *generator) body(f parser.FunctionStmt, group *tailGroup) {
+	env := g.env
+	g.fn = f
+	g.group = group
+	g.env = analysis.NewBlockEnv(env)
+	g.locals = []map[string]bool{{}}
+	g.temps = 0
+	for _, p := range f.Params {
+		g.declare(p.Name.Lexeme, g.env.LookupType(p.Kind.Lexeme))
+	}
+	ret := g.env.LookupType(f.ReturnKind.Lexeme)
+	g.stmts(f.Body.Stmts, ret)
+	if !analysis.Terminates(f.Body) {
+		if ret != analysis.Void {
+			g.line("return %s;", zero(ret))
+		} else if group != nil {
+			g.line("return;")
+		}
+	}
+	g.env = env
+	g.group = nil
+}
+
+// entry writes the C main function.
+func (g *generator) entry(m *loader.Module, main parser.FunctionStmt) {
+	g.printf("\nint main(int argc, char **argv) {\n")
+	g.printf("  if (argc != %d) {\n", len(main.Params)+1)
+	g.printf("    fprintf(stderr, \"usage: %%s%s\\n\", argv[0]);\n", usage(main))
+	g.printf("    return 2;\n")
+	g.printf("  }\n")
+	for _, mod := range g.mods {
+		g.printf("  lang_init_%s();\n", cName(mod.Name))
+	}
+	var args []string
+	for i, p := range main.Params {
+		arg := fmt.Sprintf("argv[%d]", i+1)
+		switch p.Kind.Lexeme {
+		case "int":
+			args = append(args, "lang_parseInt(lang_arg("+arg+"))")
+		case "float":
+			args = append(args, "lang_parseFloat(lang_arg("+arg+"))")
+		case "bool":
+			args = append(args, "lang_parse_bool("+arg+")")
+		default:
+			args = append(args, "lang_arg("+arg+")")
+		}
+	}
+	call := fmt.Sprintf("%s__main(%s)", cName(m.Name), strings.Join(args, ", "))
+	if main.ReturnKind.Lexeme == "int" {
+		g.printf("  return (int)%s;\n", call)
+	} else {
+		g.printf("  %s;\n", call)
+		g.printf("  return 0;\n")
+	}
+	g.printf("}\n")
+}
+
+func usage(main parser.FunctionStmt) string {
+	var s string
+	for _, p := range main.Params {
+		s += " " + p.Name.Lexeme
+	}
+	return s
+}
+
+// global returns the C name of a top-level name in the current module.
+func (g *generator) global(name string) string {
+	return cName(g.mod.Name) + "__" + name
+}
+
+// cName makes a module name usable in a C identifier.
+func cName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (g *generator) ctype(t analysis.Type) string {
+	switch t {
+	case analysis.Int:
+		return "int64_t"
+	case analysis.Float:
+		return "double"
+	case analysis.Bool:
+		return "bool"
+	case analysis.String:
+		return "lang_string"
+	case analysis.Void:
+		return "void"
+	}
+	if _, isEnum := t.(*analysis.EnumType); isEnum {
+		return "int64_t"
+	}
+	panic(fmt.Sprintf("no C type for %v", t))
+}
+
+func zero(t analysis.Type) string {
+	switch t {
+	case analysis.Float:
+		return "0.0"
+	case analysis.Bool:
+		return "false"
+	case analysis.String:
+		return "LANG_STR(\"\")"
+	default:
+		return "0"
+	}
+}
+
+// value returns the initial value of a variable.
+func (g *generator) value(s parser.VarStmt) string {
+	if s.Expr == nil {
+		return zero(g.env.LookupType(s.Kind.Lexeme))
+	}
+	return g.expr(s.Expr)
+}
+
+func (g *generator) declare(name string, t analysis.Type) {
+	g.env.Declare(name, t)
+	g.locals[len(g.locals)-1][name] = true
+}
+
+func (g *generator) isLocal(name string) bool {
+	for _, scope := range g.locals {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// temp stores the value of code in a new temporary variable of type t,
+// and returns its name.
+func (g *generator) temp(t analysis.Type, code string) string {
+	g.temps++
+	name := fmt.Sprintf("t%d", g.temps)
+	if code == "" {
+		g.line("%s %s;", g.ctype(t), name)
+	} else {
+		g.line("%s %s = %s;", g.ctype(t), name, code)
+	}
+	return name
+}
+
+// returnedCall returns the call that ret returns the result of, and the
+// name of the function it calls, if it calls one by name.
+func returnedCall(ret parser.ReturnStmt) (parser.FunctionCall, string, bool) {
+	call, ok := ret.Expr.(parser.FunctionCall)
+	if !ok {
+		return call, "", false
+	}
+	callee, ok := call.Callee.(parser.IdentExpr)
+	return call, callee.Name.Lexeme, ok
+}
+
+// selfTailCall returns the call that ret returns the result of, if it
+// calls f, the function that ret is in.
+func selfTailCall(f parser.FunctionStmt, ret parser.ReturnStmt) (parser.FunctionCall, bool) {
+	call, name, ok := returnedCall(ret)
+	return call, ok && name == f.Name.Lexeme
+}
+
+// tailCalls reports whether any of stmts is a return of a self tail call
+// of f.
+func tailCalls(f parser.FunctionStmt, stmts []parser.Stmt) bool {
+	callees := map[string]bool{}
+	tailCallees(stmts, callees)
+	return callees[f.Name.Lexeme]
+}
+
+// tailCallees adds the names of the functions whose results stmts return
+// to callees.
+func tailCallees(stmts []parser.Stmt, callees map[string]bool) {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case parser.ReturnStmt:
+			if _, name, ok := returnedCall(s); ok {
+				callees[name] = true
+			}
+		case parser.IfStmt:
+			tailCallees(s.Then.Stmts, callees)
+			tailCallees(s.Els.Stmts, callees)
+		case parser.WhileStmt:
+			tailCallees(s.Body.Stmts, callees)
+		case parser.SwitchStmt:
+			for _, c := range s.Cases {
+				tailCallees(c.Body.Stmts, callees)
+			}
+		case parser.Block:
+			tailCallees(s.Stmts, callees)
+		}
+	}
+}
+
+// shadowsParams reports whether a variable of an enclosing block hides a
+// parameter of the function being generated.
+func (g *generator) shadowsParams() bool {
+	for _, p := range g.fn.Params {
+		for _, scope := range g.locals[1:] {
+			if scope[p.Name.Lexeme] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// tailCall writes a call that the function being generated makes to
+// itself before returning as a jump back to its start, with the arguments
+// in place of its parameters, so that it runs in constant stack space.
+func (g *generator) tailCall(call parser.FunctionCall) {
+	args := g.operands(call.Args...)
+	for i, p := range g.fn.Params {
+		args[i] = g.temp(g.env.LookupType(p.Kind.Lexeme), args[i])
+	}
+	for i, p := range g.fn.Params {
+		g.line("v_%s = %s;", p.Name.Lexeme, args[i])
+	}
+	g.line("goto lang_tail;")
+}
+
+// A tailGroup is a set of functions of a module that call one another in
+// tail position. They are generated as a single C function, in which each
+// of them starts at a label and takes its arguments from variables of its
+// own, so that those calls can be jumps. The functions themselves call the
+// group's function with the number of the one to start at.
+type tailGroup struct {
+	// The name of the group's C function.
+	name  string
+	funcs []parser.FunctionStmt
+}
+
+// tailGroups returns the groups of functions of m that call one another in
+// tail position by the names of their functions. Only functions with the
+// same return type can be in a group, and a function that only calls
+// itself is in none.
+func tailGroups(m *loader.Module) map[string]*tailGroup {
+	funcs := map[string]parser.FunctionStmt{}
+	var order []string
+	for _, stmt := range m.Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && !f.Extern {
+			funcs[f.Name.Lexeme] = f
+			order = append(order, f.Name.Lexeme)
+		}
+	}
+	callees := map[string][]string{}
+	for _, name := range order {
+		f := funcs[name]
+		called := map[string]bool{}
+		tailCallees(f.Body.Stmts, called)
+		for _, callee := range order {
+			if c, ok := funcs[callee]; called[callee] && callee != name && ok && c.ReturnKind.Lexeme == f.ReturnKind.Lexeme {
+				callees[name] = append(callees[name], callee)
+			}
+		}
+	}
+	reaches := map[string]map[string]bool{}
+	for _, name := range order {
+		reached := map[string]bool{}
+		var visit func(string)
+		visit = func(name string) {
+			for _, callee := range callees[name] {
+				if !reached[callee] {
+					reached[callee] = true
+					visit(callee)
+				}
+			}
+		}
+		visit(name)
+		reaches[name] = reached
+	}
+	groups := map[string]*tailGroup{}
+	for _, name := range order {
+		if groups[name] != nil {
+			continue
+		}
+		group := &tailGroup{name: "lang_tail_" + cName(m.Name) + "__" + name}
+		for _, other := range order {
+			if other == name || reaches[name][other] && reaches[other][name] {
+				group.funcs = append(group.funcs, funcs[other])
+			}
+		}
+		if len(group.funcs) > 1 {
+			for _, f := range group.funcs {
+				groups[f.Name.Lexeme] = group
+			}
+		}
+	}
+	return groups
+}
+
+// index returns the number of the function called name in the group, or
+// -1 if it is not in it.
+func (group *tailGroup) index(name string) int {
+	for i, f := range group.funcs {
+		if f.Name.Lexeme == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (group *tailGroup) contains(name string) bool {
+	return group.index(name) >= 0
+}
+
+// param returns the name of the variable that passes the parameter p of
+// the i'th function of the group.
+func param(i int, p parser.FunctionParam) string {
+	return fmt.Sprintf("a%d_%s", i, p.Name.Lexeme)
+}
+
+// args returns the arguments with which f calls the group's function: its
+// number, its own parameters, and zero values for those of the others.
+func (g *generator) args(group *tailGroup, f parser.FunctionStmt) []string {
+	args := []string{fmt.Sprint(group.index(f.Name.Lexeme))}
+	for _, other := range group.funcs {
+		for _, p := range other.Params {
+			if other.Name.Lexeme == f.Name.Lexeme {
+				args = append(args, "v_"+p.Name.Lexeme)
+			} else {
+				args = append(args, zero(g.env.LookupType(p.Kind.Lexeme)))
+			}
+		}
+	}
+	return args
+}
+
+// tailGroup defines the C function of group.
+func (g *generator) tailGroup(group *tailGroup) {
+	params := []string{"int lang_fn"}
+	for i, f := range group.funcs {
+		for _, p := range f.Params {
+			params = append(params, g.ctype(g.env.LookupType(p.Kind.Lexeme))+" "+param(i, p))
+		}
+	}
+	ret := g.ctype(g.env.LookupType(group.funcs[0].ReturnKind.Lexeme))
+	g.printf("\nstatic %s %s(%s) {\n", ret, group.name, strings.Join(params, ", "))
+	g.printf("  switch (lang_fn) {\n")
+	for i := range group.funcs {
+		g.printf("  case %d: goto lang_tail_%d;\n", i, i)
+	}
+	g.printf("  }\n")
+	for i, f := range group.funcs {
+		g.printf("lang_tail_%d: {\n", i)
+		g.indent = 2
+		for _, p := range f.Params {
+			g.line("%s v_%s = %s;", g.ctype(g.env.LookupType(p.Kind.Lexeme)), p.Name.Lexeme, param(i, p))
+		}
+		g.body(f, group)
+		g.printf("  }\n")
+	}
+	g.printf("}\n")
+}
+
+// groupTailCall writes a call in tail position to the function called name
+// in the group of the function being generated as a jump to its label,
+// with the arguments in the variables of its parameters.
+func (g *generator) groupTailCall(call parser.FunctionCall, name string) {
+	i := g.group.index(name)
+	args := g.operands(call.Args...)
+	for j, p := range g.group.funcs[i].Params {
+		g.line("%s = %s;", param(i, p), args[j])
+	}
+	g.line("goto lang_tail_%d;", i)
+}
+
+func (g *generator) block(b parser.Block, ret analysis.Type) {
+	env := g.env
+	g.env = analysis.NewBlockEnv(env)
+	g.locals = append(g.locals, map[string]bool{})
+	g.indent++
+	g.stmts(b.Stmts, ret)
+	g.indent--
+	g.locals = g.locals[:len(g.locals)-1]
+	g.env = env
+}
+
+func (g *generator) stmts(stmts []parser.Stmt, ret analysis.Type) {
+	for _, stmt := range stmts {
+		g.stmt(stmt, ret)
+	}
+}
+
+func (g *generator) stmt(stmt parser.Stmt, ret analysis.Type) {
+	switch s := stmt.(type) {
+	case parser.VarStmt:
+		t := g.env.LookupType(s.Kind.Lexeme)
+		g.declare(s.Name.Lexeme, t)
+		g.line("%s v_%s = %s;", g.ctype(t), s.Name.Lexeme, g.value(s))
+	case parser.AssignStmt:
+		target := s.Target.(scanner.Token)
+		g.line("%s = %s;", g.variable(target.Lexeme), g.expr(s.Expr))
+	case parser.CompoundAssignStmt:
+		op := s.Op
+		op.Kind = parser.CompoundAssignOps[s.Op.Kind]
+		value := g.expr(parser.BinaryOp{Op: op, Left: parser.IdentExpr{Name: s.Target}, Right: s.Expr})
+		g.line("%s = %s;", g.variable(s.Target.Lexeme), value)
+	case parser.IncDecStmt:
+		name := g.variable(s.Target.Lexeme)
+		if analysis.TypeOf(g.env, parser.IdentExpr{Name: s.Target}) == analysis.Float {
+			g.line("%s %s= 1.0;", name, scanner.Operators[s.Op.Kind][:1])
+		} else if s.Op.Kind == scanner.Inc {
+			g.line("%s = lang_add(%s, 1);", name, name)
+		} else {
+			g.line("%s = lang_sub(%s, 1);", name, name)
+		}
+	case parser.ReturnStmt:
+		if call, name, ok := returnedCall(s); ok && g.group != nil && g.group.contains(name) && !g.isLocal(name) {
+			g.groupTailCall(call, name)
+		} else if call, ok := selfTailCall(g.fn, s); ok && !g.isLocal(g.fn.Name.Lexeme) && !g.shadowsParams() {
+			g.tailCall(call)
+		} else if s.Expr == nil {
+			g.line("return;")
+		} else {
+			g.line("return %s;", g.expr(s.Expr))
+		}
+	case parser.IfStmt:
+		g.line("if (%s) {", g.expr(s.Cond))
+		g.block(s.Then, ret)
+		if len(s.Els.Stmts) > 0 {
+			g.line("} else {")
+			g.block(s.Els, ret)
+		}
+		g.line("}")
+	case parser.WhileStmt:
+		cond, pre := g.capture(s.Cond)
+		if pre == "" {
+			g.line("while (%s) {", cond)
+		} else {
+			g.line("for (;;) {")
+			g.out.WriteString(pre)
+			g.line("  if (!(%s)) {", cond)
+			g.line("    break;")
+			g.line("  }")
+		}
+		g.block(s.Body, ret)
+		g.line("}")
+	case parser.SwitchStmt:
+		g.switchStmt(s, ret)
+	case parser.Block:
+		g.line("{")
+		g.block(s, ret)
+		g.line("}")
+	default:
+		g.line("%s;", g.expr(s))
+	}
+}
+
+// variable returns the C name of the variable called name.
+func (g *generator) variable(name string) string {
+	if g.isLocal(name) {
+		return "v_" + name
+	}
+	return g.global(name)
+}
+
+// switchStmt writes a switch as jumps to the bodies of its cases, which
+// end by jumping past the others unless they fall through.
+func (g *generator) switchStmt(s parser.SwitchStmt, ret analysis.Type) {
+	g.labels++
+	label := fmt.Sprintf("L%d", g.labels)
+	t := analysis.TypeOf(g.env, s.Expr)
+	g.line("{")
+	g.indent++
+	value := g.temp(t, g.expr(s.Expr))
+	end := label + "_end"
+	otherwise := end
+	usesEnd := false
+	for i, c := range s.Cases {
+		var conds []string
+		for _, v := range c.Values {
+			if t == analysis.String {
+				conds = append(conds, fmt.Sprintf("lang_streq(%s, %s)", value, g.expr(v)))
+			} else {
+				conds = append(conds, fmt.Sprintf("%s == %s", value, g.expr(v)))
+			}
+		}
+		if c.Default {
+			otherwise = fmt.Sprintf("%s_%d", label, i)
+		}
+		if len(conds) > 0 {
+			g.line("if (%s) {", strings.Join(conds, " || "))
+			g.line("  goto %s_%d;", label, i)
+			g.line("}")
+		}
+	}
+	g.line("goto %s;", otherwise)
+	usesEnd = otherwise == end
+	for i, c := range s.Cases {
+		g.line("%s_%d: {", label, i)
+		body := c.Body
+		fallsThrough := false
+		if n := len(body.Stmts); n > 0 {
+			_, fallsThrough = body.Stmts[n-1].(parser.FallthroughStmt)
+			if fallsThrough {
+				body.Stmts = body.Stmts[:n-1]
+			}
+		}
+		g.block(body, ret)
+		g.line("}")
+		if !fallsThrough && i < len(s.Cases)-1 && !analysis.Terminates(body) {
+			g.line("goto %s;", end)
+			usesEnd = true
+		}
+	}
+	if usesEnd {
+		g.line("%s:;", end)
+	}
+	g.indent--
+	g.line("}")
+}
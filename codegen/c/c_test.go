@@ -0,0 +1,49 @@
+package c
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"lang/codegen/codegentest"
+	"lang/loader"
+	"os/exec"
+	"testing"
+)
+
+// TestGenerate compares the C generated for each of the backends' test
+// programs against the golden file with the same name and a .gen.c
+// extension. The runtime, which every program starts with, is left out of
+// the golden files.
+func TestGenerate(t *testing.T) {
+	codegentest.Golden(t, ".gen.c", nil, func(w io.Writer, mods []*loader.Module) error {
+		var out bytes.Buffer
+		if err := Generate(&out, mods); err != nil {
+			return err
+		}
+		_, err := w.Write(bytes.Replace(out.Bytes(), []byte(Runtime), nil, 1))
+		return err
+	})
+}
+
+// TestRun compiles each program that the interpreter runs with cc, and
+// checks that it behaves as it does in the interpreter.
+func TestRun(t *testing.T) {
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("cc not found")
+	}
+	codegentest.Run(t, nil, func(exe string, mods []*loader.Module) error {
+		var src bytes.Buffer
+		if err := Generate(&src, mods); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(exe+".c", src.Bytes(), 0644); err != nil {
+			return err
+		}
+		if out, err := exec.Command(cc, "-o", exe, exe+".c", "-lm").CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %v\n%s", cc, err, out)
+		}
+		return nil
+	})
+}
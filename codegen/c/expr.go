@@ -0,0 +1,315 @@
+package c
+
+import (
+	"bytes"
+	"fmt"
+	"lang/analysis"
+	"lang/parser"
+	"lang/scanner"
+	"strconv"
+	"strings"
+)
+
+var binaryFuncs = map[scanner.TokenKind]string{
+	scanner.Plus:    "lang_add",
+	scanner.Minus:   "lang_sub",
+	scanner.Star:    "lang_mul",
+	scanner.Slash:   "lang_div",
+	scanner.Percent: "lang_mod",
+	scanner.Shl:     "lang_shl",
+	scanner.Shr:     "lang_shr",
+}
+
+// expr returns C code for e. C leaves the order in which operands are
+// evaluated unspecified, so any operand whose value might depend on when it
+// is evaluated is first stored in a temporary, in the order the language
+// evaluates them.
+func (g *generator) expr(e parser.Expr) string {
+	switch n := e.(type) {
+	case parser.LiteralNum:
+		if analysis.TypeOf(g.env, n) == analysis.Float {
+			return n.Value
+		}
+		return "INT64_C(" + n.Value + ")"
+	case parser.LiteralStr:
+		return fmt.Sprintf("LANG_STR(%s)", cString(n.Value))
+	case parser.LiteralBool:
+		return strconv.FormatBool(n.Value)
+	case parser.IdentExpr:
+		return g.variable(n.Name.Lexeme)
+	case parser.MemberAccess:
+		if enum, isEnum := analysis.TypeNamed(g.env, n.Parent).(*analysis.EnumType); isEnum {
+			for i, m := range enum.Members {
+				if string(m) == n.Name.Lexeme {
+					return fmt.Sprintf("INT64_C(%d)", i)
+				}
+			}
+		}
+		mt := analysis.TypeOf(g.env, n.Parent).(*analysis.ModuleType)
+		return cName(string(mt.Name)) + "__" + n.Name.Lexeme
+	case parser.FunctionCall:
+		return g.call(n)
+	case parser.UnaryOp:
+		x := g.expr(n.Expr)
+		switch n.Op.Kind {
+		case scanner.Minus:
+			if analysis.TypeOf(g.env, n) == analysis.Int {
+				return "lang_neg(" + x + ")"
+			}
+			return "(-" + x + ")"
+		case scanner.LNot:
+			return "(!" + x + ")"
+		default:
+			return "(~" + x + ")"
+		}
+	case parser.BinaryOp:
+		if n.Op.Kind == scanner.LAnd || n.Op.Kind == scanner.LOr {
+			return g.logical(n)
+		}
+		ops := g.operands(n.Left, n.Right)
+		l, r := ops[0], ops[1]
+		t := analysis.TypeOf(g.env, n.Left)
+		if f, ok := binaryFuncs[n.Op.Kind]; ok && t == analysis.Int {
+			return fmt.Sprintf("%s(%s, %s)", f, l, r)
+		}
+		if t == analysis.String {
+			if n.Op.Kind == scanner.Ne {
+				return fmt.Sprintf("(!lang_streq(%s, %s))", l, r)
+			}
+			return fmt.Sprintf("lang_streq(%s, %s)", l, r)
+		}
+		return fmt.Sprintf("(%s %s %s)", l, scanner.Operators[n.Op.Kind], r)
+	case parser.TernaryExpr:
+		cond := g.expr(n.Cond)
+		then, thenPre := g.capture(n.Then)
+		els, elsPre := g.capture(n.Els)
+		if thenPre == "" && elsPre == "" {
+			return fmt.Sprintf("(%s ? %s : %s)", cond, then, els)
+		}
+		t := g.temp(analysis.TypeOf(g.env, n), "")
+		g.line("if (%s) {", cond)
+		g.out.WriteString(thenPre)
+		g.line("  %s = %s;", t, then)
+		g.line("} else {")
+		g.out.WriteString(elsPre)
+		g.line("  %s = %s;", t, els)
+		g.line("}")
+		return t
+	case parser.InterpolatedStr:
+		parts := g.operands(n.Parts...)
+		for i, part := range parts {
+			parts[i] = g.toString(n.Parts[i], part)
+		}
+		return fmt.Sprintf("lang_concat(%d, (lang_string[]){%s})", len(parts), strings.Join(parts, ", "))
+	default:
+		panic(fmt.Sprintf("cannot generate C for %T", e))
+	}
+}
+
+// capture returns C code for e along with the statements that must run
+// before it, indented one level deeper, instead of writing them out.
+func (g *generator) capture(e parser.Expr) (string, string) {
+	out := g.out
+	g.out = &bytes.Buffer{}
+	g.indent++
+	code := g.expr(e)
+	g.indent--
+	pre := g.out.String()
+	g.out = out
+	return code, pre
+}
+
+// logical returns C code for && and ||, only evaluating the right operand
+// when it is needed.
+func (g *generator) logical(n parser.BinaryOp) string {
+	l := g.expr(n.Left)
+	r, pre := g.capture(n.Right)
+	op := scanner.Operators[n.Op.Kind]
+	if pre == "" {
+		return fmt.Sprintf("(%s %s %s)", l, op, r)
+	}
+	t := g.temp(analysis.Bool, l)
+	if n.Op.Kind == scanner.LAnd {
+		g.line("if (%s) {", t)
+	} else {
+		g.line("if (!%s) {", t)
+	}
+	g.out.WriteString(pre)
+	g.line("  %s = %s;", t, r)
+	g.line("}")
+	return t
+}
+
+// operands returns C code for exprs, which are evaluated from left to
+// right.
+func (g *generator) operands(exprs ...parser.Expr) []string {
+	codes := make([]string, len(exprs))
+	for i, e := range exprs {
+		codes[i] = g.expr(e)
+		if g.stable(e) {
+			continue
+		}
+		for _, later := range exprs[i+1:] {
+			if !g.stable(later) {
+				codes[i] = g.temp(analysis.TypeOf(g.env, e), codes[i])
+				break
+			}
+		}
+	}
+	return codes
+}
+
+// stable reports whether evaluating e always gives the same result without
+// side effects, so that it does not matter when it is evaluated.
+func (g *generator) stable(e parser.Expr) bool {
+	switch n := e.(type) {
+	case parser.LiteralNum, parser.LiteralStr, parser.LiteralBool:
+		return true
+	case parser.IdentExpr:
+		return g.isLocal(n.Name.Lexeme)
+	case parser.MemberAccess:
+		_, isEnum := analysis.TypeNamed(g.env, n.Parent).(*analysis.EnumType)
+		return isEnum
+	case parser.UnaryOp:
+		return g.stable(n.Expr)
+	case parser.BinaryOp:
+		switch n.Op.Kind {
+		case scanner.Slash, scanner.Percent, scanner.Shl, scanner.Shr:
+			// These can fail.
+			return false
+		}
+		return g.stable(n.Left) && g.stable(n.Right)
+	case parser.TernaryExpr:
+		return g.stable(n.Cond) && g.stable(n.Then) && g.stable(n.Els)
+	case parser.InterpolatedStr:
+		for _, part := range n.Parts {
+			if !g.stable(part) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (g *generator) call(n parser.FunctionCall) string {
+	if t := analysis.TypeNamed(g.env, n.Callee); t != nil {
+		return g.convert(t, n.Args[0])
+	}
+	args := g.operands(n.Args...)
+	var name string
+	switch callee := n.Callee.(type) {
+	case parser.IdentExpr:
+		name = callee.Name.Lexeme
+		if g.builtins[name] && !g.isLocal(name) && !g.globals[name] {
+			if name == "printf" {
+				return g.printfCall(n, args)
+			}
+			name = "lang_" + name
+		} else if !g.mod.Extern(name) {
+			name = g.variable(name)
+		}
+	case parser.MemberAccess:
+		mt := analysis.TypeOf(g.env, callee.Parent).(*analysis.ModuleType)
+		name = cName(string(mt.Name)) + "__" + callee.Name.Lexeme
+		for _, m := range g.mods {
+			if m.Name == string(mt.Name) && m.Extern(callee.Name.Lexeme) {
+				name = callee.Name.Lexeme
+			}
+		}
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+}
+
+func (g *generator) printfCall(n parser.FunctionCall, args []string) string {
+	if len(args) == 1 {
+		return fmt.Sprintf("lang_printf(%s, 0, NULL)", args[0])
+	}
+	var values []string
+	for i, arg := range args[1:] {
+		values = append(values, g.printfValue(n.Args[i+1], arg))
+	}
+	return fmt.Sprintf("lang_printf(%s, %d, (lang_value[]){%s})", args[0], len(values), strings.Join(values, ", "))
+}
+
+// printfValue returns code, which is C code for e, as an argument to
+// lang_printf.
+func (g *generator) printfValue(e parser.Expr, code string) string {
+	switch t := analysis.TypeOf(g.env, e).(type) {
+	case *analysis.EnumType:
+		return fmt.Sprintf("lang_string_value(%s[%s])", g.enums[t], code)
+	default:
+		switch t {
+		case analysis.Int:
+			return "lang_int_value(" + code + ")"
+		case analysis.Float:
+			return "lang_float_value(" + code + ")"
+		case analysis.Bool:
+			return "lang_bool_value(" + code + ")"
+		default:
+			return "lang_string_value(" + code + ")"
+		}
+	}
+}
+
+// toString returns code, which is C code for e, converted to a string.
+func (g *generator) toString(e parser.Expr, code string) string {
+	switch t := analysis.TypeOf(g.env, e).(type) {
+	case *analysis.EnumType:
+		return fmt.Sprintf("%s[%s]", g.enums[t], code)
+	default:
+		switch t {
+		case analysis.Int:
+			return "lang_int_string(" + code + ")"
+		case analysis.Float:
+			return "lang_float_string(" + code + ")"
+		case analysis.Bool:
+			return "lang_bool_string(" + code + ")"
+		default:
+			return code
+		}
+	}
+}
+
+// convert returns C code for converting e to type t.
+func (g *generator) convert(t analysis.Type, e parser.Expr) string {
+	code := g.expr(e)
+	from := analysis.TypeOf(g.env, e)
+	if from == t {
+		return code
+	}
+	if enum, isEnum := t.(*analysis.EnumType); isEnum {
+		return fmt.Sprintf("lang_enum(%s, %d, %s)", code, len(enum.Members), cString(string(enum.Name)))
+	}
+	switch t {
+	case analysis.Int:
+		if from == analysis.Float {
+			return "lang_float_to_int(" + code + ")"
+		}
+		return code
+	case analysis.Float:
+		return "(double)" + code
+	default:
+		return g.toString(e, code)
+	}
+}
+
+// cString returns s as a C string literal.
+func cString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\' || c == '?':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
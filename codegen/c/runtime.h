@@ -0,0 +1,680 @@
+/* Runtime support for programs compiled to C. Strings are immutable and
+ * never freed. Integers wrap around on overflow as they do when a program
+ * is interpreted, and runtime errors print a message and exit with status 1.
+ */
+#include <inttypes.h>
+#include <math.h>
+#include <stdarg.h>
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef struct {
+  const char *ptr;
+  int64_t len;
+} lang_string;
+
+#define LANG_STR(s) ((lang_string){(s), sizeof(s) - 1})
+
+static inline void lang_fatal(const char *format, ...) {
+  va_list args;
+  fflush(stdout);
+  fputs("runtime error: ", stderr);
+  va_start(args, format);
+  vfprintf(stderr, format, args);
+  va_end(args);
+  fputc('\n', stderr);
+  exit(1);
+}
+
+static inline char *lang_alloc(size_t n) {
+  char *p = malloc(n > 0 ? n : 1);
+  if (p == NULL) {
+    lang_fatal("out of memory");
+  }
+  return p;
+}
+
+/* lang_cstring returns a NUL-terminated copy of s. */
+static inline char *lang_cstring(lang_string s) {
+  char *p = lang_alloc((size_t)s.len + 1);
+  memcpy(p, s.ptr, (size_t)s.len);
+  p[s.len] = '\0';
+  return p;
+}
+
+static inline lang_string lang_copy(const char *p, size_t n) {
+  char *q = lang_alloc(n);
+  memcpy(q, p, n);
+  return (lang_string){q, (int64_t)n};
+}
+
+static inline lang_string lang_concat(int n, const lang_string *parts) {
+  int64_t len = 0;
+  int i;
+  char *p;
+  for (i = 0; i < n; i++) {
+    len += parts[i].len;
+  }
+  p = lang_alloc((size_t)len);
+  len = 0;
+  for (i = 0; i < n; i++) {
+    memcpy(p + len, parts[i].ptr, (size_t)parts[i].len);
+    len += parts[i].len;
+  }
+  return (lang_string){p, len};
+}
+
+static inline bool lang_streq(lang_string a, lang_string b) {
+  return a.len == b.len && memcmp(a.ptr, b.ptr, (size_t)a.len) == 0;
+}
+
+/* Integer arithmetic */
+
+static inline int64_t lang_add(int64_t a, int64_t b) {
+  return (int64_t)((uint64_t)a + (uint64_t)b);
+}
+
+static inline int64_t lang_sub(int64_t a, int64_t b) {
+  return (int64_t)((uint64_t)a - (uint64_t)b);
+}
+
+static inline int64_t lang_mul(int64_t a, int64_t b) {
+  return (int64_t)((uint64_t)a * (uint64_t)b);
+}
+
+static inline int64_t lang_neg(int64_t a) {
+  return (int64_t)(0 - (uint64_t)a);
+}
+
+static inline int64_t lang_div(int64_t a, int64_t b) {
+  if (b == 0) {
+    lang_fatal("integer division by zero");
+  }
+  if (b == -1) {
+    return lang_neg(a);
+  }
+  return a / b;
+}
+
+static inline int64_t lang_mod(int64_t a, int64_t b) {
+  if (b == 0) {
+    lang_fatal("integer division by zero");
+  }
+  if (b == -1) {
+    return 0;
+  }
+  return a % b;
+}
+
+static inline int64_t lang_shl(int64_t a, int64_t b) {
+  if (b < 0) {
+    lang_fatal("negative shift amount %" PRId64, b);
+  }
+  return b >= 64 ? 0 : (int64_t)((uint64_t)a << b);
+}
+
+static inline int64_t lang_shr(int64_t a, int64_t b) {
+  if (b < 0) {
+    lang_fatal("negative shift amount %" PRId64, b);
+  }
+  if (b >= 64) {
+    return a < 0 ? -1 : 0;
+  }
+  /* Right shifts of negative numbers are implementation-defined. */
+  return a < 0 ? ~(int64_t)(~(uint64_t)a >> b) : (int64_t)((uint64_t)a >> b);
+}
+
+/* Conversions */
+
+static inline int64_t lang_float_to_int(double f) {
+  if (!(f >= -9223372036854775808.0 && f < 9223372036854775808.0)) {
+    return INT64_MIN;
+  }
+  return (int64_t)f;
+}
+
+static inline int64_t lang_enum(int64_t i, int64_t n, const char *name) {
+  if (i < 0 || i >= n) {
+    lang_fatal("%" PRId64 " is out of range for enum %s", i, name);
+  }
+  return i;
+}
+
+static inline lang_string lang_int_string(int64_t i) {
+  char buf[32];
+  return lang_copy(buf, (size_t)sprintf(buf, "%" PRId64, i));
+}
+
+/* lang_format_float formats f with the fewest digits that read back as
+ * f, in exponent form if the exponent is less than -4 or at least 6. */
+static inline int lang_format_float(char *buf, double f) {
+  char e[32];
+  int prec, exp;
+  if (f != f) {
+    return sprintf(buf, "NaN");
+  } else if (f == HUGE_VAL) {
+    return sprintf(buf, "+Inf");
+  } else if (f == -HUGE_VAL) {
+    return sprintf(buf, "-Inf");
+  }
+  for (prec = 0; prec < 17; prec++) {
+    sprintf(e, "%.*e", prec, f);
+    if (strtod(e, NULL) == f) {
+      break;
+    }
+  }
+  exp = atoi(strchr(e, 'e') + 1);
+  if (exp < -4 || exp >= 6) {
+    return sprintf(buf, "%s", e);
+  }
+  return sprintf(buf, "%.*f", prec > exp ? prec - exp : 0, f);
+}
+
+static inline lang_string lang_float_string(double f) {
+  char buf[48];
+  return lang_copy(buf, (size_t)lang_format_float(buf, f));
+}
+
+static inline lang_string lang_bool_string(bool b) {
+  return b ? LANG_STR("true") : LANG_STR("false");
+}
+
+/* lang_quote returns s as a double-quoted string literal. */
+static inline lang_string lang_quote(lang_string s) {
+  char *p = lang_alloc((size_t)s.len * 4 + 2);
+  int64_t i, n = 0;
+  p[n++] = '"';
+  for (i = 0; i < s.len; i++) {
+    unsigned char c = (unsigned char)s.ptr[i];
+    switch (c) {
+    case '"':
+    case '\\':
+      p[n++] = '\\';
+      p[n++] = (char)c;
+      break;
+    case '\n':
+      p[n++] = '\\';
+      p[n++] = 'n';
+      break;
+    case '\r':
+      p[n++] = '\\';
+      p[n++] = 'r';
+      break;
+    case '\t':
+      p[n++] = '\\';
+      p[n++] = 't';
+      break;
+    default:
+      if (c < 0x20 || c == 0x7f) {
+        n += sprintf(p + n, "\\x%02x", c);
+      } else {
+        p[n++] = (char)c;
+      }
+    }
+  }
+  p[n++] = '"';
+  return (lang_string){p, n};
+}
+
+/* Strings are indexed by code point. */
+
+static inline int64_t lang_runes(const char *p, int64_t n) {
+  int64_t i, count = 0;
+  for (i = 0; i < n; i++) {
+    if (((unsigned char)p[i] & 0xc0) != 0x80) {
+      count++;
+    }
+  }
+  return count;
+}
+
+/* lang_rune_offset returns the byte offset of the i-th code point of s. */
+static inline int64_t lang_rune_offset(lang_string s, int64_t i) {
+  int64_t off;
+  for (off = 0; off < s.len; off++) {
+    if (((unsigned char)s.ptr[off] & 0xc0) != 0x80 && i-- == 0) {
+      break;
+    }
+  }
+  return off;
+}
+
+/* Builtins */
+
+static inline void lang_print(lang_string s) {
+  fwrite(s.ptr, 1, (size_t)s.len, stdout);
+}
+
+static inline void lang_println(lang_string s) {
+  lang_print(s);
+  putchar('\n');
+}
+
+static inline int64_t lang_len(lang_string s) {
+  return lang_runes(s.ptr, s.len);
+}
+
+static inline lang_string lang_substr(lang_string s, int64_t start, int64_t end) {
+  int64_t n = lang_len(s), from;
+  if (start < 0 || end < start || end > n) {
+    lang_fatal("substr: range [%" PRId64 ":%" PRId64 "] out of bounds for length %" PRId64, start, end, n);
+  }
+  from = lang_rune_offset(s, start);
+  return (lang_string){s.ptr + from, lang_rune_offset(s, end) - from};
+}
+
+static inline int64_t lang_indexOf(lang_string s, lang_string sub) {
+  int64_t i;
+  for (i = 0; i + sub.len <= s.len; i++) {
+    if (memcmp(s.ptr + i, sub.ptr, (size_t)sub.len) == 0) {
+      return lang_runes(s.ptr, i);
+    }
+  }
+  return -1;
+}
+
+/* lang_split_end returns the byte offset of the end of the piece of s that
+ * starts at from, which is the next sep or, if sep is empty, the next code
+ * point. */
+static inline int64_t lang_split_end(lang_string s, lang_string sep, int64_t from) {
+  int64_t i;
+  if (sep.len == 0) {
+    i = from + 1;
+    while (i < s.len && ((unsigned char)s.ptr[i] & 0xc0) == 0x80) {
+      i++;
+    }
+    return i;
+  }
+  for (i = from; i + sep.len <= s.len; i++) {
+    if (memcmp(s.ptr + i, sep.ptr, (size_t)sep.len) == 0) {
+      return i;
+    }
+  }
+  return s.len;
+}
+
+static inline int64_t lang_splitCount(lang_string s, lang_string sep) {
+  int64_t n = 1, from = 0, end;
+  if (sep.len == 0) {
+    return lang_len(s);
+  }
+  while ((end = lang_split_end(s, sep, from)) < s.len) {
+    n++;
+    from = end + sep.len;
+  }
+  return n;
+}
+
+static inline lang_string lang_split(lang_string s, lang_string sep, int64_t i) {
+  int64_t n = lang_splitCount(s, sep), from = 0, end;
+  if (i < 0 || i >= n) {
+    lang_fatal("split: index %" PRId64 " out of bounds for length %" PRId64, i, n);
+  }
+  for (;;) {
+    end = lang_split_end(s, sep, from);
+    if (i-- == 0) {
+      return (lang_string){s.ptr + from, end - from};
+    }
+    from = end + sep.len;
+  }
+}
+
+static inline int64_t lang_abs(int64_t n) {
+  return n < 0 ? lang_neg(n) : n;
+}
+
+static inline double lang_sqrt(double x) {
+  return sqrt(x);
+}
+
+static inline double lang_pow(double x, double y) {
+  return pow(x, y);
+}
+
+static inline int64_t lang_parseInt(lang_string s) {
+  int64_t i = 0, sign = 1, start = 0;
+  uint64_t n = 0, limit;
+  if (s.len > 0 && (s.ptr[0] == '+' || s.ptr[0] == '-')) {
+    sign = s.ptr[0] == '-' ? -1 : 1;
+    start = 1;
+  }
+  limit = sign < 0 ? (uint64_t)INT64_MAX + 1 : (uint64_t)INT64_MAX;
+  for (i = start; i < s.len; i++) {
+    unsigned d = (unsigned char)s.ptr[i] - '0';
+    if (d > 9 || n > (limit - d) / 10) {
+      break;
+    }
+    n = n * 10 + d;
+  }
+  if (i == start || i < s.len) {
+    lang_fatal("parseInt: invalid int %s", lang_cstring(lang_quote(s)));
+  }
+  return sign < 0 ? (int64_t)(0 - n) : (int64_t)n;
+}
+
+static inline double lang_parseFloat(lang_string s) {
+  char *p = lang_cstring(s), *end;
+  double f = strtod(p, &end);
+  if (s.len == 0 || *end != '\0' || (size_t)(end - p) != (size_t)s.len || p[0] == ' ' || p[0] == '\t' || p[0] == '\n') {
+    lang_fatal("parseFloat: invalid float %s", lang_cstring(lang_quote(s)));
+  }
+  return f;
+}
+
+/* printf */
+
+typedef enum { LANG_INT, LANG_FLOAT, LANG_BOOL, LANG_STRING } lang_kind;
+
+typedef struct {
+  lang_kind kind;
+  union {
+    int64_t i;
+    double f;
+    bool b;
+    lang_string s;
+  } u;
+} lang_value;
+
+static inline lang_value lang_int_value(int64_t i) {
+  lang_value v;
+  v.kind = LANG_INT;
+  v.u.i = i;
+  return v;
+}
+
+static inline lang_value lang_float_value(double f) {
+  lang_value v;
+  v.kind = LANG_FLOAT;
+  v.u.f = f;
+  return v;
+}
+
+static inline lang_value lang_bool_value(bool b) {
+  lang_value v;
+  v.kind = LANG_BOOL;
+  v.u.b = b;
+  return v;
+}
+
+static inline lang_value lang_string_value(lang_string s) {
+  lang_value v;
+  v.kind = LANG_STRING;
+  v.u.s = s;
+  return v;
+}
+
+static inline const char *lang_type_name(lang_value v) {
+  switch (v.kind) {
+  case LANG_INT:
+    return "int64";
+  case LANG_FLOAT:
+    return "float64";
+  case LANG_BOOL:
+    return "bool";
+  default:
+    return "string";
+  }
+}
+
+static inline lang_string lang_value_string(lang_value v) {
+  switch (v.kind) {
+  case LANG_INT:
+    return lang_int_string(v.u.i);
+  case LANG_FLOAT:
+    return lang_float_string(v.u.f);
+  case LANG_BOOL:
+    return lang_bool_string(v.u.b);
+  default:
+    return v.u.s;
+  }
+}
+
+typedef struct {
+  bool minus, plus, space, zero;
+  int width, prec;
+} lang_flags;
+
+/* lang_pad writes s, which starts with any sign, padded to the width. */
+static inline void lang_pad(const char *s, size_t n, lang_flags fl, bool number) {
+  int pad = fl.width - (int)lang_runes(s, (int64_t)n);
+  if (pad <= 0) {
+    fwrite(s, 1, n, stdout);
+  } else if (fl.minus) {
+    fwrite(s, 1, n, stdout);
+    while (pad-- > 0) {
+      putchar(' ');
+    }
+  } else if (fl.zero && number) {
+    if (n > 0 && (s[0] == '-' || s[0] == '+' || s[0] == ' ')) {
+      putchar(*s++);
+      n--;
+    }
+    while (pad-- > 0) {
+      putchar('0');
+    }
+    fwrite(s, 1, n, stdout);
+  } else {
+    while (pad-- > 0) {
+      putchar(' ');
+    }
+    fwrite(s, 1, n, stdout);
+  }
+}
+
+/* lang_digits writes the magnitude of i in base into buf, and returns its
+ * length. */
+static inline int lang_digits(char *buf, int64_t i, int base, bool upper) {
+  const char *digits = upper ? "0123456789ABCDEF" : "0123456789abcdef";
+  uint64_t u = i < 0 ? 0 - (uint64_t)i : (uint64_t)i;
+  char tmp[64];
+  int n = 0, len = 0;
+  do {
+    tmp[n++] = digits[u % (uint64_t)base];
+    u /= (uint64_t)base;
+  } while (u > 0);
+  while (n > 0) {
+    buf[len++] = tmp[--n];
+  }
+  return len;
+}
+
+static inline bool lang_format_value(char verb, lang_flags fl, lang_value v) {
+  char buf[512];
+  int n = 0;
+  bool number = v.kind == LANG_INT || v.kind == LANG_FLOAT;
+  if (number) {
+    bool neg = v.kind == LANG_INT ? v.u.i < 0 : signbit(v.u.f) != 0;
+    if (neg) {
+      buf[n++] = '-';
+    } else if (fl.plus) {
+      buf[n++] = '+';
+    } else if (fl.space) {
+      buf[n++] = ' ';
+    }
+  }
+  switch (verb) {
+  case 'v':
+    if (v.kind == LANG_INT) {
+      n += lang_digits(buf + n, v.u.i, 10, false);
+    } else if (v.kind == LANG_FLOAT) {
+      n += lang_format_float(buf + n, fabs(v.u.f));
+    } else {
+      lang_string s = lang_value_string(v);
+      lang_pad(s.ptr, (size_t)s.len, fl, false);
+      return true;
+    }
+    break;
+  case 'd':
+  case 'b':
+  case 'o':
+  case 'x':
+  case 'X':
+    if (v.kind == LANG_INT) {
+      int base = verb == 'd' ? 10 : verb == 'b' ? 2 : verb == 'o' ? 8 : 16;
+      n += lang_digits(buf + n, v.u.i, base, verb == 'X');
+    } else if (v.kind == LANG_STRING && (verb == 'x' || verb == 'X')) {
+      int64_t i;
+      char *hex = lang_alloc((size_t)v.u.s.len * 2 + 1);
+      for (i = 0; i < v.u.s.len; i++) {
+        sprintf(hex + 2 * i, verb == 'x' ? "%02x" : "%02X", (unsigned char)v.u.s.ptr[i]);
+      }
+      lang_pad(hex, (size_t)v.u.s.len * 2, fl, false);
+      return true;
+    } else {
+      return false;
+    }
+    break;
+  case 'e':
+  case 'E':
+  case 'f':
+  case 'F':
+  case 'g':
+  case 'G':
+    if (v.kind != LANG_FLOAT) {
+      return false;
+    }
+    if ((verb == 'g' || verb == 'G') && fl.prec < 0) {
+      n += lang_format_float(buf + n, fabs(v.u.f));
+    } else {
+      char spec[8] = "%.*";
+      spec[3] = verb == 'F' ? 'f' : verb;
+      spec[4] = '\0';
+      n += snprintf(buf + n, sizeof buf - (size_t)n, spec, fl.prec < 0 ? 6 : fl.prec, fabs(v.u.f));
+    }
+    break;
+  case 's':
+  case 'q':
+    if (v.kind != LANG_STRING) {
+      return false;
+    }
+    if (verb == 'q') {
+      lang_string q = lang_quote(v.u.s);
+      lang_pad(q.ptr, (size_t)q.len, fl, false);
+    } else {
+      int64_t len = v.u.s.len;
+      if (fl.prec >= 0 && fl.prec < lang_len(v.u.s)) {
+        len = lang_rune_offset(v.u.s, fl.prec);
+      }
+      lang_pad(v.u.s.ptr, (size_t)len, fl, false);
+    }
+    return true;
+  case 't':
+    if (v.kind != LANG_BOOL) {
+      return false;
+    }
+    n += sprintf(buf + n, "%s", v.u.b ? "true" : "false");
+    break;
+  case 'c':
+    if (v.kind != LANG_INT) {
+      return false;
+    } else {
+      uint32_t r = v.u.i < 0 || v.u.i > 0x10ffff ? 0xfffd : (uint32_t)v.u.i;
+      n = 0;
+      if (r < 0x80) {
+        buf[n++] = (char)r;
+      } else if (r < 0x800) {
+        buf[n++] = (char)(0xc0 | r >> 6);
+        buf[n++] = (char)(0x80 | (r & 0x3f));
+      } else if (r < 0x10000) {
+        buf[n++] = (char)(0xe0 | r >> 12);
+        buf[n++] = (char)(0x80 | (r >> 6 & 0x3f));
+        buf[n++] = (char)(0x80 | (r & 0x3f));
+      } else {
+        buf[n++] = (char)(0xf0 | r >> 18);
+        buf[n++] = (char)(0x80 | (r >> 12 & 0x3f));
+        buf[n++] = (char)(0x80 | (r >> 6 & 0x3f));
+        buf[n++] = (char)(0x80 | (r & 0x3f));
+      }
+      lang_pad(buf, (size_t)n, fl, false);
+      return true;
+    }
+  default:
+    return false;
+  }
+  lang_pad(buf, (size_t)n, fl, number);
+  return true;
+}
+
+/* lang_printf implements the common verbs of Go's fmt.Printf, which is how
+ * printf behaves when a program is interpreted. */
+static inline void lang_printf(lang_string format, int n, const lang_value *args) {
+  int64_t i;
+  int arg = 0;
+  for (i = 0; i < format.len; i++) {
+    char c = format.ptr[i];
+    lang_flags fl = {false, false, false, false, 0, -1};
+    if (c != '%') {
+      putchar(c);
+      continue;
+    }
+    for (i++; i < format.len; i++) {
+      c = format.ptr[i];
+      if (c == '-') {
+        fl.minus = true;
+      } else if (c == '+') {
+        fl.plus = true;
+      } else if (c == ' ') {
+        fl.space = true;
+      } else if (c == '0') {
+        fl.zero = true;
+      } else if (c != '#') {
+        break;
+      }
+    }
+    for (; i < format.len && format.ptr[i] >= '0' && format.ptr[i] <= '9'; i++) {
+      fl.width = fl.width * 10 + (format.ptr[i] - '0');
+    }
+    if (i < format.len && format.ptr[i] == '.') {
+      fl.prec = 0;
+      for (i++; i < format.len && format.ptr[i] >= '0' && format.ptr[i] <= '9'; i++) {
+        fl.prec = fl.prec * 10 + (format.ptr[i] - '0');
+      }
+    }
+    if (i >= format.len) {
+      fputs("%!(NOVERB)", stdout);
+      break;
+    }
+    c = format.ptr[i];
+    if (c == '%') {
+      putchar('%');
+    } else if (arg >= n) {
+      printf("%%!%c(MISSING)", c);
+    } else {
+      lang_value v = args[arg++];
+      lang_flags none = {false, false, false, false, 0, -1};
+      if (!lang_format_value(c, fl, v)) {
+        printf("%%!%c(%s=", c, lang_type_name(v));
+        lang_format_value('v', none, v);
+        putchar(')');
+      }
+    }
+  }
+  if (arg < n) {
+    fputs("%!(EXTRA ", stdout);
+    for (; arg < n; arg++) {
+      lang_flags none = {false, false, false, false, 0, -1};
+      printf("%s=", lang_type_name(args[arg]));
+      lang_format_value('v', none, args[arg]);
+      fputs(arg < n - 1 ? ", " : ")", stdout);
+    }
+  }
+}
+
+/* Arguments to main */
+
+static inline bool lang_parse_bool(const char *s) {
+  if (!strcmp(s, "1") || !strcmp(s, "t") || !strcmp(s, "T") || !strcmp(s, "true") || !strcmp(s, "TRUE") || !strcmp(s, "True")) {
+    return true;
+  } else if (!strcmp(s, "0") || !strcmp(s, "f") || !strcmp(s, "F") || !strcmp(s, "false") || !strcmp(s, "FALSE") || !strcmp(s, "False")) {
+    return false;
+  }
+  lang_fatal("invalid bool \"%s\"", s);
+  return false;
+}
+
+static inline lang_string lang_arg(const char *s) {
+  return (lang_string){s, (int64_t)strlen(s)};
+}
@@ -0,0 +1,84 @@
+/* Code generated by lang build. DO NOT EDIT. */
+
+
+/* module control */
+static const lang_string control__Color_names[] = {{"Red", 3}, {"Green", 5}, {"Blue", 4}};
+static lang_string control__describe(int64_t v_c);
+static int64_t control__main(void);
+
+static void lang_init_control(void) {
+}
+
+static lang_string control__describe(int64_t v_c) {
+  {
+    int64_t t1 = v_c;
+    if (t1 == INT64_C(0)) {
+      goto L1_0;
+    }
+    if (t1 == INT64_C(1) || t1 == INT64_C(2)) {
+      goto L1_1;
+    }
+    goto L1_end;
+    L1_0: {
+      return LANG_STR("red");
+    }
+    L1_1: {
+      return LANG_STR("not red");
+    }
+    L1_end:;
+  }
+  return LANG_STR("unreachable");
+}
+
+static int64_t control__main(void) {
+  int64_t v_i = INT64_C(0);
+  int64_t v_sum = INT64_C(0);
+  while ((v_i < INT64_C(5))) {
+    int64_t v_i2 = lang_mul(v_i, v_i);
+    if ((lang_mod(v_i, INT64_C(2)) == INT64_C(0))) {
+      v_sum = lang_add(v_sum, v_i2);
+    } else {
+      if ((v_i == INT64_C(3))) {
+        int64_t v_sum = INT64_C(100);
+        v_sum = lang_sub(v_sum, INT64_C(1));
+      } else {
+        v_sum = lang_add(v_sum, 1);
+      }
+    }
+    v_i = lang_add(v_i, 1);
+  }
+  {
+    int64_t t1 = v_sum;
+    if (t1 == INT64_C(0)) {
+      goto L2_0;
+    }
+    if (t1 == INT64_C(21)) {
+      goto L2_1;
+    }
+    goto L2_2;
+    L2_0: {
+      lang_println(LANG_STR("zero"));
+    }
+    goto L2_end;
+    L2_1: {
+      lang_println(LANG_STR("twenty-one"));
+    }
+    L2_2: {
+      lang_println(LANG_STR("default"));
+    }
+    L2_end:;
+  }
+  lang_println(control__describe(INT64_C(1)));
+  int64_t t2 = lang_enum(INT64_C(2), 3, "Color");
+  lang_println(lang_concat(5, (lang_string[]){LANG_STR(""), control__Color_names[t2], LANG_STR(" "), lang_int_string(INT64_C(2)), LANG_STR("")}));
+  return v_sum;
+}
+
+int main(int argc, char **argv) {
+  if (argc != 1) {
+    fprintf(stderr, "usage: %s\n", argv[0]);
+    return 2;
+  }
+  lang_init_control();
+  return (int)control__main();
+}
@@ -0,0 +1,59 @@
+/* Code generated by lang build. DO NOT EDIT. */
+
+
+/* module functions */
+static int64_t functions__calls;
+static double functions__scale;
+static int64_t functions__fib(int64_t v_n);
+static double functions__area(double v_r);
+static bool functions__between(int64_t v_x, int64_t v_lo, int64_t v_hi);
+static int64_t functions__main(void);
+
+static void lang_init_functions(void) {
+  functions__calls = INT64_C(0);
+  functions__scale = 1.5;
+}
+
+static int64_t functions__fib(int64_t v_n) {
+  functions__calls = lang_add(functions__calls, 1);
+  if ((v_n < INT64_C(2))) {
+    return v_n;
+  }
+  int64_t t1 = functions__fib(lang_sub(v_n, INT64_C(1)));
+  return lang_add(t1, functions__fib(lang_sub(v_n, INT64_C(2))));
+}
+
+static double functions__area(double v_r) {
+  return (((3.14159 * v_r) * v_r) * functions__scale);
+}
+
+static bool functions__between(int64_t v_x, int64_t v_lo, int64_t v_hi) {
+  return (((v_x >= v_lo) && (v_x <= v_hi)) || (v_x == lang_neg(INT64_C(1))));
+}
+
+static int64_t functions__main(void) {
+  int64_t v_n = functions__fib(INT64_C(10));
+  v_n = lang_mul(v_n, INT64_C(2));
+  v_n = lang_sub(v_n, INT64_C(1));
+  v_n = lang_shl(v_n, INT64_C(1));
+  double v_f = functions__area(2.0);
+  v_f = (v_f / 2.0);
+  v_f -= 1.0;
+  int64_t t1 = functions__calls;
+  bool t2 = functions__between(v_n, INT64_C(0), INT64_C(100));
+  lang_println(lang_concat(11, (lang_string[]){LANG_STR(""), lang_int_string(v_n), LANG_STR(" "), lang_int_string(t1), LANG_STR(" "), lang_float_string(v_f), LANG_STR(" "), lang_bool_string(t2), LANG_STR(" "), lang_bool_string((!functions__between(INT64_C(5), INT64_C(0), INT64_C(10)))), LANG_STR("")}));
+  int64_t t3 = lang_float_to_int(v_f);
+  double t4 = ((double)v_n / 4.0);
+  int64_t t5 = lang_mod(v_n, INT64_C(7));
+  lang_println(lang_concat(15, (lang_string[]){LANG_STR(""), lang_int_string(t3), LANG_STR(" "), lang_float_string(t4), LANG_STR(" "), lang_int_string(lang_neg(v_n)), LANG_STR(" "), lang_int_string((~v_n)), LANG_STR(" "), lang_int_string(t5), LANG_STR(" "), lang_int_string(((v_n & INT64_C(6)) | (INT64_C(1) ^ INT64_C(8)))), LANG_STR(" "), lang_int_string(lang_shr(v_n, INT64_C(2))), LANG_STR("")}));
+  return ((v_n > INT64_C(100)) ? lang_abs(lang_sub(v_n, INT64_C(200))) : INT64_C(0));
+}
+
+int main(int argc, char **argv) {
+  if (argc != 1) {
+    fprintf(stderr, "usage: %s\n", argv[0]);
+    return 2;
+  }
+  lang_init_functions();
+  return (int)functions__main();
+}
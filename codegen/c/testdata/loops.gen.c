@@ -0,0 +1,48 @@
+/* Code generated by lang build. DO NOT EDIT. */
+
+
+/* module loops */
+static int64_t loops__counter;
+static int64_t loops__next(void);
+static int64_t loops__main(int64_t v_n, lang_string v_s);
+
+static void lang_init_loops(void) {
+  loops__counter = INT64_C(0);
+}
+
+static int64_t loops__next(void) {
+  loops__counter = lang_add(loops__counter, 1);
+  return loops__counter;
+}
+
+static int64_t loops__main(int64_t v_n, lang_string v_s) {
+  int64_t v_i = INT64_C(0);
+  while (((v_i < v_n) && (loops__next() < INT64_C(100)))) {
+    v_i = lang_add(v_i, INT64_C(2));
+    if ((v_i == INT64_C(4))) {
+      v_i = lang_add(v_i, 1);
+    }
+  }
+  bool v_b = ((v_i > INT64_C(3)) ? (loops__next() > INT64_C(0)) : false);
+  {
+    lang_string t1 = v_s;
+    if (lang_streq(t1, LANG_STR("a"))) {
+      goto L1_0;
+    }
+    goto L1_end;
+    L1_0: {
+      lang_println(lang_concat(3, (lang_string[]){LANG_STR("a "), lang_bool_string(v_b), LANG_STR("")}));
+    }
+    L1_end:;
+  }
+  return v_i;
+}
+
+int main(int argc, char **argv) {
+  if (argc != 3) {
+    fprintf(stderr, "usage: %s n s\n", argv[0]);
+    return 2;
+  }
+  lang_init_loops();
+  return (int)loops__main(lang_parseInt(lang_arg(argv[1])), lang_arg(argv[2]));
+}
@@ -0,0 +1,56 @@
+/* Code generated by lang build. DO NOT EDIT. */
+
+
+/* module geo */
+static const lang_string geo__Shape_names[] = {{"Square", 6}, {"Triangle", 8}};
+static int64_t geo__sides;
+static int64_t geo__area(int64_t v_s, int64_t v_size);
+
+/* module main */
+extern int64_t random(int64_t v_n);
+static int64_t main__main(void);
+
+static void lang_init_geo(void) {
+  geo__sides = INT64_C(4);
+}
+
+static int64_t geo__area(int64_t v_s, int64_t v_size) {
+  {
+    int64_t t1 = v_s;
+    if (t1 == INT64_C(0)) {
+      goto L1_0;
+    }
+    if (t1 == INT64_C(1)) {
+      goto L1_1;
+    }
+    goto L1_end;
+    L1_0: {
+      return lang_mul(v_size, v_size);
+    }
+    L1_1: {
+      return lang_div(lang_mul(v_size, v_size), INT64_C(2));
+    }
+    L1_end:;
+  }
+  return INT64_C(0);
+}
+
+static void lang_init_main(void) {
+}
+
+static int64_t main__main(void) {
+  int64_t v_s = INT64_C(1);
+  int64_t t1 = geo__area(v_s, INT64_C(4));
+  lang_println(lang_concat(7, (lang_string[]){LANG_STR(""), lang_int_string(t1), LANG_STR(" "), lang_int_string(geo__sides), LANG_STR(" "), geo__Shape_names[v_s], LANG_STR("")}));
+  return geo__area(INT64_C(0), random(INT64_C(3)));
+}
+
+int main(int argc, char **argv) {
+  if (argc != 1) {
+    fprintf(stderr, "usage: %s\n", argv[0]);
+    return 2;
+  }
+  lang_init_geo();
+  lang_init_main();
+  return (int)main__main();
+}
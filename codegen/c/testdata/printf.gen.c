@@ -0,0 +1,25 @@
+/* Code generated by lang build. DO NOT EDIT. */
+
+
+/* module printf */
+static const lang_string printf__Suit_names[] = {{"Hearts", 6}, {"Spades", 6}};
+static int64_t printf__main(int64_t v_n, bool v_verbose);
+
+static void lang_init_printf(void) {
+}
+
+static int64_t printf__main(int64_t v_n, bool v_verbose) {
+  double v_ratio = ((double)v_n / 3.0);
+  lang_printf(LANG_STR("%d %5.2f %t %s %v|%-6q|\012"), 6, (lang_value[]){lang_int_value(v_n), lang_float_value(v_ratio), lang_bool_value(v_verbose), lang_string_value(LANG_STR("cards")), lang_string_value(printf__Suit_names[INT64_C(1)]), lang_string_value(LANG_STR("x"))});
+  lang_printf(LANG_STR("done\012"), 0, NULL);
+  return INT64_C(0);
+}
+
+int main(int argc, char **argv) {
+  if (argc != 3) {
+    fprintf(stderr, "usage: %s n verbose\n", argv[0]);
+    return 2;
+  }
+  lang_init_printf();
+  return (int)printf__main(lang_parseInt(lang_arg(argv[1])), lang_parse_bool(argv[2]));
+}
@@ -0,0 +1,51 @@
+/* Code generated by lang build. DO NOT EDIT. */
+
+
+/* module strings */
+static lang_string strings__greet(lang_string v_name);
+static int64_t strings__main(lang_string v_name);
+
+static void lang_init_strings(void) {
+}
+
+static lang_string strings__greet(lang_string v_name) {
+  return lang_concat(3, (lang_string[]){LANG_STR("h\303\251llo, "), v_name, LANG_STR("!")});
+}
+
+static int64_t strings__main(lang_string v_name) {
+  lang_string v_s = strings__greet(v_name);
+  lang_println(v_s);
+  int64_t t1 = lang_len(v_s);
+  lang_string t2 = lang_substr(v_s, INT64_C(0), INT64_C(5));
+  lang_print(lang_concat(7, (lang_string[]){LANG_STR(""), lang_int_string(t1), LANG_STR(" "), t2, LANG_STR(" "), lang_int_string(lang_indexOf(v_s, LANG_STR("!"))), LANG_STR("\012")}));
+  int64_t t3 = lang_parseInt(LANG_STR("-42"));
+  int64_t v_n = lang_add(t3, lang_float_to_int(lang_parseFloat(LANG_STR("2.5"))));
+  bool v_same = lang_streq(v_s, strings__greet(v_name));
+  double t4 = lang_sqrt(16.0);
+  lang_println(lang_concat(11, (lang_string[]){LANG_STR(""), lang_int_string(v_n), LANG_STR(" "), lang_bool_string(v_same), LANG_STR(" "), lang_bool_string((!lang_streq(v_s, LANG_STR("x")))), LANG_STR(" "), lang_float_string(t4), LANG_STR(" "), lang_float_string(lang_pow(2.0, 8.0)), LANG_STR("")}));
+  {
+    lang_string t5 = v_name;
+    if (lang_streq(t5, LANG_STR("")) || lang_streq(t5, LANG_STR("nobody"))) {
+      goto L1_0;
+    }
+    goto L1_1;
+    L1_0: {
+      lang_println(LANG_STR("who\?"));
+    }
+    goto L1_end;
+    L1_1: {
+      lang_println(LANG_STR("hi"));
+    }
+    L1_end:;
+  }
+  return lang_len(v_name);
+}
+
+int main(int argc, char **argv) {
+  if (argc != 2) {
+    fprintf(stderr, "usage: %s name\n", argv[0]);
+    return 2;
+  }
+  lang_init_strings();
+  return (int)strings__main(lang_arg(argv[1]));
+}
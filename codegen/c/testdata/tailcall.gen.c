@@ -0,0 +1,92 @@
+/* Code generated by lang build. DO NOT EDIT. */
+
+
+/* module tailcall */
+static int64_t tailcall__count(int64_t v_n, int64_t v_acc);
+static bool tailcall__isEven(int64_t v_n);
+static bool tailcall__isOdd(int64_t v_n);
+static double tailcall__halve(double v_x, int64_t v_times);
+static int64_t tailcall__depth(int64_t v_n);
+static int64_t tailcall__main(int64_t v_n);
+
+static void lang_init_tailcall(void) {
+}
+
+static int64_t tailcall__count(int64_t v_n, int64_t v_acc) {
+lang_tail:;
+  if ((v_n == INT64_C(0))) {
+    return v_acc;
+  }
+  int64_t t1 = lang_sub(v_n, INT64_C(1));
+  int64_t t2 = lang_add(v_acc, INT64_C(1));
+  v_n = t1;
+  v_acc = t2;
+  goto lang_tail;
+}
+
+static bool lang_tail_tailcall__isEven(int lang_fn, int64_t a0_n, int64_t a1_n) {
+  switch (lang_fn) {
+  case 0: goto lang_tail_0;
+  case 1: goto lang_tail_1;
+  }
+lang_tail_0: {
+    int64_t v_n = a0_n;
+    if ((v_n == INT64_C(0))) {
+      return true;
+    }
+    a1_n = lang_sub(v_n, INT64_C(1));
+    goto lang_tail_1;
+  }
+lang_tail_1: {
+    int64_t v_n = a1_n;
+    if ((v_n == INT64_C(0))) {
+      return false;
+    }
+    a0_n = lang_sub(v_n, INT64_C(1));
+    goto lang_tail_0;
+  }
+}
+
+static bool tailcall__isEven(int64_t v_n) {
+  return lang_tail_tailcall__isEven(0, v_n, 0);
+}
+
+static bool tailcall__isOdd(int64_t v_n) {
+  return lang_tail_tailcall__isEven(1, 0, v_n);
+}
+
+static double tailcall__halve(double v_x, int64_t v_times) {
+lang_tail:;
+  if ((v_times == INT64_C(0))) {
+    return v_x;
+  }
+  double t1 = (v_x / 2.0);
+  int64_t t2 = lang_sub(v_times, INT64_C(1));
+  v_x = t1;
+  v_times = t2;
+  goto lang_tail;
+}
+
+static int64_t tailcall__depth(int64_t v_n) {
+  if ((v_n == INT64_C(0))) {
+    return INT64_C(0);
+  }
+  return lang_add(INT64_C(1), tailcall__depth(lang_sub(v_n, INT64_C(1))));
+}
+
+static int64_t tailcall__main(int64_t v_n) {
+  int64_t t1 = tailcall__count(INT64_C(100000), v_n);
+  bool t2 = tailcall__isEven(INT64_C(100001));
+  double t3 = tailcall__halve(1024.0, INT64_C(20));
+  lang_println(lang_concat(9, (lang_string[]){LANG_STR(""), lang_int_string(t1), LANG_STR(" "), lang_bool_string(t2), LANG_STR(" "), lang_float_string(t3), LANG_STR(" "), lang_int_string(tailcall__depth(INT64_C(100))), LANG_STR("")}));
+  return tailcall__count(INT64_C(3), v_n);
+}
+
+int main(int argc, char **argv) {
+  if (argc != 2) {
+    fprintf(stderr, "usage: %s n\n", argv[0]);
+    return 2;
+  }
+  lang_init_tailcall();
+  return (int)tailcall__main(lang_parseInt(lang_arg(argv[1])));
+}
@@ -0,0 +1,195 @@
+// Package codegentest holds the test programs that the backends share, and
+// the helpers their tests use to compare what they generate for those
+// programs against golden files and to run the executables they build.
+package codegentest
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/format"
+	"lang/loader"
+	"lang/optimize"
+	"lang/parser"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the current results")
+
+// dir returns the directory holding this file.
+func dir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}
+
+// programs returns the paths of the programs in dir, other than those
+// named in skip without their .c extension.
+func programs(t *testing.T, dir string, skip []string) []string {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	skipped := map[string]bool{}
+	for _, name := range skip {
+		if _, err := os.Stat(filepath.Join(dir, name+".c")); err != nil {
+			t.Fatalf("cannot skip %s: %v", name, err)
+		}
+		skipped[name] = true
+	}
+	var programs []string
+	for _, path := range paths {
+		if !skipped[name(path)] {
+			programs = append(programs, path)
+		}
+	}
+	return programs
+}
+
+// name returns the name of the program at path.
+func name(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), ".c")
+}
+
+// Golden calls generate on each program in codegen/testdata, other than
+// those named in skip, and compares what it writes against the golden file
+// in the testdata directory of the calling test, with the same name and the
+// extension ext. With the -update flag, the golden files are rewritten
+// instead.
+func Golden(t *testing.T, ext string, skip []string, generate func(w io.Writer, mods []*loader.Module) error) {
+	for _, path := range programs(t, filepath.Join(dir(), "..", "testdata"), skip) {
+		path := path
+		t.Run(name(path), func(t *testing.T) {
+			mods, err := loader.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got bytes.Buffer
+			if err := generate(&got, mods); err != nil {
+				t.Fatal(err)
+			}
+			golden := filepath.Join("testdata", name(path)+ext)
+			if *update {
+				if err := ioutil.WriteFile(golden, got.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if os.IsNotExist(err) {
+				t.Fatalf("%s is missing; run go test -update to create it", golden)
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s differs:\n%s", golden, format.Diff(golden, "got", want, got.Bytes()))
+			}
+		})
+	}
+}
+
+// Run builds each program in the repository's testdata that the
+// interpreter runs, other than those named in skip, as lang build does: it
+// type checks and optimizes the program, and then calls build to write an
+// executable to exe. The executable is run with zero values as arguments,
+// and what it prints and its exit status must match the interpreter's
+// results in the program's .out file.
+func Run(t *testing.T, skip []string, build func(exe string, mods []*loader.Module) error) {
+	tmp, err := ioutil.TempDir("", "codegentest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	for _, path := range programs(t, filepath.Join(dir(), "..", "..", "testdata"), skip) {
+		path := path
+		want, err := ioutil.ReadFile(strings.TrimSuffix(path, ".c") + ".out")
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		exe := filepath.Join(tmp, name(path))
+		t.Run(name(path), func(t *testing.T) {
+			mods, err := loader.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !analysis.CheckIn(analysis.NewUniverse(ioutil.Discard), mods) {
+				t.Fatal("program does not type check")
+			}
+			optimize.Program(mods, nil)
+			if err := build(exe, mods); err != nil {
+				t.Fatal(err)
+			}
+			checkRun(t, exec.Command(exe, zeroArgs(mods)...), want)
+		})
+	}
+}
+
+// zeroArgs returns the command line arguments that pass zero values to the
+// main function of the last of mods.
+func zeroArgs(mods []*loader.Module) []string {
+	var args []string
+	for _, stmt := range mods[len(mods)-1].Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && f.Name.Lexeme == "main" {
+			for _, p := range f.Params {
+				switch p.Kind.Lexeme {
+				case "int", "float":
+					args = append(args, "0")
+				case "bool":
+					args = append(args, "false")
+				default:
+					args = append(args, "")
+				}
+			}
+		}
+	}
+	return args
+}
+
+// checkRun runs cmd and compares its output and exit status with those
+// that the interpreter's output in a .out file stands for: a final
+// "result: n" line is the exit status n, and a final "error: msg" line is
+// msg on standard error and exit status 1.
+func checkRun(t *testing.T, cmd *exec.Cmd, out []byte) {
+	t.Helper()
+	wantStdout, wantStderr, wantStatus := string(out), "", 0
+	i := strings.LastIndex(strings.TrimSuffix(wantStdout, "\n"), "\n") + 1
+	last := wantStdout[i:]
+	if strings.HasPrefix(last, "result: ") {
+		n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(last, "result: ")), 10, 64)
+		if err != nil {
+			t.Fatalf("bad result line %q", last)
+		}
+		wantStdout, wantStatus = wantStdout[:i], int(uint8(n))
+	} else if strings.HasPrefix(last, "error: ") {
+		wantStdout, wantStderr, wantStatus = wantStdout[:i], strings.TrimPrefix(last, "error: "), 1
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	status := 0
+	if err := cmd.Run(); err != nil {
+		exit, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatal(err)
+		}
+		status = exit.ExitCode()
+	}
+	if stdout.String() != wantStdout {
+		t.Errorf("standard output differs:\n%s", format.Diff("want", "got", []byte(wantStdout), stdout.Bytes()))
+	}
+	if stderr.String() != wantStderr {
+		t.Errorf("standard error is %q, want %q", stderr.String(), wantStderr)
+	}
+	if status != wantStatus {
+		t.Errorf("exit status is %d, want %d", status, wantStatus)
+	}
+}
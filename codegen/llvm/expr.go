@@ -0,0 +1,419 @@
+package llvm
+
+import (
+	"fmt"
+	"lang/analysis"
+	"lang/parser"
+	"lang/scanner"
+	"strconv"
+	"strings"
+)
+
+var (
+	// intOps and floatOps are the instructions for binary operators. Those
+	// that can fail on ints are runtime functions.
+	intOps = map[scanner.TokenKind]string{
+		scanner.Plus:    "add i64",
+		scanner.Minus:   "sub i64",
+		scanner.Star:    "mul i64",
+		scanner.BAnd:    "and i64",
+		scanner.BOr:     "or i64",
+		scanner.BXor:    "xor i64",
+		scanner.Slash:   "div",
+		scanner.Percent: "mod",
+		scanner.Shl:     "shl",
+		scanner.Shr:     "shr",
+		scanner.Lt:      "icmp slt",
+		scanner.Lte:     "icmp sle",
+		scanner.Gt:      "icmp sgt",
+		scanner.Gte:     "icmp sge",
+		scanner.EqEq:    "icmp eq",
+		scanner.Ne:      "icmp ne",
+	}
+	floatOps = map[scanner.TokenKind]string{
+		scanner.Plus:  "fadd",
+		scanner.Minus: "fsub",
+		scanner.Star:  "fmul",
+		scanner.Slash: "fdiv",
+		scanner.Lt:    "fcmp olt",
+		scanner.Lte:   "fcmp ole",
+		scanner.Gt:    "fcmp ogt",
+		scanner.Gte:   "fcmp oge",
+		scanner.EqEq:  "fcmp oeq",
+		scanner.Ne:    "fcmp une",
+	}
+)
+
+// runtime are the signatures of the functions of the runtime that are not
+// builtins, without their lang_asm_ prefix.
+var runtime = map[string]string{
+	"div":          "i64(i64, i64)",
+	"mod":          "i64(i64, i64)",
+	"shl":          "i64(i64, i64)",
+	"shr":          "i64(i64, i64)",
+	"float_to_int": "i64(double)",
+	"enum":         "i64(i64, i64, i8*)",
+	"int_string":   "%lang.string*(i64)",
+	"float_string": "%lang.string*(double)",
+	"bool_string":  "%lang.string*(i64)",
+	"concat":       "%lang.string*(%lang.string*, %lang.string*)",
+	"streq":        "i64(%lang.string*, %lang.string*)",
+	"printf":       "void(%lang.string*, i64, i64*)",
+	"usage":        "void(i8*, i8*)",
+	"arg_int":      "i64(i8*)",
+	"arg_float":    "double(i8*)",
+	"arg_bool":     "i64(i8*)",
+	"arg_string":   "%lang.string*(i8*)",
+}
+
+// kinds are the lang_kind of each type of argument to lang_asm_printf.
+var kinds = map[analysis.Type]int{
+	analysis.Int:    0,
+	analysis.Float:  1,
+	analysis.Bool:   2,
+	analysis.String: 3,
+}
+
+// expr writes the instructions that compute e, and returns its value.
+func (g *generator) expr(e parser.Expr) string {
+	switch n := e.(type) {
+	case parser.LiteralNum:
+		if analysis.TypeOf(g.env, n) == analysis.Float {
+			f, _ := strconv.ParseFloat(n.Value, 64)
+			return float(f)
+		}
+		i, _ := strconv.ParseInt(n.Value, 10, 64)
+		return strconv.FormatInt(i, 10)
+	case parser.LiteralStr:
+		return g.str(n.Value)
+	case parser.LiteralBool:
+		return strconv.FormatBool(n.Value)
+	case parser.IdentExpr:
+		t := ltype(analysis.TypeOf(g.env, n))
+		return g.temp("load %s, %s* %s", t, t, g.variable(n.Name.Lexeme))
+	case parser.MemberAccess:
+		if enum, isEnum := analysis.TypeNamed(g.env, n.Parent).(*analysis.EnumType); isEnum {
+			for i, m := range enum.Members {
+				if string(m) == n.Name.Lexeme {
+					return strconv.Itoa(i)
+				}
+			}
+		}
+		mt := analysis.TypeOf(g.env, n.Parent).(*analysis.ModuleType)
+		t := ltype(analysis.TypeOf(g.env, n))
+		return g.temp("load %s, %s* @%s.%s", t, t, cName(string(mt.Name)), n.Name.Lexeme)
+	case parser.FunctionCall:
+		return g.call(n, false)
+	case parser.UnaryOp:
+		x := g.expr(n.Expr)
+		switch n.Op.Kind {
+		case scanner.Minus:
+			if analysis.TypeOf(g.env, n) == analysis.Float {
+				return g.temp("fneg double %s", x)
+			}
+			return g.temp("sub i64 0, %s", x)
+		case scanner.LNot:
+			return g.temp("xor i1 %s, true", x)
+		default:
+			return g.temp("xor i64 %s, -1", x)
+		}
+	case parser.BinaryOp:
+		if n.Op.Kind == scanner.LAnd || n.Op.Kind == scanner.LOr {
+			return g.logical(n)
+		}
+		t := analysis.TypeOf(g.env, n.Left)
+		l, r := g.expr(n.Left), g.expr(n.Right)
+		switch t {
+		case analysis.Float:
+			return g.temp("%s double %s, %s", floatOps[n.Op.Kind], l, r)
+		case analysis.String:
+			eq := g.runtime("streq", "%lang.string* "+l, "%lang.string* "+r)
+			if n.Op.Kind == scanner.Ne {
+				return g.temp("icmp eq i64 %s, 0", eq)
+			}
+			return g.temp("icmp ne i64 %s, 0", eq)
+		case analysis.Bool:
+			return g.temp("%s i1 %s, %s", intOps[n.Op.Kind], l, r)
+		}
+		op := intOps[n.Op.Kind]
+		if !strings.Contains(op, " ") {
+			return g.runtime(op, "i64 "+l, "i64 "+r)
+		}
+		if strings.HasPrefix(op, "icmp") {
+			return g.temp("%s i64 %s, %s", op, l, r)
+		}
+		return g.temp("%s %s, %s", op, l, r)
+	case parser.TernaryExpr:
+		then, els, end := g.label(), g.label(), g.label()
+		g.branch("br i1 %s, label %%%s, label %%%s", g.expr(n.Cond), then, els)
+		g.start(then)
+		x := g.expr(n.Then)
+		thenEnd := g.block
+		g.jump(end)
+		g.start(els)
+		y := g.expr(n.Els)
+		elsEnd := g.block
+		g.start(end)
+		return g.temp("phi %s [ %s, %%%s ], [ %s, %%%s ]", ltype(analysis.TypeOf(g.env, n)), x, thenEnd, y, elsEnd)
+	case parser.InterpolatedStr:
+		var s string
+		for _, part := range n.Parts {
+			if lit, ok := part.(parser.LiteralStr); ok && lit.Value == "" {
+				continue
+			}
+			x := g.toString(analysis.TypeOf(g.env, part), g.expr(part))
+			if s == "" {
+				s = x
+			} else {
+				s = g.runtime("concat", "%lang.string* "+s, "%lang.string* "+x)
+			}
+		}
+		if s == "" {
+			return g.str("")
+		}
+		return s
+	default:
+		panic(fmt.Sprintf("cannot generate LLVM IR for %T", e))
+	}
+}
+
+// logical returns the value of && or ||, from a phi of the value that
+// decides it without the right operand and the value of the right operand.
+func (g *generator) logical(n parser.BinaryOp) string {
+	right, end := g.label(), g.label()
+	l := g.expr(n.Left)
+	left := g.block
+	short := "false"
+	if n.Op.Kind == scanner.LAnd {
+		g.branch("br i1 %s, label %%%s, label %%%s", l, right, end)
+	} else {
+		short = "true"
+		g.branch("br i1 %s, label %%%s, label %%%s", l, end, right)
+	}
+	g.start(right)
+	r := g.expr(n.Right)
+	rightEnd := g.block
+	g.start(end)
+	return g.temp("phi i1 [ %s, %%%s ], [ %s, %%%s ]", short, left, r, rightEnd)
+}
+
+// equal returns whether x and y, of type t, are equal.
+func (g *generator) equal(t analysis.Type, x, y string) string {
+	switch t {
+	case analysis.String:
+		return g.temp("icmp ne i64 %s, 0", g.runtime("streq", "%lang.string* "+x, "%lang.string* "+y))
+	default:
+		return g.temp("icmp eq %s %s, %s", ltype(t), x, y)
+	}
+}
+
+// runtime calls a function of the runtime with args, which are typed
+// values, and returns its result.
+func (g *generator) runtime(name string, args ...string) string {
+	sig := runtime[name]
+	i := strings.Index(sig, "(")
+	fn := "@lang_asm_" + name
+	g.decls[fn] = fmt.Sprintf("declare %s %s%s", sig[:i], fn, sig[i:])
+	call := fmt.Sprintf("call %s %s(%s)", sig[:i], fn, strings.Join(args, ", "))
+	if sig[:i] == "void" {
+		g.ins("%s", call)
+		return ""
+	}
+	return g.temp("%s", call)
+}
+
+// call calls the function n calls. If tail is set, the result of the call
+// is returned at once, and calls to functions of the program are marked as
+// tail calls.
+func (g *generator) call(n parser.FunctionCall, tail bool) string {
+	if t := analysis.TypeNamed(g.env, n.Callee); t != nil {
+		return g.convert(t, n.Args[0])
+	}
+	ft := analysis.TypeOf(g.env, n.Callee).(analysis.FunctionType)
+	switch callee := n.Callee.(type) {
+	case parser.IdentExpr:
+		name := callee.Name.Lexeme
+		if g.builtins[name] && !g.isLocal(name) && !g.globals[name] {
+			return g.builtin(name, ft, n.Args)
+		} else if g.mod.Extern(name) {
+			return g.callC("@"+name, ft, n.Args)
+		}
+		return g.callFunction(g.global(name), ft, n.Args, tail)
+	case parser.MemberAccess:
+		mt := analysis.TypeOf(g.env, callee.Parent).(*analysis.ModuleType)
+		for _, m := range g.mods {
+			if m.Name == string(mt.Name) && m.Extern(callee.Name.Lexeme) {
+				return g.callC("@"+callee.Name.Lexeme, ft, n.Args)
+			}
+		}
+		return g.callFunction("@"+cName(string(mt.Name))+"."+callee.Name.Lexeme, ft, n.Args, tail)
+	}
+	panic(fmt.Sprintf("cannot call %T", n.Callee))
+}
+
+// callFunction calls a function of the program. A tail call to one with
+// the signature of the function being generated is a musttail call, which
+// LLVM always compiles to a jump, so that recursion through such calls runs
+// in constant stack space.
+func (g *generator) callFunction(fn string, ft analysis.FunctionType, exprs []parser.Expr, tail bool) string {
+	var args []string
+	for i, e := range exprs {
+		args = append(args, ltype(ft.Params[i])+" "+g.expr(e))
+	}
+	call := fmt.Sprintf("call %s %s(%s)", ltype(ft.Return), fn, strings.Join(args, ", "))
+	if tail && sameSignature(ft, g.fn) {
+		call = "musttail " + call
+	} else if tail {
+		call = "tail " + call
+	}
+	if ft.Return == analysis.Void {
+		g.ins("%s", call)
+		return ""
+	}
+	return g.temp("%s", call)
+}
+
+func sameSignature(a, b analysis.FunctionType) bool {
+	if a.Return != b.Return || len(a.Params) != len(b.Params) {
+		return false
+	}
+	for i := range a.Params {
+		if a.Params[i] != b.Params[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// callC calls a function implemented in C, declaring it with the types C
+// passes values as.
+func (g *generator) callC(fn string, ft analysis.FunctionType, exprs []parser.Expr) string {
+	var params, args []string
+	for _, t := range ft.Params {
+		params = append(params, abiType(t))
+	}
+	if ft.Variadic {
+		params = append(params, "...")
+	}
+	for _, e := range exprs {
+		t := analysis.TypeOf(g.env, e)
+		args = append(args, abiType(t)+" "+g.toABI(t, g.expr(e)))
+	}
+	ret := abiType(ft.Return)
+	g.decls[fn] = fmt.Sprintf("declare %s %s(%s)", ret, fn, strings.Join(params, ", "))
+	if ft.Variadic {
+		ret = fmt.Sprintf("%s (%s)", ret, strings.Join(params, ", "))
+	}
+	call := fmt.Sprintf("call %s %s(%s)", ret, fn, strings.Join(args, ", "))
+	if ft.Return == analysis.Void {
+		g.ins("%s", call)
+		return ""
+	}
+	return g.fromABI(ft.Return, g.temp("%s", call))
+}
+
+// toABI converts v, of type t, to the type C passes it as.
+func (g *generator) toABI(t analysis.Type, v string) string {
+	if t == analysis.Bool {
+		return g.temp("zext i1 %s to i64", v)
+	}
+	return v
+}
+
+// fromABI converts v, of type t as C passes it, to the type of t.
+func (g *generator) fromABI(t analysis.Type, v string) string {
+	if t == analysis.Bool {
+		return g.temp("icmp ne i64 %s, 0", v)
+	}
+	return v
+}
+
+func (g *generator) builtin(name string, ft analysis.FunctionType, exprs []parser.Expr) string {
+	switch name {
+	case "printf":
+		return g.printfCall(exprs)
+	case "sqrt":
+		g.decls["@llvm.sqrt.f64"] = "declare double @llvm.sqrt.f64(double)"
+		return g.temp("call double @llvm.sqrt.f64(double %s)", g.expr(exprs[0]))
+	default:
+		return g.callC("@lang_asm_"+name, ft, exprs)
+	}
+}
+
+// printfCall stores the lang_kind and value of each argument in an array,
+// the last first, and passes it to lang_asm_printf.
+func (g *generator) printfCall(exprs []parser.Expr) string {
+	format := g.expr(exprs[0])
+	n := len(exprs) - 1
+	g.temps++
+	pairs := fmt.Sprintf("%%printf%d", g.temps)
+	array := fmt.Sprintf("[%d x i64]", 2*n)
+	fmt.Fprintf(g.allocas, "  %s = alloca %s\n", pairs, array)
+	for i, e := range exprs[1:] {
+		t := analysis.TypeOf(g.env, e)
+		v := g.expr(e)
+		switch t.(type) {
+		case *analysis.EnumType:
+			v = g.toString(t, v)
+			t = analysis.String
+		}
+		switch t {
+		case analysis.Float:
+			v = g.temp("bitcast double %s to i64", v)
+		case analysis.Bool:
+			v = g.temp("zext i1 %s to i64", v)
+		case analysis.String:
+			v = g.temp("ptrtoint %%lang.string* %s to i64", v)
+		}
+		kind := g.temp("getelementptr inbounds %s, %s* %s, i64 0, i64 %d", array, array, pairs, 2*(n-1-i))
+		g.ins("store i64 %d, i64* %s", kinds[t], kind)
+		value := g.temp("getelementptr inbounds %s, %s* %s, i64 0, i64 %d", array, array, pairs, 2*(n-1-i)+1)
+		g.ins("store i64 %s, i64* %s", v, value)
+	}
+	first := g.temp("getelementptr inbounds %s, %s* %s, i64 0, i64 0", array, array, pairs)
+	g.runtime("printf", "%lang.string* "+format, fmt.Sprintf("i64 %d", n), "i64* "+first)
+	return ""
+}
+
+// toString converts v, of type t, to a string.
+func (g *generator) toString(t analysis.Type, v string) string {
+	switch t := t.(type) {
+	case *analysis.EnumType:
+		array := fmt.Sprintf("[%d x %%lang.string]", len(t.Members))
+		return g.temp("getelementptr inbounds %s, %s* %s, i64 0, i64 %s", array, array, g.enums[t], v)
+	default:
+		switch t {
+		case analysis.Int:
+			return g.runtime("int_string", "i64 "+v)
+		case analysis.Float:
+			return g.runtime("float_string", "double "+v)
+		case analysis.Bool:
+			return g.runtime("bool_string", "i64 "+g.toABI(t, v))
+		default:
+			return v
+		}
+	}
+}
+
+// convert converts e to type t.
+func (g *generator) convert(t analysis.Type, e parser.Expr) string {
+	v := g.expr(e)
+	from := analysis.TypeOf(g.env, e)
+	if from == t {
+		return v
+	}
+	if enum, isEnum := t.(*analysis.EnumType); isEnum {
+		return g.runtime("enum", "i64 "+v, fmt.Sprintf("i64 %d", len(enum.Members)), "i8* "+g.cstr(string(enum.Name)))
+	}
+	switch t {
+	case analysis.Int:
+		if from == analysis.Float {
+			return g.runtime("float_to_int", "double "+v)
+		}
+		return v
+	case analysis.Float:
+		return g.temp("sitofp i64 %s to double", v)
+	default:
+		return g.toString(from, v)
+	}
+}
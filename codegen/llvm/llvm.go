@@ -0,0 +1,584 @@
+// Package llvm translates type checked programs to the textual form of
+// LLVM IR, so that LLVM's tools can optimize and compile them.
+//
+// Ints and enums are i64, floats are double, bools are i1 and a string is a
+// pointer to a %lang.string, which has the layout of the lang_string of
+// programs compiled to C. Variables live in allocas in the entry block of
+// each function, which LLVM's mem2reg pass turns into SSA values. The IR
+// uses typed pointers, as LLVM 14 and earlier expect.
+//
+// Builtin functions are implemented by the runtime of the amd64 backend,
+// which must be linked with the program, as in
+//
+//	clang program.ll codegen/amd64/runtime/runtime.c -Icodegen/c -lm
+//
+// Runtime and extern functions pass bools as i64, as C does for int64_t.
+package llvm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/loader"
+	"lang/parser"
+	"lang/prelude"
+	"lang/scanner"
+	"math"
+	"sort"
+	"strings"
+)
+
+type generator struct {
+	out  *bytes.Buffer
+	mods []*loader.Module
+	// Names of the builtin functions, which the runtime implements.
+	builtins map[string]bool
+	// Constants, and the names of those already written.
+	consts    *bytes.Buffer
+	arrays    map[string]string
+	strings   map[string]string
+	cstrings  map[string]string
+	constants int
+	// Names of the tables of each enum's member names.
+	enums map[*analysis.EnumType]string
+	// Declarations of the runtime and extern functions that are called.
+	decls map[string]string
+
+	mod     *loader.Module
+	env     analysis.Env
+	globals map[string]bool
+
+	// The type of the function being generated. Its allocas are written
+	// separately, to put them at the start of its entry block.
+	fn      analysis.FunctionType
+	allocas *bytes.Buffer
+	// Registers of the allocas of the variables declared in each enclosing
+	// block, innermost last, and how many of each name have been declared.
+	locals []map[string]string
+	names  map[string]int
+	temps  int
+	labels int
+	// The current basic block, and whether it has been terminated.
+	block      string
+	terminated bool
+}
+
+// Generate writes mods, ordered as returned by loader.Load, as an LLVM
+// module whose main function calls the main function of the last module
+// with its command line arguments. If that returns an int, it is the exit
+// status.
+func Generate(w io.Writer, mods []*loader.Module) error {
+	envs, ok := analysis.CheckModules(analysis.NewUniverse(ioutil.Discard), mods)
+	if !ok {
+		return errors.New("program does not type check")
+	}
+	g := &generator{
+		out:      &bytes.Buffer{},
+		mods:     mods,
+		builtins: map[string]bool{},
+		consts:   &bytes.Buffer{},
+		arrays:   map[string]string{},
+		strings:  map[string]string{},
+		cstrings: map[string]string{},
+		enums:    map[*analysis.EnumType]string{},
+		decls:    map[string]string{},
+	}
+	for _, stmt := range prelude.Stmts() {
+		g.builtins[stmt.(parser.FunctionStmt).Name.Lexeme] = true
+	}
+	entry := mods[len(mods)-1]
+	var main *parser.FunctionStmt
+	for _, stmt := range entry.Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && f.Name.Lexeme == "main" {
+			main = &f
+		}
+	}
+	if main == nil {
+		return fmt.Errorf("%s: no main function", entry.Path)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("; Code generated by lang build. DO NOT EDIT.\n\n")
+	out.WriteString("%lang.string = type { i8*, i64 }\n")
+	for i, m := range mods {
+		g.enter(m, envs[i])
+		g.module(&out)
+	}
+	g.entry(&out, *main)
+	out.WriteString("\n")
+	out.Write(g.consts.Bytes())
+	var names []string
+	for name := range g.decls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out.WriteString("\n")
+	for _, name := range names {
+		out.WriteString(g.decls[name] + "\n")
+	}
+	_, err := w.Write(out.Bytes())
+	return err
+}
+
+func (g *generator) enter(m *loader.Module, env analysis.Env) {
+	g.mod = m
+	g.env = env
+	g.globals = map[string]bool{}
+	for _, stmt := range m.Stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			g.globals[s.Name.Lexeme] = true
+		case parser.VarStmt:
+			g.globals[s.Name.Lexeme] = true
+		}
+	}
+}
+
+// global returns the name of a top-level name in the current module.
+func (g *generator) global(name string) string {
+	return "@" + cName(g.mod.Name) + "." + name
+}
+
+// cName makes a module name usable in an LLVM identifier.
+func cName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// ltype returns the LLVM type of values of type t.
+func ltype(t analysis.Type) string {
+	switch t {
+	case analysis.Float:
+		return "double"
+	case analysis.Bool:
+		return "i1"
+	case analysis.String:
+		return "%lang.string*"
+	case analysis.Void:
+		return "void"
+	default:
+		return "i64"
+	}
+}
+
+// abiType returns the LLVM type of values of type t passed to and from C.
+func abiType(t analysis.Type) string {
+	if t == analysis.Bool {
+		return "i64"
+	}
+	return ltype(t)
+}
+
+// zero returns the zero value of type t.
+func (g *generator) zero(t analysis.Type) string {
+	switch t {
+	case analysis.Float:
+		return "0.0"
+	case analysis.Bool:
+		return "false"
+	case analysis.String:
+		return g.str("")
+	default:
+		return "0"
+	}
+}
+
+// module writes the current module's variables and functions, and a
+// function that initializes its variables.
+func (g *generator) module(out *bytes.Buffer) {
+	fmt.Fprintf(out, "\n; module %s\n", g.mod.Name)
+	for _, stmt := range g.mod.Stmts {
+		switch s := stmt.(type) {
+		case parser.EnumStmt:
+			var names []string
+			for _, m := range s.Members {
+				names = append(names, "%lang.string "+g.strConst(m.Lexeme))
+			}
+			table := g.global(s.Name.Lexeme) + ".names"
+			fmt.Fprintf(g.consts, "%s = private unnamed_addr constant [%d x %%lang.string] [%s]\n", table, len(names), strings.Join(names, ", "))
+			g.enums[g.env.LookupType(s.Name.Lexeme).(*analysis.EnumType)] = table
+		case parser.VarStmt:
+			t := g.env.LookupType(s.Kind.Lexeme)
+			fmt.Fprintf(out, "%s = internal global %s %s\n", g.global(s.Name.Lexeme), ltype(t), g.zero(t))
+		}
+	}
+
+	fmt.Fprintf(out, "\ndefine internal void @lang_init_%s() {\n", cName(g.mod.Name))
+	g.begin()
+	for _, stmt := range g.mod.Stmts {
+		if s, ok := stmt.(parser.VarStmt); ok && s.Expr != nil {
+			t := g.env.LookupType(s.Kind.Lexeme)
+			g.ins("store %s %s, %s* %s", ltype(t), g.expr(s.Expr), ltype(t), g.global(s.Name.Lexeme))
+		}
+	}
+	g.branch("ret void")
+	g.end(out)
+
+	for _, stmt := range g.mod.Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && !f.Extern {
+			g.function(out, f)
+		}
+	}
+}
+
+// begin starts generating a function.
+func (g *generator) begin() {
+	g.out = &bytes.Buffer{}
+	g.allocas = &bytes.Buffer{}
+	g.locals = []map[string]string{{}}
+	g.names = map[string]int{}
+	g.temps = 0
+	g.labels = 0
+	g.block = "entry"
+	g.terminated = false
+}
+
+// end writes the function being generated to out.
+func (g *generator) end(out *bytes.Buffer) {
+	out.WriteString("entry:\n")
+	out.Write(g.allocas.Bytes())
+	out.Write(g.out.Bytes())
+	out.WriteString("}\n")
+}
+
+// function writes f, whose signature is given by its analysis.FunctionType.
+func (g *generator) function(out *bytes.Buffer, f parser.FunctionStmt) {
+	ft := analysis.TypeOf(g.env, parser.IdentExpr{Name: f.Name}).(analysis.FunctionType)
+	env := g.env
+	g.env = analysis.NewBlockEnv(env)
+	g.begin()
+	g.fn = ft
+	var params []string
+	for i, p := range f.Params {
+		t := ft.Params[i]
+		params = append(params, fmt.Sprintf("%s %%%s", ltype(t), p.Name.Lexeme))
+		addr := g.declare(p.Name.Lexeme, t)
+		g.ins("store %s %%%s, %s* %s", ltype(t), p.Name.Lexeme, ltype(t), addr)
+	}
+	fmt.Fprintf(out, "\ndefine internal %s %s(%s) {\n", ltype(ft.Return), g.global(f.Name.Lexeme), strings.Join(params, ", "))
+	g.stmts(f.Body.Stmts, ft.Return)
+	if !g.terminated {
+		if ft.Return == analysis.Void {
+			g.branch("ret void")
+		} else {
+			g.branch("ret %s %s", ltype(ft.Return), g.zero(ft.Return))
+		}
+	}
+	g.end(out)
+	g.env = env
+}
+
+// entry writes the C main function, which checks and parses the command
+// line arguments and passes them to the main function of the current
+// module.
+func (g *generator) entry(out *bytes.Buffer, main parser.FunctionStmt) {
+	ft := analysis.TypeOf(g.env, parser.IdentExpr{Name: main.Name}).(analysis.FunctionType)
+	var usage string
+	for _, p := range main.Params {
+		usage += " " + p.Name.Lexeme
+	}
+	fmt.Fprintf(out, "\ndefine i32 @main(i32 %%argc, i8** %%argv) {\n")
+	g.begin()
+	run, fail := g.label(), g.label()
+	ok := g.temp("icmp eq i32 %%argc, %d", len(main.Params)+1)
+	g.branch("br i1 %s, label %%%s, label %%%s", ok, run, fail)
+	g.start(fail)
+	program := g.temp("load i8*, i8** %%argv")
+	g.runtime("usage", "i8* "+program, "i8* "+g.cstr(usage))
+	g.branch("unreachable")
+	g.start(run)
+	for _, m := range g.mods {
+		g.ins("call void @lang_init_%s()", cName(m.Name))
+	}
+	var args []string
+	for i, p := range main.Params {
+		ptr := g.temp("getelementptr inbounds i8*, i8** %%argv, i64 %d", i+1)
+		arg := g.temp("load i8*, i8** %s", ptr)
+		v := g.fromABI(ft.Params[i], g.runtime("arg_"+p.Kind.Lexeme, "i8* "+arg))
+		args = append(args, ltype(ft.Params[i])+" "+v)
+	}
+	call := fmt.Sprintf("call %s %s(%s)", ltype(ft.Return), g.global("main"), strings.Join(args, ", "))
+	if ft.Return == analysis.Int {
+		status := g.temp("trunc i64 %s to i32", g.temp("%s", call))
+		g.branch("ret i32 %s", status)
+	} else {
+		if ft.Return == analysis.Void {
+			g.ins("%s", call)
+		} else {
+			g.temp("%s", call)
+		}
+		g.branch("ret i32 0")
+	}
+	g.end(out)
+}
+
+// ins writes an instruction, in a new basic block if the current one has
+// been terminated, since code following a return is still generated.
+func (g *generator) ins(format string, args ...interface{}) {
+	if g.terminated {
+		g.start(g.label())
+	}
+	fmt.Fprintf(g.out, "  "+format+"\n", args...)
+}
+
+// temp writes an instruction that produces a value, and returns the
+// register holding it.
+func (g *generator) temp(format string, args ...interface{}) string {
+	g.temps++
+	reg := fmt.Sprintf("%%t%d", g.temps)
+	g.ins("%s = "+format, append([]interface{}{reg}, args...)...)
+	return reg
+}
+
+// branch writes an instruction that terminates the current basic block.
+func (g *generator) branch(format string, args ...interface{}) {
+	g.ins(format, args...)
+	g.terminated = true
+}
+
+// jump ends the current basic block with a branch to label, unless it has
+// already been terminated.
+func (g *generator) jump(label string) {
+	if !g.terminated {
+		g.branch("br label %%%s", label)
+	}
+}
+
+// start starts a basic block, which the current one falls through to.
+func (g *generator) start(label string) {
+	g.jump(label)
+	fmt.Fprintf(g.out, "%s:\n", label)
+	g.block = label
+	g.terminated = false
+}
+
+func (g *generator) label() string {
+	g.labels++
+	return fmt.Sprintf("L%d", g.labels)
+}
+
+// declare allocates a variable of type t, and returns its address.
+func (g *generator) declare(name string, t analysis.Type) string {
+	g.env.Declare(name, t)
+	addr := "%" + name + ".addr"
+	if n := g.names[name]; n > 0 {
+		addr = fmt.Sprintf("%%%s.addr%d", name, n)
+	}
+	g.names[name]++
+	fmt.Fprintf(g.allocas, "  %s = alloca %s\n", addr, ltype(t))
+	g.locals[len(g.locals)-1][name] = addr
+	return addr
+}
+
+// variable returns the address of the variable called name.
+func (g *generator) variable(name string) string {
+	for i := len(g.locals) - 1; i >= 0; i-- {
+		if addr, ok := g.locals[i][name]; ok {
+			return addr
+		}
+	}
+	return g.global(name)
+}
+
+func (g *generator) isLocal(name string) bool {
+	for _, scope := range g.locals {
+		if _, ok := scope[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// strConst returns a %lang.string constant holding s.
+func (g *generator) strConst(s string) string {
+	bytes, ok := g.arrays[s]
+	if !ok {
+		g.constants++
+		bytes = fmt.Sprintf("@.bytes%d", g.constants)
+		fmt.Fprintf(g.consts, "%s = private unnamed_addr constant [%d x i8] c\"%s\"\n", bytes, len(s), escape(s))
+		g.arrays[s] = bytes
+	}
+	return fmt.Sprintf("{ i8* getelementptr inbounds ([%d x i8], [%d x i8]* %s, i64 0, i64 0), i64 %d }", len(s), len(s), bytes, len(s))
+}
+
+// str returns a global %lang.string holding s.
+func (g *generator) str(s string) string {
+	if name, ok := g.strings[s]; ok {
+		return name
+	}
+	value := g.strConst(s)
+	g.constants++
+	name := fmt.Sprintf("@.str%d", g.constants)
+	fmt.Fprintf(g.consts, "%s = private unnamed_addr constant %%lang.string %s\n", name, value)
+	g.strings[s] = name
+	return name
+}
+
+// cstr returns a pointer to s as a NUL-terminated C string.
+func (g *generator) cstr(s string) string {
+	name, ok := g.cstrings[s]
+	if !ok {
+		g.constants++
+		name = fmt.Sprintf("@.cstr%d", g.constants)
+		fmt.Fprintf(g.consts, "%s = private unnamed_addr constant [%d x i8] c\"%s\\00\"\n", name, len(s)+1, escape(s))
+		g.cstrings[s] = name
+	}
+	return fmt.Sprintf("getelementptr inbounds ([%d x i8], [%d x i8]* %s, i64 0, i64 0)", len(s)+1, len(s)+1, name)
+}
+
+// escape escapes s for an LLVM string constant.
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			fmt.Fprintf(&b, "\\%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// float returns f as an LLVM constant, which must be exact.
+func float(f float64) string {
+	return fmt.Sprintf("0x%016X", math.Float64bits(f))
+}
+
+func (g *generator) blockStmt(b parser.Block, ret analysis.Type) {
+	env := g.env
+	g.env = analysis.NewBlockEnv(env)
+	g.locals = append(g.locals, map[string]string{})
+	g.stmts(b.Stmts, ret)
+	g.locals = g.locals[:len(g.locals)-1]
+	g.env = env
+}
+
+func (g *generator) stmts(stmts []parser.Stmt, ret analysis.Type) {
+	for _, stmt := range stmts {
+		g.stmt(stmt, ret)
+	}
+}
+
+func (g *generator) stmt(stmt parser.Stmt, ret analysis.Type) {
+	switch s := stmt.(type) {
+	case parser.VarStmt:
+		t := g.env.LookupType(s.Kind.Lexeme)
+		value := g.zero(t)
+		if s.Expr != nil {
+			value = g.expr(s.Expr)
+		}
+		g.ins("store %s %s, %s* %s", ltype(t), value, ltype(t), g.declare(s.Name.Lexeme, t))
+	case parser.AssignStmt:
+		name := s.Target.(scanner.Token).Lexeme
+		t := analysis.TypeOf(g.env, s.Expr)
+		g.ins("store %s %s, %s* %s", ltype(t), g.expr(s.Expr), ltype(t), g.variable(name))
+	case parser.CompoundAssignStmt:
+		op := s.Op
+		op.Kind = parser.CompoundAssignOps[s.Op.Kind]
+		value := parser.BinaryOp{Op: op, Left: parser.IdentExpr{Name: s.Target}, Right: s.Expr}
+		t := analysis.TypeOf(g.env, value)
+		g.ins("store %s %s, %s* %s", ltype(t), g.expr(value), ltype(t), g.variable(s.Target.Lexeme))
+	case parser.IncDecStmt:
+		addr := g.variable(s.Target.Lexeme)
+		if analysis.TypeOf(g.env, parser.IdentExpr{Name: s.Target}) == analysis.Float {
+			op := "fadd"
+			if s.Op.Kind == scanner.Dec {
+				op = "fsub"
+			}
+			x := g.temp("load double, double* %s", addr)
+			g.ins("store double %s, double* %s", g.temp("%s double %s, %s", op, x, float(1)), addr)
+		} else {
+			op := "add"
+			if s.Op.Kind == scanner.Dec {
+				op = "sub"
+			}
+			x := g.temp("load i64, i64* %s", addr)
+			g.ins("store i64 %s, i64* %s", g.temp("%s i64 %s, 1", op, x), addr)
+		}
+	case parser.ReturnStmt:
+		if s.Expr == nil {
+			g.branch("ret void")
+		} else if call, ok := s.Expr.(parser.FunctionCall); ok {
+			g.branch("ret %s %s", ltype(ret), g.call(call, true))
+		} else {
+			g.branch("ret %s %s", ltype(ret), g.expr(s.Expr))
+		}
+	case parser.IfStmt:
+		then, els, end := g.label(), g.label(), g.label()
+		if len(s.Els.Stmts) == 0 {
+			els = end
+		}
+		g.branch("br i1 %s, label %%%s, label %%%s", g.expr(s.Cond), then, els)
+		g.start(then)
+		g.blockStmt(s.Then, ret)
+		if len(s.Els.Stmts) > 0 {
+			g.jump(end)
+			g.start(els)
+			g.blockStmt(s.Els, ret)
+		}
+		g.start(end)
+	case parser.WhileStmt:
+		cond, body, end := g.label(), g.label(), g.label()
+		g.start(cond)
+		g.branch("br i1 %s, label %%%s, label %%%s", g.expr(s.Cond), body, end)
+		g.start(body)
+		g.blockStmt(s.Body, ret)
+		g.jump(cond)
+		g.start(end)
+	case parser.SwitchStmt:
+		g.switchStmt(s, ret)
+	case parser.Block:
+		g.blockStmt(s, ret)
+	default:
+		g.expr(s)
+	}
+}
+
+// switchStmt writes a switch as a chain of comparisons that branch to the
+// bodies of its cases, which end by branching past the others unless they
+// fall through.
+func (g *generator) switchStmt(s parser.SwitchStmt, ret analysis.Type) {
+	t := analysis.TypeOf(g.env, s.Expr)
+	value := g.expr(s.Expr)
+	end := g.label()
+	otherwise := end
+	var bodies []string
+	for range s.Cases {
+		bodies = append(bodies, g.label())
+	}
+	for i, c := range s.Cases {
+		for _, v := range c.Values {
+			next := g.label()
+			g.branch("br i1 %s, label %%%s, label %%%s", g.equal(t, value, g.expr(v)), bodies[i], next)
+			g.start(next)
+		}
+		if c.Default {
+			otherwise = bodies[i]
+		}
+	}
+	g.jump(otherwise)
+	for i, c := range s.Cases {
+		g.start(bodies[i])
+		body := c.Body
+		fallsThrough := false
+		if n := len(body.Stmts); n > 0 {
+			_, fallsThrough = body.Stmts[n-1].(parser.FallthroughStmt)
+			if fallsThrough {
+				body.Stmts = body.Stmts[:n-1]
+			}
+		}
+		g.blockStmt(body, ret)
+		if !fallsThrough {
+			g.jump(end)
+		}
+	}
+	g.start(end)
+}
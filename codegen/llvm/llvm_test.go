@@ -0,0 +1,13 @@
+package llvm
+
+import (
+	"lang/codegen/codegentest"
+	"testing"
+)
+
+// TestGenerate compares the LLVM IR generated for each of the backends'
+// test programs against the golden file with the same name and an .ll
+// extension.
+func TestGenerate(t *testing.T) {
+	codegentest.Golden(t, ".ll", nil, Generate)
+}
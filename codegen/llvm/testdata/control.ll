@@ -0,0 +1,157 @@
+; Code generated by lang build. DO NOT EDIT.
+
+%lang.string = type { i8*, i64 }
+
+; module control
+
+define internal void @lang_init_control() {
+entry:
+  ret void
+}
+
+define internal %lang.string* @control.describe(i64 %c) {
+entry:
+  %c.addr = alloca i64
+  store i64 %c, i64* %c.addr
+  %t1 = load i64, i64* %c.addr
+  %t2 = icmp eq i64 %t1, 0
+  br i1 %t2, label %L2, label %L4
+L4:
+  %t3 = icmp eq i64 %t1, 1
+  br i1 %t3, label %L3, label %L5
+L5:
+  %t4 = icmp eq i64 %t1, 2
+  br i1 %t4, label %L3, label %L6
+L6:
+  br label %L1
+L2:
+  ret %lang.string* @.str5
+L3:
+  ret %lang.string* @.str7
+L1:
+  ret %lang.string* @.str9
+}
+
+define internal i64 @control.main() {
+entry:
+  %i.addr = alloca i64
+  %sum.addr = alloca i64
+  %i2.addr = alloca i64
+  %sum.addr1 = alloca i64
+  store i64 0, i64* %i.addr
+  store i64 0, i64* %sum.addr
+  br label %L1
+L1:
+  %t1 = load i64, i64* %i.addr
+  %t2 = icmp slt i64 %t1, 5
+  br i1 %t2, label %L2, label %L3
+L2:
+  %t3 = load i64, i64* %i.addr
+  %t4 = load i64, i64* %i.addr
+  %t5 = mul i64 %t3, %t4
+  store i64 %t5, i64* %i2.addr
+  %t6 = load i64, i64* %i.addr
+  %t7 = call i64 @lang_asm_mod(i64 %t6, i64 2)
+  %t8 = icmp eq i64 %t7, 0
+  br i1 %t8, label %L4, label %L5
+L4:
+  %t9 = load i64, i64* %sum.addr
+  %t10 = load i64, i64* %i2.addr
+  %t11 = add i64 %t9, %t10
+  store i64 %t11, i64* %sum.addr
+  br label %L6
+L5:
+  %t12 = load i64, i64* %i.addr
+  %t13 = icmp eq i64 %t12, 3
+  br i1 %t13, label %L7, label %L8
+L7:
+  store i64 100, i64* %sum.addr1
+  %t14 = load i64, i64* %sum.addr1
+  %t15 = sub i64 %t14, 1
+  store i64 %t15, i64* %sum.addr1
+  br label %L9
+L8:
+  %t16 = load i64, i64* %sum.addr
+  %t17 = add i64 %t16, 1
+  store i64 %t17, i64* %sum.addr
+  br label %L9
+L9:
+  br label %L6
+L6:
+  %t18 = load i64, i64* %i.addr
+  %t19 = add i64 %t18, 1
+  store i64 %t19, i64* %i.addr
+  br label %L1
+L3:
+  %t20 = load i64, i64* %sum.addr
+  %t21 = icmp eq i64 %t20, 0
+  br i1 %t21, label %L11, label %L14
+L14:
+  %t22 = icmp eq i64 %t20, 21
+  br i1 %t22, label %L12, label %L15
+L15:
+  br label %L13
+L11:
+  call void @lang_asm_println(%lang.string* @.str11)
+  br label %L10
+L12:
+  call void @lang_asm_println(%lang.string* @.str13)
+  br label %L13
+L13:
+  call void @lang_asm_println(%lang.string* @.str15)
+  br label %L10
+L10:
+  %t23 = call %lang.string* @control.describe(i64 1)
+  call void @lang_asm_println(%lang.string* %t23)
+  %t24 = call i64 @lang_asm_enum(i64 2, i64 3, i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.cstr16, i64 0, i64 0))
+  %t25 = getelementptr inbounds [3 x %lang.string], [3 x %lang.string]* @control.Color.names, i64 0, i64 %t24
+  %t26 = call %lang.string* @lang_asm_concat(%lang.string* %t25, %lang.string* @.str18)
+  %t27 = call %lang.string* @lang_asm_int_string(i64 2)
+  %t28 = call %lang.string* @lang_asm_concat(%lang.string* %t26, %lang.string* %t27)
+  call void @lang_asm_println(%lang.string* %t28)
+  %t29 = load i64, i64* %sum.addr
+  ret i64 %t29
+}
+
+define i32 @main(i32 %argc, i8** %argv) {
+entry:
+  %t1 = icmp eq i32 %argc, 1
+  br i1 %t1, label %L1, label %L2
+L2:
+  %t2 = load i8*, i8** %argv
+  call void @lang_asm_usage(i8* %t2, i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.cstr19, i64 0, i64 0))
+  unreachable
+L1:
+  call void @lang_init_control()
+  %t3 = call i64 @control.main()
+  %t4 = trunc i64 %t3 to i32
+  ret i32 %t4
+}
+
+@.bytes1 = private unnamed_addr constant [3 x i8] c"Red"
+@.bytes2 = private unnamed_addr constant [5 x i8] c"Green"
+@.bytes3 = private unnamed_addr constant [4 x i8] c"Blue"
+@control.Color.names = private unnamed_addr constant [3 x %lang.string] [%lang.string { i8* getelementptr inbounds ([3 x i8], [3 x i8]* @.bytes1, i64 0, i64 0), i64 3 }, %lang.string { i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.bytes2, i64 0, i64 0), i64 5 }, %lang.string { i8* getelementptr inbounds ([4 x i8], [4 x i8]* @.bytes3, i64 0, i64 0), i64 4 }]
+@.bytes4 = private unnamed_addr constant [3 x i8] c"red"
+@.str5 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([3 x i8], [3 x i8]* @.bytes4, i64 0, i64 0), i64 3 }
+@.bytes6 = private unnamed_addr constant [7 x i8] c"not red"
+@.str7 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([7 x i8], [7 x i8]* @.bytes6, i64 0, i64 0), i64 7 }
+@.bytes8 = private unnamed_addr constant [11 x i8] c"unreachable"
+@.str9 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([11 x i8], [11 x i8]* @.bytes8, i64 0, i64 0), i64 11 }
+@.bytes10 = private unnamed_addr constant [4 x i8] c"zero"
+@.str11 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([4 x i8], [4 x i8]* @.bytes10, i64 0, i64 0), i64 4 }
+@.bytes12 = private unnamed_addr constant [10 x i8] c"twenty-one"
+@.str13 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([10 x i8], [10 x i8]* @.bytes12, i64 0, i64 0), i64 10 }
+@.bytes14 = private unnamed_addr constant [7 x i8] c"default"
+@.str15 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([7 x i8], [7 x i8]* @.bytes14, i64 0, i64 0), i64 7 }
+@.cstr16 = private unnamed_addr constant [6 x i8] c"Color\00"
+@.bytes17 = private unnamed_addr constant [1 x i8] c" "
+@.str18 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes17, i64 0, i64 0), i64 1 }
+@.cstr19 = private unnamed_addr constant [1 x i8] c"\00"
+
+declare %lang.string* @lang_asm_concat(%lang.string*, %lang.string*)
+declare i64 @lang_asm_enum(i64, i64, i8*)
+declare %lang.string* @lang_asm_int_string(i64)
+declare i64 @lang_asm_mod(i64, i64)
+declare void @lang_asm_println(%lang.string*)
+declare void @lang_asm_usage(i8*, i8*)
@@ -0,0 +1,210 @@
+; Code generated by lang build. DO NOT EDIT.
+
+%lang.string = type { i8*, i64 }
+
+; module functions
+@functions.calls = internal global i64 0
+@functions.scale = internal global double 0.0
+
+define internal void @lang_init_functions() {
+entry:
+  store i64 0, i64* @functions.calls
+  store double 0x3FF8000000000000, double* @functions.scale
+  ret void
+}
+
+define internal i64 @functions.fib(i64 %n) {
+entry:
+  %n.addr = alloca i64
+  store i64 %n, i64* %n.addr
+  %t1 = load i64, i64* @functions.calls
+  %t2 = add i64 %t1, 1
+  store i64 %t2, i64* @functions.calls
+  %t3 = load i64, i64* %n.addr
+  %t4 = icmp slt i64 %t3, 2
+  br i1 %t4, label %L1, label %L3
+L1:
+  %t5 = load i64, i64* %n.addr
+  ret i64 %t5
+L3:
+  %t6 = load i64, i64* %n.addr
+  %t7 = sub i64 %t6, 1
+  %t8 = call i64 @functions.fib(i64 %t7)
+  %t9 = load i64, i64* %n.addr
+  %t10 = sub i64 %t9, 2
+  %t11 = call i64 @functions.fib(i64 %t10)
+  %t12 = add i64 %t8, %t11
+  ret i64 %t12
+}
+
+define internal double @functions.area(double %r) {
+entry:
+  %r.addr = alloca double
+  store double %r, double* %r.addr
+  %t1 = load double, double* %r.addr
+  %t2 = fmul double 0x400921F9F01B866E, %t1
+  %t3 = load double, double* %r.addr
+  %t4 = fmul double %t2, %t3
+  %t5 = load double, double* @functions.scale
+  %t6 = fmul double %t4, %t5
+  ret double %t6
+}
+
+define internal i1 @functions.between(i64 %x, i64 %lo, i64 %hi) {
+entry:
+  %x.addr = alloca i64
+  %lo.addr = alloca i64
+  %hi.addr = alloca i64
+  store i64 %x, i64* %x.addr
+  store i64 %lo, i64* %lo.addr
+  store i64 %hi, i64* %hi.addr
+  %t1 = load i64, i64* %x.addr
+  %t2 = load i64, i64* %lo.addr
+  %t3 = icmp sge i64 %t1, %t2
+  br i1 %t3, label %L3, label %L4
+L3:
+  %t4 = load i64, i64* %x.addr
+  %t5 = load i64, i64* %hi.addr
+  %t6 = icmp sle i64 %t4, %t5
+  br label %L4
+L4:
+  %t7 = phi i1 [ false, %entry ], [ %t6, %L3 ]
+  br i1 %t7, label %L2, label %L1
+L1:
+  %t8 = load i64, i64* %x.addr
+  %t9 = sub i64 0, 1
+  %t10 = icmp eq i64 %t8, %t9
+  br label %L2
+L2:
+  %t11 = phi i1 [ true, %L4 ], [ %t10, %L1 ]
+  ret i1 %t11
+}
+
+define internal i64 @functions.main() {
+entry:
+  %n.addr = alloca i64
+  %f.addr = alloca double
+  %t1 = call i64 @functions.fib(i64 10)
+  store i64 %t1, i64* %n.addr
+  %t2 = load i64, i64* %n.addr
+  %t3 = mul i64 %t2, 2
+  store i64 %t3, i64* %n.addr
+  %t4 = load i64, i64* %n.addr
+  %t5 = sub i64 %t4, 1
+  store i64 %t5, i64* %n.addr
+  %t6 = load i64, i64* %n.addr
+  %t7 = call i64 @lang_asm_shl(i64 %t6, i64 1)
+  store i64 %t7, i64* %n.addr
+  %t8 = call double @functions.area(double 0x4000000000000000)
+  store double %t8, double* %f.addr
+  %t9 = load double, double* %f.addr
+  %t10 = fdiv double %t9, 0x4000000000000000
+  store double %t10, double* %f.addr
+  %t11 = load double, double* %f.addr
+  %t12 = fsub double %t11, 0x3FF0000000000000
+  store double %t12, double* %f.addr
+  %t13 = load i64, i64* %n.addr
+  %t14 = call %lang.string* @lang_asm_int_string(i64 %t13)
+  %t15 = call %lang.string* @lang_asm_concat(%lang.string* %t14, %lang.string* @.str2)
+  %t16 = load i64, i64* @functions.calls
+  %t17 = call %lang.string* @lang_asm_int_string(i64 %t16)
+  %t18 = call %lang.string* @lang_asm_concat(%lang.string* %t15, %lang.string* %t17)
+  %t19 = call %lang.string* @lang_asm_concat(%lang.string* %t18, %lang.string* @.str2)
+  %t20 = load double, double* %f.addr
+  %t21 = call %lang.string* @lang_asm_float_string(double %t20)
+  %t22 = call %lang.string* @lang_asm_concat(%lang.string* %t19, %lang.string* %t21)
+  %t23 = call %lang.string* @lang_asm_concat(%lang.string* %t22, %lang.string* @.str2)
+  %t24 = load i64, i64* %n.addr
+  %t25 = call i1 @functions.between(i64 %t24, i64 0, i64 100)
+  %t26 = zext i1 %t25 to i64
+  %t27 = call %lang.string* @lang_asm_bool_string(i64 %t26)
+  %t28 = call %lang.string* @lang_asm_concat(%lang.string* %t23, %lang.string* %t27)
+  %t29 = call %lang.string* @lang_asm_concat(%lang.string* %t28, %lang.string* @.str2)
+  %t30 = call i1 @functions.between(i64 5, i64 0, i64 10)
+  %t31 = xor i1 %t30, true
+  %t32 = zext i1 %t31 to i64
+  %t33 = call %lang.string* @lang_asm_bool_string(i64 %t32)
+  %t34 = call %lang.string* @lang_asm_concat(%lang.string* %t29, %lang.string* %t33)
+  call void @lang_asm_println(%lang.string* %t34)
+  %t35 = load double, double* %f.addr
+  %t36 = call i64 @lang_asm_float_to_int(double %t35)
+  %t37 = call %lang.string* @lang_asm_int_string(i64 %t36)
+  %t38 = call %lang.string* @lang_asm_concat(%lang.string* %t37, %lang.string* @.str2)
+  %t39 = load i64, i64* %n.addr
+  %t40 = sitofp i64 %t39 to double
+  %t41 = fdiv double %t40, 0x4010000000000000
+  %t42 = call %lang.string* @lang_asm_float_string(double %t41)
+  %t43 = call %lang.string* @lang_asm_concat(%lang.string* %t38, %lang.string* %t42)
+  %t44 = call %lang.string* @lang_asm_concat(%lang.string* %t43, %lang.string* @.str2)
+  %t45 = load i64, i64* %n.addr
+  %t46 = sub i64 0, %t45
+  %t47 = call %lang.string* @lang_asm_int_string(i64 %t46)
+  %t48 = call %lang.string* @lang_asm_concat(%lang.string* %t44, %lang.string* %t47)
+  %t49 = call %lang.string* @lang_asm_concat(%lang.string* %t48, %lang.string* @.str2)
+  %t50 = load i64, i64* %n.addr
+  %t51 = xor i64 %t50, -1
+  %t52 = call %lang.string* @lang_asm_int_string(i64 %t51)
+  %t53 = call %lang.string* @lang_asm_concat(%lang.string* %t49, %lang.string* %t52)
+  %t54 = call %lang.string* @lang_asm_concat(%lang.string* %t53, %lang.string* @.str2)
+  %t55 = load i64, i64* %n.addr
+  %t56 = call i64 @lang_asm_mod(i64 %t55, i64 7)
+  %t57 = call %lang.string* @lang_asm_int_string(i64 %t56)
+  %t58 = call %lang.string* @lang_asm_concat(%lang.string* %t54, %lang.string* %t57)
+  %t59 = call %lang.string* @lang_asm_concat(%lang.string* %t58, %lang.string* @.str2)
+  %t60 = load i64, i64* %n.addr
+  %t61 = and i64 %t60, 6
+  %t62 = xor i64 1, 8
+  %t63 = or i64 %t61, %t62
+  %t64 = call %lang.string* @lang_asm_int_string(i64 %t63)
+  %t65 = call %lang.string* @lang_asm_concat(%lang.string* %t59, %lang.string* %t64)
+  %t66 = call %lang.string* @lang_asm_concat(%lang.string* %t65, %lang.string* @.str2)
+  %t67 = load i64, i64* %n.addr
+  %t68 = call i64 @lang_asm_shr(i64 %t67, i64 2)
+  %t69 = call %lang.string* @lang_asm_int_string(i64 %t68)
+  %t70 = call %lang.string* @lang_asm_concat(%lang.string* %t66, %lang.string* %t69)
+  call void @lang_asm_println(%lang.string* %t70)
+  %t71 = load i64, i64* %n.addr
+  %t72 = icmp sgt i64 %t71, 100
+  br i1 %t72, label %L1, label %L2
+L1:
+  %t73 = load i64, i64* %n.addr
+  %t74 = sub i64 %t73, 200
+  %t75 = call i64 @lang_asm_abs(i64 %t74)
+  br label %L3
+L2:
+  br label %L3
+L3:
+  %t76 = phi i64 [ %t75, %L1 ], [ 0, %L2 ]
+  ret i64 %t76
+}
+
+define i32 @main(i32 %argc, i8** %argv) {
+entry:
+  %t1 = icmp eq i32 %argc, 1
+  br i1 %t1, label %L1, label %L2
+L2:
+  %t2 = load i8*, i8** %argv
+  call void @lang_asm_usage(i8* %t2, i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.cstr3, i64 0, i64 0))
+  unreachable
+L1:
+  call void @lang_init_functions()
+  %t3 = call i64 @functions.main()
+  %t4 = trunc i64 %t3 to i32
+  ret i32 %t4
+}
+
+@.bytes1 = private unnamed_addr constant [1 x i8] c" "
+@.str2 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes1, i64 0, i64 0), i64 1 }
+@.cstr3 = private unnamed_addr constant [1 x i8] c"\00"
+
+declare i64 @lang_asm_abs(i64)
+declare %lang.string* @lang_asm_bool_string(i64)
+declare %lang.string* @lang_asm_concat(%lang.string*, %lang.string*)
+declare %lang.string* @lang_asm_float_string(double)
+declare i64 @lang_asm_float_to_int(double)
+declare %lang.string* @lang_asm_int_string(i64)
+declare i64 @lang_asm_mod(i64, i64)
+declare void @lang_asm_println(%lang.string*)
+declare i64 @lang_asm_shl(i64, i64)
+declare i64 @lang_asm_shr(i64, i64)
+declare void @lang_asm_usage(i8*, i8*)
@@ -0,0 +1,123 @@
+; Code generated by lang build. DO NOT EDIT.
+
+%lang.string = type { i8*, i64 }
+
+; module loops
+@loops.counter = internal global i64 0
+
+define internal void @lang_init_loops() {
+entry:
+  store i64 0, i64* @loops.counter
+  ret void
+}
+
+define internal i64 @loops.next() {
+entry:
+  %t1 = load i64, i64* @loops.counter
+  %t2 = add i64 %t1, 1
+  store i64 %t2, i64* @loops.counter
+  %t3 = load i64, i64* @loops.counter
+  ret i64 %t3
+}
+
+define internal i64 @loops.main(i64 %n, %lang.string* %s) {
+entry:
+  %n.addr = alloca i64
+  %s.addr = alloca %lang.string*
+  %i.addr = alloca i64
+  %b.addr = alloca i1
+  store i64 %n, i64* %n.addr
+  store %lang.string* %s, %lang.string** %s.addr
+  store i64 0, i64* %i.addr
+  br label %L1
+L1:
+  %t1 = load i64, i64* %i.addr
+  %t2 = load i64, i64* %n.addr
+  %t3 = icmp slt i64 %t1, %t2
+  br i1 %t3, label %L4, label %L5
+L4:
+  %t4 = call i64 @loops.next()
+  %t5 = icmp slt i64 %t4, 100
+  br label %L5
+L5:
+  %t6 = phi i1 [ false, %L1 ], [ %t5, %L4 ]
+  br i1 %t6, label %L2, label %L3
+L2:
+  %t7 = load i64, i64* %i.addr
+  %t8 = add i64 %t7, 2
+  store i64 %t8, i64* %i.addr
+  %t9 = load i64, i64* %i.addr
+  %t10 = icmp eq i64 %t9, 4
+  br i1 %t10, label %L6, label %L8
+L6:
+  %t11 = load i64, i64* %i.addr
+  %t12 = add i64 %t11, 1
+  store i64 %t12, i64* %i.addr
+  br label %L8
+L8:
+  br label %L1
+L3:
+  %t13 = load i64, i64* %i.addr
+  %t14 = icmp sgt i64 %t13, 3
+  br i1 %t14, label %L9, label %L10
+L9:
+  %t15 = call i64 @loops.next()
+  %t16 = icmp sgt i64 %t15, 0
+  br label %L11
+L10:
+  br label %L11
+L11:
+  %t17 = phi i1 [ %t16, %L9 ], [ false, %L10 ]
+  store i1 %t17, i1* %b.addr
+  %t18 = load %lang.string*, %lang.string** %s.addr
+  %t19 = call i64 @lang_asm_streq(%lang.string* %t18, %lang.string* @.str2)
+  %t20 = icmp ne i64 %t19, 0
+  br i1 %t20, label %L13, label %L14
+L14:
+  br label %L12
+L13:
+  %t21 = load i1, i1* %b.addr
+  %t22 = zext i1 %t21 to i64
+  %t23 = call %lang.string* @lang_asm_bool_string(i64 %t22)
+  %t24 = call %lang.string* @lang_asm_concat(%lang.string* @.str4, %lang.string* %t23)
+  call void @lang_asm_println(%lang.string* %t24)
+  br label %L12
+L12:
+  %t25 = load i64, i64* %i.addr
+  ret i64 %t25
+}
+
+define i32 @main(i32 %argc, i8** %argv) {
+entry:
+  %t1 = icmp eq i32 %argc, 3
+  br i1 %t1, label %L1, label %L2
+L2:
+  %t2 = load i8*, i8** %argv
+  call void @lang_asm_usage(i8* %t2, i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.cstr5, i64 0, i64 0))
+  unreachable
+L1:
+  call void @lang_init_loops()
+  %t3 = getelementptr inbounds i8*, i8** %argv, i64 1
+  %t4 = load i8*, i8** %t3
+  %t5 = call i64 @lang_asm_arg_int(i8* %t4)
+  %t6 = getelementptr inbounds i8*, i8** %argv, i64 2
+  %t7 = load i8*, i8** %t6
+  %t8 = call %lang.string* @lang_asm_arg_string(i8* %t7)
+  %t9 = call i64 @loops.main(i64 %t5, %lang.string* %t8)
+  %t10 = trunc i64 %t9 to i32
+  ret i32 %t10
+}
+
+@.bytes1 = private unnamed_addr constant [1 x i8] c"a"
+@.str2 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes1, i64 0, i64 0), i64 1 }
+@.bytes3 = private unnamed_addr constant [2 x i8] c"a "
+@.str4 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([2 x i8], [2 x i8]* @.bytes3, i64 0, i64 0), i64 2 }
+@.cstr5 = private unnamed_addr constant [5 x i8] c" n s\00"
+
+declare i64 @lang_asm_arg_int(i8*)
+declare %lang.string* @lang_asm_arg_string(i8*)
+declare %lang.string* @lang_asm_bool_string(i64)
+declare %lang.string* @lang_asm_concat(%lang.string*, %lang.string*)
+declare void @lang_asm_println(%lang.string*)
+declare i64 @lang_asm_streq(%lang.string*, %lang.string*)
+declare void @lang_asm_usage(i8*, i8*)
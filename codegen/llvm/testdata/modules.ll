@@ -0,0 +1,99 @@
+; Code generated by lang build. DO NOT EDIT.
+
+%lang.string = type { i8*, i64 }
+
+; module geo
+@geo.sides = internal global i64 0
+
+define internal void @lang_init_geo() {
+entry:
+  store i64 4, i64* @geo.sides
+  ret void
+}
+
+define internal i64 @geo.area(i64 %s, i64 %size) {
+entry:
+  %s.addr = alloca i64
+  %size.addr = alloca i64
+  store i64 %s, i64* %s.addr
+  store i64 %size, i64* %size.addr
+  %t1 = load i64, i64* %s.addr
+  %t2 = icmp eq i64 %t1, 0
+  br i1 %t2, label %L2, label %L4
+L4:
+  %t3 = icmp eq i64 %t1, 1
+  br i1 %t3, label %L3, label %L5
+L5:
+  br label %L1
+L2:
+  %t4 = load i64, i64* %size.addr
+  %t5 = load i64, i64* %size.addr
+  %t6 = mul i64 %t4, %t5
+  ret i64 %t6
+L3:
+  %t7 = load i64, i64* %size.addr
+  %t8 = load i64, i64* %size.addr
+  %t9 = mul i64 %t7, %t8
+  %t10 = call i64 @lang_asm_div(i64 %t9, i64 2)
+  ret i64 %t10
+L1:
+  ret i64 0
+}
+
+; module main
+
+define internal void @lang_init_main() {
+entry:
+  ret void
+}
+
+define internal i64 @main.main() {
+entry:
+  %s.addr = alloca i64
+  store i64 1, i64* %s.addr
+  %t1 = load i64, i64* %s.addr
+  %t2 = call i64 @geo.area(i64 %t1, i64 4)
+  %t3 = call %lang.string* @lang_asm_int_string(i64 %t2)
+  %t4 = call %lang.string* @lang_asm_concat(%lang.string* %t3, %lang.string* @.str4)
+  %t5 = load i64, i64* @geo.sides
+  %t6 = call %lang.string* @lang_asm_int_string(i64 %t5)
+  %t7 = call %lang.string* @lang_asm_concat(%lang.string* %t4, %lang.string* %t6)
+  %t8 = call %lang.string* @lang_asm_concat(%lang.string* %t7, %lang.string* @.str4)
+  %t9 = load i64, i64* %s.addr
+  %t10 = getelementptr inbounds [2 x %lang.string], [2 x %lang.string]* @geo.Shape.names, i64 0, i64 %t9
+  %t11 = call %lang.string* @lang_asm_concat(%lang.string* %t8, %lang.string* %t10)
+  call void @lang_asm_println(%lang.string* %t11)
+  %t12 = call i64 @random(i64 3)
+  %t13 = tail call i64 @geo.area(i64 0, i64 %t12)
+  ret i64 %t13
+}
+
+define i32 @main(i32 %argc, i8** %argv) {
+entry:
+  %t1 = icmp eq i32 %argc, 1
+  br i1 %t1, label %L1, label %L2
+L2:
+  %t2 = load i8*, i8** %argv
+  call void @lang_asm_usage(i8* %t2, i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.cstr5, i64 0, i64 0))
+  unreachable
+L1:
+  call void @lang_init_geo()
+  call void @lang_init_main()
+  %t3 = call i64 @main.main()
+  %t4 = trunc i64 %t3 to i32
+  ret i32 %t4
+}
+
+@.bytes1 = private unnamed_addr constant [6 x i8] c"Square"
+@.bytes2 = private unnamed_addr constant [8 x i8] c"Triangle"
+@geo.Shape.names = private unnamed_addr constant [2 x %lang.string] [%lang.string { i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.bytes1, i64 0, i64 0), i64 6 }, %lang.string { i8* getelementptr inbounds ([8 x i8], [8 x i8]* @.bytes2, i64 0, i64 0), i64 8 }]
+@.bytes3 = private unnamed_addr constant [1 x i8] c" "
+@.str4 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes3, i64 0, i64 0), i64 1 }
+@.cstr5 = private unnamed_addr constant [1 x i8] c"\00"
+
+declare %lang.string* @lang_asm_concat(%lang.string*, %lang.string*)
+declare i64 @lang_asm_div(i64, i64)
+declare %lang.string* @lang_asm_int_string(i64)
+declare void @lang_asm_println(%lang.string*)
+declare void @lang_asm_usage(i8*, i8*)
+declare i64 @random(i64)
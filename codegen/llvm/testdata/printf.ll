@@ -0,0 +1,103 @@
+; Code generated by lang build. DO NOT EDIT.
+
+%lang.string = type { i8*, i64 }
+
+; module printf
+
+define internal void @lang_init_printf() {
+entry:
+  ret void
+}
+
+define internal i64 @printf.main(i64 %n, i1 %verbose) {
+entry:
+  %n.addr = alloca i64
+  %verbose.addr = alloca i1
+  %ratio.addr = alloca double
+  %printf4 = alloca [12 x i64]
+  %printf27 = alloca [0 x i64]
+  store i64 %n, i64* %n.addr
+  store i1 %verbose, i1* %verbose.addr
+  %t1 = load i64, i64* %n.addr
+  %t2 = sitofp i64 %t1 to double
+  %t3 = fdiv double %t2, 0x4008000000000000
+  store double %t3, double* %ratio.addr
+  %t5 = load i64, i64* %n.addr
+  %t6 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 10
+  store i64 0, i64* %t6
+  %t7 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 11
+  store i64 %t5, i64* %t7
+  %t8 = load double, double* %ratio.addr
+  %t9 = bitcast double %t8 to i64
+  %t10 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 8
+  store i64 1, i64* %t10
+  %t11 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 9
+  store i64 %t9, i64* %t11
+  %t12 = load i1, i1* %verbose.addr
+  %t13 = zext i1 %t12 to i64
+  %t14 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 6
+  store i64 2, i64* %t14
+  %t15 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 7
+  store i64 %t13, i64* %t15
+  %t16 = ptrtoint %lang.string* @.str6 to i64
+  %t17 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 4
+  store i64 3, i64* %t17
+  %t18 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 5
+  store i64 %t16, i64* %t18
+  %t19 = getelementptr inbounds [2 x %lang.string], [2 x %lang.string]* @printf.Suit.names, i64 0, i64 1
+  %t20 = ptrtoint %lang.string* %t19 to i64
+  %t21 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 2
+  store i64 3, i64* %t21
+  %t22 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 3
+  store i64 %t20, i64* %t22
+  %t23 = ptrtoint %lang.string* @.str8 to i64
+  %t24 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 0
+  store i64 3, i64* %t24
+  %t25 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 1
+  store i64 %t23, i64* %t25
+  %t26 = getelementptr inbounds [12 x i64], [12 x i64]* %printf4, i64 0, i64 0
+  call void @lang_asm_printf(%lang.string* @.str4, i64 6, i64* %t26)
+  %t28 = getelementptr inbounds [0 x i64], [0 x i64]* %printf27, i64 0, i64 0
+  call void @lang_asm_printf(%lang.string* @.str10, i64 0, i64* %t28)
+  ret i64 0
+}
+
+define i32 @main(i32 %argc, i8** %argv) {
+entry:
+  %t1 = icmp eq i32 %argc, 3
+  br i1 %t1, label %L1, label %L2
+L2:
+  %t2 = load i8*, i8** %argv
+  call void @lang_asm_usage(i8* %t2, i8* getelementptr inbounds ([11 x i8], [11 x i8]* @.cstr11, i64 0, i64 0))
+  unreachable
+L1:
+  call void @lang_init_printf()
+  %t3 = getelementptr inbounds i8*, i8** %argv, i64 1
+  %t4 = load i8*, i8** %t3
+  %t5 = call i64 @lang_asm_arg_int(i8* %t4)
+  %t6 = getelementptr inbounds i8*, i8** %argv, i64 2
+  %t7 = load i8*, i8** %t6
+  %t8 = call i64 @lang_asm_arg_bool(i8* %t7)
+  %t9 = icmp ne i64 %t8, 0
+  %t10 = call i64 @printf.main(i64 %t5, i1 %t9)
+  %t11 = trunc i64 %t10 to i32
+  ret i32 %t11
+}
+
+@.bytes1 = private unnamed_addr constant [6 x i8] c"Hearts"
+@.bytes2 = private unnamed_addr constant [6 x i8] c"Spades"
+@printf.Suit.names = private unnamed_addr constant [2 x %lang.string] [%lang.string { i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.bytes1, i64 0, i64 0), i64 6 }, %lang.string { i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.bytes2, i64 0, i64 0), i64 6 }]
+@.bytes3 = private unnamed_addr constant [24 x i8] c"%d %5.2f %t %s %v|%-6q|\0A"
+@.str4 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([24 x i8], [24 x i8]* @.bytes3, i64 0, i64 0), i64 24 }
+@.bytes5 = private unnamed_addr constant [5 x i8] c"cards"
+@.str6 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.bytes5, i64 0, i64 0), i64 5 }
+@.bytes7 = private unnamed_addr constant [1 x i8] c"x"
+@.str8 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes7, i64 0, i64 0), i64 1 }
+@.bytes9 = private unnamed_addr constant [5 x i8] c"done\0A"
+@.str10 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([5 x i8], [5 x i8]* @.bytes9, i64 0, i64 0), i64 5 }
+@.cstr11 = private unnamed_addr constant [11 x i8] c" n verbose\00"
+
+declare i64 @lang_asm_arg_bool(i8*)
+declare i64 @lang_asm_arg_int(i8*)
+declare void @lang_asm_printf(%lang.string*, i64, i64*)
+declare void @lang_asm_usage(i8*, i8*)
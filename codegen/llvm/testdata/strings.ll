@@ -0,0 +1,162 @@
+; Code generated by lang build. DO NOT EDIT.
+
+%lang.string = type { i8*, i64 }
+
+; module strings
+
+define internal void @lang_init_strings() {
+entry:
+  ret void
+}
+
+define internal %lang.string* @strings.greet(%lang.string* %name) {
+entry:
+  %name.addr = alloca %lang.string*
+  store %lang.string* %name, %lang.string** %name.addr
+  %t1 = load %lang.string*, %lang.string** %name.addr
+  %t2 = call %lang.string* @lang_asm_concat(%lang.string* @.str2, %lang.string* %t1)
+  %t3 = call %lang.string* @lang_asm_concat(%lang.string* %t2, %lang.string* @.str4)
+  ret %lang.string* %t3
+}
+
+define internal i64 @strings.main(%lang.string* %name) {
+entry:
+  %name.addr = alloca %lang.string*
+  %s.addr = alloca %lang.string*
+  %n.addr = alloca i64
+  %same.addr = alloca i1
+  store %lang.string* %name, %lang.string** %name.addr
+  %t1 = load %lang.string*, %lang.string** %name.addr
+  %t2 = call %lang.string* @strings.greet(%lang.string* %t1)
+  store %lang.string* %t2, %lang.string** %s.addr
+  %t3 = load %lang.string*, %lang.string** %s.addr
+  call void @lang_asm_println(%lang.string* %t3)
+  %t4 = load %lang.string*, %lang.string** %s.addr
+  %t5 = call i64 @lang_asm_len(%lang.string* %t4)
+  %t6 = call %lang.string* @lang_asm_int_string(i64 %t5)
+  %t7 = call %lang.string* @lang_asm_concat(%lang.string* %t6, %lang.string* @.str8)
+  %t8 = load %lang.string*, %lang.string** %s.addr
+  %t9 = call %lang.string* @lang_asm_substr(%lang.string* %t8, i64 0, i64 5)
+  %t10 = call %lang.string* @lang_asm_concat(%lang.string* %t7, %lang.string* %t9)
+  %t11 = call %lang.string* @lang_asm_concat(%lang.string* %t10, %lang.string* @.str8)
+  %t12 = load %lang.string*, %lang.string** %s.addr
+  %t13 = call i64 @lang_asm_indexOf(%lang.string* %t12, %lang.string* @.str4)
+  %t14 = call %lang.string* @lang_asm_int_string(i64 %t13)
+  %t15 = call %lang.string* @lang_asm_concat(%lang.string* %t11, %lang.string* %t14)
+  %t16 = call %lang.string* @lang_asm_concat(%lang.string* %t15, %lang.string* @.str10)
+  call void @lang_asm_print(%lang.string* %t16)
+  %t17 = call i64 @lang_asm_parseInt(%lang.string* @.str12)
+  %t18 = call double @lang_asm_parseFloat(%lang.string* @.str14)
+  %t19 = call i64 @lang_asm_float_to_int(double %t18)
+  %t20 = add i64 %t17, %t19
+  store i64 %t20, i64* %n.addr
+  %t21 = load %lang.string*, %lang.string** %s.addr
+  %t22 = load %lang.string*, %lang.string** %name.addr
+  %t23 = call %lang.string* @strings.greet(%lang.string* %t22)
+  %t24 = call i64 @lang_asm_streq(%lang.string* %t21, %lang.string* %t23)
+  %t25 = icmp ne i64 %t24, 0
+  store i1 %t25, i1* %same.addr
+  %t26 = load i64, i64* %n.addr
+  %t27 = call %lang.string* @lang_asm_int_string(i64 %t26)
+  %t28 = call %lang.string* @lang_asm_concat(%lang.string* %t27, %lang.string* @.str8)
+  %t29 = load i1, i1* %same.addr
+  %t30 = zext i1 %t29 to i64
+  %t31 = call %lang.string* @lang_asm_bool_string(i64 %t30)
+  %t32 = call %lang.string* @lang_asm_concat(%lang.string* %t28, %lang.string* %t31)
+  %t33 = call %lang.string* @lang_asm_concat(%lang.string* %t32, %lang.string* @.str8)
+  %t34 = load %lang.string*, %lang.string** %s.addr
+  %t35 = call i64 @lang_asm_streq(%lang.string* %t34, %lang.string* @.str16)
+  %t36 = icmp eq i64 %t35, 0
+  %t37 = zext i1 %t36 to i64
+  %t38 = call %lang.string* @lang_asm_bool_string(i64 %t37)
+  %t39 = call %lang.string* @lang_asm_concat(%lang.string* %t33, %lang.string* %t38)
+  %t40 = call %lang.string* @lang_asm_concat(%lang.string* %t39, %lang.string* @.str8)
+  %t41 = call double @llvm.sqrt.f64(double 0x4030000000000000)
+  %t42 = call %lang.string* @lang_asm_float_string(double %t41)
+  %t43 = call %lang.string* @lang_asm_concat(%lang.string* %t40, %lang.string* %t42)
+  %t44 = call %lang.string* @lang_asm_concat(%lang.string* %t43, %lang.string* @.str8)
+  %t45 = call double @lang_asm_pow(double 0x4000000000000000, double 0x4020000000000000)
+  %t46 = call %lang.string* @lang_asm_float_string(double %t45)
+  %t47 = call %lang.string* @lang_asm_concat(%lang.string* %t44, %lang.string* %t46)
+  call void @lang_asm_println(%lang.string* %t47)
+  %t48 = load %lang.string*, %lang.string** %name.addr
+  %t49 = call i64 @lang_asm_streq(%lang.string* %t48, %lang.string* @.str6)
+  %t50 = icmp ne i64 %t49, 0
+  br i1 %t50, label %L2, label %L4
+L4:
+  %t51 = call i64 @lang_asm_streq(%lang.string* %t48, %lang.string* @.str18)
+  %t52 = icmp ne i64 %t51, 0
+  br i1 %t52, label %L2, label %L5
+L5:
+  br label %L3
+L2:
+  call void @lang_asm_println(%lang.string* @.str20)
+  br label %L1
+L3:
+  call void @lang_asm_println(%lang.string* @.str22)
+  br label %L1
+L1:
+  %t53 = load %lang.string*, %lang.string** %name.addr
+  %t54 = call i64 @lang_asm_len(%lang.string* %t53)
+  ret i64 %t54
+}
+
+define i32 @main(i32 %argc, i8** %argv) {
+entry:
+  %t1 = icmp eq i32 %argc, 2
+  br i1 %t1, label %L1, label %L2
+L2:
+  %t2 = load i8*, i8** %argv
+  call void @lang_asm_usage(i8* %t2, i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.cstr23, i64 0, i64 0))
+  unreachable
+L1:
+  call void @lang_init_strings()
+  %t3 = getelementptr inbounds i8*, i8** %argv, i64 1
+  %t4 = load i8*, i8** %t3
+  %t5 = call %lang.string* @lang_asm_arg_string(i8* %t4)
+  %t6 = call i64 @strings.main(%lang.string* %t5)
+  %t7 = trunc i64 %t6 to i32
+  ret i32 %t7
+}
+
+@.bytes1 = private unnamed_addr constant [8 x i8] c"h\C3\A9llo, "
+@.str2 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([8 x i8], [8 x i8]* @.bytes1, i64 0, i64 0), i64 8 }
+@.bytes3 = private unnamed_addr constant [1 x i8] c"!"
+@.str4 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes3, i64 0, i64 0), i64 1 }
+@.bytes5 = private unnamed_addr constant [0 x i8] c""
+@.str6 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([0 x i8], [0 x i8]* @.bytes5, i64 0, i64 0), i64 0 }
+@.bytes7 = private unnamed_addr constant [1 x i8] c" "
+@.str8 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes7, i64 0, i64 0), i64 1 }
+@.bytes9 = private unnamed_addr constant [1 x i8] c"\0A"
+@.str10 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes9, i64 0, i64 0), i64 1 }
+@.bytes11 = private unnamed_addr constant [3 x i8] c"-42"
+@.str12 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([3 x i8], [3 x i8]* @.bytes11, i64 0, i64 0), i64 3 }
+@.bytes13 = private unnamed_addr constant [3 x i8] c"2.5"
+@.str14 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([3 x i8], [3 x i8]* @.bytes13, i64 0, i64 0), i64 3 }
+@.bytes15 = private unnamed_addr constant [1 x i8] c"x"
+@.str16 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes15, i64 0, i64 0), i64 1 }
+@.bytes17 = private unnamed_addr constant [6 x i8] c"nobody"
+@.str18 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([6 x i8], [6 x i8]* @.bytes17, i64 0, i64 0), i64 6 }
+@.bytes19 = private unnamed_addr constant [4 x i8] c"who?"
+@.str20 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([4 x i8], [4 x i8]* @.bytes19, i64 0, i64 0), i64 4 }
+@.bytes21 = private unnamed_addr constant [2 x i8] c"hi"
+@.str22 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([2 x i8], [2 x i8]* @.bytes21, i64 0, i64 0), i64 2 }
+@.cstr23 = private unnamed_addr constant [6 x i8] c" name\00"
+
+declare %lang.string* @lang_asm_arg_string(i8*)
+declare %lang.string* @lang_asm_bool_string(i64)
+declare %lang.string* @lang_asm_concat(%lang.string*, %lang.string*)
+declare %lang.string* @lang_asm_float_string(double)
+declare i64 @lang_asm_float_to_int(double)
+declare i64 @lang_asm_indexOf(%lang.string*, %lang.string*)
+declare %lang.string* @lang_asm_int_string(i64)
+declare i64 @lang_asm_len(%lang.string*)
+declare double @lang_asm_parseFloat(%lang.string*)
+declare i64 @lang_asm_parseInt(%lang.string*)
+declare double @lang_asm_pow(double, double)
+declare void @lang_asm_print(%lang.string*)
+declare void @lang_asm_println(%lang.string*)
+declare i64 @lang_asm_streq(%lang.string*, %lang.string*)
+declare %lang.string* @lang_asm_substr(%lang.string*, i64, i64)
+declare void @lang_asm_usage(i8*, i8*)
+declare double @llvm.sqrt.f64(double)
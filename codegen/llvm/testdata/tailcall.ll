@@ -0,0 +1,157 @@
+; Code generated by lang build. DO NOT EDIT.
+
+%lang.string = type { i8*, i64 }
+
+; module tailcall
+
+define internal void @lang_init_tailcall() {
+entry:
+  ret void
+}
+
+define internal i64 @tailcall.count(i64 %n, i64 %acc) {
+entry:
+  %n.addr = alloca i64
+  %acc.addr = alloca i64
+  store i64 %n, i64* %n.addr
+  store i64 %acc, i64* %acc.addr
+  %t1 = load i64, i64* %n.addr
+  %t2 = icmp eq i64 %t1, 0
+  br i1 %t2, label %L1, label %L3
+L1:
+  %t3 = load i64, i64* %acc.addr
+  ret i64 %t3
+L3:
+  %t4 = load i64, i64* %n.addr
+  %t5 = sub i64 %t4, 1
+  %t6 = load i64, i64* %acc.addr
+  %t7 = add i64 %t6, 1
+  %t8 = musttail call i64 @tailcall.count(i64 %t5, i64 %t7)
+  ret i64 %t8
+}
+
+define internal i1 @tailcall.isEven(i64 %n) {
+entry:
+  %n.addr = alloca i64
+  store i64 %n, i64* %n.addr
+  %t1 = load i64, i64* %n.addr
+  %t2 = icmp eq i64 %t1, 0
+  br i1 %t2, label %L1, label %L3
+L1:
+  ret i1 true
+L3:
+  %t3 = load i64, i64* %n.addr
+  %t4 = sub i64 %t3, 1
+  %t5 = musttail call i1 @tailcall.isOdd(i64 %t4)
+  ret i1 %t5
+}
+
+define internal i1 @tailcall.isOdd(i64 %n) {
+entry:
+  %n.addr = alloca i64
+  store i64 %n, i64* %n.addr
+  %t1 = load i64, i64* %n.addr
+  %t2 = icmp eq i64 %t1, 0
+  br i1 %t2, label %L1, label %L3
+L1:
+  ret i1 false
+L3:
+  %t3 = load i64, i64* %n.addr
+  %t4 = sub i64 %t3, 1
+  %t5 = musttail call i1 @tailcall.isEven(i64 %t4)
+  ret i1 %t5
+}
+
+define internal double @tailcall.halve(double %x, i64 %times) {
+entry:
+  %x.addr = alloca double
+  %times.addr = alloca i64
+  store double %x, double* %x.addr
+  store i64 %times, i64* %times.addr
+  %t1 = load i64, i64* %times.addr
+  %t2 = icmp eq i64 %t1, 0
+  br i1 %t2, label %L1, label %L3
+L1:
+  %t3 = load double, double* %x.addr
+  ret double %t3
+L3:
+  %t4 = load double, double* %x.addr
+  %t5 = fdiv double %t4, 0x4000000000000000
+  %t6 = load i64, i64* %times.addr
+  %t7 = sub i64 %t6, 1
+  %t8 = musttail call double @tailcall.halve(double %t5, i64 %t7)
+  ret double %t8
+}
+
+define internal i64 @tailcall.depth(i64 %n) {
+entry:
+  %n.addr = alloca i64
+  store i64 %n, i64* %n.addr
+  %t1 = load i64, i64* %n.addr
+  %t2 = icmp eq i64 %t1, 0
+  br i1 %t2, label %L1, label %L3
+L1:
+  ret i64 0
+L3:
+  %t3 = load i64, i64* %n.addr
+  %t4 = sub i64 %t3, 1
+  %t5 = call i64 @tailcall.depth(i64 %t4)
+  %t6 = add i64 1, %t5
+  ret i64 %t6
+}
+
+define internal i64 @tailcall.main(i64 %n) {
+entry:
+  %n.addr = alloca i64
+  store i64 %n, i64* %n.addr
+  %t1 = load i64, i64* %n.addr
+  %t2 = call i64 @tailcall.count(i64 100000, i64 %t1)
+  %t3 = call %lang.string* @lang_asm_int_string(i64 %t2)
+  %t4 = call %lang.string* @lang_asm_concat(%lang.string* %t3, %lang.string* @.str2)
+  %t5 = call i1 @tailcall.isEven(i64 100001)
+  %t6 = zext i1 %t5 to i64
+  %t7 = call %lang.string* @lang_asm_bool_string(i64 %t6)
+  %t8 = call %lang.string* @lang_asm_concat(%lang.string* %t4, %lang.string* %t7)
+  %t9 = call %lang.string* @lang_asm_concat(%lang.string* %t8, %lang.string* @.str2)
+  %t10 = call double @tailcall.halve(double 0x4090000000000000, i64 20)
+  %t11 = call %lang.string* @lang_asm_float_string(double %t10)
+  %t12 = call %lang.string* @lang_asm_concat(%lang.string* %t9, %lang.string* %t11)
+  %t13 = call %lang.string* @lang_asm_concat(%lang.string* %t12, %lang.string* @.str2)
+  %t14 = call i64 @tailcall.depth(i64 100)
+  %t15 = call %lang.string* @lang_asm_int_string(i64 %t14)
+  %t16 = call %lang.string* @lang_asm_concat(%lang.string* %t13, %lang.string* %t15)
+  call void @lang_asm_println(%lang.string* %t16)
+  %t17 = load i64, i64* %n.addr
+  %t18 = tail call i64 @tailcall.count(i64 3, i64 %t17)
+  ret i64 %t18
+}
+
+define i32 @main(i32 %argc, i8** %argv) {
+entry:
+  %t1 = icmp eq i32 %argc, 2
+  br i1 %t1, label %L1, label %L2
+L2:
+  %t2 = load i8*, i8** %argv
+  call void @lang_asm_usage(i8* %t2, i8* getelementptr inbounds ([3 x i8], [3 x i8]* @.cstr3, i64 0, i64 0))
+  unreachable
+L1:
+  call void @lang_init_tailcall()
+  %t3 = getelementptr inbounds i8*, i8** %argv, i64 1
+  %t4 = load i8*, i8** %t3
+  %t5 = call i64 @lang_asm_arg_int(i8* %t4)
+  %t6 = call i64 @tailcall.main(i64 %t5)
+  %t7 = trunc i64 %t6 to i32
+  ret i32 %t7
+}
+
+@.bytes1 = private unnamed_addr constant [1 x i8] c" "
+@.str2 = private unnamed_addr constant %lang.string { i8* getelementptr inbounds ([1 x i8], [1 x i8]* @.bytes1, i64 0, i64 0), i64 1 }
+@.cstr3 = private unnamed_addr constant [3 x i8] c" n\00"
+
+declare i64 @lang_asm_arg_int(i8*)
+declare %lang.string* @lang_asm_bool_string(i64)
+declare %lang.string* @lang_asm_concat(%lang.string*, %lang.string*)
+declare %lang.string* @lang_asm_float_string(double)
+declare %lang.string* @lang_asm_int_string(i64)
+declare void @lang_asm_println(%lang.string*)
+declare void @lang_asm_usage(i8*, i8*)
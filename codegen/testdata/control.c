@@ -0,0 +1,40 @@
+enum Color { Red, Green, Blue }
+
+string describe(Color c) {
+  switch (c) {
+  case Color.Red:
+    return "red";
+  case Color.Green, Color.Blue:
+    return "not red";
+  }
+  return "unreachable";
+}
+
+int main() {
+  int i = 0;
+  int sum = 0;
+  while (i < 5) {
+    int i2 = i * i;
+    if (i % 2 == 0) {
+      sum += i2;
+    } else if (i == 3) {
+      int sum = 100;
+      sum -= 1;
+    } else {
+      sum++;
+    }
+    i++;
+  }
+  switch (sum) {
+  case 0:
+    println("zero");
+  case 21:
+    println("twenty-one");
+    fallthrough;
+  default:
+    println("default");
+  }
+  println(describe(Color.Green));
+  println("${Color(2)} ${int(Color.Blue)}");
+  return sum;
+}
@@ -0,0 +1,31 @@
+int calls = 0;
+float scale = 1.5;
+
+int fib(int n) {
+  calls++;
+  if (n < 2) {
+    return n;
+  }
+  return fib(n - 1) + fib(n - 2);
+}
+
+float area(float r) {
+  return 3.14159 * r * r * scale;
+}
+
+bool between(int x, int lo, int hi) {
+  return x >= lo && x <= hi || x == -1;
+}
+
+int main() {
+  int n = fib(10);
+  n *= 2;
+  n -= 1;
+  n <<= 1;
+  float f = area(2.0);
+  f /= 2.0;
+  f--;
+  println("${n} ${calls} ${f} ${between(n, 0, 100)} ${!between(5, 0, 10)}");
+  println("${int(f)} ${float(n) / 4.0} ${-n} ${~n} ${n % 7} ${n & 6 | 1 ^ 8} ${n >> 2}");
+  return n > 100 ? abs(n - 200) : 0;
+}
@@ -0,0 +1,22 @@
+int counter = 0;
+
+int next() {
+  counter++;
+  return counter;
+}
+
+int main(int n, string s) {
+  int i = 0;
+  while (i < n && next() < 100) {
+    i += 2;
+    if (i == 4) {
+      i++;
+    }
+  }
+  bool b = i > 3 ? next() > 0 : false;
+  switch (s) {
+  case "a":
+    println("a ${b}");
+  }
+  return i;
+}
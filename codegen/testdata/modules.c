@@ -0,0 +1,11 @@
+module main;
+
+import "modules/geo";
+
+extern int random(int n);
+
+int main() {
+  geo.Shape s = geo.Shape.Triangle;
+  println("${geo.area(s, 4)} ${geo.sides} ${s}");
+  return geo.area(geo.Shape.Square, random(3));
+}
@@ -0,0 +1,15 @@
+module geo;
+
+export enum Shape { Square, Triangle }
+
+export int sides = 4;
+
+export int area(Shape s, int size) {
+  switch (s) {
+  case Shape.Square:
+    return size * size;
+  case Shape.Triangle:
+    return size * size / 2;
+  }
+  return 0;
+}
@@ -0,0 +1,8 @@
+enum Suit { Hearts, Spades }
+
+int main(int n, bool verbose) {
+  float ratio = float(n) / 3.0;
+  printf("%d %5.2f %t %s %v|%-6q|\n", n, ratio, verbose, "cards", Suit.Spades, "x");
+  printf("done\n");
+  return 0;
+}
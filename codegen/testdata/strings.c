@@ -0,0 +1,19 @@
+string greet(string name) {
+  return "héllo, ${name}!";
+}
+
+int main(string name) {
+  string s = greet(name);
+  println(s);
+  print("${len(s)} ${substr(s, 0, 5)} ${indexOf(s, "!")}\n");
+  int n = parseInt("-42") + int(parseFloat("2.5"));
+  bool same = s == greet(name);
+  println("${n} ${same} ${s != "x"} ${sqrt(16.0)} ${pow(2.0, 8.0)}");
+  switch (name) {
+  case "", "nobody":
+    println("who?");
+  default:
+    println("hi");
+  }
+  return len(name);
+}
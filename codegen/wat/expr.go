@@ -0,0 +1,269 @@
+package wat
+
+import (
+	"fmt"
+	"lang/analysis"
+	"lang/parser"
+	"lang/scanner"
+	"strings"
+)
+
+// intOps and floatOps are the instructions for binary operators, without
+// their type prefix. Those that can fail on ints are runtime functions.
+var (
+	intOps = map[scanner.TokenKind]string{
+		scanner.Plus:    "add",
+		scanner.Minus:   "sub",
+		scanner.Star:    "mul",
+		scanner.Slash:   "$lang_div",
+		scanner.Percent: "$lang_mod",
+		scanner.BAnd:    "and",
+		scanner.BOr:     "or",
+		scanner.BXor:    "xor",
+		scanner.Shl:     "$lang_shl",
+		scanner.Shr:     "$lang_shr",
+		scanner.Lt:      "lt_s",
+		scanner.Lte:     "le_s",
+		scanner.Gt:      "gt_s",
+		scanner.Gte:     "ge_s",
+		scanner.EqEq:    "eq",
+		scanner.Ne:      "ne",
+	}
+	floatOps = map[scanner.TokenKind]string{
+		scanner.Plus:  "add",
+		scanner.Minus: "sub",
+		scanner.Star:  "mul",
+		scanner.Slash: "div",
+		scanner.Lt:    "lt",
+		scanner.Lte:   "le",
+		scanner.Gt:    "gt",
+		scanner.Gte:   "ge",
+		scanner.EqEq:  "eq",
+		scanner.Ne:    "ne",
+	}
+)
+
+// expr returns e as a folded instruction.
+func (g *generator) expr(e parser.Expr) string {
+	switch n := e.(type) {
+	case parser.LiteralNum:
+		if analysis.TypeOf(g.env, n) == analysis.Float {
+			return "(f64.const " + n.Value + ")"
+		}
+		return "(i64.const " + n.Value + ")"
+	case parser.LiteralStr:
+		return fmt.Sprintf("(i32.const %d)", g.intern(n.Value))
+	case parser.LiteralBool:
+		if n.Value {
+			return "(i32.const 1)"
+		}
+		return "(i32.const 0)"
+	case parser.IdentExpr:
+		if local, ok := g.local(n.Name.Lexeme); ok {
+			return "(local.get " + local + ")"
+		}
+		return "(global.get " + g.global(n.Name.Lexeme) + ")"
+	case parser.MemberAccess:
+		if enum, isEnum := analysis.TypeNamed(g.env, n.Parent).(*analysis.EnumType); isEnum {
+			for i, m := range enum.Members {
+				if string(m) == n.Name.Lexeme {
+					return fmt.Sprintf("(i64.const %d)", i)
+				}
+			}
+		}
+		mt := analysis.TypeOf(g.env, n.Parent).(*analysis.ModuleType)
+		return "(global.get $" + watName(string(mt.Name)) + "." + n.Name.Lexeme + ")"
+	case parser.FunctionCall:
+		return g.call(n)
+	case parser.UnaryOp:
+		x := g.expr(n.Expr)
+		switch n.Op.Kind {
+		case scanner.Minus:
+			if analysis.TypeOf(g.env, n) == analysis.Float {
+				return "(f64.neg " + x + ")"
+			}
+			return "(i64.sub (i64.const 0) " + x + ")"
+		case scanner.LNot:
+			return "(i32.eqz " + x + ")"
+		default:
+			return "(i64.xor " + x + " (i64.const -1))"
+		}
+	case parser.BinaryOp:
+		l, r := g.expr(n.Left), g.expr(n.Right)
+		switch n.Op.Kind {
+		case scanner.LAnd:
+			return fmt.Sprintf("(if (result i32) %s (then %s) (else (i32.const 0)))", l, r)
+		case scanner.LOr:
+			return fmt.Sprintf("(if (result i32) %s (then (i32.const 1)) (else %s))", l, r)
+		case scanner.EqEq:
+			return g.equal(analysis.TypeOf(g.env, n.Left), l, r)
+		case scanner.Ne:
+			return "(i32.eqz " + g.equal(analysis.TypeOf(g.env, n.Left), l, r) + ")"
+		}
+		if analysis.TypeOf(g.env, n.Left) == analysis.Float {
+			return fmt.Sprintf("(f64.%s %s %s)", floatOps[n.Op.Kind], l, r)
+		}
+		op := intOps[n.Op.Kind]
+		if strings.HasPrefix(op, "$") {
+			return fmt.Sprintf("(call %s %s %s)", op, l, r)
+		}
+		return fmt.Sprintf("(i64.%s %s %s)", op, l, r)
+	case parser.TernaryExpr:
+		return fmt.Sprintf("(if (result %s) %s (then %s) (else %s))", g.wtype(analysis.TypeOf(g.env, n)), g.expr(n.Cond), g.expr(n.Then), g.expr(n.Els))
+	case parser.InterpolatedStr:
+		var s string
+		for _, part := range n.Parts {
+			if lit, ok := part.(parser.LiteralStr); ok && lit.Value == "" {
+				continue
+			}
+			code := g.toString(part, g.expr(part))
+			if s == "" {
+				s = code
+			} else {
+				s = fmt.Sprintf("(call $lang_concat %s %s)", s, code)
+			}
+		}
+		if s == "" {
+			return fmt.Sprintf("(i32.const %d)", g.intern(""))
+		}
+		return s
+	default:
+		panic(fmt.Sprintf("cannot generate WebAssembly for %T", e))
+	}
+}
+
+// equal returns an instruction comparing l and r, which are of type t.
+func (g *generator) equal(t analysis.Type, l, r string) string {
+	switch t {
+	case analysis.Float:
+		return fmt.Sprintf("(f64.eq %s %s)", l, r)
+	case analysis.Bool:
+		return fmt.Sprintf("(i32.eq %s %s)", l, r)
+	case analysis.String:
+		return fmt.Sprintf("(call $lang_streq %s %s)", l, r)
+	default:
+		return fmt.Sprintf("(i64.eq %s %s)", l, r)
+	}
+}
+
+func (g *generator) call(n parser.FunctionCall) string {
+	if t := analysis.TypeNamed(g.env, n.Callee); t != nil {
+		return g.convert(t, n.Args[0])
+	}
+	if callee, ok := n.Callee.(parser.IdentExpr); ok && g.isBuiltin(callee.Name.Lexeme) {
+		var args []string
+		for _, arg := range n.Args {
+			args = append(args, g.expr(arg))
+		}
+		return g.builtin(callee.Name, args)
+	}
+	return "(call " + g.operands(n) + ")"
+}
+
+// isTailCall reports whether n, which a function with results of type ret
+// returns the result of, can be a return_call instruction, which does not
+// grow the stack: it must call a function of the program with the same
+// results.
+func (g *generator) isTailCall(n parser.FunctionCall, ret analysis.Type) bool {
+	if analysis.TypeNamed(g.env, n.Callee) != nil || analysis.TypeOf(g.env, n) != ret {
+		return false
+	}
+	callee, ok := n.Callee.(parser.IdentExpr)
+	return !ok || !g.isBuiltin(callee.Name.Lexeme)
+}
+
+// operands returns the function that n calls, which is not a builtin, and
+// its arguments, as the operands of a call instruction.
+func (g *generator) operands(n parser.FunctionCall) string {
+	operands := []string{g.callee(n)}
+	for _, arg := range n.Args {
+		operands = append(operands, g.expr(arg))
+	}
+	return strings.Join(operands, " ")
+}
+
+// isBuiltin reports whether name refers to a builtin function in the
+// current scope.
+func (g *generator) isBuiltin(name string) bool {
+	_, isLocal := g.local(name)
+	return g.builtins[name] && !isLocal && !g.globals[name]
+}
+
+// callee returns the WebAssembly name of the function that n calls, which
+// is not a builtin.
+func (g *generator) callee(n parser.FunctionCall) string {
+	switch callee := n.Callee.(type) {
+	case parser.IdentExpr:
+		name := callee.Name.Lexeme
+		if g.mod.Extern(name) {
+			return "$extern." + name
+		}
+		return g.global(name)
+	case parser.MemberAccess:
+		mt := analysis.TypeOf(g.env, callee.Parent).(*analysis.ModuleType)
+		for _, m := range g.mods {
+			if m.Name == string(mt.Name) && m.Extern(callee.Name.Lexeme) {
+				return "$extern." + callee.Name.Lexeme
+			}
+		}
+		return "$" + watName(string(mt.Name)) + "." + callee.Name.Lexeme
+	}
+	panic(fmt.Sprintf("cannot call %T", n.Callee))
+}
+
+func (g *generator) builtin(name scanner.Token, args []string) string {
+	switch name.Lexeme {
+	case "printf":
+		g.fail(name, "printf is not supported in WebAssembly")
+		return "(unreachable)"
+	case "sqrt":
+		return "(f64.sqrt " + args[0] + ")"
+	case "pow":
+		return "(call $lang_host_pow " + strings.Join(args, " ") + ")"
+	default:
+		return "(call " + strings.Join(append([]string{"$lang_" + name.Lexeme}, args...), " ") + ")"
+	}
+}
+
+// toString returns code, which is e as a folded instruction, converted to
+// a string.
+func (g *generator) toString(e parser.Expr, code string) string {
+	switch t := analysis.TypeOf(g.env, e).(type) {
+	case *analysis.EnumType:
+		return fmt.Sprintf("(i32.load (i32.add (i32.const %d) (i32.shl (i32.wrap_i64 %s) (i32.const 2))))", g.enums[t], code)
+	default:
+		switch t {
+		case analysis.Int:
+			return "(call $lang_int_string " + code + ")"
+		case analysis.Float:
+			return "(call $lang_float_string " + code + ")"
+		case analysis.Bool:
+			return "(call $lang_bool_string " + code + ")"
+		default:
+			return code
+		}
+	}
+}
+
+// convert returns an instruction converting e to type t.
+func (g *generator) convert(t analysis.Type, e parser.Expr) string {
+	code := g.expr(e)
+	from := analysis.TypeOf(g.env, e)
+	if from == t {
+		return code
+	}
+	if enum, isEnum := t.(*analysis.EnumType); isEnum {
+		return fmt.Sprintf("(call $lang_enum %s (i64.const %d) (i32.const %d))", code, len(enum.Members), g.intern(string(enum.Name)))
+	}
+	switch t {
+	case analysis.Int:
+		if from == analysis.Float {
+			return "(call $lang_float_to_int " + code + ")"
+		}
+		return code
+	case analysis.Float:
+		return "(f64.convert_i64_s " + code + ")"
+	default:
+		return g.toString(e, code)
+	}
+}
@@ -0,0 +1,358 @@
+  ;; Runtime
+
+  ;; A string is the address of its length in bytes, stored as an i32,
+  ;; followed by its UTF-8 bytes. Memory is allocated from $lang_heap and
+  ;; never freed.
+
+  (func $lang_alloc (param $n i32) (result i32)
+    (local $p i32)
+    (local.set $p (global.get $lang_heap))
+    (global.set $lang_heap (i32.and (i32.add (i32.add (local.get $p) (local.get $n)) (i32.const 7)) (i32.const -8)))
+    (if (i32.gt_u (global.get $lang_heap) (i32.shl (memory.size) (i32.const 16)))
+      (then
+        (if (i32.eq (memory.grow (i32.sub (i32.shr_u (i32.add (global.get $lang_heap) (i32.const 65535)) (i32.const 16)) (memory.size))) (i32.const -1))
+          (then (call $lang_fatal (i32.const {{str "runtime error: out of memory"}}))))))
+    (local.get $p))
+
+  ;; lang_new_string allocates a string of n bytes, which the host can fill
+  ;; in to pass strings to main.
+  (func $lang_new_string (export "new_string") (param $n i32) (result i32)
+    (local $s i32)
+    (local.set $s (call $lang_alloc (i32.add (local.get $n) (i32.const 4))))
+    (i32.store (local.get $s) (local.get $n))
+    (local.get $s))
+
+  ;; lang_slice returns a new string holding the n bytes at p.
+  (func $lang_slice (param $p i32) (param $n i32) (result i32)
+    (local $s i32)
+    (local.set $s (call $lang_new_string (local.get $n)))
+    (memory.copy (i32.add (local.get $s) (i32.const 4)) (local.get $p) (local.get $n))
+    (local.get $s))
+
+  (func $lang_byte (param $s i32) (param $i i32) (result i32)
+    (i32.load8_u (i32.add (i32.add (local.get $s) (i32.const 4)) (local.get $i))))
+
+  (func $lang_fatal (param $msg i32)
+    (call $lang_host_fatal (i32.add (local.get $msg) (i32.const 4)) (i32.load (local.get $msg)))
+    (unreachable))
+
+  (func $lang_concat (param $a i32) (param $b i32) (result i32)
+    (local $s i32)
+    (local.set $s (call $lang_new_string (i32.add (i32.load (local.get $a)) (i32.load (local.get $b)))))
+    (memory.copy (i32.add (local.get $s) (i32.const 4)) (i32.add (local.get $a) (i32.const 4)) (i32.load (local.get $a)))
+    (memory.copy (i32.add (i32.add (local.get $s) (i32.const 4)) (i32.load (local.get $a))) (i32.add (local.get $b) (i32.const 4)) (i32.load (local.get $b)))
+    (local.get $s))
+
+  ;; lang_equal reports whether the n bytes at p and q are equal.
+  (func $lang_equal (param $p i32) (param $q i32) (param $n i32) (result i32)
+    (local $i i32)
+    (block $done
+      (loop $next
+        (br_if $done (i32.ge_u (local.get $i) (local.get $n)))
+        (if (i32.ne (i32.load8_u (i32.add (local.get $p) (local.get $i))) (i32.load8_u (i32.add (local.get $q) (local.get $i))))
+          (then (return (i32.const 0))))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))
+    (i32.const 1))
+
+  (func $lang_streq (param $a i32) (param $b i32) (result i32)
+    (if (result i32) (i32.ne (i32.load (local.get $a)) (i32.load (local.get $b)))
+      (then (i32.const 0))
+      (else (call $lang_equal (i32.add (local.get $a) (i32.const 4)) (i32.add (local.get $b) (i32.const 4)) (i32.load (local.get $a))))))
+
+  ;; Integer operations
+
+  (func $lang_div (param $a i64) (param $b i64) (result i64)
+    (if (i64.eqz (local.get $b))
+      (then (call $lang_fatal (i32.const {{str "runtime error: integer division by zero"}}))))
+    ;; i64.div_s traps on overflow, which wraps around instead.
+    (if (i64.eq (local.get $b) (i64.const -1))
+      (then (return (i64.sub (i64.const 0) (local.get $a)))))
+    (i64.div_s (local.get $a) (local.get $b)))
+
+  (func $lang_mod (param $a i64) (param $b i64) (result i64)
+    (if (i64.eqz (local.get $b))
+      (then (call $lang_fatal (i32.const {{str "runtime error: integer division by zero"}}))))
+    (i64.rem_s (local.get $a) (local.get $b)))
+
+  (func $lang_check_shift (param $b i64)
+    (if (i64.lt_s (local.get $b) (i64.const 0))
+      (then (call $lang_fatal (call $lang_concat (i32.const {{str "runtime error: negative shift amount "}}) (call $lang_int_string (local.get $b)))))))
+
+  ;; WebAssembly only uses the low 6 bits of shift amounts.
+  (func $lang_shl (param $a i64) (param $b i64) (result i64)
+    (call $lang_check_shift (local.get $b))
+    (if (result i64) (i64.ge_s (local.get $b) (i64.const 64))
+      (then (i64.const 0))
+      (else (i64.shl (local.get $a) (local.get $b)))))
+
+  (func $lang_shr (param $a i64) (param $b i64) (result i64)
+    (call $lang_check_shift (local.get $b))
+    (if (i64.ge_s (local.get $b) (i64.const 64))
+      (then (local.set $b (i64.const 63))))
+    (i64.shr_s (local.get $a) (local.get $b)))
+
+  ;; Conversions
+
+  (func $lang_float_to_int (param $f f64) (result i64)
+    (if (result i64) (i32.and (f64.ge (local.get $f) (f64.const -0x1p63)) (f64.lt (local.get $f) (f64.const 0x1p63)))
+      (then (i64.trunc_f64_s (local.get $f)))
+      (else (i64.const -9223372036854775808))))
+
+  (func $lang_enum (param $i i64) (param $n i64) (param $name i32) (result i64)
+    (if (i32.or (i64.lt_s (local.get $i) (i64.const 0)) (i64.ge_s (local.get $i) (local.get $n)))
+      (then
+        (call $lang_fatal
+          (call $lang_concat
+            (call $lang_concat (i32.const {{str "runtime error: "}}) (call $lang_int_string (local.get $i)))
+            (call $lang_concat (i32.const {{str " is out of range for enum "}}) (local.get $name))))))
+    (local.get $i))
+
+  (func $lang_int_string (param $i i64) (result i32)
+    (local $end i32)
+    (local $p i32)
+    (local $n i64)
+    ;; The digits are written backwards, into room for the longest int.
+    (local.set $end (i32.add (call $lang_alloc (i32.const 20)) (i32.const 20)))
+    (local.set $p (local.get $end))
+    (local.set $n (local.get $i))
+    (loop $digit
+      (local.set $p (i32.sub (local.get $p) (i32.const 1)))
+      (i32.store8 (local.get $p) (i32.add (i32.const 48) (i32.wrap_i64 (call $lang_abs (i64.rem_s (local.get $n) (i64.const 10))))))
+      (local.set $n (i64.div_s (local.get $n) (i64.const 10)))
+      (br_if $digit (i64.ne (local.get $n) (i64.const 0))))
+    (if (i64.lt_s (local.get $i) (i64.const 0))
+      (then
+        (local.set $p (i32.sub (local.get $p) (i32.const 1)))
+        (i32.store8 (local.get $p) (i32.const 45))))
+    (call $lang_slice (local.get $p) (i32.sub (local.get $end) (local.get $p))))
+
+  (func $lang_float_string (param $f f64) (result i32)
+    (local $s i32)
+    (local.set $s (call $lang_alloc (i32.const 36)))
+    (i32.store (local.get $s) (call $lang_host_format_float (local.get $f) (i32.add (local.get $s) (i32.const 4))))
+    (local.get $s))
+
+  (func $lang_bool_string (param $b i32) (result i32)
+    (select (i32.const {{str "true"}}) (i32.const {{str "false"}}) (local.get $b)))
+
+  ;; lang_escape returns the letter that follows a backslash when c is
+  ;; quoted, or 0 if it needs no escape or a hexadecimal one.
+  (func $lang_escape (param $c i32) (result i32)
+    (if (i32.or (i32.eq (local.get $c) (i32.const 34)) (i32.eq (local.get $c) (i32.const 92)))
+      (then (return (local.get $c))))
+    (if (i32.eq (local.get $c) (i32.const 10))
+      (then (return (i32.const 110))))
+    (if (i32.eq (local.get $c) (i32.const 13))
+      (then (return (i32.const 114))))
+    (if (i32.eq (local.get $c) (i32.const 9))
+      (then (return (i32.const 116))))
+    (i32.const 0))
+
+  (func $lang_quote (param $s i32) (result i32)
+    (local $start i32)
+    (local $p i32)
+    (local $i i32)
+    (local $c i32)
+    (local.set $start (call $lang_alloc (i32.add (i32.mul (i32.load (local.get $s)) (i32.const 4)) (i32.const 2))))
+    (local.set $p (i32.add (local.get $start) (i32.const 1)))
+    (i32.store8 (local.get $start) (i32.const 34))
+    (block $done
+      (loop $next
+        (br_if $done (i32.ge_u (local.get $i) (i32.load (local.get $s))))
+        (local.set $c (call $lang_byte (local.get $s) (local.get $i)))
+        (if (call $lang_escape (local.get $c))
+          (then
+            (i32.store8 (local.get $p) (i32.const 92))
+            (i32.store8 offset=1 (local.get $p) (call $lang_escape (local.get $c)))
+            (local.set $p (i32.add (local.get $p) (i32.const 2))))
+          (else
+            (if (i32.or (i32.lt_u (local.get $c) (i32.const 32)) (i32.eq (local.get $c) (i32.const 127)))
+              (then
+                (i32.store8 (local.get $p) (i32.const 92))
+                (i32.store8 offset=1 (local.get $p) (i32.const 120))
+                (i32.store8 offset=2 (local.get $p) (call $lang_byte (i32.const {{str "0123456789abcdef"}}) (i32.shr_u (local.get $c) (i32.const 4))))
+                (i32.store8 offset=3 (local.get $p) (call $lang_byte (i32.const {{str "0123456789abcdef"}}) (i32.and (local.get $c) (i32.const 15))))
+                (local.set $p (i32.add (local.get $p) (i32.const 4))))
+              (else
+                (i32.store8 (local.get $p) (local.get $c))
+                (local.set $p (i32.add (local.get $p) (i32.const 1)))))))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))
+    (i32.store8 (local.get $p) (i32.const 34))
+    (call $lang_slice (local.get $start) (i32.add (i32.sub (local.get $p) (local.get $start)) (i32.const 1))))
+
+  ;; lang_runes returns the number of code points in the first n bytes of s.
+  (func $lang_runes (param $s i32) (param $n i32) (result i64)
+    (local $i i32)
+    (local $count i64)
+    (block $done
+      (loop $next
+        (br_if $done (i32.ge_u (local.get $i) (local.get $n)))
+        (if (i32.ne (i32.and (call $lang_byte (local.get $s) (local.get $i)) (i32.const 192)) (i32.const 128))
+          (then (local.set $count (i64.add (local.get $count) (i64.const 1)))))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))
+    (local.get $count))
+
+  ;; lang_rune_offset returns the byte offset of the i-th code point of s.
+  (func $lang_rune_offset (param $s i32) (param $i i64) (result i32)
+    (local $off i32)
+    (block $done
+      (loop $next
+        (br_if $done (i32.ge_u (local.get $off) (i32.load (local.get $s))))
+        (if (i32.ne (i32.and (call $lang_byte (local.get $s) (local.get $off)) (i32.const 192)) (i32.const 128))
+          (then
+            (br_if $done (i64.eqz (local.get $i)))
+            (local.set $i (i64.sub (local.get $i) (i64.const 1)))))
+        (local.set $off (i32.add (local.get $off) (i32.const 1)))
+        (br $next)))
+    (local.get $off))
+
+  ;; Builtins
+
+  (func $lang_print (param $s i32)
+    (call $lang_host_write (i32.add (local.get $s) (i32.const 4)) (i32.load (local.get $s))))
+
+  (func $lang_println (param $s i32)
+    (call $lang_print (local.get $s))
+    (call $lang_print (i32.const {{str "\n"}})))
+
+  (func $lang_len (param $s i32) (result i64)
+    (call $lang_runes (local.get $s) (i32.load (local.get $s))))
+
+  (func $lang_substr (param $s i32) (param $start i64) (param $end i64) (result i32)
+    (local $n i64)
+    (local $from i32)
+    (local.set $n (call $lang_len (local.get $s)))
+    (if (i32.or (i32.or (i64.lt_s (local.get $start) (i64.const 0)) (i64.lt_s (local.get $end) (local.get $start))) (i64.gt_s (local.get $end) (local.get $n)))
+      (then
+        (call $lang_fatal
+          (call $lang_concat
+            (call $lang_concat
+              (call $lang_concat (i32.const {{str "runtime error: substr: range ["}}) (call $lang_int_string (local.get $start)))
+              (call $lang_concat (i32.const {{str ":"}}) (call $lang_int_string (local.get $end))))
+            (call $lang_concat (i32.const {{str "] out of bounds for length "}}) (call $lang_int_string (local.get $n)))))))
+    (local.set $from (call $lang_rune_offset (local.get $s) (local.get $start)))
+    (call $lang_slice
+      (i32.add (i32.add (local.get $s) (i32.const 4)) (local.get $from))
+      (i32.sub (call $lang_rune_offset (local.get $s) (local.get $end)) (local.get $from))))
+
+  (func $lang_indexOf (param $s i32) (param $sub i32) (result i64)
+    (local $i i32)
+    (block $done
+      (loop $next
+        (br_if $done (i32.gt_u (i32.add (local.get $i) (i32.load (local.get $sub))) (i32.load (local.get $s))))
+        (if (call $lang_equal (i32.add (i32.add (local.get $s) (i32.const 4)) (local.get $i)) (i32.add (local.get $sub) (i32.const 4)) (i32.load (local.get $sub)))
+          (then (return (call $lang_runes (local.get $s) (local.get $i)))))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))
+    (i64.const -1))
+
+  ;; lang_split_end returns the byte offset of the end of the piece of s that
+  ;; starts at from, which is the next sep or, if sep is empty, the next code
+  ;; point.
+  (func $lang_split_end (param $s i32) (param $sep i32) (param $from i32) (result i32)
+    (local $i i32)
+    (if (i32.eqz (i32.load (local.get $sep)))
+      (then
+        (local.set $i (i32.add (local.get $from) (i32.const 1)))
+        (block $done
+          (loop $next
+            (br_if $done (i32.ge_u (local.get $i) (i32.load (local.get $s))))
+            (br_if $done (i32.ne (i32.and (call $lang_byte (local.get $s) (local.get $i)) (i32.const 192)) (i32.const 128)))
+            (local.set $i (i32.add (local.get $i) (i32.const 1)))
+            (br $next)))
+        (return (local.get $i))))
+    (local.set $i (local.get $from))
+    (block $done
+      (loop $next
+        (br_if $done (i32.gt_u (i32.add (local.get $i) (i32.load (local.get $sep))) (i32.load (local.get $s))))
+        (if (call $lang_equal (i32.add (i32.add (local.get $s) (i32.const 4)) (local.get $i)) (i32.add (local.get $sep) (i32.const 4)) (i32.load (local.get $sep)))
+          (then (return (local.get $i))))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))
+    (i32.load (local.get $s)))
+
+  (func $lang_splitCount (param $s i32) (param $sep i32) (result i64)
+    (local $n i64)
+    (local $from i32)
+    (local $end i32)
+    (if (i32.eqz (i32.load (local.get $sep)))
+      (then (return (call $lang_len (local.get $s)))))
+    (local.set $n (i64.const 1))
+    (block $done
+      (loop $next
+        (local.set $end (call $lang_split_end (local.get $s) (local.get $sep) (local.get $from)))
+        (br_if $done (i32.ge_u (local.get $end) (i32.load (local.get $s))))
+        (local.set $n (i64.add (local.get $n) (i64.const 1)))
+        (local.set $from (i32.add (local.get $end) (i32.load (local.get $sep))))
+        (br $next)))
+    (local.get $n))
+
+  (func $lang_split (param $s i32) (param $sep i32) (param $i i64) (result i32)
+    (local $n i64)
+    (local $from i32)
+    (local $end i32)
+    (local.set $n (call $lang_splitCount (local.get $s) (local.get $sep)))
+    (if (i32.or (i64.lt_s (local.get $i) (i64.const 0)) (i64.ge_s (local.get $i) (local.get $n)))
+      (then
+        (call $lang_fatal
+          (call $lang_concat
+            (call $lang_concat (i32.const {{str "runtime error: split: index "}}) (call $lang_int_string (local.get $i)))
+            (call $lang_concat (i32.const {{str " out of bounds for length "}}) (call $lang_int_string (local.get $n)))))))
+    (block $done
+      (loop $next
+        (local.set $end (call $lang_split_end (local.get $s) (local.get $sep) (local.get $from)))
+        (br_if $done (i64.eqz (local.get $i)))
+        (local.set $i (i64.sub (local.get $i) (i64.const 1)))
+        (local.set $from (i32.add (local.get $end) (i32.load (local.get $sep))))
+        (br $next)))
+    (call $lang_slice
+      (i32.add (i32.add (local.get $s) (i32.const 4)) (local.get $from))
+      (i32.sub (local.get $end) (local.get $from))))
+
+  (func $lang_abs (param $n i64) (result i64)
+    (if (result i64) (i64.lt_s (local.get $n) (i64.const 0))
+      (then (i64.sub (i64.const 0) (local.get $n)))
+      (else (local.get $n))))
+
+  (func $lang_parseInt (param $s i32) (result i64)
+    (local $i i32)
+    (local $start i32)
+    (local $negative i32)
+    (local $n i64)
+    (local $d i64)
+    (local $limit i64)
+    (if (i32.load (local.get $s))
+      (then
+        (if (i32.eq (call $lang_byte (local.get $s) (i32.const 0)) (i32.const 45))
+          (then
+            (local.set $negative (i32.const 1))
+            (local.set $start (i32.const 1))))
+        (if (i32.eq (call $lang_byte (local.get $s) (i32.const 0)) (i32.const 43))
+          (then (local.set $start (i32.const 1))))))
+    ;; n is unsigned, so that it can hold the magnitude of the smallest int.
+    (local.set $limit (i64.add (i64.const 9223372036854775807) (i64.extend_i32_u (local.get $negative))))
+    (local.set $i (local.get $start))
+    (block $done
+      (loop $next
+        (br_if $done (i32.ge_u (local.get $i) (i32.load (local.get $s))))
+        (local.set $d (i64.extend_i32_u (i32.sub (call $lang_byte (local.get $s) (local.get $i)) (i32.const 48))))
+        (br_if $done (i64.gt_u (local.get $d) (i64.const 9)))
+        (br_if $done (i64.gt_u (local.get $n) (i64.div_u (i64.sub (local.get $limit) (local.get $d)) (i64.const 10))))
+        (local.set $n (i64.add (i64.mul (local.get $n) (i64.const 10)) (local.get $d)))
+        (local.set $i (i32.add (local.get $i) (i32.const 1)))
+        (br $next)))
+    (if (i32.or (i32.eq (local.get $i) (local.get $start)) (i32.lt_u (local.get $i) (i32.load (local.get $s))))
+      (then (call $lang_fatal (call $lang_concat (i32.const {{str "runtime error: parseInt: invalid int "}}) (call $lang_quote (local.get $s))))))
+    (if (result i64) (local.get $negative)
+      (then (i64.sub (i64.const 0) (local.get $n)))
+      (else (local.get $n))))
+
+  (func $lang_parseFloat (param $s i32) (result f64)
+    (local $f i32)
+    (local.set $f (call $lang_alloc (i32.const 8)))
+    (if (i32.eqz (call $lang_host_parse_float (i32.add (local.get $s) (i32.const 4)) (i32.load (local.get $s)) (local.get $f)))
+      (then (call $lang_fatal (call $lang_concat (i32.const {{str "runtime error: parseFloat: invalid float "}}) (call $lang_quote (local.get $s))))))
+    (f64.load (local.get $f)))
@@ -0,0 +1,582 @@
+// Package wat translates type checked programs to the WebAssembly text
+// format, so that they can be run by sandboxed hosts.
+//
+// An int or enum is an i64, a float an f64, and a bool an i32. Strings are
+// kept in linear memory, which is exported as "memory", and are i32
+// addresses. The host must provide these functions from the "lang" module:
+//
+//	write(p, n i32)                     writes the n bytes at p to standard output
+//	fatal(p, n i32)                     reports the runtime error in the n bytes at p, and must not return
+//	format_float(f f64, p i32) i32      writes f as formatted by Go's %v to p, and returns its length, which is at most 32
+//	parse_float(p, n, f i32) i32        parses the n bytes at p as a float, stores it at f, and reports whether it is valid
+//	pow(x, y f64) f64                   returns x to the power y
+//
+// Calls whose results a function returns are tail calls, so hosts must
+// support the WebAssembly tail call extension. Extern functions are
+// imported from the "env" module. The main function
+// of the program is exported as "main", and "new_string" allocates a string
+// of the given length for passing to it, whose bytes follow its i32 length.
+// Programs that call printf cannot be compiled.
+package wat
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/loader"
+	"lang/parser"
+	"lang/prelude"
+	"lang/scanner"
+	"strings"
+	"text/template"
+)
+
+//go:embed runtime.wat
+var runtime string
+
+// dataStart is the address of the first string. Address 0 is never used.
+const dataStart = 8
+
+type generator struct {
+	out  *bytes.Buffer
+	mods []*loader.Module
+	// Names of the builtin functions, which the runtime implements.
+	builtins map[string]bool
+	// The contents of memory before the heap, the address of each string
+	// in it, and the bounds of each string and enum name table.
+	data     []byte
+	strings  map[string]int
+	segments [][2]int
+	// Addresses of the tables of each enum's member names.
+	enums map[*analysis.EnumType]int
+	err   error
+
+	mod     *loader.Module
+	env     analysis.Env
+	globals map[string]bool
+	// The WebAssembly names of the variables declared in each enclosing
+	// block of the function being generated, innermost last.
+	locals []map[string]string
+	// Declarations of the locals of the function being generated.
+	decls  []string
+	names  map[string]int
+	indent int
+	temps  int
+	labels int
+}
+
+// Generate writes mods, ordered as returned by loader.Load, as a
+// WebAssembly module whose exported main function calls the main function
+// of the last module.
+func Generate(w io.Writer, mods []*loader.Module) error {
+	envs, ok := analysis.CheckModules(analysis.NewUniverse(ioutil.Discard), mods)
+	if !ok {
+		return errors.New("program does not type check")
+	}
+	g := &generator{
+		out:      &bytes.Buffer{},
+		mods:     mods,
+		builtins: map[string]bool{},
+		data:     make([]byte, dataStart),
+		strings:  map[string]int{},
+		enums:    map[*analysis.EnumType]int{},
+	}
+	for _, stmt := range prelude.Stmts() {
+		g.builtins[stmt.(parser.FunctionStmt).Name.Lexeme] = true
+	}
+	entry := mods[len(mods)-1]
+	main := findFunction(entry, "main")
+	if main == nil {
+		return fmt.Errorf("%s: no main function", entry.Path)
+	}
+
+	var imports bytes.Buffer
+	for i, m := range mods {
+		g.enter(m, envs[i])
+		g.declarations(&imports)
+	}
+	tmpl := template.Must(template.New("runtime").Funcs(template.FuncMap{"str": g.intern}).Parse(runtime))
+	if err := tmpl.Execute(g.out, nil); err != nil {
+		return err
+	}
+	for i, m := range mods {
+		g.enter(m, envs[i])
+		g.definitions()
+	}
+	g.entry(entry, *main)
+	if g.err != nil {
+		return g.err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(";; Code generated by lang build. DO NOT EDIT.\n\n")
+	b.WriteString("(module\n")
+	b.WriteString("  (import \"lang\" \"write\" (func $lang_host_write (param i32 i32)))\n")
+	b.WriteString("  (import \"lang\" \"fatal\" (func $lang_host_fatal (param i32 i32)))\n")
+	b.WriteString("  (import \"lang\" \"format_float\" (func $lang_host_format_float (param f64 i32) (result i32)))\n")
+	b.WriteString("  (import \"lang\" \"parse_float\" (func $lang_host_parse_float (param i32 i32 i32) (result i32)))\n")
+	b.WriteString("  (import \"lang\" \"pow\" (func $lang_host_pow (param f64 f64) (result f64)))\n")
+	b.Write(imports.Bytes())
+	heap := align(len(g.data), 8)
+	fmt.Fprintf(&b, "\n  (memory (export \"memory\") %d)\n", heap>>16+1)
+	fmt.Fprintf(&b, "  (global $lang_heap (mut i32) (i32.const %d))\n\n", heap)
+	b.Write(g.out.Bytes())
+	b.WriteString("\n")
+	g.writeData(&b)
+	b.WriteString(")\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+func findFunction(m *loader.Module, name string) *parser.FunctionStmt {
+	for _, stmt := range m.Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && f.Name.Lexeme == name {
+			return &f
+		}
+	}
+	return nil
+}
+
+func align(n, to int) int {
+	return (n + to - 1) / to * to
+}
+
+// intern returns the address of a string holding s.
+func (g *generator) intern(s string) int {
+	if addr, ok := g.strings[s]; ok {
+		return addr
+	}
+	addr := align(len(g.data), 4)
+	g.data = append(g.data, make([]byte, addr-len(g.data))...)
+	g.data = appendInt32(g.data, len(s))
+	g.data = append(g.data, s...)
+	g.strings[s] = addr
+	g.segments = append(g.segments, [2]int{addr, len(g.data)})
+	return addr
+}
+
+func appendInt32(b []byte, n int) []byte {
+	return append(b, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+}
+
+// writeData writes the initial contents of memory as a data segment for
+// each string and enum.
+func (g *generator) writeData(b *bytes.Buffer) {
+	for _, s := range g.segments {
+		fmt.Fprintf(b, "  (data (i32.const %d) %s)\n", s[0], watString(g.data[s[0]:s[1]]))
+	}
+}
+
+// watString returns b as a WebAssembly string literal.
+func watString(b []byte) string {
+	var s strings.Builder
+	s.WriteByte('"')
+	for _, c := range b {
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			fmt.Fprintf(&s, "\\%02x", c)
+		} else {
+			s.WriteByte(c)
+		}
+	}
+	s.WriteByte('"')
+	return s.String()
+}
+
+func (g *generator) enter(m *loader.Module, env analysis.Env) {
+	g.mod = m
+	g.env = env
+	g.globals = map[string]bool{}
+	for _, stmt := range m.Stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			g.globals[s.Name.Lexeme] = true
+		case parser.VarStmt:
+			g.globals[s.Name.Lexeme] = true
+		}
+	}
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(g.out, format, args...)
+}
+
+// line writes a line of a function body at the current indentation.
+func (g *generator) line(format string, args ...interface{}) {
+	g.out.WriteString(strings.Repeat("  ", g.indent))
+	g.printf(format, args...)
+	g.out.WriteByte('\n')
+}
+
+// fail records that the program cannot be compiled because of the
+// construct at tok.
+func (g *generator) fail(tok scanner.Token, format string, args ...interface{}) {
+	if g.err == nil {
+		g.err = fmt.Errorf("%s:%d:%d: %s", g.mod.Path, tok.Row+1, tok.Col+1, fmt.Sprintf(format, args...))
+	}
+}
+
+// declarations writes the imports of the extern functions of the current
+// module to imports, and lays out its enums' member names in memory.
+func (g *generator) declarations(imports *bytes.Buffer) {
+	for _, stmt := range g.mod.Stmts {
+		switch s := stmt.(type) {
+		case parser.EnumStmt:
+			var names []int
+			for _, m := range s.Members {
+				names = append(names, g.intern(m.Lexeme))
+			}
+			addr := align(len(g.data), 4)
+			g.data = append(g.data, make([]byte, addr-len(g.data))...)
+			for _, name := range names {
+				g.data = appendInt32(g.data, name)
+			}
+			g.enums[g.env.LookupType(s.Name.Lexeme).(*analysis.EnumType)] = addr
+			g.segments = append(g.segments, [2]int{addr, len(g.data)})
+		case parser.FunctionStmt:
+			if s.Extern {
+				fmt.Fprintf(imports, "  (import \"env\" %q (func $extern.%s%s))\n", s.Name.Lexeme, s.Name.Lexeme, g.signature(s, false))
+			}
+		}
+	}
+}
+
+// signature returns the parameters and result of f, which are named if
+// named is set.
+func (g *generator) signature(f parser.FunctionStmt, named bool) string {
+	var s string
+	for _, p := range f.Params {
+		if named {
+			s += fmt.Sprintf(" (param $%s %s)", p.Name.Lexeme, g.wtype(g.env.LookupType(p.Kind.Lexeme)))
+		} else {
+			s += fmt.Sprintf(" (param %s)", g.wtype(g.env.LookupType(p.Kind.Lexeme)))
+		}
+	}
+	if ret := g.env.LookupType(f.ReturnKind.Lexeme); ret != analysis.Void {
+		s += fmt.Sprintf(" (result %s)", g.wtype(ret))
+	}
+	return s
+}
+
+// definitions defines the variables and functions of the current module,
+// and a function that initializes its variables.
+func (g *generator) definitions() {
+	g.printf("\n  ;; module %s\n", g.mod.Name)
+	first := true
+	for _, stmt := range g.mod.Stmts {
+		if s, ok := stmt.(parser.VarStmt); ok {
+			if first {
+				g.printf("\n")
+				first = false
+			}
+			t := g.env.LookupType(s.Kind.Lexeme)
+			g.printf("  (global %s (mut %s) %s)\n", g.global(s.Name.Lexeme), g.wtype(t), g.zero(t))
+		}
+	}
+
+	g.function(fmt.Sprintf("$lang_init_%s", watName(g.mod.Name)), "", func() {
+		for _, stmt := range g.mod.Stmts {
+			if s, ok := stmt.(parser.VarStmt); ok && s.Expr != nil {
+				g.line("(global.set %s %s)", g.global(s.Name.Lexeme), g.expr(s.Expr))
+			}
+		}
+	})
+
+	for _, stmt := range g.mod.Stmts {
+		f, ok := stmt.(parser.FunctionStmt)
+		if !ok || f.Extern {
+			continue
+		}
+		env := g.env
+		g.env = analysis.NewBlockEnv(env)
+		g.function(g.global(f.Name.Lexeme), g.signature(f, true), func() {
+			for _, p := range f.Params {
+				g.declare(p.Name.Lexeme, g.env.LookupType(p.Kind.Lexeme), true)
+			}
+			ret := g.env.LookupType(f.ReturnKind.Lexeme)
+			g.stmts(f.Body.Stmts, ret)
+			if n := len(f.Body.Stmts); ret != analysis.Void && (n == 0 || !isReturn(f.Body.Stmts[n-1])) {
+				// The function still has to end with a value of its
+				// result type, even where that end is unreachable.
+				if analysis.Terminates(f.Body) {
+					g.line("unreachable")
+				} else {
+					g.line("%s", g.zero(ret))
+				}
+			}
+		})
+		g.env = env
+	}
+}
+
+func isReturn(stmt parser.Stmt) bool {
+	_, ok := stmt.(parser.ReturnStmt)
+	return ok
+}
+
+// function writes a function called name whose body is written by body.
+func (g *generator) function(name, signature string, body func()) {
+	out := g.out
+	g.out = &bytes.Buffer{}
+	g.locals = []map[string]string{{}}
+	g.decls = nil
+	g.names = map[string]int{}
+	g.temps = 0
+	g.labels = 0
+	g.indent = 2
+	body()
+	code := g.out
+	g.out = out
+	g.printf("\n  (func %s%s", name, signature)
+	for _, decl := range g.decls {
+		g.printf("\n    %s", decl)
+	}
+	if code.Len() > 0 {
+		g.printf("\n%s", bytes.TrimSuffix(code.Bytes(), []byte("\n")))
+	}
+	g.printf(")\n")
+}
+
+// entry writes the exported main function, which initializes each module's
+// variables and then calls the main function of m.
+func (g *generator) entry(m *loader.Module, main parser.FunctionStmt) {
+	var args []string
+	for _, p := range main.Params {
+		args = append(args, "(local.get $"+p.Name.Lexeme+")")
+	}
+	g.printf("\n  ;; entry\n")
+	g.function("$lang_main (export \"main\")", g.signature(main, true), func() {
+		for _, mod := range g.mods {
+			g.line("(call $lang_init_%s)", watName(mod.Name))
+		}
+		g.line("(call %s)", strings.Join(append([]string{g.global("main")}, args...), " "))
+	})
+}
+
+// global returns the WebAssembly name of a top-level name in the current
+// module.
+func (g *generator) global(name string) string {
+	return "$" + watName(g.mod.Name) + "." + name
+}
+
+// watName makes a module name usable in a WebAssembly name that is
+// qualified with a dot.
+func watName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func (g *generator) wtype(t analysis.Type) string {
+	switch t {
+	case analysis.Int:
+		return "i64"
+	case analysis.Float:
+		return "f64"
+	case analysis.Bool, analysis.String:
+		return "i32"
+	}
+	if _, isEnum := t.(*analysis.EnumType); isEnum {
+		return "i64"
+	}
+	panic(fmt.Sprintf("no WebAssembly type for %v", t))
+}
+
+func (g *generator) zero(t analysis.Type) string {
+	switch t {
+	case analysis.Float:
+		return "(f64.const 0)"
+	case analysis.String:
+		return fmt.Sprintf("(i32.const %d)", g.intern(""))
+	default:
+		return fmt.Sprintf("(%s.const 0)", g.wtype(t))
+	}
+}
+
+// declare declares a variable of type t in the current block, and returns
+// its WebAssembly name, which is suffixed with a number if an enclosing
+// block already has one with the same name.
+func (g *generator) declare(name string, t analysis.Type, param bool) string {
+	g.env.Declare(name, t)
+	local := "$" + name
+	if n := g.names[name]; n > 0 {
+		local = fmt.Sprintf("$%s.%d", name, n)
+	}
+	g.names[name]++
+	g.locals[len(g.locals)-1][name] = local
+	if !param {
+		g.decls = append(g.decls, fmt.Sprintf("(local %s %s)", local, g.wtype(t)))
+	}
+	return local
+}
+
+func (g *generator) local(name string) (string, bool) {
+	for i := len(g.locals) - 1; i >= 0; i-- {
+		if local, ok := g.locals[i][name]; ok {
+			return local, true
+		}
+	}
+	return "", false
+}
+
+// temp declares a new temporary local of type t and returns its name.
+func (g *generator) temp(t analysis.Type) string {
+	g.temps++
+	name := fmt.Sprintf("$t.%d", g.temps)
+	g.decls = append(g.decls, fmt.Sprintf("(local %s %s)", name, g.wtype(t)))
+	return name
+}
+
+func (g *generator) label() string {
+	g.labels++
+	return fmt.Sprintf("$L%d", g.labels)
+}
+
+func (g *generator) block(b parser.Block, ret analysis.Type) {
+	env := g.env
+	g.env = analysis.NewBlockEnv(env)
+	g.locals = append(g.locals, map[string]string{})
+	g.stmts(b.Stmts, ret)
+	g.locals = g.locals[:len(g.locals)-1]
+	g.env = env
+}
+
+func (g *generator) stmts(stmts []parser.Stmt, ret analysis.Type) {
+	for _, stmt := range stmts {
+		g.stmt(stmt, ret)
+	}
+}
+
+// nested writes the statements of b one level deeper.
+func (g *generator) nested(b parser.Block, ret analysis.Type) {
+	g.indent++
+	g.block(b, ret)
+	g.indent--
+}
+
+func (g *generator) stmt(stmt parser.Stmt, ret analysis.Type) {
+	switch s := stmt.(type) {
+	case parser.VarStmt:
+		t := g.env.LookupType(s.Kind.Lexeme)
+		value := g.zero(t)
+		if s.Expr != nil {
+			value = g.expr(s.Expr)
+		}
+		g.line("(local.set %s %s)", g.declare(s.Name.Lexeme, t, false), value)
+	case parser.AssignStmt:
+		g.assign(s.Target.(scanner.Token).Lexeme, g.expr(s.Expr))
+	case parser.CompoundAssignStmt:
+		op := s.Op
+		op.Kind = parser.CompoundAssignOps[s.Op.Kind]
+		g.assign(s.Target.Lexeme, g.expr(parser.BinaryOp{Op: op, Left: parser.IdentExpr{Name: s.Target}, Right: s.Expr}))
+	case parser.IncDecStmt:
+		x := g.expr(parser.IdentExpr{Name: s.Target})
+		t := g.wtype(analysis.TypeOf(g.env, parser.IdentExpr{Name: s.Target}))
+		op := "add"
+		if s.Op.Kind == scanner.Dec {
+			op = "sub"
+		}
+		g.assign(s.Target.Lexeme, fmt.Sprintf("(%s.%s %s (%s.const 1))", t, op, x, t))
+	case parser.ReturnStmt:
+		if call, ok := s.Expr.(parser.FunctionCall); ok && g.isTailCall(call, ret) {
+			g.line("(return_call %s)", g.operands(call))
+		} else if s.Expr == nil {
+			g.line("(return)")
+		} else {
+			g.line("(return %s)", g.expr(s.Expr))
+		}
+	case parser.IfStmt:
+		g.line("(if %s", g.expr(s.Cond))
+		g.line("  (then")
+		g.indent++
+		g.nested(s.Then, ret)
+		g.indent--
+		if len(s.Els.Stmts) > 0 {
+			g.line("  )")
+			g.line("  (else")
+			g.indent++
+			g.nested(s.Els, ret)
+			g.indent--
+		}
+		g.line("  ))")
+	case parser.WhileStmt:
+		label := g.label()
+		g.line("(block %s.end", label)
+		g.line("  (loop %s", label)
+		g.line("    (br_if %s.end (i32.eqz %s))", label, g.expr(s.Cond))
+		g.indent++
+		g.nested(s.Body, ret)
+		g.indent--
+		g.line("    (br %s)))", label)
+	case parser.SwitchStmt:
+		g.switchStmt(s, ret)
+	case parser.Block:
+		g.block(s, ret)
+	default:
+		code := g.expr(s)
+		if analysis.TypeOf(g.env, s) == analysis.Void {
+			g.line("%s", code)
+		} else {
+			g.line("(drop %s)", code)
+		}
+	}
+}
+
+func (g *generator) assign(name, value string) {
+	if local, ok := g.local(name); ok {
+		g.line("(local.set %s %s)", local, value)
+	} else {
+		g.line("(global.set %s %s)", g.global(name), value)
+	}
+}
+
+// switchStmt writes a switch as a block for each case, nested so that
+// branching out of the i-th block enters the body of the i-th case, and
+// each body falls into the next unless it branches out of the whole
+// switch.
+func (g *generator) switchStmt(s parser.SwitchStmt, ret analysis.Type) {
+	label := g.label()
+	t := analysis.TypeOf(g.env, s.Expr)
+	value := g.temp(t)
+	g.line("(local.set %s %s)", value, g.expr(s.Expr))
+	g.line("(block %s.end", label)
+	for i := len(s.Cases) - 1; i >= 0; i-- {
+		g.indent++
+		g.line("(block %s.%d", label, i)
+	}
+	g.indent++
+	otherwise := label + ".end"
+	for i, c := range s.Cases {
+		for _, v := range c.Values {
+			g.line("(br_if %s.%d %s)", label, i, g.equal(t, "(local.get "+value+")", g.expr(v)))
+		}
+		if c.Default {
+			otherwise = fmt.Sprintf("%s.%d", label, i)
+		}
+	}
+	g.line("(br %s)", otherwise)
+	g.indent--
+	for i, c := range s.Cases {
+		g.line(")")
+		body := c.Body
+		fallsThrough := false
+		if n := len(body.Stmts); n > 0 {
+			_, fallsThrough = body.Stmts[n-1].(parser.FallthroughStmt)
+			if fallsThrough {
+				body.Stmts = body.Stmts[:n-1]
+			}
+		}
+		g.block(body, ret)
+		if !fallsThrough && i < len(s.Cases)-1 && !analysis.Terminates(body) {
+			g.line("(br %s.end)", label)
+		}
+		g.indent--
+	}
+	g.line(")")
+}
@@ -0,0 +1,38 @@
+package wat
+
+import (
+	"io/ioutil"
+	"lang/codegen/codegentest"
+	"lang/loader"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenerate compares the WebAssembly generated for each of the
+// backends' test programs against the golden file with the same name and
+// a .wat extension. Printing with printf is not supported.
+func TestGenerate(t *testing.T) {
+	codegentest.Golden(t, ".wat", []string{"printf"}, Generate)
+}
+
+func TestPrintf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "printf.c")
+	src := "void main() {\n  printf(\"%d\\n\", 1);\n}\n"
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mods, err := loader.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Generate(ioutil.Discard, mods)
+	if want := path + ":2:3: printf is not supported in WebAssembly"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
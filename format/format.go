@@ -0,0 +1,473 @@
+package format
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"lang/parser"
+	"lang/scanner"
+	"reflect"
+	"strings"
+)
+
+const indent = "  "
+
+// Binding strength of each binary operator, matching the parser's
+// precedence chain.
+var precedences = map[scanner.TokenKind]int{
+	scanner.LOr:     2,
+	scanner.LAnd:    3,
+	scanner.BOr:     4,
+	scanner.BXor:    5,
+	scanner.BAnd:    6,
+	scanner.EqEq:    7,
+	scanner.Ne:      7,
+	scanner.Gt:      8,
+	scanner.Gte:     8,
+	scanner.Lt:      8,
+	scanner.Lte:     8,
+	scanner.Shl:     9,
+	scanner.Shr:     9,
+	scanner.Plus:    10,
+	scanner.Minus:   10,
+	scanner.Star:    11,
+	scanner.Slash:   11,
+	scanner.Percent: 11,
+}
+
+const (
+	ternaryPrec = 1
+	unaryPrec   = 12
+	atomPrec    = 13
+)
+
+// Source formats src canonically, keeping its comments. The result parses
+// to the same program as src, which is checked before returning it.
+func Source(src []byte) ([]byte, error) {
+	stmts, err := parse(string(src), true)
+	if err != nil {
+		return nil, err
+	}
+	var p printer
+	p.stmts(stmts, true)
+	out := p.buf.Bytes()
+
+	before, _ := parse(string(src), false)
+	after, err := parse(string(out), false)
+	if err != nil || !reflect.DeepEqual(stripPositions(before), stripPositions(after)) {
+		return nil, errors.New("formatting changed the meaning of the program")
+	}
+	return out, nil
+}
+
+// Stmts prints stmts as source, as when formatting. Statements that the
+// parser does not produce, like the blocks left by optimizations, are
+// printed as if it did.
+func Stmts(stmts []parser.Stmt) []byte {
+	var p printer
+	p.stmts(stmts, true)
+	return p.buf.Bytes()
+}
+
+func parse(src string, comments bool) (stmts []parser.Stmt, err error) {
+	var p parser.Parser
+	defer func() {
+		if r := recover(); r != nil {
+			msg, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			if p.Tokens == nil {
+				err = errors.New(msg)
+			} else {
+				t := p.Current()
+				err = fmt.Errorf("%d:%d: %s", t.Row+1, t.Col+1, msg)
+			}
+		}
+	}()
+	tokens, cs := scanner.ScanComments(src)
+	p.Tokens = tokens
+	if comments {
+		p.Comments = cs
+	}
+	return p.ConsumeTopLevelStmts(), nil
+}
+
+// stripPositions returns a copy of the syntax tree v without the positions
+// of its tokens or its braces, so that trees can be compared regardless of
+// layout.
+func stripPositions(v interface{}) interface{} {
+	return strip(reflect.ValueOf(v)).Interface()
+}
+
+func strip(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(strip(v.Elem()))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(strip(v.Index(i)))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		if t, ok := v.Interface().(scanner.Token); ok {
+			// Braces are dropped from else if blocks.
+			if t.Kind != scanner.LBrace && t.Kind != scanner.RBrace {
+				out.Set(reflect.ValueOf(scanner.Token{Kind: t.Kind, Lexeme: t.Lexeme}))
+			}
+			return out
+		}
+		for i := 0; i < v.NumField(); i++ {
+			out.Field(i).Set(strip(v.Field(i)))
+		}
+		return out
+	}
+	return v
+}
+
+type printer struct {
+	buf   bytes.Buffer
+	depth int
+}
+
+// line prints a line at the current depth. Expressions that span several
+// lines, like calls with comments between their arguments, are indented
+// along with it.
+func (p *printer) line(format string, args ...interface{}) {
+	prefix := strings.Repeat(indent, p.depth)
+	p.buf.WriteString(prefix)
+	p.buf.WriteString(strings.ReplaceAll(fmt.Sprintf(format, args...), "\n", "\n"+prefix))
+	p.buf.WriteByte('\n')
+}
+
+// trailing appends a comment to the last line written.
+func (p *printer) trailing(text string) {
+	if p.buf.Len() == 0 {
+		p.line("//%s", text)
+		return
+	}
+	p.buf.Truncate(p.buf.Len() - 1)
+	fmt.Fprintf(&p.buf, " //%s\n", text)
+}
+
+// stmts prints a statement list, keeping at most one blank line between
+// statements. At the top level a function definition is always followed by
+// a blank line.
+func (p *printer) stmts(stmts []parser.Stmt, topLevel bool) {
+	blank := false
+	for i, stmt := range stmts {
+		switch s := stmt.(type) {
+		case parser.BlankLine:
+			blank = i > 0
+			continue
+		case parser.Comment:
+			if s.Trailing {
+				p.trailing(s.Text.Lexeme)
+				continue
+			}
+		}
+		if blank {
+			p.buf.WriteByte('\n')
+			blank = false
+		}
+		p.stmt(stmt)
+		if f, ok := stmt.(parser.FunctionStmt); ok && topLevel && !f.Extern {
+			blank = true
+		}
+	}
+}
+
+func (p *printer) block(head string, blk parser.Block) {
+	if len(blk.Stmts) == 0 {
+		p.line("%s{}", head)
+		return
+	}
+	p.line("%s{", head)
+	p.body(blk)
+	p.line("}")
+}
+
+func (p *printer) body(blk parser.Block) {
+	p.depth++
+	p.stmts(blk.Stmts, false)
+	p.depth--
+}
+
+func exported(s string, exported bool) string {
+	if exported {
+		return "export " + s
+	}
+	return s
+}
+
+func (p *printer) stmt(stmt parser.Stmt) {
+	switch s := stmt.(type) {
+	case parser.Comment:
+		p.line("//%s", s.Text.Lexeme)
+	case parser.ModuleStmt:
+		p.line("module %s;", s.Name.Lexeme)
+	case parser.ImportStmt:
+		p.line("import %s;", quote(s.Path.Lexeme))
+	case parser.FunctionStmt:
+		params := make([]string, len(s.Params))
+		for i, param := range s.Params {
+			params[i] = param.Kind.Lexeme + " " + param.Name.Lexeme
+		}
+		if s.Variadic {
+			params = append(params, "...")
+		}
+		sig := fmt.Sprintf("%s %s(%s)", s.ReturnKind.Lexeme, s.Name.Lexeme, strings.Join(params, ", "))
+		for _, a := range s.Annotations {
+			p.line("@%s", a.Lexeme)
+		}
+		if s.Extern {
+			p.line("%s;", exported("extern "+sig, s.Exported))
+		} else {
+			p.block(exported(sig+" ", s.Exported), s.Body)
+		}
+	case parser.EnumStmt:
+		members := make([]string, len(s.Members))
+		for i, m := range s.Members {
+			members[i] = m.Lexeme
+		}
+		if s.Comments != nil {
+			// One member per line, with its comments before it.
+			p.line("%s", exported("enum "+s.Name.Lexeme+" {", s.Exported))
+			p.depth++
+			for i, m := range members {
+				p.comments(s.Comments[i])
+				if i < len(members)-1 {
+					m += ","
+				}
+				p.line("%s", m)
+			}
+			p.comments(s.Comments[len(members)])
+			p.depth--
+			p.line("}")
+		} else if len(members) == 0 {
+			p.line("%s", exported("enum "+s.Name.Lexeme+" {}", s.Exported))
+		} else {
+			p.line("%s", exported("enum "+s.Name.Lexeme+" { "+strings.Join(members, ", ")+" }", s.Exported))
+		}
+	case parser.VarStmt:
+		decl := s.Kind.Lexeme + " " + s.Name.Lexeme
+		if s.Expr != nil {
+			decl += " = " + expr(s.Expr, 0)
+		}
+		p.line("%s;", exported(decl, s.Exported))
+	case parser.ReturnStmt:
+		if s.Expr == nil {
+			p.line("return;")
+		} else {
+			p.line("return %s;", expr(s.Expr, 0))
+		}
+	case parser.AssignStmt:
+		p.line("%s = %s;", s.Target.(scanner.Token).Lexeme, expr(s.Expr, 0))
+	case parser.CompoundAssignStmt:
+		p.line("%s %s %s;", s.Target.Lexeme, scanner.Operators[s.Op.Kind], expr(s.Expr, 0))
+	case parser.IncDecStmt:
+		p.line("%s%s;", s.Target.Lexeme, scanner.Operators[s.Op.Kind])
+	case parser.FallthroughStmt:
+		p.line("fallthrough;")
+	case parser.Block:
+		p.block("", s)
+	case parser.IfStmt:
+		p.ifStmt("", s)
+	case parser.WhileStmt:
+		p.block("while ("+expr(s.Cond, 0)+") ", s.Body)
+	case parser.SwitchStmt:
+		p.line("switch (%s) {", expr(s.Expr, 0))
+		for i, c := range s.Cases {
+			if len(c.Comments) > 0 {
+				if _, ok := c.Comments[0].(parser.BlankLine); ok && i > 0 {
+					p.buf.WriteByte('\n')
+				}
+				p.stmts(c.Comments, false)
+				if _, ok := c.Comments[len(c.Comments)-1].(parser.BlankLine); ok {
+					p.buf.WriteByte('\n')
+				}
+			}
+			if c.Default {
+				p.line("default:")
+			} else {
+				values := make([]string, len(c.Values))
+				for i, v := range c.Values {
+					values[i] = expr(v, 0)
+				}
+				p.line("case %s:", strings.Join(values, ", "))
+			}
+			p.depth++
+			p.stmts(c.Body.Stmts, false)
+			p.depth--
+		}
+		p.line("}")
+	default:
+		p.line("%s;", expr(s, 0))
+	}
+}
+
+// comments prints the comments before an element of a list, the trailing
+// ones after the line before it.
+func (p *printer) comments(comments []parser.Comment) {
+	for _, c := range comments {
+		if c.Trailing {
+			p.trailing(c.Text.Lexeme)
+		} else {
+			p.line("//%s", c.Text.Lexeme)
+		}
+	}
+}
+
+// ifStmt prints an else block that only holds another if statement as an
+// else if chain, which the parser does not tell apart.
+func (p *printer) ifStmt(head string, s parser.IfStmt) {
+	head += "if (" + expr(s.Cond, 0) + ") "
+	if len(s.Els.Stmts) == 0 {
+		p.block(head, s.Then)
+		return
+	}
+	p.line("%s{", head)
+	p.body(s.Then)
+	if elif, ok := s.Els.Stmts[0].(parser.IfStmt); ok && len(s.Els.Stmts) == 1 {
+		p.ifStmt("} else ", elif)
+		return
+	}
+	p.line("} else {")
+	p.body(s.Els)
+	p.line("}")
+}
+
+func precedence(e parser.Expr) int {
+	switch e := e.(type) {
+	case parser.TernaryExpr:
+		return ternaryPrec
+	case parser.BinaryOp:
+		return precedences[e.Op.Kind]
+	case parser.UnaryOp:
+		return unaryPrec
+	default:
+		return atomPrec
+	}
+}
+
+// expr prints e, in parentheses if it binds less tightly than prec.
+func expr(e parser.Expr, prec int) string {
+	s := exprNoParens(e)
+	if precedence(e) < prec {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+func exprNoParens(e parser.Expr) string {
+	switch e := e.(type) {
+	case parser.TernaryExpr:
+		return expr(e.Cond, ternaryPrec+1) + " ? " + expr(e.Then, 0) + " : " + expr(e.Els, ternaryPrec)
+	case parser.BinaryOp:
+		prec := precedences[e.Op.Kind]
+		return expr(e.Left, prec) + " " + scanner.Operators[e.Op.Kind] + " " + expr(e.Right, prec+1)
+	case parser.UnaryOp:
+		operand := expr(e.Expr, unaryPrec)
+		// Keep "- -x" from being read as a decrement.
+		if e.Op.Kind == scanner.Minus && strings.HasPrefix(operand, "-") {
+			operand = "(" + operand + ")"
+		}
+		return scanner.Operators[e.Op.Kind] + operand
+	case parser.FunctionCall:
+		args := make([]string, len(e.Args))
+		for i, arg := range e.Args {
+			args[i] = expr(arg, 0)
+		}
+		if e.Comments == nil {
+			return expr(e.Callee, atomPrec) + "(" + strings.Join(args, ", ") + ")"
+		}
+		// One argument per line, with its comments before it.
+		var b strings.Builder
+		b.WriteString(expr(e.Callee, atomPrec) + "(")
+		for i, arg := range args {
+			writeComments(&b, e.Comments[i])
+			b.WriteString("\n" + indent + strings.ReplaceAll(arg, "\n", "\n"+indent) + ",")
+		}
+		writeComments(&b, e.Comments[len(args)])
+		b.WriteString("\n)")
+		return b.String()
+	case parser.MemberAccess:
+		return expr(e.Parent, atomPrec) + "." + e.Name.Lexeme
+	case parser.IdentExpr:
+		return e.Name.Lexeme
+	case parser.LiteralNum:
+		return e.Value
+	case parser.LiteralBool:
+		return fmt.Sprint(e.Value)
+	case parser.LiteralNull:
+		return "null"
+	case parser.LiteralStr:
+		return quote(e.Value)
+	case parser.InterpolatedStr:
+		var b strings.Builder
+		b.WriteByte('"')
+		for i, part := range e.Parts {
+			if i%2 == 0 {
+				b.WriteString(escape(part.(parser.LiteralStr).Value))
+			} else {
+				b.WriteString("${" + expr(part, 0) + "}")
+			}
+		}
+		b.WriteByte('"')
+		return b.String()
+	default:
+		panic(fmt.Sprintf("cannot format %T", e))
+	}
+}
+
+// writeComments writes the comments before an argument of a call, the
+// trailing ones at the end of the line before it.
+func writeComments(b *strings.Builder, comments []parser.Comment) {
+	for _, c := range comments {
+		if !c.Trailing {
+			b.WriteString("\n" + indent)
+		} else {
+			b.WriteByte(' ')
+		}
+		b.WriteString("//" + c.Text.Lexeme)
+	}
+}
+
+func quote(s string) string {
+	return `"` + escape(s) + `"`
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '$':
+			if i+1 < len(s) && s[i+1] == '{' {
+				b.WriteString(`\$`)
+			} else {
+				b.WriteByte(c)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package format
+
+import (
+	"bytes"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSource formats each program in the repository's testdata, except
+// syntax.c, which does not parse. Formatting must keep the syntax tree,
+// including comments and blank lines, and formatting the result again
+// must not change it.
+func TestSource(t *testing.T) {
+	paths, err := filepath.Glob("../testdata/*.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	modules, err := filepath.Glob("../testdata/modules/*.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range append(paths, modules...) {
+		path := path
+		if filepath.Base(path) == "syntax.c" {
+			continue
+		}
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".c"), func(t *testing.T) {
+			src, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testSource(t, src)
+		})
+	}
+}
+
+// TestSourceLayout formats a program laid out badly. Blank lines are
+// collapsed, dropped inside if chains and added between top-level
+// declarations, so only the result of formatting it is compared.
+func TestSourceLayout(t *testing.T) {
+	src := `// Header.
+
+
+int   add( int a,int b ){return a+(b*2) ;} // trailing
+void main(){
+  // leading
+  int x=add(1,2);   if(x>1){println("${x}");}else if (x<0) { x -= 1; }
+
+
+  else { x++; } // after else
+
+  while(x<10){x+=1;}
+  switch (x) { case 1: fallthrough; case 2, 3: x = 0; default: }
+}
+`
+	want := `// Header.
+
+int add(int a, int b) {
+  return a + b * 2;
+} // trailing
+
+void main() {
+  // leading
+  int x = add(1, 2);
+  if (x > 1) {
+    println("${x}");
+  } else if (x < 0) {
+    x -= 1;
+  } else {
+    x++;
+  } // after else
+
+  while (x < 10) {
+    x += 1;
+  }
+  switch (x) {
+  case 1:
+    fallthrough;
+  case 2, 3:
+    x = 0;
+  default:
+  }
+}
+`
+	got, err := Source([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("formatted source differs:\n%s", Diff("want", "got", []byte(want), got))
+	}
+	testSource(t, got)
+}
+
+// TestSourceComments formats comments inside enums, switch statements and
+// calls, which stay with the member, case or argument they come before.
+func TestSourceComments(t *testing.T) {
+	for _, test := range []struct{ src, want string }{
+		{
+			"enum Color { Red, // the red one\n Green }\n",
+			"enum Color {\n  Red, // the red one\n  Green\n}\n",
+		},
+		{
+			"enum Color {\n  // first\n  Red,\n  Green,\n  // last\n}\n",
+			"enum Color {\n  // first\n  Red,\n  Green\n  // last\n}\n",
+		},
+		{
+			"void main() {\n  switch (1) {\n  case 0:\n    println(\"zero\");\n  // one\n  case 1:\n    println(\"one\");\n\n  // the rest\n  default:\n  }\n}\n",
+			"void main() {\n  switch (1) {\n  case 0:\n    println(\"zero\");\n  // one\n  case 1:\n    println(\"one\");\n\n  // the rest\n  default:\n  }\n}\n",
+		},
+		{
+			"void main() {\n  switch (1) { // cases\n  // zero\n  case 0:\n    // body\n  }\n}\n",
+			"void main() {\n  switch (1) { // cases\n  // zero\n  case 0:\n    // body\n  }\n}\n",
+		},
+		{
+			"void f(int a, int b) {}\n\nvoid main() {\n  f(1, // one\n    2);\n}\n",
+			"void f(int a, int b) {}\n\nvoid main() {\n  f(\n    1, // one\n    2,\n  );\n}\n",
+		},
+		{
+			"int add(int a, int b) { return a + b; }\n\nvoid main() {\n  add(1, // first\n    2); // second\n}\n",
+			"int add(int a, int b) {\n  return a + b;\n}\n\nvoid main() {\n  add(\n    1, // first\n    2,\n  ); // second\n}\n",
+		},
+		{
+			"void main() {\n  if (true) {\n    f(g(1, // inner\n 2), 3 // last\n    );\n  }\n}\n",
+			"void main() {\n  if (true) {\n    f(\n      g(\n        1, // inner\n        2,\n      ),\n      3, // last\n    );\n  }\n}\n",
+		},
+		{
+			"void main() {\n  int x = 1 + // one\n    2;\n}\n",
+			"void main() {\n  // one\n  int x = 1 + 2;\n}\n",
+		},
+	} {
+		got, err := Source([]byte(test.src))
+		if err != nil {
+			t.Errorf("%q: %v", test.src, err)
+			continue
+		}
+		if string(got) != test.want {
+			t.Errorf("formatted source differs:\n%s", Diff("want", "got", []byte(test.want), got))
+		}
+		testSource(t, []byte(test.src))
+	}
+}
+
+func testSource(t *testing.T, src []byte) {
+	t.Helper()
+	out, err := Source(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Source(out)
+	if err != nil {
+		t.Fatalf("formatting the result: %v", err)
+	}
+	if !bytes.Equal(out, again) {
+		t.Errorf("formatting is not idempotent:\n%s", Diff("once", "twice", out, again))
+	}
+	before, err := parse(string(src), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := parse(string(out), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before, after := stripPositions(before), stripPositions(after); !reflect.DeepEqual(before, after) {
+		t.Errorf("formatting changed the syntax tree:\n%s", strings.Join(pretty.Diff(before, after), "\n"))
+	}
+}
@@ -2,8 +2,9 @@ module lang
 
 go 1.17
 
+require github.com/kr/pretty v0.3.0
+
 require (
-	github.com/kr/pretty v0.3.0 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/rogpeppe/go-internal v1.6.1 // indirect
 	golang.org/x/mod v0.5.1 // indirect
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/kr/pretty"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/astjson"
+	"lang/format"
+	"lang/interp"
+	"lang/loader"
+	"lang/parser"
+	"lang/scanner"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the current results")
+
+// TestGolden runs each program in testdata through the whole pipeline. The
+// tokens, statements and output of testdata/x.c are compared against
+// x.tokens.json, x.ast.json and x.out, and the JSON files must decode back
+// to the same tokens and statements. Every diagnostic must be matched by an
+// annotation on its line such as
+//
+//	x = "s"; // ERROR "cannot use string value"
+//
+// whose regular expressions must each match one of the line's diagnostics.
+// Warnings are matched with their "warning: " prefix. Those about shadowed
+// variables are only reported for programs whose first line is the comment
+//
+//	// shadow
+//
+// A program is only run if it has no errors, and then its main function is
+// called with zero values as arguments.
+func TestGolden(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		path := path
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".c"), func(t *testing.T) {
+			runGolden(t, path)
+		})
+	}
+}
+
+type diagnostic struct {
+	row int
+	msg string
+}
+
+func runGolden(t *testing.T, path string) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens, comments, err := scan(string(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := strings.TrimSuffix(path, ".c")
+	b, err := astjson.MarshalTokens(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, golden+".tokens.json", b)
+	if decoded, err := astjson.UnmarshalTokens(b); err != nil {
+		t.Errorf("decoding %s.tokens.json: %v", golden, err)
+	} else if !reflect.DeepEqual(decoded, tokens) {
+		t.Errorf("%s.tokens.json decodes to other tokens:\n%s", golden, strings.Join(pretty.Diff(tokens, decoded), "\n"))
+	}
+
+	var diags []diagnostic
+	stmts, pos, err := parse(tokens)
+	if err != nil {
+		diags = append(diags, diagnostic{pos.Row, err.Error()})
+		checkGolden(t, golden+".ast.json", nil)
+		checkGolden(t, golden+".out", nil)
+		checkDiagnostics(t, comments, diags)
+		return
+	}
+	b, err = astjson.Marshal(stmts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, golden+".ast.json", b)
+	if decoded, err := astjson.Unmarshal(b); err != nil {
+		t.Errorf("decoding %s.ast.json: %v", golden, err)
+	} else if !reflect.DeepEqual(decoded, stmts) {
+		t.Errorf("%s.ast.json decodes to another tree:\n%s", golden, strings.Join(pretty.Diff(stmts, decoded), "\n"))
+	}
+
+	mods, err := loader.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	universe := analysis.NewUniverse(ioutil.Discard)
+	universe.WarnShadowing = len(comments) > 0 && comments[0].Row == 0 && strings.TrimSpace(comments[0].Lexeme) == "shadow"
+	analysis.CheckIn(universe, mods)
+	errors := 0
+	for _, d := range universe.Diagnostics() {
+		if d.Path != filepath.Clean(path) {
+			t.Errorf("%v", d)
+			continue
+		}
+		msg := d.Msg
+		if d.Warning {
+			msg = "warning: " + msg
+		} else {
+			errors++
+		}
+		diags = append(diags, diagnostic{d.Pos.Row, msg})
+	}
+	checkDiagnostics(t, comments, diags)
+	if errors > 0 {
+		checkGolden(t, golden+".out", nil)
+		return
+	}
+	checkGolden(t, golden+".out", run(mods))
+}
+
+func scan(src string) (tokens, comments []scanner.Token, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	tokens, comments = scanner.ScanComments(src)
+	return tokens, comments, nil
+}
+
+func parse(tokens []scanner.Token) (stmts []parser.Stmt, pos scanner.Token, err error) {
+	p := parser.Parser{Tokens: tokens}
+	defer func() {
+		if r := recover(); r != nil {
+			pos, err = p.Current(), fmt.Errorf("%v", r)
+		}
+	}()
+	return p.ConsumeTopLevelStmts(), scanner.Token{}, nil
+}
+
+// run runs the main function of the last module and returns what it
+// printed, followed by what it returned or the error it failed with.
+func run(mods []*loader.Module) []byte {
+	var out bytes.Buffer
+	in, err := interp.New(mods, nil)
+	if err != nil {
+		fmt.Fprintf(&out, "error: %v\n", err)
+		return out.Bytes()
+	}
+	in.Stdout = &out
+	var args []interp.Value
+	for _, stmt := range mods[len(mods)-1].Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && f.Name.Lexeme == "main" {
+			for _, p := range f.Params {
+				args = append(args, zero(p.Kind.Lexeme))
+			}
+		}
+	}
+	result, err := in.Call("main", args...)
+	if err != nil {
+		fmt.Fprintf(&out, "error: %v\n", err)
+	} else if result != nil {
+		fmt.Fprintf(&out, "result: %s\n", interp.Format(result))
+	}
+	return out.Bytes()
+}
+
+func zero(kind string) interp.Value {
+	switch kind {
+	case "int":
+		return int64(0)
+	case "float":
+		return 0.0
+	case "bool":
+		return false
+	case "string":
+		return ""
+	default:
+		return nil
+	}
+}
+
+var (
+	errorAnnotation = regexp.MustCompile(`^\s*ERROR\s+(.*)$`)
+	quoted          = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+)
+
+// checkDiagnostics matches diags against the ERROR annotations in
+// comments.
+func checkDiagnostics(t *testing.T, comments []scanner.Token, diags []diagnostic) {
+	want := map[int][]*regexp.Regexp{}
+	for _, c := range comments {
+		m := errorAnnotation.FindStringSubmatch(c.Lexeme)
+		if m == nil {
+			continue
+		}
+		for _, q := range quoted.FindAllString(m[1], -1) {
+			s, err := strconv.Unquote(q)
+			if err != nil {
+				t.Fatalf("%d: bad annotation %s: %v", c.Row+1, q, err)
+			}
+			re, err := regexp.Compile(s)
+			if err != nil {
+				t.Fatalf("%d: bad annotation %s: %v", c.Row+1, q, err)
+			}
+			want[c.Row] = append(want[c.Row], re)
+		}
+	}
+	got := map[int][]string{}
+	for _, d := range diags {
+		got[d.row] = append(got[d.row], d.msg)
+	}
+	for row, msgs := range got {
+		for _, msg := range msgs {
+			if !matchesAny(want[row], msg) {
+				t.Errorf("%d: unexpected diagnostic: %s", row+1, msg)
+			}
+		}
+	}
+	for row, res := range want {
+		for _, re := range res {
+			found := false
+			for _, msg := range got[row] {
+				found = found || re.MatchString(msg)
+			}
+			if !found {
+				t.Errorf("%d: missing diagnostic matching %q", row+1, re)
+			}
+		}
+	}
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGolden compares got against the golden file at path, which should
+// not exist if got is nil.
+func checkGolden(t *testing.T, path string, got []byte) {
+	if *update {
+		if got == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				t.Fatal(err)
+			}
+		} else if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if got != nil {
+			t.Errorf("%s is missing; run go test -update to create it", path)
+		}
+		return
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil {
+		t.Errorf("%s should not exist; run go test -update to remove it", path)
+	} else if !bytes.Equal(got, want) {
+		t.Errorf("%s differs:\n%s", path, format.Diff(path, "got", want, got))
+	}
+}
@@ -0,0 +1,87 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+func (in *Interpreter) newBuiltins() map[string]Builtin {
+	return map[string]Builtin{
+		"print": func(args []Value) (Value, error) {
+			_, err := io.WriteString(in.Stdout, args[0].(string))
+			return nil, err
+		},
+		"println": func(args []Value) (Value, error) {
+			_, err := io.WriteString(in.Stdout, args[0].(string)+"\n")
+			return nil, err
+		},
+		"printf": func(args []Value) (Value, error) {
+			a := make([]interface{}, len(args)-1)
+			for i, arg := range args[1:] {
+				a[i] = arg
+			}
+			_, err := fmt.Fprintf(in.Stdout, args[0].(string), a...)
+			return nil, err
+		},
+		"len": func(args []Value) (Value, error) {
+			return int64(utf8.RuneCountInString(args[0].(string))), nil
+		},
+		"substr": func(args []Value) (Value, error) {
+			s := []rune(args[0].(string))
+			start, end := args[1].(int64), args[2].(int64)
+			if start < 0 || end < start || end > int64(len(s)) {
+				return nil, runtimeErrorf("substr: range [%d:%d] out of bounds for length %d", start, end, len(s))
+			}
+			return string(s[start:end]), nil
+		},
+		"indexOf": func(args []Value) (Value, error) {
+			s := args[0].(string)
+			i := strings.Index(s, args[1].(string))
+			if i < 0 {
+				return int64(-1), nil
+			}
+			return int64(utf8.RuneCountInString(s[:i])), nil
+		},
+		"splitCount": func(args []Value) (Value, error) {
+			return int64(len(strings.Split(args[0].(string), args[1].(string)))), nil
+		},
+		"split": func(args []Value) (Value, error) {
+			parts := strings.Split(args[0].(string), args[1].(string))
+			i := args[2].(int64)
+			if i < 0 || i >= int64(len(parts)) {
+				return nil, runtimeErrorf("split: index %d out of bounds for length %d", i, len(parts))
+			}
+			return parts[i], nil
+		},
+		"abs": func(args []Value) (Value, error) {
+			if n := args[0].(int64); n < 0 {
+				return -n, nil
+			}
+			return args[0], nil
+		},
+		"sqrt": func(args []Value) (Value, error) {
+			return math.Sqrt(args[0].(float64)), nil
+		},
+		"pow": func(args []Value) (Value, error) {
+			return math.Pow(args[0].(float64), args[1].(float64)), nil
+		},
+		"parseInt": func(args []Value) (Value, error) {
+			i, err := strconv.ParseInt(args[0].(string), 10, 64)
+			if err != nil {
+				return nil, runtimeErrorf("parseInt: invalid int %q", args[0])
+			}
+			return i, nil
+		},
+		"parseFloat": func(args []Value) (Value, error) {
+			f, err := strconv.ParseFloat(args[0].(string), 64)
+			if err != nil {
+				return nil, runtimeErrorf("parseFloat: invalid float %q", args[0])
+			}
+			return f, nil
+		},
+	}
+}
@@ -0,0 +1,726 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"lang/loader"
+	"lang/parser"
+	"lang/prelude"
+	"lang/scanner"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const maxCallDepth = 10000
+
+// Value is a runtime value: an int64, float64, bool, string or EnumValue
+// for the language's own types, or a function or module.
+type Value interface{}
+
+type Enum struct {
+	Name    string
+	Members []string
+}
+
+type EnumValue struct {
+	Enum  *Enum
+	Index int
+}
+
+func (v EnumValue) String() string {
+	return v.Enum.Members[v.Index]
+}
+
+// Builtin is a function implemented in Go. Its arguments have already been
+// type checked against its extern declaration.
+type Builtin func(args []Value) (Value, error)
+
+type RuntimeError struct {
+	Msg string
+}
+
+func (e *RuntimeError) Error() string {
+	return "runtime error: " + e.Msg
+}
+
+func runtimeErrorf(format string, a ...interface{}) *RuntimeError {
+	return &RuntimeError{fmt.Sprintf(format, a...)}
+}
+
+type primitive string
+
+type function struct {
+	decl parser.FunctionStmt
+	mod  *module
+}
+
+// tailCall is what a function returns in place of the result of calling
+// another one, which its caller then calls in its stead, so that tail
+// calls do not grow the Go stack or count towards maxCallDepth.
+type tailCall struct {
+	f    *function
+	args []Value
+}
+
+type module struct {
+	name    string
+	globals *scope
+	types   map[string]interface{}
+	exports map[string]Value
+	enums   map[string]*Enum
+}
+
+type scope struct {
+	parent *scope
+	vars   map[string]Value
+}
+
+func (s *scope) lookup(name string) (Value, bool) {
+	if v, ok := s.vars[name]; ok {
+		return v, true
+	} else if s.parent == nil {
+		return nil, false
+	} else {
+		return s.parent.lookup(name)
+	}
+}
+
+func (s *scope) assign(name string, v Value) {
+	if _, ok := s.vars[name]; ok {
+		s.vars[name] = v
+	} else if s.parent == nil {
+		panic(runtimeErrorf("assignment to undeclared variable %q", name))
+	} else {
+		s.parent.assign(name, v)
+	}
+}
+
+type env struct {
+	mod  *module
+	vars *scope
+}
+
+func (e env) child() env {
+	return env{e.mod, &scope{parent: e.vars, vars: map[string]Value{}}}
+}
+
+type control int
+
+const (
+	next control = iota
+	returned
+	fellThrough
+)
+
+type Interpreter struct {
+	Stdout   io.Writer
+	entry    *module
+	builtins map[string]Builtin
+	depth    int
+}
+
+// New prepares mods, ordered as returned by loader.Load, for execution and
+// initializes their top-level variables. Functions of the last module can
+// then be run with Call. Every module sees the values in host as globals,
+// in addition to the prelude's builtins.
+func New(mods []*loader.Module, host map[string]Value) (*Interpreter, error) {
+	in, universe, err := newInterpreter(host)
+	if err != nil {
+		return nil, err
+	}
+	loaded := map[*loader.Module]*module{}
+	err = protect(func() {
+		for _, m := range mods {
+			loaded[m] = in.load(m, universe, loaded)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	in.entry = loaded[mods[len(mods)-1]]
+	return in, nil
+}
+
+// NewSession returns an interpreter whose entry module, called name, starts
+// out empty and is built up one statement at a time with Exec.
+func NewSession(name string, host map[string]Value) (*Interpreter, error) {
+	in, universe, err := newInterpreter(host)
+	if err != nil {
+		return nil, err
+	}
+	in.entry = newModule(name, universe)
+	return in, nil
+}
+
+func newInterpreter(host map[string]Value) (*Interpreter, *scope, error) {
+	in := &Interpreter{Stdout: os.Stdout}
+	in.builtins = in.newBuiltins()
+	universe := &scope{vars: map[string]Value{}}
+	for name, v := range host {
+		universe.vars[name] = v
+	}
+	for _, stmt := range prelude.Stmts() {
+		f := stmt.(parser.FunctionStmt)
+		b, ok := in.builtins[f.Name.Lexeme]
+		if !ok {
+			return nil, nil, fmt.Errorf("no implementation for builtin %q", f.Name.Lexeme)
+		}
+		universe.vars[f.Name.Lexeme] = b
+	}
+	return in, universe, nil
+}
+
+func newModule(name string, universe *scope) *module {
+	return &module{
+		name:    name,
+		globals: &scope{parent: universe, vars: map[string]Value{}},
+		types: map[string]interface{}{
+			"bool":   primitive("bool"),
+			"float":  primitive("float"),
+			"int":    primitive("int"),
+			"string": primitive("string"),
+			"void":   primitive("void"),
+		},
+		exports: map[string]Value{},
+		enums:   map[string]*Enum{},
+	}
+}
+
+func (in *Interpreter) load(m *loader.Module, universe *scope, loaded map[*loader.Module]*module) *module {
+	mod := newModule(m.Name, universe)
+	for _, imported := range m.Imports {
+		im := loaded[imported]
+		mod.globals.vars[im.name] = im
+		for name, enum := range im.enums {
+			mod.types[im.name+"."+name] = enum
+		}
+	}
+	for _, stmt := range m.Stmts {
+		if s, ok := stmt.(parser.EnumStmt); ok {
+			in.declare(mod, s)
+		}
+	}
+	for _, stmt := range m.Stmts {
+		if s, ok := stmt.(parser.FunctionStmt); ok {
+			in.declare(mod, s)
+		}
+	}
+	for _, stmt := range m.Stmts {
+		if s, ok := stmt.(parser.VarStmt); ok {
+			in.declare(mod, s)
+		}
+	}
+	return mod
+}
+
+func (in *Interpreter) declare(mod *module, stmt parser.Stmt) {
+	switch s := stmt.(type) {
+	case parser.EnumStmt:
+		enum := &Enum{Name: s.Name.Lexeme}
+		for _, member := range s.Members {
+			enum.Members = append(enum.Members, member.Lexeme)
+		}
+		mod.types[enum.Name] = enum
+		if s.Exported {
+			mod.enums[enum.Name] = enum
+		}
+	case parser.FunctionStmt:
+		var f Value = &function{s, mod}
+		if s.Extern {
+			b, ok := in.builtins[s.Name.Lexeme]
+			if !ok {
+				panic(runtimeErrorf("no implementation for extern function %q", s.Name.Lexeme))
+			}
+			f = b
+		}
+		mod.globals.vars[s.Name.Lexeme] = f
+		if s.Exported {
+			mod.exports[s.Name.Lexeme] = f
+		}
+	case parser.VarStmt:
+		in.exec(env{mod, mod.globals}, s)
+		if s.Exported {
+			mod.exports[s.Name.Lexeme] = mod.globals.vars[s.Name.Lexeme]
+		}
+	default:
+		in.exec(env{mod, mod.globals}, s)
+	}
+}
+
+// Exec runs stmt at the top level of the entry module, adding whatever it
+// declares to the module.
+func (in *Interpreter) Exec(stmt parser.Stmt) error {
+	return protect(func() {
+		in.declare(in.entry, stmt)
+	})
+}
+
+// Eval evaluates expr at the top level of the entry module.
+func (in *Interpreter) Eval(expr parser.Expr) (Value, error) {
+	var v Value
+	err := protect(func() {
+		v = in.eval(env{in.entry, in.entry.globals}, expr)
+	})
+	return v, err
+}
+
+func protect(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if rerr, ok := r.(*RuntimeError); ok {
+				err = rerr
+				return
+			}
+			panic(r)
+		}
+	}()
+	f()
+	return nil
+}
+
+// Call runs the function called name in the entry module with args and
+// returns its result, which is nil for void functions.
+func (in *Interpreter) Call(name string, args ...Value) (Value, error) {
+	v, ok := in.entry.globals.vars[name]
+	f, isFunction := v.(*function)
+	if !ok || !isFunction {
+		return nil, fmt.Errorf("no function %q in module %q", name, in.entry.name)
+	}
+	if len(args) != len(f.decl.Params) {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name, len(f.decl.Params), len(args))
+	}
+	for i, param := range f.decl.Params {
+		if !in.hasType(f.mod, args[i], param.Kind.Lexeme) {
+			return nil, fmt.Errorf("argument %d of %s must be %s", i+1, name, param.Kind.Lexeme)
+		}
+	}
+	var result Value
+	err := protect(func() {
+		result = in.call(f, args)
+	})
+	return result, err
+}
+
+func (in *Interpreter) hasType(mod *module, v Value, kind string) bool {
+	switch t := mod.types[kind].(type) {
+	case primitive:
+		switch v.(type) {
+		case bool:
+			return t == "bool"
+		case float64:
+			return t == "float"
+		case int64:
+			return t == "int"
+		case string:
+			return t == "string"
+		}
+	case *Enum:
+		if ev, ok := v.(EnumValue); ok {
+			return ev.Enum == t
+		}
+	}
+	return false
+}
+
+func (in *Interpreter) call(callee Value, args []Value) Value {
+	switch f := callee.(type) {
+	case Builtin:
+		v, err := f(args)
+		if err != nil {
+			if rerr, ok := err.(*RuntimeError); ok {
+				panic(rerr)
+			}
+			panic(&RuntimeError{err.Error()})
+		}
+		return v
+	case *function:
+		in.depth++
+		defer func() { in.depth-- }()
+		if in.depth > maxCallDepth {
+			panic(runtimeErrorf("stack overflow in %s", f.decl.Name.Lexeme))
+		}
+		for {
+			e := env{f.mod, &scope{parent: f.mod.globals, vars: map[string]Value{}}}
+			for i, param := range f.decl.Params {
+				e.vars.vars[param.Name.Lexeme] = args[i]
+			}
+			_, v := in.execBlock(e, f.decl.Body)
+			tail, ok := v.(tailCall)
+			if !ok {
+				if v == nil && f.decl.ReturnKind.Lexeme != "void" {
+					panic(runtimeErrorf("missing return at the end of %s", f.decl.Name.Lexeme))
+				}
+				return v
+			}
+			f, args = tail.f, tail.args
+		}
+	default:
+		panic(runtimeErrorf("cannot call a value of type %T", callee))
+	}
+}
+
+func (in *Interpreter) execBlock(e env, blk parser.Block) (control, Value) {
+	for _, stmt := range blk.Stmts {
+		if ctl, v := in.exec(e, stmt); ctl != next {
+			return ctl, v
+		}
+	}
+	return next, nil
+}
+
+func (in *Interpreter) exec(e env, stmt parser.Stmt) (control, Value) {
+	switch s := stmt.(type) {
+	case parser.Block:
+		return in.execBlock(e.child(), s)
+	case parser.VarStmt:
+		var v Value
+		if s.Expr != nil {
+			v = in.eval(e, s.Expr)
+		} else {
+			v = in.zero(e, s.Kind.Lexeme)
+		}
+		e.vars.vars[s.Name.Lexeme] = v
+	case parser.AssignStmt:
+		e.vars.assign(s.Target.(scanner.Token).Lexeme, in.eval(e, s.Expr))
+	case parser.CompoundAssignStmt:
+		cur, _ := e.vars.lookup(s.Target.Lexeme)
+		e.vars.assign(s.Target.Lexeme, binary(parser.CompoundAssignOps[s.Op.Kind], cur, in.eval(e, s.Expr)))
+	case parser.IncDecStmt:
+		cur, _ := e.vars.lookup(s.Target.Lexeme)
+		var one Value = int64(1)
+		if _, isFloat := cur.(float64); isFloat {
+			one = float64(1)
+		}
+		op := scanner.Plus
+		if s.Op.Kind == scanner.Dec {
+			op = scanner.Minus
+		}
+		e.vars.assign(s.Target.Lexeme, binary(op, cur, one))
+	case parser.ReturnStmt:
+		if s.Expr == nil {
+			return returned, nil
+		}
+		if call, ok := s.Expr.(parser.FunctionCall); ok && in.typeNamed(e, call.Callee) == nil {
+			if f, ok := in.eval(e, call.Callee).(*function); ok {
+				args := make([]Value, len(call.Args))
+				for i, arg := range call.Args {
+					args[i] = in.eval(e, arg)
+				}
+				return returned, tailCall{f, args}
+			}
+		}
+		return returned, in.eval(e, s.Expr)
+	case parser.IfStmt:
+		if in.eval(e, s.Cond).(bool) {
+			return in.execBlock(e.child(), s.Then)
+		}
+		return in.execBlock(e.child(), s.Els)
+	case parser.WhileStmt:
+		for in.eval(e, s.Cond).(bool) {
+			if ctl, v := in.execBlock(e.child(), s.Body); ctl == returned {
+				return ctl, v
+			}
+		}
+	case parser.SwitchStmt:
+		return in.execSwitch(e, s)
+	case parser.FallthroughStmt:
+		return fellThrough, nil
+	case parser.FunctionCall:
+		in.eval(e, s)
+	default:
+		panic(fmt.Sprintf("Unknown statement %T", stmt))
+	}
+	return next, nil
+}
+
+func (in *Interpreter) execSwitch(e env, s parser.SwitchStmt) (control, Value) {
+	v := in.eval(e, s.Expr)
+	start := -1
+	for i, c := range s.Cases {
+		if c.Default && start < 0 {
+			start = i
+		}
+		for _, cv := range c.Values {
+			if in.eval(e, cv) == v {
+				return in.execCases(e, s.Cases[i:])
+			}
+		}
+	}
+	if start < 0 {
+		return next, nil
+	}
+	return in.execCases(e, s.Cases[start:])
+}
+
+func (in *Interpreter) execCases(e env, cases []parser.SwitchCase) (control, Value) {
+	for _, c := range cases {
+		ctl, v := in.execBlock(e.child(), c.Body)
+		if ctl != fellThrough {
+			return ctl, v
+		}
+	}
+	return next, nil
+}
+
+func (in *Interpreter) eval(e env, expr parser.Expr) Value {
+	switch n := expr.(type) {
+	case parser.LiteralBool:
+		return n.Value
+	case parser.LiteralStr:
+		return n.Value
+	case parser.LiteralNum:
+		if strings.ContainsRune(n.Value, '.') {
+			f, err := strconv.ParseFloat(n.Value, 64)
+			if err != nil {
+				panic(runtimeErrorf("invalid float literal %s", n.Value))
+			}
+			return f
+		}
+		i, err := strconv.ParseInt(n.Value, 10, 64)
+		if err != nil {
+			panic(runtimeErrorf("invalid int literal %s", n.Value))
+		}
+		return i
+	case parser.LiteralNull:
+		return nil
+	case parser.InterpolatedStr:
+		var b strings.Builder
+		for _, part := range n.Parts {
+			b.WriteString(stringify(in.eval(e, part)))
+		}
+		return b.String()
+	case parser.IdentExpr:
+		v, ok := e.vars.lookup(n.Name.Lexeme)
+		if !ok {
+			panic(runtimeErrorf("undefined variable %q", n.Name.Lexeme))
+		}
+		return v
+	case parser.MemberAccess:
+		if enum, ok := in.typeNamed(e, n.Parent).(*Enum); ok {
+			for i, member := range enum.Members {
+				if member == n.Name.Lexeme {
+					return EnumValue{enum, i}
+				}
+			}
+			panic(runtimeErrorf("%s has no member %s", enum.Name, n.Name.Lexeme))
+		}
+		if mod, ok := in.eval(e, n.Parent).(*module); ok {
+			return mod.exports[n.Name.Lexeme]
+		}
+		panic(runtimeErrorf("cannot access member %s", n.Name.Lexeme))
+	case parser.FunctionCall:
+		if t := in.typeNamed(e, n.Callee); t != nil {
+			return convert(t, in.eval(e, n.Args[0]))
+		}
+		callee := in.eval(e, n.Callee)
+		args := make([]Value, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = in.eval(e, arg)
+		}
+		return in.call(callee, args)
+	case parser.UnaryOp:
+		v := in.eval(e, n.Expr)
+		switch n.Op.Kind {
+		case scanner.Minus:
+			if f, ok := v.(float64); ok {
+				return -f
+			}
+			return -v.(int64)
+		case scanner.LNot:
+			return !v.(bool)
+		case scanner.BNot:
+			return ^v.(int64)
+		default:
+			panic("Unknown unary op")
+		}
+	case parser.BinaryOp:
+		switch n.Op.Kind {
+		case scanner.LAnd:
+			return in.eval(e, n.Left).(bool) && in.eval(e, n.Right).(bool)
+		case scanner.LOr:
+			return in.eval(e, n.Left).(bool) || in.eval(e, n.Right).(bool)
+		default:
+			return binary(n.Op.Kind, in.eval(e, n.Left), in.eval(e, n.Right))
+		}
+	case parser.TernaryExpr:
+		if in.eval(e, n.Cond).(bool) {
+			return in.eval(e, n.Then)
+		}
+		return in.eval(e, n.Els)
+	default:
+		panic(fmt.Sprintf("Unknown expression %T", expr))
+	}
+}
+
+func (in *Interpreter) typeNamed(e env, expr parser.Expr) interface{} {
+	switch n := expr.(type) {
+	case parser.IdentExpr:
+		if _, isVar := e.vars.lookup(n.Name.Lexeme); !isVar {
+			return e.mod.types[n.Name.Lexeme]
+		}
+	case parser.MemberAccess:
+		if parent, ok := n.Parent.(parser.IdentExpr); ok {
+			if v, _ := e.vars.lookup(parent.Name.Lexeme); v != nil {
+				if _, isModule := v.(*module); isModule {
+					return e.mod.types[parent.Name.Lexeme+"."+n.Name.Lexeme]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (in *Interpreter) zero(e env, kind string) Value {
+	switch t := e.mod.types[kind].(type) {
+	case *Enum:
+		return EnumValue{t, 0}
+	case primitive:
+		switch t {
+		case "bool":
+			return false
+		case "float":
+			return float64(0)
+		case "int":
+			return int64(0)
+		case "string":
+			return ""
+		}
+	}
+	panic(runtimeErrorf("no zero value for type %s", kind))
+}
+
+func convert(t interface{}, v Value) Value {
+	if enum, ok := t.(*Enum); ok {
+		if i, ok := v.(int64); ok {
+			if i < 0 || i >= int64(len(enum.Members)) {
+				panic(runtimeErrorf("%d is out of range for enum %s", i, enum.Name))
+			}
+			return EnumValue{enum, int(i)}
+		}
+		return v
+	}
+	switch t.(primitive) {
+	case "int":
+		switch v := v.(type) {
+		case float64:
+			return int64(v)
+		case EnumValue:
+			return int64(v.Index)
+		}
+	case "float":
+		if i, ok := v.(int64); ok {
+			return float64(i)
+		}
+	case "string":
+		return stringify(v)
+	}
+	return v
+}
+
+// Format returns v as it would be written in source, quoting strings.
+func Format(v Value) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case EnumValue:
+		return v.Enum.Name + "." + v.String()
+	default:
+		return stringify(v)
+	}
+}
+
+func stringify(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case EnumValue:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func binary(op scanner.TokenKind, left, right Value) Value {
+	switch op {
+	case scanner.EqEq:
+		return left == right
+	case scanner.Ne:
+		return left != right
+	}
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		switch op {
+		case scanner.Plus:
+			return l + r
+		case scanner.Minus:
+			return l - r
+		case scanner.Star:
+			return l * r
+		case scanner.Slash:
+			if r == 0 {
+				panic(runtimeErrorf("integer division by zero"))
+			}
+			return l / r
+		case scanner.Percent:
+			if r == 0 {
+				panic(runtimeErrorf("integer division by zero"))
+			}
+			return l % r
+		case scanner.BAnd:
+			return l & r
+		case scanner.BOr:
+			return l | r
+		case scanner.BXor:
+			return l ^ r
+		case scanner.Shl:
+			if r < 0 {
+				panic(runtimeErrorf("negative shift amount %d", r))
+			}
+			return l << uint64(r)
+		case scanner.Shr:
+			if r < 0 {
+				panic(runtimeErrorf("negative shift amount %d", r))
+			}
+			return l >> uint64(r)
+		case scanner.Gt:
+			return l > r
+		case scanner.Gte:
+			return l >= r
+		case scanner.Lt:
+			return l < r
+		case scanner.Lte:
+			return l <= r
+		}
+	case float64:
+		r := right.(float64)
+		switch op {
+		case scanner.Plus:
+			return l + r
+		case scanner.Minus:
+			return l - r
+		case scanner.Star:
+			return l * r
+		case scanner.Slash:
+			return l / r
+		case scanner.Gt:
+			return l > r
+		case scanner.Gte:
+			return l >= r
+		case scanner.Lt:
+			return l < r
+		case scanner.Lte:
+			return l <= r
+		}
+	}
+	panic("Unknown binary op")
+}
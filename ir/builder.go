@@ -0,0 +1,344 @@
+package ir
+
+import "lang/scanner"
+
+// Variable is a local variable, whose assignments the Builder turns into
+// SSA values.
+type Variable struct {
+	Name string
+	Type Type
+}
+
+// Builder appends instructions to a function, and constructs SSA form for
+// its local variables as it goes, following Braun et al., "Simple and
+// Efficient Construction of Static Single Assignment Form". A block must be
+// sealed once all of its predecessors are known.
+type Builder struct {
+	Func *Func
+	// Block is the block that instructions are appended to, which is nil
+	// after a terminator until another is set.
+	Block *Block
+	// Pos is given to the values that are created.
+	Pos scanner.Token
+
+	defs map[*Block]map[*Variable]*Value
+	// The phis of blocks that have not been sealed, in the order they were
+	// added, so that values are numbered deterministically.
+	incomplete map[*Block][]incompletePhi
+	sealed     map[*Block]bool
+	// The values that removed phis were replaced by.
+	replaced map[*Value]*Value
+}
+
+type incompletePhi struct {
+	v   *Variable
+	phi *Value
+}
+
+// NewBuilder returns a builder for a new function, positioned at its
+// sealed entry block.
+func NewBuilder(f *Func) *Builder {
+	b := &Builder{
+		Func:       f,
+		defs:       map[*Block]map[*Variable]*Value{},
+		incomplete: map[*Block][]incompletePhi{},
+		sealed:     map[*Block]bool{},
+		replaced:   map[*Value]*Value{},
+	}
+	b.Block = b.NewBlock()
+	b.Seal(b.Block)
+	return b
+}
+
+// NewBlock adds an empty block to the function.
+func (b *Builder) NewBlock() *Block {
+	f := b.Func
+	blk := &Block{ID: f.blocks, Func: f}
+	f.blocks++
+	f.Blocks = append(f.Blocks, blk)
+	return blk
+}
+
+// SetBlock makes blk the block that instructions are appended to.
+func (b *Builder) SetBlock(blk *Block) {
+	b.Block = blk
+}
+
+func (b *Builder) newValue(blk *Block, op Op, t Type, aux interface{}, args []*Value) *Value {
+	v := &Value{ID: b.Func.values, Op: op, Type: t, Args: args, Aux: aux, Block: blk, Pos: b.Pos}
+	b.Func.values++
+	return v
+}
+
+// Emit appends an instruction to the current block. If there is none,
+// because the last one was terminated, it is appended to a new block that
+// is unreachable.
+func (b *Builder) Emit(op Op, t Type, aux interface{}, args ...*Value) *Value {
+	if b.Block == nil {
+		b.Block = b.NewBlock()
+		b.Seal(b.Block)
+	}
+	v := b.newValue(b.Block, op, t, aux, args)
+	b.Block.Values = append(b.Block.Values, v)
+	return v
+}
+
+func (b *Builder) Const(t Type, x interface{}) *Value {
+	return b.Emit(OpConst, t, x)
+}
+
+// Zero returns the zero value of type t.
+func (b *Builder) Zero(t Type) *Value {
+	switch t {
+	case Float:
+		return b.Const(t, 0.0)
+	case Bool:
+		return b.Const(t, false)
+	case String:
+		return b.Const(t, "")
+	default:
+		return b.Const(t, int64(0))
+	}
+}
+
+func (b *Builder) terminate(kind BlockKind, control *Value, succs ...*Block) {
+	if b.Block == nil {
+		b.Block = b.NewBlock()
+		b.Seal(b.Block)
+	}
+	blk := b.Block
+	blk.Kind = kind
+	blk.Control = control
+	blk.Succs = succs
+	for _, s := range succs {
+		s.Preds = append(s.Preds, blk)
+	}
+	b.Block = nil
+}
+
+// Jump ends the current block with a jump to blk.
+func (b *Builder) Jump(blk *Block) {
+	b.terminate(Plain, nil, blk)
+}
+
+// If ends the current block with a branch on cond.
+func (b *Builder) If(cond *Value, then, els *Block) {
+	b.terminate(If, cond, then, els)
+}
+
+// Return ends the current block with a return of v, which is nil for void
+// functions.
+func (b *Builder) Return(v *Value) {
+	b.terminate(Return, v)
+}
+
+// Write assigns val to v in the current block.
+func (b *Builder) Write(v *Variable, val *Value) {
+	b.write(v, b.Block, val)
+}
+
+// Read returns the value of v in the current block.
+func (b *Builder) Read(v *Variable) *Value {
+	if b.Block == nil {
+		b.Block = b.NewBlock()
+		b.Seal(b.Block)
+	}
+	return b.resolve(b.read(v, b.Block))
+}
+
+func (b *Builder) write(v *Variable, blk *Block, val *Value) {
+	if b.defs[blk] == nil {
+		b.defs[blk] = map[*Variable]*Value{}
+	}
+	b.defs[blk][v] = val
+}
+
+func (b *Builder) read(v *Variable, blk *Block) *Value {
+	if val, ok := b.defs[blk][v]; ok {
+		return val
+	}
+	var val *Value
+	switch {
+	case !b.sealed[blk]:
+		val = b.phi(blk, v.Type)
+		b.incomplete[blk] = append(b.incomplete[blk], incompletePhi{v, val})
+	case len(blk.Preds) == 1:
+		val = b.read(v, blk.Preds[0])
+	case len(blk.Preds) == 0:
+		// The block is unreachable, so any value will do.
+		val = b.zeroIn(blk, v.Type)
+	default:
+		phi := b.phi(blk, v.Type)
+		b.write(v, blk, phi)
+		val = b.addPhiOperands(v, phi)
+	}
+	val = b.resolve(val)
+	b.write(v, blk, val)
+	return val
+}
+
+// phi adds a phi without arguments to blk.
+func (b *Builder) phi(blk *Block, t Type) *Value {
+	v := b.newValue(blk, OpPhi, t, nil, nil)
+	i := 0
+	for i < len(blk.Values) && blk.Values[i].Op == OpPhi {
+		i++
+	}
+	blk.Values = append(blk.Values[:i], append([]*Value{v}, blk.Values[i:]...)...)
+	return v
+}
+
+func (b *Builder) zeroIn(blk *Block, t Type) *Value {
+	current := b.Block
+	b.Block = blk
+	v := b.Zero(t)
+	b.Block = current
+	return v
+}
+
+func (b *Builder) addPhiOperands(v *Variable, phi *Value) *Value {
+	for _, pred := range phi.Block.Preds {
+		phi.Args = append(phi.Args, b.read(v, pred))
+	}
+	return b.removeTrivialPhi(phi)
+}
+
+// removeTrivialPhi replaces phi by the only value other than itself that
+// it merges, if there is one.
+func (b *Builder) removeTrivialPhi(phi *Value) *Value {
+	var same *Value
+	for _, arg := range phi.Args {
+		if arg == same || arg == phi {
+			continue
+		}
+		if same != nil {
+			return phi
+		}
+		same = arg
+	}
+	if same == nil {
+		same = b.zeroIn(phi.Block, phi.Type)
+	}
+	b.replaced[phi] = same
+	blk := phi.Block
+	for i, v := range blk.Values {
+		if v == phi {
+			blk.Values = append(blk.Values[:i], blk.Values[i+1:]...)
+			break
+		}
+	}
+	var users []*Value
+	for _, blk := range b.Func.Blocks {
+		for _, v := range blk.Values {
+			for i, arg := range v.Args {
+				if arg == phi {
+					v.Args[i] = same
+					if v.Op == OpPhi && v != phi {
+						users = append(users, v)
+					}
+				}
+			}
+		}
+		if blk.Control == phi {
+			blk.Control = same
+		}
+	}
+	for _, vars := range b.defs {
+		for v, val := range vars {
+			if val == phi {
+				vars[v] = same
+			}
+		}
+	}
+	for _, user := range users {
+		if !b.isIncomplete(user) {
+			b.removeTrivialPhi(user)
+		}
+	}
+	return b.resolve(same)
+}
+
+// resolve returns the value that replaces v, which may be a phi that has
+// since been removed in turn.
+func (b *Builder) resolve(v *Value) *Value {
+	for b.replaced[v] != nil {
+		v = b.replaced[v]
+	}
+	return v
+}
+
+// isIncomplete reports whether phi is waiting for its block to be sealed.
+func (b *Builder) isIncomplete(phi *Value) bool {
+	for _, p := range b.incomplete[phi.Block] {
+		if p.phi == phi {
+			return true
+		}
+	}
+	return false
+}
+
+// Seal records that all predecessors of blk are known.
+func (b *Builder) Seal(blk *Block) {
+	for len(b.incomplete[blk]) > 0 {
+		p := b.incomplete[blk][0]
+		b.incomplete[blk] = b.incomplete[blk][1:]
+		b.addPhiOperands(p.v, p.phi)
+	}
+	delete(b.incomplete, blk)
+	b.sealed[blk] = true
+}
+
+// Finish removes the blocks that are unreachable from the entry block,
+// which must all have been sealed.
+func (b *Builder) Finish() {
+	f := b.Func
+	reachable := map[*Block]bool{}
+	var visit func(*Block)
+	visit = func(blk *Block) {
+		if reachable[blk] {
+			return
+		}
+		reachable[blk] = true
+		for _, s := range blk.Succs {
+			visit(s)
+		}
+	}
+	visit(f.Blocks[0])
+	blocks := f.Blocks[:0]
+	for _, blk := range f.Blocks {
+		if !reachable[blk] {
+			continue
+		}
+		var preds []*Block
+		var keep []bool
+		for _, p := range blk.Preds {
+			keep = append(keep, reachable[p])
+			if reachable[p] {
+				preds = append(preds, p)
+			}
+		}
+		for _, v := range blk.Values {
+			if v.Op != OpPhi {
+				continue
+			}
+			var args []*Value
+			for i, arg := range v.Args {
+				if keep[i] {
+					args = append(args, arg)
+				}
+			}
+			v.Args = args
+		}
+		blk.Preds = preds
+		blocks = append(blocks, blk)
+	}
+	f.Blocks = blocks
+}
+
+// Phi adds a phi of args, one for each predecessor of the current block,
+// in order.
+func (b *Builder) Phi(t Type, args ...*Value) *Value {
+	phi := b.phi(b.Block, t)
+	phi.Args = args
+	return phi
+}
@@ -0,0 +1,125 @@
+package ir
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+func (v *Value) String() string {
+	return fmt.Sprintf("v%d", v.ID)
+}
+
+// LongString returns v as an instruction, as in "v3 = add int v1 v2".
+func (v *Value) LongString() string {
+	var b strings.Builder
+	if v.Type != Void {
+		fmt.Fprintf(&b, "%s = ", v)
+	}
+	b.WriteString(v.Op.String())
+	if v.Type != Void {
+		b.WriteString(" " + v.Type.String())
+	}
+	switch aux := v.Aux.(type) {
+	case nil:
+	case string:
+		if v.Op == OpConst {
+			b.WriteString(" " + strconv.Quote(aux))
+		} else {
+			b.WriteString(" " + aux)
+		}
+	case float64:
+		b.WriteString(" " + strconv.FormatFloat(aux, 'g', -1, 64))
+	case *Enum:
+		b.WriteString(" " + aux.Name)
+	default:
+		fmt.Fprintf(&b, " %v", aux)
+	}
+	for _, arg := range v.Args {
+		b.WriteString(" " + arg.String())
+	}
+	return b.String()
+}
+
+func (b *Block) String() string {
+	return fmt.Sprintf("b%d", b.ID)
+}
+
+// terminator returns how b ends, as in "if v3 b1 b2".
+func (b *Block) terminator() string {
+	switch b.Kind {
+	case If:
+		return fmt.Sprintf("if %s %s %s", b.Control, b.Succs[0], b.Succs[1])
+	case Return:
+		if b.Control == nil {
+			return "ret"
+		}
+		return "ret " + b.Control.String()
+	default:
+		return "jump " + b.Succs[0].String()
+	}
+}
+
+// String returns f in the textual form that Fprint writes.
+func (f *Func) String() string {
+	var b strings.Builder
+	var params []string
+	for i, t := range f.Params {
+		if f.Extern {
+			params = append(params, t.String())
+		} else {
+			params = append(params, f.ParamNames[i]+" "+t.String())
+		}
+	}
+	if f.Variadic {
+		params = append(params, "...")
+	}
+	if f.Extern {
+		fmt.Fprintf(&b, "extern func %s(%s) %s\n", f.Name, strings.Join(params, ", "), f.Return)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "func %s(%s) %s {\n", f.Name, strings.Join(params, ", "), f.Return)
+	for _, blk := range f.Blocks {
+		b.WriteString(blk.String() + ":")
+		if len(blk.Preds) > 1 || len(blk.Preds) == 1 && blk.Preds[0].ID != blk.ID-1 {
+			b.WriteString(" <-")
+			for _, p := range blk.Preds {
+				b.WriteString(" " + p.String())
+			}
+		}
+		b.WriteString("\n")
+		for _, v := range blk.Values {
+			b.WriteString("  " + v.LongString() + "\n")
+		}
+		b.WriteString("  " + blk.terminator() + "\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Fprint writes p to w in a textual form, which lists the predecessors of
+// blocks that are not only entered from the block before them.
+func Fprint(w io.Writer, p *Program) error {
+	var b strings.Builder
+	for _, e := range p.Enums {
+		fmt.Fprintf(&b, "enum %s %s\n", e.Name, strings.Join(e.Members, " "))
+	}
+	for _, g := range p.Globals {
+		fmt.Fprintf(&b, "global %s %s\n", g.Name, g.Type)
+	}
+	var inits []string
+	for _, f := range p.Inits {
+		inits = append(inits, f.Name)
+	}
+	fmt.Fprintf(&b, "init %s\n", strings.Join(inits, " "))
+	fmt.Fprintf(&b, "main %s\n", p.Main.Name)
+	for _, f := range p.Inits {
+		b.WriteString("\n" + f.String())
+	}
+	for _, f := range p.Funcs {
+		b.WriteString("\n" + f.String())
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,207 @@
+// Package ir defines an intermediate representation of programs, in which
+// each function is a control flow graph of basic blocks of instructions in
+// static single assignment form, so that optimizations and backends can
+// share it instead of working on syntax trees.
+//
+// Every instruction is a Value, which is assigned once and refers to the
+// values it uses directly. Local variables become values, joined by phis
+// where control flow merges; module variables are loaded and stored.
+package ir
+
+import "lang/scanner"
+
+type Type int
+
+const (
+	Void Type = iota
+	Int
+	Float
+	Bool
+	String
+)
+
+func (t Type) String() string {
+	return [...]string{"void", "int", "float", "bool", "string"}[t]
+}
+
+type Op int
+
+const (
+	// OpConst is a constant, whose Aux is an int64, float64, bool or
+	// string.
+	OpConst Op = iota
+	// OpParam is the parameter of its function whose index is Aux.
+	OpParam
+	// OpPhi has the value of its argument for the predecessor of its block
+	// that control came from.
+	OpPhi
+	// OpLoad and OpStore read and write the module variable named by Aux.
+	OpLoad
+	OpStore
+
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpShl
+	OpShr
+	OpAnd
+	OpOr
+	OpXor
+	OpNeg
+	OpNot
+	OpCompl
+	OpEq
+	OpNe
+	OpLt
+	OpLe
+	OpGt
+	OpGe
+
+	OpIntToFloat
+	OpFloatToInt
+	// OpToEnum checks that an int is a member of the *Enum that is Aux.
+	OpToEnum
+	// OpEnumName is the name of a member of the *Enum that is Aux.
+	OpEnumName
+	// OpToString formats an int, float or bool as a string.
+	OpToString
+	OpConcat
+
+	// OpCall calls the function of the program named by Aux, and OpBuiltin
+	// the builtin function named by Aux.
+	OpCall
+	OpBuiltin
+)
+
+var opNames = [...]string{
+	OpConst:      "const",
+	OpParam:      "param",
+	OpPhi:        "phi",
+	OpLoad:       "load",
+	OpStore:      "store",
+	OpAdd:        "add",
+	OpSub:        "sub",
+	OpMul:        "mul",
+	OpDiv:        "div",
+	OpMod:        "mod",
+	OpShl:        "shl",
+	OpShr:        "shr",
+	OpAnd:        "and",
+	OpOr:         "or",
+	OpXor:        "xor",
+	OpNeg:        "neg",
+	OpNot:        "not",
+	OpCompl:      "compl",
+	OpEq:         "eq",
+	OpNe:         "ne",
+	OpLt:         "lt",
+	OpLe:         "le",
+	OpGt:         "gt",
+	OpGe:         "ge",
+	OpIntToFloat: "inttofloat",
+	OpFloatToInt: "floattoint",
+	OpToEnum:     "toenum",
+	OpEnumName:   "enumname",
+	OpToString:   "tostring",
+	OpConcat:     "concat",
+	OpCall:       "call",
+	OpBuiltin:    "builtin",
+}
+
+func (op Op) String() string {
+	return opNames[op]
+}
+
+// Value is an instruction and the value it produces, if its Type is not
+// Void.
+type Value struct {
+	ID    int
+	Op    Op
+	Type  Type
+	Args  []*Value
+	Aux   interface{}
+	Block *Block
+	// Pos is the token that the value was lowered from, for those that can
+	// fail at run time.
+	Pos scanner.Token
+}
+
+type BlockKind int
+
+const (
+	// Plain blocks continue to their only successor.
+	Plain BlockKind = iota
+	// If blocks continue to their first successor if their control is true
+	// and to their second otherwise.
+	If
+	// Return blocks return their control, if any.
+	Return
+)
+
+type Block struct {
+	ID   int
+	Func *Func
+	Kind BlockKind
+	// Phis come first.
+	Values  []*Value
+	Control *Value
+	Succs   []*Block
+	Preds   []*Block
+}
+
+type Func struct {
+	Name       string
+	Params     []Type
+	ParamNames []string
+	Return     Type
+	// Extern functions are implemented outside the program, and have no
+	// blocks.
+	Extern   bool
+	Variadic bool
+	// The entry block is first.
+	Blocks []*Block
+	values int
+	blocks int
+}
+
+type Global struct {
+	Name string
+	Type Type
+}
+
+type Enum struct {
+	Name    string
+	Members []string
+}
+
+// Program is a whole program. Its Inits initialize the variables of each
+// module in order, before Main is called with the command line arguments.
+type Program struct {
+	Globals []*Global
+	Enums   []*Enum
+	Funcs   []*Func
+	Inits   []*Func
+	Main    *Func
+}
+
+// Func returns the function called name, or nil if there is none.
+func (p *Program) Func(name string) *Func {
+	for _, f := range p.Funcs {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// Global returns the module variable called name, or nil if there is none.
+func (p *Program) Global(name string) *Global {
+	for _, g := range p.Globals {
+		if g.Name == name {
+			return g
+		}
+	}
+	return nil
+}
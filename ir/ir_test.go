@@ -0,0 +1,55 @@
+package ir
+
+import (
+	"io"
+	"lang/codegen/codegentest"
+	"lang/loader"
+	"strings"
+	"testing"
+)
+
+// TestLower compares the IR that each of the backends' test programs is
+// lowered to, which must verify, against the golden file with the same
+// name and an .ir extension.
+func TestLower(t *testing.T) {
+	codegentest.Golden(t, ".ir", nil, func(w io.Writer, mods []*loader.Module) error {
+		prog, err := Lower(mods)
+		if err != nil {
+			return err
+		}
+		if err := Verify(prog); err != nil {
+			return err
+		}
+		return Fprint(w, prog)
+	})
+}
+
+// TestVerify checks that Verify reports broken programs.
+func TestVerify(t *testing.T) {
+	f := &Func{Name: "main.main", Return: Int}
+	b := NewBuilder(f)
+	x := b.Const(Int, int64(1))
+	y := b.Const(Float, 2.0)
+	sum := b.Emit(OpAdd, Int, nil, x, y)
+	then, els := b.NewBlock(), b.NewBlock()
+	b.If(sum, then, els)
+	b.SetBlock(then)
+	b.Return(nil)
+	b.SetBlock(els)
+	b.Return(b.Emit(OpCall, Int, "main.missing"))
+	prog := &Program{Funcs: []*Func{f}, Main: f}
+	err := Verify(prog)
+	if err == nil {
+		t.Fatal("Verify succeeded")
+	}
+	for _, want := range []string{
+		"operand 1 of v2 = add int v0 v1 is float, not int",
+		"b0 does not branch on a bool",
+		"b1 returns no value",
+		"v3 = call int main.missing calls no function",
+	} {
+		if !strings.Contains(err.Error(), "main.main: "+want) {
+			t.Errorf("Verify did not report %q in:\n%s", want, err)
+		}
+	}
+}
@@ -0,0 +1,567 @@
+package ir
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/loader"
+	"lang/parser"
+	"lang/prelude"
+	"lang/scanner"
+	"strconv"
+)
+
+var binaryOps = map[scanner.TokenKind]Op{
+	scanner.Plus:    OpAdd,
+	scanner.Minus:   OpSub,
+	scanner.Star:    OpMul,
+	scanner.Slash:   OpDiv,
+	scanner.Percent: OpMod,
+	scanner.Shl:     OpShl,
+	scanner.Shr:     OpShr,
+	scanner.BAnd:    OpAnd,
+	scanner.BOr:     OpOr,
+	scanner.BXor:    OpXor,
+	scanner.EqEq:    OpEq,
+	scanner.Ne:      OpNe,
+	scanner.Lt:      OpLt,
+	scanner.Lte:     OpLe,
+	scanner.Gt:      OpGt,
+	scanner.Gte:     OpGe,
+}
+
+type lowerer struct {
+	prog *Program
+	mods []*loader.Module
+	// Names of the builtin functions.
+	builtins map[string]bool
+	enums    map[*analysis.EnumType]*Enum
+
+	mod     *loader.Module
+	env     analysis.Env
+	globals map[string]bool
+
+	b *Builder
+	// The local variables declared in each enclosing block, innermost last.
+	scopes []map[string]*Variable
+}
+
+// Lower translates mods, ordered as returned by loader.Load, to a program
+// whose Main is the main function of the last module.
+func Lower(mods []*loader.Module) (*Program, error) {
+	envs, ok := analysis.CheckModules(analysis.NewUniverse(ioutil.Discard), mods)
+	if !ok {
+		return nil, errors.New("program does not type check")
+	}
+	l := &lowerer{
+		prog:     &Program{},
+		mods:     mods,
+		builtins: map[string]bool{},
+		enums:    map[*analysis.EnumType]*Enum{},
+	}
+	for _, stmt := range prelude.Stmts() {
+		l.builtins[stmt.(parser.FunctionStmt).Name.Lexeme] = true
+	}
+	for i, m := range mods {
+		l.enter(m, envs[i])
+		l.declarations()
+	}
+	for i, m := range mods {
+		l.enter(m, envs[i])
+		l.definitions()
+	}
+	l.prog.Main = l.prog.Func(l.global("main"))
+	if l.prog.Main == nil {
+		return nil, fmt.Errorf("%s: no main function", mods[len(mods)-1].Path)
+	}
+	return l.prog, nil
+}
+
+func (l *lowerer) enter(m *loader.Module, env analysis.Env) {
+	l.mod = m
+	l.env = env
+	l.globals = map[string]bool{}
+	for _, stmt := range m.Stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			l.globals[s.Name.Lexeme] = true
+		case parser.VarStmt:
+			l.globals[s.Name.Lexeme] = true
+		}
+	}
+}
+
+// global returns the name of a top-level name in the current module.
+func (l *lowerer) global(name string) string {
+	return l.mod.Name + "." + name
+}
+
+// irType returns the type of values of type t. Enums are ints.
+func irType(t analysis.Type) Type {
+	switch t {
+	case analysis.Float:
+		return Float
+	case analysis.Bool:
+		return Bool
+	case analysis.String:
+		return String
+	case analysis.Void:
+		return Void
+	default:
+		return Int
+	}
+}
+
+// declarations adds the enums, variables and functions of the current
+// module to the program.
+func (l *lowerer) declarations() {
+	for _, stmt := range l.mod.Stmts {
+		switch s := stmt.(type) {
+		case parser.EnumStmt:
+			e := &Enum{Name: l.global(s.Name.Lexeme)}
+			for _, m := range s.Members {
+				e.Members = append(e.Members, m.Lexeme)
+			}
+			l.prog.Enums = append(l.prog.Enums, e)
+			l.enums[l.env.LookupType(s.Name.Lexeme).(*analysis.EnumType)] = e
+		case parser.VarStmt:
+			l.prog.Globals = append(l.prog.Globals, &Global{Name: l.global(s.Name.Lexeme), Type: irType(l.env.LookupType(s.Kind.Lexeme))})
+		case parser.FunctionStmt:
+			ft := analysis.TypeOf(l.env, parser.IdentExpr{Name: s.Name}).(analysis.FunctionType)
+			f := &Func{Name: l.global(s.Name.Lexeme), Return: irType(ft.Return), Extern: s.Extern, Variadic: ft.Variadic}
+			if s.Extern {
+				f.Name = s.Name.Lexeme
+			}
+			for i, p := range s.Params {
+				f.Params = append(f.Params, irType(ft.Params[i]))
+				f.ParamNames = append(f.ParamNames, p.Name.Lexeme)
+			}
+			l.prog.Funcs = append(l.prog.Funcs, f)
+		}
+	}
+}
+
+// definitions lowers the functions of the current module, and a function
+// that initializes its variables.
+func (l *lowerer) definitions() {
+	init := &Func{Name: "lang_init_" + l.mod.Name, Return: Void}
+	l.begin(init)
+	for _, stmt := range l.mod.Stmts {
+		if s, ok := stmt.(parser.VarStmt); ok {
+			t := irType(l.env.LookupType(s.Kind.Lexeme))
+			l.b.Emit(OpStore, Void, l.global(s.Name.Lexeme), l.value(s, t))
+		}
+	}
+	l.b.Return(nil)
+	l.b.Finish()
+	l.prog.Inits = append(l.prog.Inits, init)
+
+	for _, stmt := range l.mod.Stmts {
+		s, ok := stmt.(parser.FunctionStmt)
+		if !ok || s.Extern {
+			continue
+		}
+		f := l.prog.Func(l.global(s.Name.Lexeme))
+		env := l.env
+		l.env = analysis.NewBlockEnv(env)
+		l.begin(f)
+		for i, p := range s.Params {
+			l.env.Declare(p.Name.Lexeme, l.env.LookupType(p.Kind.Lexeme))
+			l.b.Write(l.declare(p.Name.Lexeme, f.Params[i]), l.b.Emit(OpParam, f.Params[i], i))
+		}
+		l.stmts(s.Body.Stmts)
+		if l.b.Block != nil {
+			if f.Return == Void {
+				l.b.Return(nil)
+			} else {
+				l.b.Return(l.b.Zero(f.Return))
+			}
+		}
+		l.b.Finish()
+		l.env = env
+	}
+}
+
+func (l *lowerer) begin(f *Func) {
+	l.b = NewBuilder(f)
+	l.scopes = []map[string]*Variable{{}}
+}
+
+func (l *lowerer) declare(name string, t Type) *Variable {
+	v := &Variable{Name: name, Type: t}
+	l.scopes[len(l.scopes)-1][name] = v
+	return v
+}
+
+// variable returns the local variable called name, or nil if name is a
+// module variable.
+func (l *lowerer) variable(name string) *Variable {
+	for i := len(l.scopes) - 1; i >= 0; i-- {
+		if v, ok := l.scopes[i][name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// value returns the initial value of a variable of type t.
+func (l *lowerer) value(s parser.VarStmt, t Type) *Value {
+	if s.Expr == nil {
+		return l.b.Zero(t)
+	}
+	return l.expr(s.Expr)
+}
+
+// assign assigns val to the variable called name.
+func (l *lowerer) assign(name string, val *Value) {
+	if v := l.variable(name); v != nil {
+		l.b.Write(v, val)
+	} else {
+		l.b.Emit(OpStore, Void, l.global(name), val)
+	}
+}
+
+func (l *lowerer) block(b parser.Block) {
+	env := l.env
+	l.env = analysis.NewBlockEnv(env)
+	l.scopes = append(l.scopes, map[string]*Variable{})
+	l.stmts(b.Stmts)
+	l.scopes = l.scopes[:len(l.scopes)-1]
+	l.env = env
+}
+
+func (l *lowerer) stmts(stmts []parser.Stmt) {
+	for _, stmt := range stmts {
+		l.stmt(stmt)
+	}
+}
+
+func (l *lowerer) stmt(stmt parser.Stmt) {
+	b := l.b
+	switch s := stmt.(type) {
+	case parser.VarStmt:
+		t := l.env.LookupType(s.Kind.Lexeme)
+		val := l.value(s, irType(t))
+		l.env.Declare(s.Name.Lexeme, t)
+		b.Write(l.declare(s.Name.Lexeme, irType(t)), val)
+	case parser.AssignStmt:
+		l.assign(s.Target.(scanner.Token).Lexeme, l.expr(s.Expr))
+	case parser.CompoundAssignStmt:
+		op := s.Op
+		op.Kind = parser.CompoundAssignOps[s.Op.Kind]
+		l.assign(s.Target.Lexeme, l.expr(parser.BinaryOp{Op: op, Left: parser.IdentExpr{Name: s.Target}, Right: s.Expr}))
+	case parser.IncDecStmt:
+		x := l.expr(parser.IdentExpr{Name: s.Target})
+		var one *Value
+		if x.Type == Float {
+			one = b.Const(Float, 1.0)
+		} else {
+			one = b.Const(Int, int64(1))
+		}
+		op := OpAdd
+		if s.Op.Kind == scanner.Dec {
+			op = OpSub
+		}
+		l.assign(s.Target.Lexeme, b.Emit(op, x.Type, nil, x, one))
+	case parser.ReturnStmt:
+		if s.Expr == nil {
+			b.Return(nil)
+		} else {
+			b.Return(l.expr(s.Expr))
+		}
+	case parser.IfStmt:
+		then, end := b.NewBlock(), b.NewBlock()
+		els := end
+		if len(s.Els.Stmts) > 0 {
+			els = b.NewBlock()
+		}
+		b.If(l.expr(s.Cond), then, els)
+		b.SetBlock(then)
+		b.Seal(then)
+		l.block(s.Then)
+		if b.Block != nil {
+			b.Jump(end)
+		}
+		if els != end {
+			b.SetBlock(els)
+			b.Seal(els)
+			l.block(s.Els)
+			if b.Block != nil {
+				b.Jump(end)
+			}
+		}
+		b.SetBlock(end)
+		b.Seal(end)
+	case parser.WhileStmt:
+		cond, body, end := b.NewBlock(), b.NewBlock(), b.NewBlock()
+		b.Jump(cond)
+		b.SetBlock(cond)
+		b.If(l.expr(s.Cond), body, end)
+		b.SetBlock(body)
+		b.Seal(body)
+		l.block(s.Body)
+		if b.Block != nil {
+			b.Jump(cond)
+		}
+		b.Seal(cond)
+		b.SetBlock(end)
+		b.Seal(end)
+	case parser.SwitchStmt:
+		l.switchStmt(s)
+	case parser.Block:
+		l.block(s)
+	default:
+		l.expr(s)
+	}
+}
+
+// switchStmt lowers a switch to a chain of comparisons that branch to the
+// bodies of its cases, which end by jumping past the others unless they
+// fall through.
+func (l *lowerer) switchStmt(s parser.SwitchStmt) {
+	b := l.b
+	value := l.expr(s.Expr)
+	end := b.NewBlock()
+	otherwise := end
+	var bodies []*Block
+	for range s.Cases {
+		bodies = append(bodies, b.NewBlock())
+	}
+	for i, c := range s.Cases {
+		for _, v := range c.Values {
+			next := b.NewBlock()
+			b.If(b.Emit(OpEq, Bool, nil, value, l.expr(v)), bodies[i], next)
+			b.SetBlock(next)
+			b.Seal(next)
+		}
+		if c.Default {
+			otherwise = bodies[i]
+		}
+	}
+	b.Jump(otherwise)
+	for i, c := range s.Cases {
+		b.SetBlock(bodies[i])
+		b.Seal(bodies[i])
+		body := c.Body
+		next := end
+		if n := len(body.Stmts); n > 0 {
+			if _, fallsThrough := body.Stmts[n-1].(parser.FallthroughStmt); fallsThrough && i < len(s.Cases)-1 {
+				body.Stmts = body.Stmts[:n-1]
+				next = bodies[i+1]
+			}
+		}
+		l.block(body)
+		if b.Block != nil {
+			b.Jump(next)
+		}
+	}
+	b.SetBlock(end)
+	b.Seal(end)
+}
+
+func (l *lowerer) expr(e parser.Expr) *Value {
+	b := l.b
+	switch n := e.(type) {
+	case parser.LiteralNum:
+		if analysis.TypeOf(l.env, n) == analysis.Float {
+			f, _ := strconv.ParseFloat(n.Value, 64)
+			return b.Const(Float, f)
+		}
+		i, _ := strconv.ParseInt(n.Value, 10, 64)
+		return b.Const(Int, i)
+	case parser.LiteralStr:
+		return b.Const(String, n.Value)
+	case parser.LiteralBool:
+		return b.Const(Bool, n.Value)
+	case parser.IdentExpr:
+		if v := l.variable(n.Name.Lexeme); v != nil {
+			return b.Read(v)
+		}
+		return b.Emit(OpLoad, irType(analysis.TypeOf(l.env, n)), l.global(n.Name.Lexeme))
+	case parser.MemberAccess:
+		if enum, isEnum := analysis.TypeNamed(l.env, n.Parent).(*analysis.EnumType); isEnum {
+			for i, m := range enum.Members {
+				if string(m) == n.Name.Lexeme {
+					return b.Const(Int, int64(i))
+				}
+			}
+		}
+		mt := analysis.TypeOf(l.env, n.Parent).(*analysis.ModuleType)
+		return b.Emit(OpLoad, irType(analysis.TypeOf(l.env, n)), string(mt.Name)+"."+n.Name.Lexeme)
+	case parser.FunctionCall:
+		return l.call(n)
+	case parser.UnaryOp:
+		x := l.expr(n.Expr)
+		switch n.Op.Kind {
+		case scanner.Minus:
+			return b.Emit(OpNeg, x.Type, nil, x)
+		case scanner.LNot:
+			return b.Emit(OpNot, Bool, nil, x)
+		default:
+			return b.Emit(OpCompl, Int, nil, x)
+		}
+	case parser.BinaryOp:
+		if n.Op.Kind == scanner.LAnd || n.Op.Kind == scanner.LOr {
+			return l.logical(n)
+		}
+		x, y := l.expr(n.Left), l.expr(n.Right)
+		op := binaryOps[n.Op.Kind]
+		t := x.Type
+		if op >= OpEq && op <= OpGe {
+			t = Bool
+		} else if t == String {
+			op = OpConcat
+		}
+		b.Pos = n.Op
+		v := b.Emit(op, t, nil, x, y)
+		b.Pos = scanner.Token{}
+		return v
+	case parser.TernaryExpr:
+		then, els, end := b.NewBlock(), b.NewBlock(), b.NewBlock()
+		b.If(l.expr(n.Cond), then, els)
+		b.SetBlock(then)
+		b.Seal(then)
+		x := l.expr(n.Then)
+		b.Jump(end)
+		b.SetBlock(els)
+		b.Seal(els)
+		y := l.expr(n.Els)
+		b.Jump(end)
+		b.SetBlock(end)
+		b.Seal(end)
+		return b.Phi(x.Type, x, y)
+	case parser.InterpolatedStr:
+		var s *Value
+		for _, part := range n.Parts {
+			if lit, ok := part.(parser.LiteralStr); ok && lit.Value == "" {
+				continue
+			}
+			x := l.toString(analysis.TypeOf(l.env, part), l.expr(part))
+			if s == nil {
+				s = x
+			} else {
+				s = b.Emit(OpConcat, String, nil, s, x)
+			}
+		}
+		if s == nil {
+			return b.Const(String, "")
+		}
+		return s
+	default:
+		panic(fmt.Sprintf("cannot lower %T", e))
+	}
+}
+
+// logical lowers && and ||, which only evaluate their right operand when
+// the left does not decide their value.
+func (l *lowerer) logical(n parser.BinaryOp) *Value {
+	b := l.b
+	x := l.expr(n.Left)
+	short := b.Const(Bool, n.Op.Kind == scanner.LOr)
+	right, end := b.NewBlock(), b.NewBlock()
+	if n.Op.Kind == scanner.LAnd {
+		b.If(x, right, end)
+	} else {
+		b.If(x, end, right)
+	}
+	b.SetBlock(right)
+	b.Seal(right)
+	y := l.expr(n.Right)
+	b.Jump(end)
+	b.SetBlock(end)
+	b.Seal(end)
+	return b.Phi(Bool, short, y)
+}
+
+func (l *lowerer) call(n parser.FunctionCall) *Value {
+	b := l.b
+	if t := analysis.TypeNamed(l.env, n.Callee); t != nil {
+		return l.convert(t, n.Args[0], parser.Pos(n))
+	}
+	var args []*Value
+	for _, arg := range n.Args {
+		x := l.expr(arg)
+		if _, isEnum := analysis.TypeOf(l.env, arg).(*analysis.EnumType); isEnum && l.isPrintf(n.Callee) {
+			x = l.toString(analysis.TypeOf(l.env, arg), x)
+		}
+		args = append(args, x)
+	}
+	ft := analysis.TypeOf(l.env, n.Callee).(analysis.FunctionType)
+	op, name := OpCall, ""
+	switch callee := n.Callee.(type) {
+	case parser.IdentExpr:
+		name = callee.Name.Lexeme
+		if l.builtins[name] && l.variable(name) == nil && !l.globals[name] {
+			op = OpBuiltin
+		} else if !extern(l.mod, name) {
+			name = l.global(name)
+		}
+	case parser.MemberAccess:
+		mt := analysis.TypeOf(l.env, callee.Parent).(*analysis.ModuleType)
+		name = string(mt.Name) + "." + callee.Name.Lexeme
+		for _, m := range l.mods {
+			if m.Name == string(mt.Name) && extern(m, callee.Name.Lexeme) {
+				name = callee.Name.Lexeme
+			}
+		}
+	}
+	b.Pos = parser.Pos(n)
+	v := b.Emit(op, irType(ft.Return), name, args...)
+	b.Pos = scanner.Token{}
+	return v
+}
+
+// isPrintf reports whether callee is the builtin printf, whose enum
+// arguments are passed as their names.
+func (l *lowerer) isPrintf(callee parser.Expr) bool {
+	id, ok := callee.(parser.IdentExpr)
+	return ok && id.Name.Lexeme == "printf" && l.variable("printf") == nil && !l.globals["printf"]
+}
+
+// extern reports whether name is a function declared extern in m.
+func extern(m *loader.Module, name string) bool {
+	for _, stmt := range m.Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && f.Name.Lexeme == name {
+			return f.Extern
+		}
+	}
+	return false
+}
+
+// toString converts x, of type t, to a string.
+func (l *lowerer) toString(t analysis.Type, x *Value) *Value {
+	if enum, isEnum := t.(*analysis.EnumType); isEnum {
+		return l.b.Emit(OpEnumName, String, l.enums[enum], x)
+	}
+	if t == analysis.String {
+		return x
+	}
+	return l.b.Emit(OpToString, String, nil, x)
+}
+
+// convert converts e to type t.
+func (l *lowerer) convert(t analysis.Type, e parser.Expr, pos scanner.Token) *Value {
+	b := l.b
+	x := l.expr(e)
+	from := analysis.TypeOf(l.env, e)
+	if from == t {
+		return x
+	}
+	b.Pos = pos
+	defer func() { b.Pos = scanner.Token{} }()
+	if enum, isEnum := t.(*analysis.EnumType); isEnum {
+		return b.Emit(OpToEnum, Int, l.enums[enum], x)
+	}
+	switch t {
+	case analysis.Int:
+		if from == analysis.Float {
+			return b.Emit(OpFloatToInt, Int, nil, x)
+		}
+		return x
+	case analysis.Float:
+		return b.Emit(OpIntToFloat, Float, nil, x)
+	default:
+		return l.toString(from, x)
+	}
+}
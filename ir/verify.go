@@ -0,0 +1,420 @@
+package ir
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type verifier struct {
+	prog *Program
+	f    *Func
+	errs []string
+}
+
+// Verify checks that p is well formed: that blocks are properly terminated
+// and linked, that phis match the predecessors of their blocks, that
+// definitions dominate their uses, and that operands, calls and module
+// variables have the right types. It reports every problem it finds.
+func Verify(p *Program) error {
+	v := &verifier{prog: p}
+	if p.Main == nil {
+		v.errorf("program has no main function")
+	}
+	for _, f := range p.Inits {
+		v.function(f)
+	}
+	for _, f := range p.Funcs {
+		v.function(f)
+	}
+	if len(v.errs) > 0 {
+		return errors.New(strings.Join(v.errs, "\n"))
+	}
+	return nil
+}
+
+func (v *verifier) errorf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if v.f != nil {
+		msg = v.f.Name + ": " + msg
+	}
+	v.errs = append(v.errs, msg)
+}
+
+func (v *verifier) function(f *Func) {
+	v.f = f
+	defer func() { v.f = nil }()
+	if f.Extern {
+		if len(f.Blocks) > 0 {
+			v.errorf("extern function has blocks")
+		}
+		return
+	}
+	if len(f.Blocks) == 0 {
+		v.errorf("function has no blocks")
+		return
+	}
+	if len(f.Blocks[0].Preds) > 0 {
+		v.errorf("entry block %s has predecessors", f.Blocks[0])
+	}
+	blocks := map[*Block]bool{}
+	values := map[int]*Value{}
+	for _, b := range f.Blocks {
+		if b.Func != f {
+			v.errorf("%s belongs to another function", b)
+		}
+		blocks[b] = true
+		for _, x := range b.Values {
+			if other, ok := values[x.ID]; ok && other != x {
+				v.errorf("%s is defined twice", x)
+			}
+			values[x.ID] = x
+		}
+	}
+	for _, b := range f.Blocks {
+		v.block(b, blocks)
+	}
+	order := postorder(f.Blocks[0])
+	if len(order) < len(f.Blocks) {
+		reached := map[*Block]bool{}
+		for _, b := range order {
+			reached[b] = true
+		}
+		for _, b := range f.Blocks {
+			if !reached[b] {
+				v.errorf("%s is unreachable", b)
+			}
+		}
+		return
+	}
+	v.dominance(f, dominators(order))
+}
+
+func (v *verifier) block(b *Block, blocks map[*Block]bool) {
+	switch b.Kind {
+	case Plain:
+		if len(b.Succs) != 1 {
+			v.errorf("%s has %d successors, not 1", b, len(b.Succs))
+		}
+		if b.Control != nil {
+			v.errorf("%s has a control value", b)
+		}
+	case If:
+		if len(b.Succs) != 2 {
+			v.errorf("%s has %d successors, not 2", b, len(b.Succs))
+		}
+		if b.Control == nil || b.Control.Type != Bool {
+			v.errorf("%s does not branch on a bool", b)
+		}
+	case Return:
+		if len(b.Succs) != 0 {
+			v.errorf("%s returns but has successors", b)
+		}
+		switch {
+		case b.Control == nil && b.Func.Return != Void:
+			v.errorf("%s returns no value", b)
+		case b.Control != nil && b.Control.Type != b.Func.Return:
+			v.errorf("%s returns %s, not %s", b, b.Control.Type, b.Func.Return)
+		}
+	default:
+		v.errorf("%s has unknown kind %d", b, b.Kind)
+	}
+	for _, s := range b.Succs {
+		if !blocks[s] {
+			v.errorf("successor %s of %s is not in the function", s, b)
+		} else if count(s.Preds, b) != count(b.Succs, s) {
+			v.errorf("%s is a successor of %s but not a predecessor", b, s)
+		}
+	}
+	for _, p := range b.Preds {
+		if !blocks[p] {
+			v.errorf("predecessor %s of %s is not in the function", p, b)
+		} else if count(p.Succs, b) != count(b.Preds, p) {
+			v.errorf("%s is a predecessor of %s but not a successor", p, b)
+		}
+	}
+	phis := true
+	for _, x := range b.Values {
+		if x.Block != b {
+			v.errorf("%s is in %s but belongs to another block", x, b)
+		}
+		if x.Op == OpPhi && !phis {
+			v.errorf("phi %s follows other instructions in %s", x, b)
+		}
+		phis = x.Op == OpPhi
+		v.value(x)
+	}
+}
+
+func count(blocks []*Block, b *Block) int {
+	n := 0
+	for _, x := range blocks {
+		if x == b {
+			n++
+		}
+	}
+	return n
+}
+
+// value checks the operands of x.
+func (v *verifier) value(x *Value) {
+	for _, arg := range x.Args {
+		if arg == nil {
+			v.errorf("%s has a nil operand", x)
+			return
+		}
+		if arg.Type == Void {
+			v.errorf("%s uses %s, which has no value", x, arg)
+		}
+	}
+	argc := func(n int) bool {
+		if len(x.Args) != n {
+			v.errorf("%s has %d operands, not %d", x, len(x.Args), n)
+			return false
+		}
+		return true
+	}
+	args := func(types ...Type) {
+		if !argc(len(types)) {
+			return
+		}
+		for i, t := range types {
+			if x.Args[i].Type != t {
+				v.errorf("operand %d of %s is %s, not %s", i, x.LongString(), x.Args[i].Type, t)
+			}
+		}
+	}
+	result := func(types ...Type) {
+		for _, t := range types {
+			if x.Type == t {
+				return
+			}
+		}
+		v.errorf("%s has type %s", x.LongString(), x.Type)
+	}
+	switch x.Op {
+	case OpConst:
+		argc(0)
+		ok := false
+		switch x.Aux.(type) {
+		case int64:
+			ok = x.Type == Int
+		case float64:
+			ok = x.Type == Float
+		case bool:
+			ok = x.Type == Bool
+		case string:
+			ok = x.Type == String
+		}
+		if !ok {
+			v.errorf("%s is not a constant of its type", x.LongString())
+		}
+	case OpParam:
+		argc(0)
+		i, ok := x.Aux.(int)
+		if !ok || i < 0 || i >= len(x.Block.Func.Params) {
+			v.errorf("%s is not a parameter", x.LongString())
+		} else if x.Type != x.Block.Func.Params[i] {
+			v.errorf("%s is not of the type of the parameter", x.LongString())
+		}
+	case OpPhi:
+		argc(len(x.Block.Preds))
+		for _, arg := range x.Args {
+			if arg != nil && arg.Type != x.Type {
+				v.errorf("%s merges %s, which is %s", x.LongString(), arg, arg.Type)
+			}
+		}
+	case OpLoad, OpStore:
+		name, _ := x.Aux.(string)
+		g := v.prog.Global(name)
+		switch {
+		case g == nil:
+			v.errorf("%s refers to no module variable", x.LongString())
+		case x.Op == OpLoad:
+			argc(0)
+			result(g.Type)
+		default:
+			args(g.Type)
+			result(Void)
+		}
+	case OpAdd, OpSub, OpMul, OpDiv:
+		result(Int, Float)
+		args(x.Type, x.Type)
+	case OpMod, OpShl, OpShr, OpAnd, OpOr, OpXor:
+		result(Int)
+		args(Int, Int)
+	case OpNeg:
+		result(Int, Float)
+		args(x.Type)
+	case OpNot:
+		result(Bool)
+		args(Bool)
+	case OpCompl:
+		result(Int)
+		args(Int)
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe:
+		result(Bool)
+		if argc(2) && x.Args[0].Type != x.Args[1].Type {
+			v.errorf("%s compares %s with %s", x.LongString(), x.Args[0].Type, x.Args[1].Type)
+		}
+	case OpIntToFloat:
+		result(Float)
+		args(Int)
+	case OpFloatToInt:
+		result(Int)
+		args(Float)
+	case OpToEnum, OpEnumName:
+		if _, ok := x.Aux.(*Enum); !ok {
+			v.errorf("%s has no enum", x.LongString())
+		}
+		if x.Op == OpToEnum {
+			result(Int)
+		} else {
+			result(String)
+		}
+		args(Int)
+	case OpToString:
+		result(String)
+		if argc(1) && x.Args[0].Type != Int && x.Args[0].Type != Float && x.Args[0].Type != Bool {
+			v.errorf("%s formats a %s", x.LongString(), x.Args[0].Type)
+		}
+	case OpConcat:
+		result(String)
+		args(String, String)
+	case OpCall:
+		name, _ := x.Aux.(string)
+		f := v.prog.Func(name)
+		if f == nil {
+			v.errorf("%s calls no function", x.LongString())
+			return
+		}
+		result(f.Return)
+		if len(x.Args) < len(f.Params) || len(x.Args) > len(f.Params) && !f.Variadic {
+			v.errorf("%s passes %d arguments to %s, which takes %d", x, len(x.Args), f.Name, len(f.Params))
+			return
+		}
+		for i, t := range f.Params {
+			if x.Args[i].Type != t {
+				v.errorf("argument %d of %s is %s, not %s", i, x.LongString(), x.Args[i].Type, t)
+			}
+		}
+	case OpBuiltin:
+		if _, ok := x.Aux.(string); !ok {
+			v.errorf("%s calls no builtin", x.LongString())
+		}
+	default:
+		v.errorf("%s has unknown op %d", x, x.Op)
+	}
+}
+
+// postorder returns the blocks reachable from entry in postorder.
+func postorder(entry *Block) []*Block {
+	var order []*Block
+	seen := map[*Block]bool{}
+	var visit func(*Block)
+	visit = func(b *Block) {
+		seen[b] = true
+		for _, s := range b.Succs {
+			if !seen[s] {
+				visit(s)
+			}
+		}
+		order = append(order, b)
+	}
+	visit(entry)
+	return order
+}
+
+// dominators returns the immediate dominator of each block, given in
+// postorder, following Cooper, Harvey and Kennedy, "A Simple, Fast
+// Dominance Algorithm". The entry block is its own.
+func dominators(order []*Block) map[*Block]*Block {
+	index := map[*Block]int{}
+	for i, b := range order {
+		index[b] = i
+	}
+	entry := order[len(order)-1]
+	idom := map[*Block]*Block{entry: entry}
+	intersect := func(a, b *Block) *Block {
+		for a != b {
+			for index[a] < index[b] {
+				a = idom[a]
+			}
+			for index[b] < index[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(order) - 2; i >= 0; i-- {
+			b := order[i]
+			var dom *Block
+			for _, p := range b.Preds {
+				if idom[p] == nil {
+					continue
+				}
+				if dom == nil {
+					dom = p
+				} else {
+					dom = intersect(p, dom)
+				}
+			}
+			if idom[b] != dom {
+				idom[b] = dom
+				changed = true
+			}
+		}
+	}
+	return idom
+}
+
+// dominance checks that every value is defined before it is used on every
+// path from the entry block.
+func (v *verifier) dominance(f *Func, idom map[*Block]*Block) {
+	dominates := func(a, b *Block) bool {
+		for {
+			if a == b {
+				return true
+			}
+			if idom[b] == b {
+				return false
+			}
+			b = idom[b]
+		}
+	}
+	// position is the index of each value in its block.
+	position := map[*Value]int{}
+	for _, b := range f.Blocks {
+		for i, x := range b.Values {
+			position[x] = i
+		}
+	}
+	defined := func(arg *Value) bool {
+		_, ok := position[arg]
+		return ok && arg.Block.Func == f
+	}
+	for _, b := range f.Blocks {
+		for i, x := range b.Values {
+			for j, arg := range x.Args {
+				switch {
+				case arg == nil:
+				case !defined(arg):
+					v.errorf("%s uses %s, which is not in the function", x, arg)
+				case x.Op == OpPhi:
+					if j < len(b.Preds) && !dominates(arg.Block, b.Preds[j]) {
+						v.errorf("%s does not dominate predecessor %s of %s", arg, b.Preds[j], x)
+					}
+				case arg.Block == b && position[arg] >= i, arg.Block != b && !dominates(arg.Block, b):
+					v.errorf("%s does not dominate its use by %s", arg, x)
+				}
+			}
+		}
+		if c := b.Control; c != nil {
+			if !defined(c) || !dominates(c.Block, b) {
+				v.errorf("control %s of %s does not dominate it", c, b)
+			}
+		}
+	}
+}
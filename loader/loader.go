@@ -0,0 +1,152 @@
+package loader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"lang/parser"
+	"lang/scanner"
+	"path/filepath"
+	"strings"
+)
+
+const ext = ".c"
+
+type Module struct {
+	Name   string
+	Path   string
+	Tokens []scanner.Token
+	// Comments are the comments in the module's source, as returned by
+	// scanner.ScanComments.
+	Comments []scanner.Token
+	Stmts    []parser.Stmt
+	Imports  []*Module
+}
+
+// Extern reports whether name is a function declared extern in m, which
+// the backends call by its own name rather than a mangled one.
+func (m *Module) Extern(name string) bool {
+	for _, stmt := range m.Stmts {
+		if f, ok := stmt.(parser.FunctionStmt); ok && f.Name.Lexeme == name {
+			return f.Extern
+		}
+	}
+	return false
+}
+
+type loader struct {
+	root    string
+	sources map[string]string
+	loaded  map[string]*Module
+	loading []string
+	order   []*Module
+}
+
+// Load reads the module at path along with every module it imports,
+// directly or not. Import paths are resolved relative to the directory
+// containing path. The returned modules are ordered so that each one comes
+// after all of its imports, which puts the module at path last.
+func Load(path string) ([]*Module, error) {
+	return LoadSource(path, "")
+}
+
+// LoadSource is like Load, but uses src as the contents of the module at
+// path instead of reading it from disk, unless src is empty.
+func LoadSource(path string, src string) ([]*Module, error) {
+	l := loader{
+		root:    filepath.Dir(path),
+		sources: map[string]string{},
+		loaded:  map[string]*Module{},
+	}
+	path = filepath.Clean(path)
+	if src != "" {
+		l.sources[path] = src
+	}
+	if _, err := l.load(path); err != nil {
+		return nil, err
+	}
+	return l.order, nil
+}
+
+func (l *loader) read(path string) (string, error) {
+	if src, ok := l.sources[path]; ok {
+		return src, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	return string(b), err
+}
+
+// parse scans and parses src, turning the panics the scanner and parser
+// raise on malformed input into an error.
+func parse(path string, src string) (tokens, comments []scanner.Token, stmts []parser.Stmt, err error) {
+	var p parser.Parser
+	defer func() {
+		if r := recover(); r != nil {
+			msg, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			if p.Tokens == nil {
+				err = fmt.Errorf("%s: %s", path, msg)
+			} else {
+				t := p.Current()
+				err = fmt.Errorf("%s:%d:%d: %s", path, t.Row+1, t.Col+1, msg)
+			}
+		}
+	}()
+	tokens, comments = scanner.ScanComments(src)
+	p = parser.Parser{Tokens: tokens}
+	return tokens, comments, p.ConsumeTopLevelStmts(), nil
+}
+
+func (l *loader) resolve(importPath string) string {
+	return filepath.Join(l.root, filepath.FromSlash(importPath)+ext)
+}
+
+func (l *loader) load(path string) (*Module, error) {
+	if m, ok := l.loaded[path]; ok {
+		return m, nil
+	}
+	for i, p := range l.loading {
+		if p == path {
+			cycle := append(append([]string{}, l.loading[i:]...), path)
+			return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " imports "))
+		}
+	}
+	src, err := l.read(path)
+	if err != nil {
+		return nil, err
+	}
+	tokens, comments, stmts, err := parse(path, src)
+	if err != nil {
+		return nil, err
+	}
+	l.loading = append(l.loading, path)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	m := &Module{
+		Name:     strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path:     path,
+		Tokens:   tokens,
+		Comments: comments,
+		Stmts:    stmts,
+	}
+	for _, stmt := range m.Stmts {
+		switch s := stmt.(type) {
+		case parser.ModuleStmt:
+			m.Name = s.Name.Lexeme
+		case parser.ImportStmt:
+			importPath := l.resolve(s.Path.Lexeme)
+			if _, err := l.read(importPath); err != nil {
+				return nil, fmt.Errorf("%s: cannot find module %q", path, s.Path.Lexeme)
+			}
+			imported, err := l.load(importPath)
+			if err != nil {
+				return nil, err
+			}
+			m.Imports = append(m.Imports, imported)
+		}
+	}
+	l.loaded[path] = m
+	l.order = append(l.order, m)
+	return m, nil
+}
@@ -0,0 +1,547 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/loader"
+	"lang/parser"
+	"lang/scanner"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var keywords = []string{
+	"bool", "float", "int", "string", "void",
+	"if", "else", "while", "switch", "case", "default", "fallthrough", "return",
+	"true", "false", "null",
+	"module", "import", "export", "extern", "enum",
+}
+
+type document struct {
+	uri   string
+	path  string
+	lines []string
+	// index is from the last version of the document that parsed, so that
+	// names can still be looked up while it is being edited.
+	index *analysis.Index
+}
+
+type server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*document
+	shutdown bool
+}
+
+// Serve runs a language server on r and w, which are the standard input and
+// output of the server process for an editor, until the client asks it to
+// exit.
+func Serve(r io.Reader, w io.Writer) error {
+	s := &server{
+		in:   bufio.NewReader(r),
+		out:  w,
+		docs: map[string]*document{},
+	}
+	for {
+		body, err := s.read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.write(errorResponse{JSONRPC: "2.0", Error: &responseError{parseError, err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			return nil
+		}
+		result, err := s.handle(req)
+		if req.ID == nil {
+			continue
+		}
+		var resp interface{} = response{JSONRPC: "2.0", ID: req.ID, Result: result}
+		if err != nil {
+			rerr, ok := err.(*responseError)
+			if !ok {
+				rerr = &responseError{invalidParams, err.Error()}
+			}
+			resp = errorResponse{JSONRPC: "2.0", ID: req.ID, Error: rerr}
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (e *responseError) Error() string {
+	return e.Message
+}
+
+// read reads the body of the next message, which is preceded by headers.
+func (s *server) read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if i := strings.Index(line, ":"); i >= 0 && strings.EqualFold(line[:i], "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(line[i+1:])); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %v", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	_, err := io.ReadFull(s.in, body)
+	return body, err
+}
+
+func (s *server) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (s *server) handle(req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		var result initializeResult
+		result.Capabilities.TextDocumentSync = 1
+		result.Capabilities.HoverProvider = true
+		result.Capabilities.DefinitionProvider = true
+		result.Capabilities.ReferencesProvider = true
+		result.Capabilities.DocumentSymbolProvider = true
+		result.Capabilities.CompletionProvider.TriggerCharacters = []string{"."}
+		result.ServerInfo.Name = "lang"
+		return result, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			return nil, s.update(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.publish(params.TextDocument.URI, nil)
+	case "textDocument/hover":
+		return s.withPosition(req.Params, s.hover)
+	case "textDocument/definition":
+		return s.withPosition(req.Params, s.definition)
+	case "textDocument/references":
+		var params struct {
+			textDocumentPositionParams
+			Context struct {
+				IncludeDeclaration bool `json:"includeDeclaration"`
+			} `json:"context"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.withPosition(req.Params, func(d *document, row, col int) interface{} {
+			return s.references(d, row, col, params.Context.IncludeDeclaration)
+		})
+	case "textDocument/documentSymbol":
+		var params documentSymbolParams
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, err
+		}
+		d, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil, fmt.Errorf("unknown document %s", params.TextDocument.URI)
+		}
+		return s.symbols(d), nil
+	case "textDocument/completion":
+		return s.withPosition(req.Params, s.completion)
+	default:
+		if strings.HasPrefix(req.Method, "$/") || req.ID == nil {
+			return nil, nil
+		}
+		return nil, &responseError{methodNotFound, "method not supported: " + req.Method}
+	}
+}
+
+// withPosition calls f with the document and the byte position within it
+// that params refer to.
+func (s *server) withPosition(params json.RawMessage, f func(d *document, row, col int) interface{}) (interface{}, error) {
+	var p textDocumentPositionParams
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	d, ok := s.docs[p.TextDocument.URI]
+	if !ok {
+		return nil, fmt.Errorf("unknown document %s", p.TextDocument.URI)
+	}
+	row := p.Position.Line
+	col := 0
+	if row < len(d.lines) {
+		col = byteCol(d.lines[row], p.Position.Character)
+	}
+	return f(d, row, col), nil
+}
+
+func (s *server) update(uri string, text string) error {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return err
+	}
+	d, ok := s.docs[uri]
+	if !ok {
+		d = &document{uri: uri, path: path}
+		s.docs[uri] = d
+	}
+	d.lines = strings.Split(text, "\n")
+	return s.publish(uri, s.check(d, text))
+}
+
+// check type checks a document along with the modules it imports, which
+// are read from disk, and indexes them if it parses.
+func (s *server) check(d *document, text string) []diagnostic {
+	if pos, msg, ok := syntaxError(text); ok {
+		return []diagnostic{s.diagnostic(d.path, pos, msg, false)}
+	}
+	mods, err := loader.LoadSource(d.path, text)
+	if err != nil {
+		return []diagnostic{s.diagnostic(d.path, scanner.Token{}, err.Error(), false)}
+	}
+	universe := analysis.NewUniverse(ioutil.Discard)
+	analysis.CheckIn(universe, mods)
+	diags := []diagnostic{}
+	for _, diag := range universe.Diagnostics() {
+		if diag.Path == d.path {
+			diags = append(diags, s.diagnostic(d.path, diag.Pos, diag.Msg, diag.Warning))
+		}
+	}
+	d.index = analysis.NewIndex(analysis.NewUniverse(ioutil.Discard), mods)
+	return diags
+}
+
+func syntaxError(text string) (pos scanner.Token, msg string, failed bool) {
+	var p parser.Parser
+	defer func() {
+		if r := recover(); r != nil {
+			m, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			if p.Tokens != nil {
+				pos = p.Current()
+			}
+			msg, failed = m, true
+		}
+	}()
+	p.Tokens = scanner.Scan(text)
+	p.ConsumeTopLevelStmts()
+	return scanner.Token{}, "", false
+}
+
+func (s *server) diagnostic(path string, pos scanner.Token, msg string, warning bool) diagnostic {
+	severity := severityError
+	if warning {
+		severity = severityWarning
+	}
+	return diagnostic{Range: s.tokenRange(path, pos), Severity: severity, Source: "lang", Message: msg}
+}
+
+func (s *server) publish(uri string, diags []diagnostic) error {
+	if diags == nil {
+		diags = []diagnostic{}
+	}
+	return s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  publishDiagnosticsParams{URI: uri, Diagnostics: diags},
+	})
+}
+
+func (s *server) hover(d *document, row, col int) interface{} {
+	if d.index == nil {
+		return nil
+	}
+	ref := d.index.RefAt(d.path, row, col)
+	if ref == nil {
+		return nil
+	}
+	return hoverResult{
+		Contents: markupContent{Kind: "markdown", Value: "```\n" + ref.Decl.String() + "\n```"},
+		Range:    s.tokenRange(d.path, ref.Name),
+	}
+}
+
+func (s *server) definition(d *document, row, col int) interface{} {
+	if d.index == nil {
+		return nil
+	}
+	ref := d.index.RefAt(d.path, row, col)
+	if ref == nil || ref.Decl.Path == "" {
+		return nil
+	}
+	return s.location(ref.Decl.Path, ref.Decl.Name)
+}
+
+func (s *server) references(d *document, row, col int, includeDecl bool) interface{} {
+	if d.index == nil {
+		return nil
+	}
+	ref := d.index.RefAt(d.path, row, col)
+	if ref == nil {
+		return nil
+	}
+	locs := []location{}
+	for _, r := range d.index.RefsTo(ref.Decl) {
+		if !includeDecl && r.Path == ref.Decl.Path && r.Name == ref.Decl.Name {
+			continue
+		}
+		locs = append(locs, s.location(r.Path, r.Name))
+	}
+	return locs
+}
+
+func (s *server) symbols(d *document) []documentSymbol {
+	symbols := []documentSymbol{}
+	if d.index == nil {
+		return symbols
+	}
+	for _, decl := range d.index.Module(d.path) {
+		if decl.Path != d.path {
+			continue
+		}
+		sym := documentSymbol{
+			Name:           decl.Name.Lexeme,
+			Detail:         decl.String(),
+			Range:          s.tokenRange(d.path, decl.Name),
+			SelectionRange: s.tokenRange(d.path, decl.Name),
+		}
+		switch stmt := decl.Stmt.(type) {
+		case parser.FunctionStmt:
+			sym.Kind = symbolFunction
+			if !stmt.Extern {
+				sym.Range = s.span(d.path, stmt.ReturnKind, stmt.Body.Close)
+			}
+		case parser.EnumStmt:
+			sym.Kind = symbolEnum
+			for _, m := range decl.Members {
+				r := s.tokenRange(d.path, m.Name)
+				sym.Children = append(sym.Children, documentSymbol{Name: m.Name.Lexeme, Kind: symbolEnumMember, Range: r, SelectionRange: r})
+			}
+		default:
+			sym.Kind = symbolVariable
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols
+}
+
+func (s *server) completion(d *document, row, col int) interface{} {
+	items := []completionItem{}
+	if d.index == nil || row >= len(d.lines) {
+		return items
+	}
+	line := d.lines[row]
+	if col > len(line) {
+		col = len(line)
+	}
+	// Complete the members of what precedes a dot before the word being
+	// typed, if there is one.
+	start := col
+	for start > 0 && isIdentChar(line[start-1]) {
+		start--
+	}
+	if start > 0 && line[start-1] == '.' {
+		var decl *analysis.Decl
+		for i, name := range qualifier(line[:start-1]) {
+			if i == 0 {
+				decl = lookup(d.index.Visible(d.path, row, col), name)
+			} else if decl != nil {
+				decl = lookup(decl.Members, name)
+			}
+		}
+		if decl != nil {
+			for _, m := range decl.Members {
+				items = append(items, completionItem{Label: m.Name.Lexeme, Kind: completionKind(m), Detail: m.String()})
+			}
+		}
+		return items
+	}
+
+	for _, decl := range d.index.Visible(d.path, row, col) {
+		items = append(items, completionItem{Label: decl.Name.Lexeme, Kind: completionKind(decl), Detail: decl.String()})
+	}
+	for _, k := range keywords {
+		items = append(items, completionItem{Label: k, Kind: completionKeyword})
+	}
+	return items
+}
+
+// qualifier returns the names in the chain of member accesses that s ends
+// with.
+func qualifier(s string) []string {
+	var names []string
+	end := len(s)
+	for {
+		start := end
+		for start > 0 && isIdentChar(s[start-1]) {
+			start--
+		}
+		if start == end {
+			return nil
+		}
+		names = append([]string{s[start:end]}, names...)
+		if start == 0 || s[start-1] != '.' {
+			return names
+		}
+		end = start - 1
+	}
+}
+
+func isIdentChar(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '_'
+}
+
+func lookup(decls []*analysis.Decl, name string) *analysis.Decl {
+	for _, d := range decls {
+		if d.Name.Lexeme == name {
+			return d
+		}
+	}
+	return nil
+}
+
+func completionKind(d *analysis.Decl) int {
+	switch d.Kind {
+	case analysis.FunctionDecl:
+		return completionFunction
+	case analysis.EnumDecl:
+		return completionEnum
+	case analysis.MemberDecl:
+		return completionEnumMember
+	case analysis.ModuleDecl:
+		return completionModule
+	default:
+		return completionVariable
+	}
+}
+
+func (s *server) location(path string, t scanner.Token) location {
+	return location{URI: pathToURI(path), Range: s.tokenRange(path, t)}
+}
+
+// lines returns the lines of the file at path, preferring an open document
+// over what is on disk.
+func (s *server) lines(path string) []string {
+	for _, d := range s.docs {
+		if d.path == path {
+			return d.lines
+		}
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return strings.Split(string(b), "\n")
+}
+
+func (s *server) tokenRange(path string, t scanner.Token) textRange {
+	return s.span(path, t, t)
+}
+
+// span returns the range from the start of one token to the end of another.
+func (s *server) span(path string, from, to scanner.Token) textRange {
+	lines := s.lines(path)
+	return textRange{
+		Start: toPosition(lines, from.Row, from.Col),
+		End:   toPosition(lines, to.Row, to.Col+to.Len()),
+	}
+}
+
+// toPosition converts a byte position to one counting UTF-16 code units, as
+// the protocol does.
+func toPosition(lines []string, row, col int) position {
+	if row < 0 || row >= len(lines) || col < 0 {
+		return position{Line: row, Character: col}
+	}
+	line := lines[row]
+	if col > len(line) {
+		col = len(line)
+	}
+	n := 0
+	for _, r := range line[:col] {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return position{Line: row, Character: n}
+}
+
+// byteCol converts a column counting UTF-16 code units to a byte offset.
+func byteCol(line string, char int) int {
+	n := 0
+	for i, r := range line {
+		if n >= char {
+			return i
+		}
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return len(line)
+}
+
+func uriToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme %q", u.Scheme)
+	}
+	return filepath.Clean(filepath.FromSlash(u.Path)), nil
+}
+
+func pathToURI(path string) string {
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// client talks to a server running Serve over in-memory pipes, reusing the
+// server's own framing of messages.
+type client struct {
+	t    *testing.T
+	conn *server
+	id   int
+	done chan error
+}
+
+func newClient(t *testing.T) *client {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := &client{
+		t:    t,
+		conn: &server{in: bufio.NewReader(outR), out: inW},
+		done: make(chan error, 1),
+	}
+	go func() {
+		err := Serve(inR, outW)
+		outW.Close()
+		c.done <- err
+	}()
+	return c
+}
+
+func (c *client) send(id *json.RawMessage, method string, params interface{}) {
+	c.t.Helper()
+	body, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.conn.write(request{JSONRPC: "2.0", ID: id, Method: method, Params: body}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive reads the next message from the server into v.
+func (c *client) receive(v interface{}) {
+	c.t.Helper()
+	body, err := c.conn.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		c.t.Fatalf("%v in %s", err, body)
+	}
+}
+
+// call sends a request and decodes the result of its response into
+// result.
+func (c *client) call(method string, params interface{}, result interface{}) {
+	c.t.Helper()
+	c.id++
+	id := json.RawMessage(strconv.Itoa(c.id))
+	c.send(&id, method, params)
+	var resp struct {
+		ID     int
+		Result json.RawMessage
+		Error  *responseError
+	}
+	c.receive(&resp)
+	if resp.ID != c.id {
+		c.t.Fatalf("got response to request %d, want %d", resp.ID, c.id)
+	}
+	if resp.Error != nil {
+		c.t.Fatalf("%s: %s", method, resp.Error.Message)
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		c.t.Fatalf("%v in %s", err, resp.Result)
+	}
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	c.send(nil, method, params)
+}
+
+func TestServer(t *testing.T) {
+	c := newClient(t)
+
+	var init initializeResult
+	c.call("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &init)
+	if !init.Capabilities.DefinitionProvider || init.Capabilities.TextDocumentSync != 1 || init.ServerInfo.Name != "lang" {
+		t.Errorf("unexpected initialize result %+v", init)
+	}
+	c.notify("initialized", struct{}{})
+
+	path, err := filepath.Abs(filepath.Join("testdata", "main.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(path)
+	text := "int twice(int n) {\n  return n * 2;\n}\n\nint main() {\n  string s = twice(1);\n  int unused = 0;\n  return twice(2);\n}\n"
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "lang", "version": 1, "text": text},
+	})
+	var published struct {
+		Method string
+		Params publishDiagnosticsParams
+	}
+	c.receive(&published)
+	at := func(line, start, end int) textRange {
+		return textRange{Start: position{Line: line, Character: start}, End: position{Line: line, Character: end}}
+	}
+	want := publishDiagnosticsParams{URI: uri, Diagnostics: []diagnostic{
+		{Range: at(5, 13, 18), Severity: severityError, Source: "lang", Message: "cannot use int value as string in declaration of s"},
+		{Range: at(5, 9, 10), Severity: severityWarning, Source: "lang", Message: "variable s is unused"},
+		{Range: at(6, 6, 12), Severity: severityWarning, Source: "lang", Message: "variable unused is unused"},
+	}}
+	if published.Method != "textDocument/publishDiagnostics" || !reflect.DeepEqual(published.Params, want) {
+		t.Errorf("didOpen published %+v, want %+v", published, want)
+	}
+
+	for _, tt := range []struct {
+		pos  position
+		want *location
+	}{
+		// twice in return twice(2), and its parameter n.
+		{pos: position{Line: 7, Character: 10}, want: &location{URI: uri, Range: at(0, 4, 9)}},
+		{pos: position{Line: 1, Character: 9}, want: &location{URI: uri, Range: at(0, 14, 15)}},
+		// Keywords have no definition.
+		{pos: position{Line: 7, Character: 3}, want: nil},
+	} {
+		var got *location
+		c.call("textDocument/definition", textDocumentPositionParams{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Position:     tt.pos,
+		}, &got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("definition at %+v = %+v, want %+v", tt.pos, got, tt.want)
+		}
+	}
+
+	var null interface{}
+	c.call("shutdown", nil, &null)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+}
+
+// TestServerShadowing looks up names where a local variable shadows a
+// global one: the local's initializer still refers to the global, and the
+// names after it to the local.
+func TestServerShadowing(t *testing.T) {
+	c := newClient(t)
+	var init initializeResult
+	c.call("initialize", map[string]interface{}{"capabilities": map[string]interface{}{}}, &init)
+	c.notify("initialized", struct{}{})
+
+	path, err := filepath.Abs(filepath.Join("testdata", "shadow.c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri := pathToURI(path)
+	text := "int x = 1;\n\nint main() {\n  string x = \"${x}\";\n  println(x);\n  return 0;\n}\n"
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "lang", "version": 1, "text": text},
+	})
+	var published struct{ Method string }
+	c.receive(&published)
+	at := func(line, start, end int) textRange {
+		return textRange{Start: position{Line: line, Character: start}, End: position{Line: line, Character: end}}
+	}
+
+	for _, tt := range []struct {
+		pos   position
+		want  location
+		hover string
+	}{
+		// x in the initializer of the local x.
+		{pos: position{Line: 3, Character: 16}, want: location{URI: uri, Range: at(0, 4, 5)}, hover: "int x"},
+		// x in println(x).
+		{pos: position{Line: 4, Character: 10}, want: location{URI: uri, Range: at(3, 9, 10)}, hover: "string x"},
+	} {
+		params := textDocumentPositionParams{TextDocument: textDocumentIdentifier{URI: uri}, Position: tt.pos}
+		var got location
+		c.call("textDocument/definition", params, &got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("definition at %+v = %+v, want %+v", tt.pos, got, tt.want)
+		}
+		var hover hoverResult
+		c.call("textDocument/hover", params, &hover)
+		if want := "```\n" + tt.hover + "\n```"; hover.Contents.Value != want {
+			t.Errorf("hover at %+v = %q, want %q", tt.pos, hover.Contents.Value, want)
+		}
+	}
+
+	var null interface{}
+	c.call("shutdown", nil, &null)
+	c.notify("exit", nil)
+	if err := <-c.done; err != nil {
+		t.Errorf("Serve returned %v", err)
+	}
+}
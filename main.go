@@ -1,27 +1,252 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
-	"github.com/kr/pretty"
+	"io"
+	"io/ioutil"
 	"lang/analysis"
-	"lang/parser"
-	"lang/scanner"
+	"lang/astjson"
+	"lang/codegen/amd64"
+	"lang/codegen/c"
+	"lang/codegen/llvm"
+	"lang/codegen/wat"
+	"lang/format"
+	"lang/interp"
+	"lang/ir"
+	"lang/loader"
+	"lang/lsp"
+	"lang/optimize"
+	"lang/repl"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 )
 
 func main() {
-	tokens := scanner.Scan()
+	if len(os.Args) > 1 && os.Args[1] == "repl" {
+		if err := repl.Run(os.Stdin, os.Stdout); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "fmt" {
+		os.Exit(runFmt(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "build" {
+		os.Exit(runBuild(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		if err := lsp.Serve(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	for _, t := range tokens {
-		fmt.Printf("%s\t%q\n", t.Kind, t.Lexeme)
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: lang file [arg ...]\n       lang repl\n       lang fmt [-w] [-d] [file ...]\n       lang build [-target=c|wat|amd64|llvm|ir|elf] [-o output] [-dump] [-shadow] file\n       lang lsp")
+		os.Exit(2)
+	}
+	path := os.Args[1]
+	mods, err := loader.Load(path)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
 
-	p := parser.Parser{Tokens: tokens}
-	stmts := p.ConsumeTopLevelStmts()
-	for _, stmt := range stmts {
-		_, err := pretty.Println(stmt)
+	for _, m := range mods {
+		tokens, err := astjson.MarshalTokens(m.Tokens)
 		if err != nil {
 			panic(err)
 		}
+		os.Stdout.Write(tokens)
+		stmts, err := astjson.Marshal(m.Stmts)
+		if err != nil {
+			panic(err)
+		}
+		os.Stdout.Write(stmts)
+	}
+	ok := analysis.Check(mods)
+	println("Typecheck:", ok)
+	if !ok {
+		os.Exit(1)
+	}
+	optimize.Program(mods, nil)
+
+	in, err := interp.New(mods, nil)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	var args []interp.Value
+	if len(os.Args) > 2 {
+		for _, arg := range os.Args[2:] {
+			args = append(args, parseArg(arg))
+		}
+	}
+	result, err := in.Call("main", args...)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	println("Result:", fmt.Sprint(result))
+}
+
+func parseArg(arg string) interp.Value {
+	if i, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		return i
+	} else if f, err := strconv.ParseFloat(arg, 64); err == nil {
+		return f
+	} else if b, err := strconv.ParseBool(arg); err == nil {
+		return b
+	}
+	return arg
+}
+
+// runBuild compiles the program in args to the target language and returns
+// the exit status.
+func runBuild(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	target := flags.String("target", "c", "the language to compile to, c, wat, amd64 or llvm, ir to dump the intermediate representation, or elf for an x86-64 Linux executable")
+	output := flags.String("o", "", "the file to write, by default the input's base name with a .gen.c, .wat, .s, .ll or .ir extension, or none for an executable")
+	dump := flags.Bool("dump", false, "write what each optimization pass changes to standard error, as a diff")
+	shadow := flags.Bool("shadow", false, "warn about variables that hide another of an enclosing scope")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lang build [-target=c|wat|amd64|llvm|ir|elf] [-o output] [-dump] [-shadow] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	generate, ext := c.Generate, ".gen.c"
+	switch *target {
+	case "c":
+	case "wat":
+		generate, ext = wat.Generate, ".wat"
+	case "amd64":
+		generate, ext = amd64.Generate, ".s"
+	case "llvm":
+		generate, ext = llvm.Generate, ".ll"
+	case "ir":
+		generate, ext = dumpIR, ".ir"
+	case "elf":
+		ext = ""
+	default:
+		fmt.Fprintf(os.Stderr, "unknown target %q\n", *target)
+		return 2
+	}
+
+	path := flags.Arg(0)
+	mods, err := loader.Load(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	universe := analysis.NewUniverse(os.Stdout)
+	universe.WarnShadowing = *shadow
+	if !analysis.CheckIn(universe, mods) {
+		return 1
+	}
+	var dumpTo io.Writer
+	if *dump {
+		dumpTo = os.Stderr
+	}
+	optimize.Program(mods, dumpTo)
+	if *output == "" {
+		*output = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)) + ext
+	}
+	if *target == "elf" {
+		if err := amd64.Build(*output, mods); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	var out bytes.Buffer
+	if err := generate(&out, mods); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := ioutil.WriteFile(*output, out.Bytes(), 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// dumpIR writes the intermediate representation of mods to w.
+func dumpIR(w io.Writer, mods []*loader.Module) error {
+	prog, err := ir.Lower(mods)
+	if err != nil {
+		return err
+	}
+	if err := ir.Verify(prog); err != nil {
+		return err
+	}
+	return ir.Fprint(w, prog)
+}
+
+// runFmt formats each file in args, or standard input if there are none,
+// and returns the exit status.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of standard output")
+	diff := flags.Bool("d", false, "print a diff of the changes instead of the result")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lang fmt [-w] [-d] [file ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	status := 0
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			return 2
+		}
+		src, err := ioutil.ReadAll(os.Stdin)
+		if err == nil {
+			err = formatFile("<standard input>", src, false, *diff)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+		return status
+	}
+	for _, path := range flags.Args() {
+		src, err := ioutil.ReadFile(path)
+		if err == nil {
+			err = formatFile(path, src, *write, *diff)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatFile(path string, src []byte, write, diff bool) error {
+	out, err := format.Source(src)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if diff {
+		os.Stdout.Write(format.Diff(path+".orig", path, src, out))
+	}
+	if write {
+		if !bytes.Equal(src, out) {
+			return ioutil.WriteFile(path, out, 0644)
+		}
+	} else if !diff {
+		os.Stdout.Write(out)
 	}
-	println("Typecheck:", analysis.Check(stmts))
+	return nil
 }
@@ -0,0 +1,86 @@
+package optimize
+
+import (
+	"io/ioutil"
+	"lang/analysis"
+	"lang/loader"
+	"lang/parser"
+	"lang/scanner"
+)
+
+// RemoveDeadCode removes the statements of mods that can never run because
+// they follow one that terminates, and the functions that are not used by
+// the main function of the last module or the initializers of module
+// variables.
+func RemoveDeadCode(mods []*loader.Module) {
+	for _, m := range mods {
+		m.Stmts = live(m.Stmts)
+	}
+	ix := analysis.NewIndex(analysis.NewUniverse(ioutil.Discard), mods)
+	var roots []*analysis.Decl
+	for i, m := range mods {
+		for _, d := range ix.Module(m.Path) {
+			switch s := d.Stmt.(type) {
+			case parser.FunctionStmt:
+				if i == len(mods)-1 && s.Name.Lexeme == "main" {
+					roots = append(roots, d)
+				}
+			case parser.VarStmt:
+				roots = append(roots, d)
+			}
+		}
+	}
+	reached := ix.Reachable(roots)
+	for _, m := range mods {
+		dead := map[scanner.Token]bool{}
+		for _, d := range ix.Module(m.Path) {
+			if _, ok := d.Stmt.(parser.FunctionStmt); ok && !reached[d] {
+				dead[d.Name] = true
+			}
+		}
+		var stmts []parser.Stmt
+		for _, stmt := range m.Stmts {
+			if f, ok := stmt.(parser.FunctionStmt); ok && dead[f.Name] {
+				continue
+			}
+			stmts = append(stmts, stmt)
+		}
+		m.Stmts = stmts
+	}
+}
+
+// live returns stmts without those that follow one that terminates, in
+// them and in the blocks they contain.
+func live(stmts []parser.Stmt) []parser.Stmt {
+	var out []parser.Stmt
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			s.Body.Stmts = live(s.Body.Stmts)
+			stmt = s
+		case parser.IfStmt:
+			s.Then.Stmts = live(s.Then.Stmts)
+			s.Els.Stmts = live(s.Els.Stmts)
+			stmt = s
+		case parser.WhileStmt:
+			s.Body.Stmts = live(s.Body.Stmts)
+			stmt = s
+		case parser.SwitchStmt:
+			cases := make([]parser.SwitchCase, len(s.Cases))
+			for i, c := range s.Cases {
+				c.Body.Stmts = live(c.Body.Stmts)
+				cases[i] = c
+			}
+			s.Cases = cases
+			stmt = s
+		case parser.Block:
+			s.Stmts = live(s.Stmts)
+			stmt = s
+		}
+		out = append(out, stmt)
+		if analysis.Terminates(stmt) {
+			break
+		}
+	}
+	return out
+}
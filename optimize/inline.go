@@ -0,0 +1,608 @@
+package optimize
+
+import (
+	"fmt"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/loader"
+	"lang/parser"
+	"lang/scanner"
+)
+
+// maxInlineSize is the most statements and expressions that the body of a
+// function can have to be inlined without an @inline annotation.
+const maxInlineSize = 16
+
+// Inline replaces calls to small functions of the same module by their
+// bodies. Functions annotated @inline are inlined whatever their size, and
+// those annotated @noinline never are. Neither are functions that call
+// themselves, directly or through others, or that return anywhere but at
+// the end of their bodies.
+//
+// A call within an expression is replaced by the expression a function
+// returns when its arguments are literals or local variables, which can be
+// substituted for its parameters. A call that is the whole of an
+// expression statement, a declaration, an assignment or a return
+// statement is replaced by a block that declares the parameters and runs
+// the body, with its variables renamed so as not to clash with those of
+// the caller. Calls are left alone where a local variable of the caller
+// would hide a name that the function uses.
+func Inline(mods []*loader.Module) {
+	ix := analysis.NewIndex(analysis.NewUniverse(ioutil.Discard), mods)
+	for _, m := range mods {
+		in := inliner{functions: inlinable(ix, m), names: map[string]bool{}}
+		for _, t := range m.Tokens {
+			if t.Kind == scanner.Ident {
+				in.names[t.Lexeme] = true
+			}
+		}
+		m.Stmts = in.stmts(m.Stmts)
+	}
+}
+
+// inlinable returns the functions of m that can be inlined, by name.
+func inlinable(ix *analysis.Index, m *loader.Module) map[string]parser.FunctionStmt {
+	functions := map[string]parser.FunctionStmt{}
+	for _, d := range ix.Module(m.Path) {
+		f, ok := d.Stmt.(parser.FunctionStmt)
+		if !ok || f.Extern || annotated(f, "noinline") || !singleExit(f.Body.Stmts) {
+			continue
+		}
+		if !annotated(f, "inline") && size(f.Body) > maxInlineSize {
+			continue
+		}
+		var uses []*analysis.Decl
+		for _, ref := range ix.Refs {
+			if ref.In == d && ref.Name != d.Name {
+				uses = append(uses, ref.Decl)
+			}
+		}
+		if !ix.Reachable(uses)[d] {
+			functions[f.Name.Lexeme] = f
+		}
+	}
+	return functions
+}
+
+func annotated(f parser.FunctionStmt, name string) bool {
+	for _, a := range f.Annotations {
+		if a.Lexeme == name {
+			return true
+		}
+	}
+	return false
+}
+
+// singleExit reports whether the only return in stmts is the last of them.
+func singleExit(stmts []parser.Stmt) bool {
+	exits := false
+	for i, stmt := range stmts {
+		if _, ok := stmt.(parser.ReturnStmt); ok && i == len(stmts)-1 {
+			break
+		}
+		inspect(stmt, func(node interface{}) {
+			switch node.(type) {
+			case parser.ReturnStmt, parser.FunctionStmt:
+				exits = true
+			}
+		})
+	}
+	return !exits
+}
+
+// size counts the statements and expressions in node.
+func size(node interface{}) int {
+	n := 0
+	inspect(node, func(node interface{}) {
+		switch node.(type) {
+		case parser.Block, parser.Comment, parser.BlankLine:
+		default:
+			n++
+		}
+	})
+	return n
+}
+
+// pure reports whether evaluating e can neither fail nor have effects, so
+// that it need not be evaluated if its value is not used.
+func pure(e parser.Expr) bool {
+	ok := true
+	inspect(e, func(node interface{}) {
+		switch n := node.(type) {
+		case parser.FunctionCall:
+			ok = false
+		case parser.BinaryOp:
+			switch n.Op.Kind {
+			case scanner.Slash, scanner.Percent, scanner.Shl, scanner.Shr:
+				ok = false
+			}
+		}
+	})
+	return ok
+}
+
+// dividesByZero reports whether e divides by a constant zero, which fails
+// when run but does not type check.
+func dividesByZero(e parser.Expr) bool {
+	found := false
+	inspect(e, func(node interface{}) {
+		if n, ok := node.(parser.BinaryOp); ok && zeroDivisor(n.Op.Kind, n.Right) {
+			found = true
+		}
+	})
+	return found
+}
+
+// inspect calls f for node and every statement and expression within it.
+func inspect(node interface{}, f func(interface{})) {
+	f(node)
+	switch n := node.(type) {
+	case parser.FunctionStmt:
+		inspect(n.Body, f)
+	case parser.Block:
+		for _, stmt := range n.Stmts {
+			inspect(stmt, f)
+		}
+	case parser.VarStmt:
+		if n.Expr != nil {
+			inspect(n.Expr, f)
+		}
+	case parser.AssignStmt:
+		inspect(n.Expr, f)
+	case parser.CompoundAssignStmt:
+		inspect(n.Expr, f)
+	case parser.ReturnStmt:
+		if n.Expr != nil {
+			inspect(n.Expr, f)
+		}
+	case parser.IfStmt:
+		inspect(n.Cond, f)
+		inspect(n.Then, f)
+		inspect(n.Els, f)
+	case parser.WhileStmt:
+		inspect(n.Cond, f)
+		inspect(n.Body, f)
+	case parser.SwitchStmt:
+		inspect(n.Expr, f)
+		for _, c := range n.Cases {
+			for _, v := range c.Values {
+				inspect(v, f)
+			}
+			inspect(c.Body, f)
+		}
+	case parser.FunctionCall:
+		inspect(n.Callee, f)
+		for _, arg := range n.Args {
+			inspect(arg, f)
+		}
+	case parser.MemberAccess:
+		inspect(n.Parent, f)
+	case parser.UnaryOp:
+		inspect(n.Expr, f)
+	case parser.BinaryOp:
+		inspect(n.Left, f)
+		inspect(n.Right, f)
+	case parser.TernaryExpr:
+		inspect(n.Cond, f)
+		inspect(n.Then, f)
+		inspect(n.Els, f)
+	case parser.InterpolatedStr:
+		for _, part := range n.Parts {
+			inspect(part, f)
+		}
+	}
+}
+
+type inliner struct {
+	functions map[string]parser.FunctionStmt
+	// Every name used in the module, so that new ones do not clash.
+	names map[string]bool
+	// The local variables declared in each enclosing block, innermost last.
+	scopes []map[string]bool
+}
+
+func (in *inliner) local(name string) bool {
+	for _, scope := range in.scopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// fresh returns a name based on name that is not used anywhere else.
+func (in *inliner) fresh(name string) string {
+	for i := 1; ; i++ {
+		s := fmt.Sprintf("%s_%d", name, i)
+		if !in.names[s] {
+			in.names[s] = true
+			return s
+		}
+	}
+}
+
+// callee returns the function that e calls, if e is a call that can be
+// inlined.
+func (in *inliner) callee(e parser.Expr) (parser.FunctionCall, parser.FunctionStmt, bool) {
+	call, ok := e.(parser.FunctionCall)
+	if !ok {
+		return call, parser.FunctionStmt{}, false
+	}
+	name, ok := call.Callee.(parser.IdentExpr)
+	if !ok || in.local(name.Name.Lexeme) {
+		return call, parser.FunctionStmt{}, false
+	}
+	f, ok := in.functions[name.Name.Lexeme]
+	return call, f, ok && len(call.Args) == len(f.Params)
+}
+
+// captures reports whether any of names would refer to a local variable
+// at the call being inlined.
+func (in *inliner) captures(names map[string]bool) bool {
+	for name := range names {
+		if in.local(name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (in *inliner) block(b parser.Block) parser.Block {
+	in.scopes = append(in.scopes, map[string]bool{})
+	b.Stmts = in.stmts(b.Stmts)
+	in.scopes = in.scopes[:len(in.scopes)-1]
+	return b
+}
+
+func (in *inliner) stmts(stmts []parser.Stmt) []parser.Stmt {
+	var out []parser.Stmt
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			if !s.Extern {
+				in.scopes = append(in.scopes, map[string]bool{})
+				for _, p := range s.Params {
+					in.scopes[len(in.scopes)-1][p.Name.Lexeme] = true
+				}
+				s.Body = in.block(s.Body)
+				in.scopes = in.scopes[:len(in.scopes)-1]
+			}
+			stmt = s
+		case parser.VarStmt:
+			if len(in.scopes) == 0 {
+				if s.Expr != nil {
+					s.Expr = in.expr(s.Expr)
+				}
+				stmt = s
+				break
+			}
+			in.scopes[len(in.scopes)-1][s.Name.Lexeme] = true
+			if s.Expr != nil {
+				s.Expr = in.expr(s.Expr)
+				body, ok := in.call(s.Expr, func(r parser.Expr) parser.Stmt {
+					return parser.AssignStmt{Target: s.Name, Expr: r}
+				})
+				if ok {
+					s.Expr = nil
+					out = append(out, s)
+					out = append(out, body...)
+					continue
+				}
+			}
+			stmt = s
+		case parser.AssignStmt:
+			s.Expr = in.expr(s.Expr)
+			body, ok := in.call(s.Expr, func(r parser.Expr) parser.Stmt {
+				return parser.AssignStmt{Target: s.Target, Expr: r}
+			})
+			if ok {
+				out = append(out, body...)
+				continue
+			}
+			stmt = s
+		case parser.CompoundAssignStmt:
+			s.Expr = in.expr(s.Expr)
+			stmt = s
+		case parser.ReturnStmt:
+			if s.Expr == nil {
+				break
+			}
+			s.Expr = in.expr(s.Expr)
+			body, ok := in.call(s.Expr, func(r parser.Expr) parser.Stmt {
+				return parser.ReturnStmt{Keyword: s.Keyword, Expr: r}
+			})
+			if ok {
+				out = append(out, body...)
+				continue
+			}
+			stmt = s
+		case parser.IfStmt:
+			s.Cond = in.expr(s.Cond)
+			s.Then = in.block(s.Then)
+			s.Els = in.block(s.Els)
+			stmt = s
+		case parser.WhileStmt:
+			s.Cond = in.expr(s.Cond)
+			s.Body = in.block(s.Body)
+			stmt = s
+		case parser.SwitchStmt:
+			s.Expr = in.expr(s.Expr)
+			cases := make([]parser.SwitchCase, len(s.Cases))
+			for i, c := range s.Cases {
+				values := make([]parser.Expr, len(c.Values))
+				for j, v := range c.Values {
+					values[j] = in.expr(v)
+				}
+				c.Values = values
+				c.Body = in.block(c.Body)
+				cases[i] = c
+			}
+			s.Cases = cases
+			stmt = s
+		case parser.Block:
+			stmt = in.block(s)
+		case parser.FunctionCall:
+			e := in.expr(s)
+			if body, ok := in.call(e, nil); ok {
+				out = append(out, body...)
+				continue
+			}
+			stmt = e
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
+
+// call returns the statements that run the body of the function that e
+// calls, if it can be inlined, ending with the statement that tail makes
+// of the value it returns. Without a tail, that value must be pure, as it
+// is thrown away.
+func (in *inliner) call(e parser.Expr, tail func(parser.Expr) parser.Stmt) ([]parser.Stmt, bool) {
+	call, f, ok := in.callee(e)
+	if !ok {
+		return nil, false
+	}
+	sub := substitution{fresh: in.fresh, free: map[string]bool{}}
+	sub.scopes = append(sub.scopes, map[string]parser.Expr{})
+	var body []parser.Stmt
+	for i, p := range f.Params {
+		body = append(body, parser.VarStmt{Kind: p.Kind, Name: sub.declare(p.Name), Expr: call.Args[i]})
+	}
+	stmts := f.Body.Stmts
+	var result parser.Expr
+	if n := len(stmts); n > 0 {
+		if ret, ok := stmts[n-1].(parser.ReturnStmt); ok {
+			stmts, result = stmts[:n-1], ret.Expr
+		}
+	}
+	body = append(body, sub.stmts(stmts)...)
+	if result != nil {
+		result = sub.expr(result)
+	}
+	if in.captures(sub.free) {
+		return nil, false
+	}
+	if tail != nil {
+		body = append(body, tail(result))
+	} else if result != nil && !pure(result) {
+		return nil, false
+	}
+	return splice(parser.Block{Stmts: body}), true
+}
+
+func (in *inliner) expr(e parser.Expr) parser.Expr {
+	switch n := e.(type) {
+	case parser.FunctionCall:
+		args := make([]parser.Expr, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = in.expr(arg)
+		}
+		n.Args = args
+		if inlined, ok := in.result(n); ok {
+			return inlined
+		}
+		return n
+	case parser.MemberAccess:
+		n.Parent = in.expr(n.Parent)
+		return n
+	case parser.UnaryOp:
+		n.Expr = in.expr(n.Expr)
+		return n
+	case parser.BinaryOp:
+		n.Left = in.expr(n.Left)
+		n.Right = in.expr(n.Right)
+		return n
+	case parser.TernaryExpr:
+		n.Cond = in.expr(n.Cond)
+		n.Then = in.expr(n.Then)
+		n.Els = in.expr(n.Els)
+		return n
+	case parser.InterpolatedStr:
+		parts := make([]parser.Expr, len(n.Parts))
+		for i, part := range n.Parts {
+			parts[i] = in.expr(part)
+		}
+		n.Parts = parts
+		return n
+	}
+	return e
+}
+
+// result returns the expression that the function called by e returns,
+// with its arguments in place of its parameters, if its body is nothing
+// but that return and the arguments can be evaluated any number of times
+// at any point of the body.
+func (in *inliner) result(e parser.Expr) (parser.Expr, bool) {
+	call, f, ok := in.callee(e)
+	if !ok || len(f.Body.Stmts) != 1 {
+		return nil, false
+	}
+	ret, ok := f.Body.Stmts[0].(parser.ReturnStmt)
+	if !ok || ret.Expr == nil {
+		return nil, false
+	}
+	sub := substitution{fresh: in.fresh, free: map[string]bool{}}
+	sub.scopes = append(sub.scopes, map[string]parser.Expr{})
+	for i, arg := range call.Args {
+		if name, ok := arg.(parser.IdentExpr); !isLiteral(arg) && !(ok && in.local(name.Name.Lexeme)) {
+			return nil, false
+		}
+		sub.scopes[0][f.Params[i].Name.Lexeme] = arg
+	}
+	result := sub.expr(ret.Expr)
+	if in.captures(sub.free) || dividesByZero(result) {
+		return nil, false
+	}
+	return result, true
+}
+
+// substitution copies the body of a function being inlined, replacing the
+// names of its parameters and variables.
+type substitution struct {
+	// What each name declared in each enclosing block of the body is
+	// replaced by, innermost last.
+	scopes []map[string]parser.Expr
+	fresh  func(name string) string
+	// The names used by the body that it does not declare.
+	free map[string]bool
+}
+
+// declare gives the variable declared as name a fresh name, and returns
+// it.
+func (s *substitution) declare(name scanner.Token) scanner.Token {
+	renamed := name
+	renamed.Lexeme = s.fresh(name.Lexeme)
+	s.scopes[len(s.scopes)-1][name.Lexeme] = parser.IdentExpr{Name: renamed}
+	return renamed
+}
+
+func (s *substitution) lookup(name string) parser.Expr {
+	for i := len(s.scopes) - 1; i >= 0; i-- {
+		if e, ok := s.scopes[i][name]; ok {
+			return e
+		}
+	}
+	s.free[name] = true
+	return nil
+}
+
+// rename returns the name that a variable assigned to as name is called.
+func (s *substitution) rename(name scanner.Token) scanner.Token {
+	if e, ok := s.lookup(name.Lexeme).(parser.IdentExpr); ok {
+		name.Lexeme = e.Name.Lexeme
+	}
+	return name
+}
+
+func (s *substitution) block(b parser.Block) parser.Block {
+	s.scopes = append(s.scopes, map[string]parser.Expr{})
+	b.Stmts = s.stmts(b.Stmts)
+	s.scopes = s.scopes[:len(s.scopes)-1]
+	return b
+}
+
+func (s *substitution) stmts(stmts []parser.Stmt) []parser.Stmt {
+	out := make([]parser.Stmt, len(stmts))
+	for i, stmt := range stmts {
+		switch n := stmt.(type) {
+		case parser.VarStmt:
+			n.Name = s.declare(n.Name)
+			if n.Expr != nil {
+				n.Expr = s.expr(n.Expr)
+			}
+			stmt = n
+		case parser.AssignStmt:
+			n.Target = s.rename(n.Target.(scanner.Token))
+			n.Expr = s.expr(n.Expr)
+			stmt = n
+		case parser.CompoundAssignStmt:
+			n.Target = s.rename(n.Target)
+			n.Expr = s.expr(n.Expr)
+			stmt = n
+		case parser.IncDecStmt:
+			n.Target = s.rename(n.Target)
+			stmt = n
+		case parser.ReturnStmt:
+			if n.Expr != nil {
+				n.Expr = s.expr(n.Expr)
+			}
+			stmt = n
+		case parser.IfStmt:
+			n.Cond = s.expr(n.Cond)
+			n.Then = s.block(n.Then)
+			n.Els = s.block(n.Els)
+			stmt = n
+		case parser.WhileStmt:
+			n.Cond = s.expr(n.Cond)
+			n.Body = s.block(n.Body)
+			stmt = n
+		case parser.SwitchStmt:
+			n.Expr = s.expr(n.Expr)
+			cases := make([]parser.SwitchCase, len(n.Cases))
+			for j, c := range n.Cases {
+				values := make([]parser.Expr, len(c.Values))
+				for k, v := range c.Values {
+					values[k] = s.expr(v)
+				}
+				c.Values = values
+				c.Body = s.block(c.Body)
+				cases[j] = c
+			}
+			n.Cases = cases
+			stmt = n
+		case parser.Block:
+			stmt = s.block(n)
+		case parser.FunctionCall:
+			stmt = s.expr(n)
+		}
+		out[i] = stmt
+	}
+	return out
+}
+
+func (s *substitution) expr(e parser.Expr) parser.Expr {
+	switch n := e.(type) {
+	case parser.IdentExpr:
+		switch r := s.lookup(n.Name.Lexeme).(type) {
+		case nil:
+			return n
+		case parser.IdentExpr:
+			r.Name.Row, r.Name.Col = n.Name.Row, n.Name.Col
+			return r
+		default:
+			return r
+		}
+	case parser.FunctionCall:
+		n.Callee = s.expr(n.Callee)
+		args := make([]parser.Expr, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = s.expr(arg)
+		}
+		n.Args = args
+		return n
+	case parser.MemberAccess:
+		n.Parent = s.expr(n.Parent)
+		return n
+	case parser.UnaryOp:
+		n.Expr = s.expr(n.Expr)
+		return n
+	case parser.BinaryOp:
+		n.Left = s.expr(n.Left)
+		n.Right = s.expr(n.Right)
+		return n
+	case parser.TernaryExpr:
+		n.Cond = s.expr(n.Cond)
+		n.Then = s.expr(n.Then)
+		n.Els = s.expr(n.Els)
+		return n
+	case parser.InterpolatedStr:
+		parts := make([]parser.Expr, len(n.Parts))
+		for i, part := range n.Parts {
+			parts[i] = s.expr(part)
+		}
+		n.Parts = parts
+		return n
+	}
+	return e
+}
@@ -0,0 +1,346 @@
+// Package optimize rewrites the syntax trees of checked programs into
+// equivalent ones that do less work when run. Its passes change the
+// statements of modules in place, and keep them type checking.
+package optimize
+
+import (
+	"io"
+	"lang/analysis"
+	"lang/format"
+	"lang/loader"
+	"lang/parser"
+	"lang/scanner"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// passes are the passes that Program runs, in order. Folding again after
+// inlining simplifies the bodies with the arguments they were given.
+var passes = []struct {
+	name string
+	run  func(mods []*loader.Module)
+}{
+	{"fold", Fold},
+	{"inline", Inline},
+	{"fold", Fold},
+	{"deadcode", RemoveDeadCode},
+}
+
+// Program optimizes the whole program mods, ordered as returned by
+// loader.Load, with each pass in turn. If dump is not nil, a diff of what
+// each pass changes in each module is written to it.
+func Program(mods []*loader.Module, dump io.Writer) {
+	for _, pass := range passes {
+		var before [][]byte
+		if dump != nil {
+			for _, m := range mods {
+				before = append(before, format.Stmts(m.Stmts))
+			}
+		}
+		pass.run(mods)
+		if dump != nil {
+			for i, m := range mods {
+				dump.Write(format.Diff(m.Path+" before "+pass.name, m.Path+" after "+pass.name, before[i], format.Stmts(m.Stmts)))
+			}
+		}
+	}
+}
+
+// Fold replaces constant expressions in mods by their values, and reads of
+// local variables that are initialized with a constant and never assigned
+// by that constant. Ifs and whiles whose conditions are constant are
+// replaced by the statements they would run.
+func Fold(mods []*loader.Module) {
+	for _, m := range mods {
+		var f folder
+		m.Stmts = f.stmts(m.Stmts)
+	}
+}
+
+type folder struct {
+	// The values of the local variables declared in each enclosing block,
+	// innermost last, which are nil for those that are not constant.
+	scopes []map[string]parser.Expr
+}
+
+func (f *folder) declare(name string, value parser.Expr) {
+	f.scopes[len(f.scopes)-1][name] = value
+}
+
+func (f *folder) lookup(name string) parser.Expr {
+	for i := len(f.scopes) - 1; i >= 0; i-- {
+		if value, ok := f.scopes[i][name]; ok {
+			return value
+		}
+	}
+	return nil
+}
+
+func (f *folder) block(b parser.Block) parser.Block {
+	f.scopes = append(f.scopes, map[string]parser.Expr{})
+	b.Stmts = f.stmts(b.Stmts)
+	f.scopes = f.scopes[:len(f.scopes)-1]
+	return b
+}
+
+func (f *folder) stmts(stmts []parser.Stmt) []parser.Stmt {
+	var out []parser.Stmt
+	for i, stmt := range stmts {
+		switch s := stmt.(type) {
+		case parser.FunctionStmt:
+			if !s.Extern {
+				f.scopes = append(f.scopes, map[string]parser.Expr{})
+				for _, p := range s.Params {
+					f.declare(p.Name.Lexeme, nil)
+				}
+				s.Body = f.block(s.Body)
+				f.scopes = f.scopes[:len(f.scopes)-1]
+			}
+			stmt = s
+		case parser.VarStmt:
+			if s.Expr != nil {
+				s.Expr = f.expr(s.Expr)
+			}
+			if len(f.scopes) > 0 {
+				var value parser.Expr
+				if isLiteral(s.Expr) && !assigned(s.Name.Lexeme, stmts[i+1:]) {
+					value = s.Expr
+				}
+				f.declare(s.Name.Lexeme, value)
+			}
+			stmt = s
+		case parser.AssignStmt:
+			s.Expr = f.expr(s.Expr)
+			stmt = s
+		case parser.CompoundAssignStmt:
+			if e := f.expr(s.Expr); !zeroDivisor(parser.CompoundAssignOps[s.Op.Kind], e) {
+				s.Expr = e
+			}
+			stmt = s
+		case parser.ReturnStmt:
+			if s.Expr != nil {
+				s.Expr = f.expr(s.Expr)
+			}
+			stmt = s
+		case parser.IfStmt:
+			s.Cond = f.expr(s.Cond)
+			if cond, ok := s.Cond.(parser.LiteralBool); ok {
+				taken := s.Els
+				if cond.Value {
+					taken = s.Then
+				}
+				out = append(out, splice(f.block(taken))...)
+				continue
+			}
+			s.Then = f.block(s.Then)
+			s.Els = f.block(s.Els)
+			stmt = s
+		case parser.WhileStmt:
+			s.Cond = f.expr(s.Cond)
+			if cond, ok := s.Cond.(parser.LiteralBool); ok && !cond.Value {
+				continue
+			}
+			s.Body = f.block(s.Body)
+			stmt = s
+		case parser.SwitchStmt:
+			s.Expr = f.expr(s.Expr)
+			cases := make([]parser.SwitchCase, len(s.Cases))
+			for i, c := range s.Cases {
+				values := make([]parser.Expr, len(c.Values))
+				for j, v := range c.Values {
+					values[j] = f.expr(v)
+				}
+				c.Values = values
+				c.Body = f.block(c.Body)
+				cases[i] = c
+			}
+			s.Cases = cases
+			stmt = s
+		case parser.Block:
+			stmt = f.block(s)
+		case parser.FunctionCall:
+			stmt = f.expr(s)
+		}
+		out = append(out, stmt)
+	}
+	return out
+}
+
+// splice returns the statements that run b in place of a statement: its
+// own, unless it declares variables that must stay in their own scope.
+func splice(b parser.Block) []parser.Stmt {
+	for _, stmt := range b.Stmts {
+		if _, ok := stmt.(parser.VarStmt); ok {
+			return []parser.Stmt{b}
+		}
+	}
+	return b.Stmts
+}
+
+// assigned reports whether any of stmts assigns to a variable called name.
+func assigned(name string, stmts []parser.Stmt) bool {
+	for _, stmt := range stmts {
+		switch s := stmt.(type) {
+		case parser.AssignStmt:
+			if s.Target.(scanner.Token).Lexeme == name {
+				return true
+			}
+		case parser.CompoundAssignStmt:
+			if s.Target.Lexeme == name {
+				return true
+			}
+		case parser.IncDecStmt:
+			if s.Target.Lexeme == name {
+				return true
+			}
+		case parser.IfStmt:
+			if assigned(name, s.Then.Stmts) || assigned(name, s.Els.Stmts) {
+				return true
+			}
+		case parser.WhileStmt:
+			if assigned(name, s.Body.Stmts) {
+				return true
+			}
+		case parser.SwitchStmt:
+			for _, c := range s.Cases {
+				if assigned(name, c.Body.Stmts) {
+					return true
+				}
+			}
+		case parser.Block:
+			if assigned(name, s.Stmts) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *folder) expr(e parser.Expr) parser.Expr {
+	switch n := e.(type) {
+	case parser.IdentExpr:
+		if value := f.lookup(n.Name.Lexeme); value != nil {
+			x, _ := analysis.Constant(value)
+			lit, _ := literal(x, n.Name)
+			return lit
+		}
+		return n
+	case parser.UnaryOp:
+		n.Expr = f.expr(n.Expr)
+		e = n
+	case parser.BinaryOp:
+		n.Left = f.expr(n.Left)
+		if right := f.expr(n.Right); !zeroDivisor(n.Op.Kind, right) {
+			n.Right = right
+		}
+		if left, ok := n.Left.(parser.LiteralBool); ok {
+			// The right operand of && and || is the value of the whole
+			// when the left does not decide it.
+			if n.Op.Kind == scanner.LAnd && left.Value || n.Op.Kind == scanner.LOr && !left.Value {
+				return n.Right
+			}
+		}
+		e = n
+	case parser.TernaryExpr:
+		n.Cond = f.expr(n.Cond)
+		n.Then = f.expr(n.Then)
+		n.Els = f.expr(n.Els)
+		if cond, ok := n.Cond.(parser.LiteralBool); ok {
+			if cond.Value {
+				return n.Then
+			}
+			return n.Els
+		}
+		e = n
+	case parser.FunctionCall:
+		args := make([]parser.Expr, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = f.expr(arg)
+		}
+		n.Args = args
+		return n
+	case parser.MemberAccess:
+		n.Parent = f.expr(n.Parent)
+		return n
+	case parser.InterpolatedStr:
+		parts := make([]parser.Expr, len(n.Parts))
+		for i, part := range n.Parts {
+			parts[i] = f.expr(part)
+		}
+		n.Parts = parts
+		return n
+	default:
+		return e
+	}
+	if isLiteral(e) {
+		return e
+	}
+	if x, ok := analysis.Constant(e); ok {
+		if lit, ok := literal(x, parser.Pos(e)); ok {
+			return lit
+		}
+	}
+	return e
+}
+
+// zeroDivisor reports whether e is a constant zero that op divides by,
+// which fails when run but would not type check if written out.
+func zeroDivisor(op scanner.TokenKind, e parser.Expr) bool {
+	x, _ := analysis.Constant(e)
+	return (op == scanner.Slash || op == scanner.Percent) && x == int64(0)
+}
+
+// isLiteral reports whether e is a literal, or the negation of a numeric
+// one.
+func isLiteral(e parser.Expr) bool {
+	switch n := e.(type) {
+	case parser.LiteralNum, parser.LiteralBool, parser.LiteralStr:
+		return true
+	case parser.UnaryOp:
+		_, isNum := n.Expr.(parser.LiteralNum)
+		return isNum && n.Op.Kind == scanner.Minus
+	}
+	return false
+}
+
+// literal returns an expression for the constant x at pos, unless x cannot
+// be written as one, like an infinite float or the smallest int, whose
+// magnitude is too large for a literal.
+func literal(x interface{}, pos scanner.Token) (parser.Expr, bool) {
+	num := func(value string, negative bool) parser.Expr {
+		var e parser.Expr = parser.LiteralNum{Value: value, Token: token(pos, scanner.Num, value)}
+		if negative {
+			e = parser.UnaryOp{Op: token(pos, scanner.Minus, "-"), Expr: e}
+		}
+		return e
+	}
+	switch x := x.(type) {
+	case int64:
+		if x == math.MinInt64 {
+			return nil, false
+		}
+		if x < 0 {
+			return num(strconv.FormatInt(-x, 10), true), true
+		}
+		return num(strconv.FormatInt(x, 10), false), true
+	case float64:
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return nil, false
+		}
+		value := strconv.FormatFloat(math.Abs(x), 'f', -1, 64)
+		if !strings.ContainsRune(value, '.') {
+			value += ".0"
+		}
+		return num(value, math.Signbit(x)), true
+	case bool:
+		return parser.LiteralBool{Value: x, Token: token(pos, scanner.Ident, strconv.FormatBool(x))}, true
+	case string:
+		return parser.LiteralStr{Value: x, Token: token(pos, scanner.Str, x)}, true
+	}
+	return nil, false
+}
+
+func token(pos scanner.Token, kind scanner.TokenKind, lexeme string) scanner.Token {
+	return scanner.Token{Kind: kind, Lexeme: lexeme, Row: pos.Row, Col: pos.Col}
+}
@@ -0,0 +1,85 @@
+package optimize
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/format"
+	"lang/interp"
+	"lang/loader"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the current results")
+
+// TestProgram compares each program in testdata after optimizing it
+// against the golden file with the same name and an .opt.c extension, and
+// checks that optimizing does not change what the program prints.
+func TestProgram(t *testing.T) {
+	paths, err := filepath.Glob("testdata/*.c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		if strings.Count(filepath.Base(path), ".") > 1 {
+			continue
+		}
+		path := path
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".c"), func(t *testing.T) {
+			mods, err := loader.Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !analysis.CheckIn(analysis.NewUniverse(ioutil.Discard), mods) {
+				t.Fatal("program does not type check")
+			}
+			before := run(t, mods)
+			Program(mods, nil)
+			if !analysis.CheckIn(analysis.NewUniverse(ioutil.Discard), mods) {
+				t.Error("optimized program does not type check")
+			}
+			if after := run(t, mods); after != before {
+				t.Errorf("optimizing changed the output from\n%s\nto\n%s", before, after)
+			}
+			got := format.Stmts(mods[len(mods)-1].Stmts)
+			golden := strings.TrimSuffix(path, ".c") + ".opt.c"
+			if *update {
+				if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ioutil.ReadFile(golden)
+			if os.IsNotExist(err) {
+				t.Fatalf("%s is missing; run go test -update to create it", golden)
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s differs:\n%s", golden, format.Diff(golden, "got", want, got))
+			}
+		})
+	}
+}
+
+// run calls the main function of the last of mods with zero arguments, and
+// returns what it printed and returned.
+func run(t *testing.T, mods []*loader.Module) string {
+	in, err := interp.New(mods, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	in.Stdout = &out
+	result, err := in.Call("main", int64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(&out, "result: %s\n", interp.Format(result))
+	return out.String()
+}
@@ -0,0 +1,52 @@
+int calls = 0;
+int start = first();
+
+int first() {
+  calls++;
+  return 1;
+}
+
+int unused(int n) {
+  return unused(n - 1);
+}
+
+int loop(int n) {
+  while (true) {
+    if (n > 10) {
+      return n;
+    }
+    n *= 2;
+  }
+  return -1;
+}
+
+string describe(int n) {
+  switch (n) {
+  case 1:
+    return "one";
+    println("after return");
+  case 2:
+    fallthrough;
+  default:
+    if (n > 5) {
+      return "big";
+    } else {
+      return "small";
+    }
+    println("after if");
+  }
+}
+
+int main(int n) {
+  println("${describe(start)} ${describe(2)} ${loop(3)} ${calls}");
+  if (n == 0) {
+    return 0;
+    println("dead");
+  }
+  return helper();
+}
+
+@noinline
+int helper() {
+  return 1;
+}
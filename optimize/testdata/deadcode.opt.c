@@ -0,0 +1,43 @@
+int calls = 0;
+int start = first();
+int first() {
+  calls++;
+  return 1;
+}
+
+int loop(int n) {
+  while (true) {
+    if (n > 10) {
+      return n;
+    }
+    n *= 2;
+  }
+}
+
+string describe(int n) {
+  switch (n) {
+  case 1:
+    return "one";
+  case 2:
+    fallthrough;
+  default:
+    if (n > 5) {
+      return "big";
+    } else {
+      return "small";
+    }
+  }
+}
+
+int main(int n) {
+  println("${describe(start)} ${describe(2)} ${loop(3)} ${calls}");
+  if (n == 0) {
+    return 0;
+  }
+  return helper();
+}
+
+@noinline
+int helper() {
+  return 1;
+}
@@ -0,0 +1,49 @@
+int limit = 2 * 3 + 1;
+
+float area(float r) {
+  float pi = 3.0 + 0.14159;
+  return pi * r * r;
+}
+
+int count(int n) {
+  int step = 1;
+  int total = 0;
+  while (false) {
+    total = -1;
+  }
+  while (total < n * (4 - 2)) {
+    total += step << 2;
+  }
+  return total;
+}
+
+int main(int n) {
+  bool debug = !true;
+  int x = 7 / 2 + 7 % 2 - (1 << 3) + (~0 & 5 | 16 ^ 3);
+  int y = x;
+  y++;
+  if (debug) {
+    println("debug");
+  } else if (x > 100) {
+    println("big");
+  } else {
+    println("small");
+  }
+  if (true && x > 0 || false) {
+    int x = 2;
+    println("${x * 10} ${y} ${1.5 * 2.0} ${0.1 + 0.2} ${-(3 - 5)}");
+  }
+  string s = "a" == "a" ? "yes" : "no";
+  println("${s} ${limit} ${area(1.0)} ${count(3)} ${9223372036854775807 + 1} ${-2.0 * 0.0}");
+  println("${1 << 62} ${1 << 64} ${-1 >> 70}");
+  switch (n) {
+  case 0:
+    println("${1 > 2 || 2.5 <= 2.5}");
+  }
+  int zero = 0;
+  if (n > 0) {
+    y %= zero;
+    println("${x / zero} ${y}");
+  }
+  return x;
+}
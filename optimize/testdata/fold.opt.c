@@ -0,0 +1,39 @@
+int limit = 7;
+float area(float r) {
+  float pi = 3.14159;
+  return 3.14159 * r * r;
+}
+
+int count(int n) {
+  int step = 1;
+  int total = 0;
+  while (total < n * 2) {
+    total += 4;
+  }
+  return total;
+}
+
+int main(int n) {
+  bool debug = false;
+  int x = 19;
+  int y = 19;
+  y++;
+  println("small");
+  {
+    int x = 2;
+    println("${20} ${y} ${3.0} ${0.30000000000000004} ${2}");
+  }
+  string s = "yes";
+  println("${"yes"} ${limit} ${area(1.0)} ${count(3)} ${9223372036854775807 + 1} ${-0.0}");
+  println("${4611686018427387904} ${1 << 64} ${-1 >> 70}");
+  switch (n) {
+  case 0:
+    println("${true}");
+  }
+  int zero = 0;
+  if (n > 0) {
+    y %= zero;
+    println("${19 / zero} ${y}");
+  }
+  return 19;
+}
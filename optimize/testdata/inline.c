@@ -0,0 +1,102 @@
+int total = 0;
+
+bool isTrue() {
+  return true;
+}
+
+int twice(int n) {
+  return n * 2;
+}
+
+int add(int a, int b) {
+  total += a;
+  return a + b;
+}
+
+void log(string s) {
+  total++;
+  println("log: ${s}");
+}
+
+int count() {
+  return total;
+}
+
+int clamp(int n, int lo, int hi) {
+  int r = n;
+  if (r < lo) {
+    r = lo;
+  }
+  if (r > hi) {
+    r = hi;
+  }
+  return r;
+}
+
+int ratio(int a, int b) {
+  return a / b;
+}
+
+int fib(int n) {
+  if (n < 2) {
+    return n;
+  }
+  return fib(n - 1) + fib(n - 2);
+}
+
+@noinline
+int square(int n) {
+  return n * n;
+}
+
+@inline
+int sum(int n) {
+  int s = 0;
+  int i = 0;
+  while (i < n) {
+    i++;
+    s += i;
+    if (s > 100) {
+      println("big");
+    }
+    if (s > 1000) {
+      println("huge");
+    }
+  }
+  return s;
+}
+
+int big(int n) {
+  int s = 0;
+  int i = 0;
+  while (i < n) {
+    i++;
+    s += i;
+    if (s > 100) {
+      println("big");
+    }
+    if (s > 1000) {
+      println("huge");
+    }
+  }
+  return s;
+}
+
+int main(int argc) {
+  if (isTrue()) {
+    println("true");
+  }
+  int x = 3;
+  println("${twice(x)} ${twice(4)} ${twice(x + 1)}");
+  int y = add(x, 2);
+  log("y is ${y}");
+  y = clamp(y, 0, 4);
+  int total = 10;
+  println("${count()} ${total} ${y}");
+  int s = sum(x);
+  println("${fib(10)} ${square(x)} ${s} ${big(x)}");
+  if (argc > 0) {
+    println("${ratio(x, 0)}");
+  }
+  return add(y, total);
+}
@@ -0,0 +1,96 @@
+int total = 0;
+int add(int a, int b) {
+  total += a;
+  return a + b;
+}
+
+int count() {
+  return total;
+}
+
+int ratio(int a, int b) {
+  return a / b;
+}
+
+int fib(int n) {
+  if (n < 2) {
+    return n;
+  }
+  return fib(n - 1) + fib(n - 2);
+}
+
+@noinline
+int square(int n) {
+  return n * n;
+}
+
+int big(int n) {
+  int s = 0;
+  int i = 0;
+  while (i < n) {
+    i++;
+    s += i;
+    if (s > 100) {
+      println("big");
+    }
+    if (s > 1000) {
+      println("huge");
+    }
+  }
+  return s;
+}
+
+int main(int argc) {
+  println("true");
+  int x = 3;
+  println("${6} ${8} ${8}");
+  int y;
+  {
+    int a_1 = 3;
+    int b_1 = 2;
+    total += 3;
+    y = 5;
+  }
+  {
+    string s_1 = "y is ${y}";
+    total++;
+    println("log: ${s_1}");
+  }
+  {
+    int n_1 = y;
+    int lo_1 = 0;
+    int hi_1 = 4;
+    int r_1 = n_1;
+    if (r_1 < 0) {
+      r_1 = 0;
+    }
+    if (r_1 > 4) {
+      r_1 = 4;
+    }
+    y = r_1;
+  }
+  int total = 10;
+  println("${count()} ${10} ${y}");
+  int s;
+  {
+    int n_2 = 3;
+    int s_2 = 0;
+    int i_1 = 0;
+    while (i_1 < 3) {
+      i_1++;
+      s_2 += i_1;
+      if (s_2 > 100) {
+        println("big");
+      }
+      if (s_2 > 1000) {
+        println("huge");
+      }
+    }
+    s = s_2;
+  }
+  println("${fib(10)} ${square(3)} ${s} ${big(3)}");
+  if (argc > 0) {
+    println("${ratio(3, 0)}");
+  }
+  return add(y, 10);
+}
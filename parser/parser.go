@@ -2,9 +2,29 @@ package parser
 
 import "lang/scanner"
 
+// CompoundAssignOps maps each compound assignment operator to the binary
+// operator it applies to its target.
+var CompoundAssignOps = map[scanner.TokenKind]scanner.TokenKind{
+	scanner.PlusEq:    scanner.Plus,
+	scanner.MinusEq:   scanner.Minus,
+	scanner.StarEq:    scanner.Star,
+	scanner.SlashEq:   scanner.Slash,
+	scanner.PercentEq: scanner.Percent,
+	scanner.BAndEq:    scanner.BAnd,
+	scanner.BOrEq:     scanner.BOr,
+	scanner.BXorEq:    scanner.BXor,
+	scanner.ShlEq:     scanner.Shl,
+	scanner.ShrEq:     scanner.Shr,
+}
+
 type Parser struct {
 	i      int
 	Tokens []scanner.Token
+	// Comments, as returned by scanner.ScanComments, are only needed by
+	// the formatter. When non-nil, statement lists include them as Comment
+	// and BlankLine statements.
+	Comments []scanner.Token
+	c        int
 }
 
 func (p *Parser) peek() scanner.Token {
@@ -28,17 +48,26 @@ func (p *Parser) consumeOne() scanner.Token {
 }
 
 func (p *Parser) consume(kind scanner.TokenKind, msg string) scanner.Token {
-	t := p.consumeOne()
-	if t.Kind != kind {
+	if p.peek().Kind != kind {
 		panic(msg)
 	}
-	return t
+	return p.consumeOne()
 }
 
-func (p *Parser) consumeKeyword(keyword string, msg string) {
-	if p.consume(scanner.Ident, msg).Lexeme != keyword {
+func (p *Parser) consumeKeyword(keyword string, msg string) scanner.Token {
+	if !p.matchKeyword(keyword) {
 		panic(msg)
 	}
+	return p.consumeOne()
+}
+
+// Current returns the next token to be parsed. After the parser panics, it
+// is the token at which parsing failed.
+func (p *Parser) Current() scanner.Token {
+	if p.i >= len(p.Tokens) {
+		return p.Tokens[len(p.Tokens)-1]
+	}
+	return p.Tokens[p.i]
 }
 
 func (p *Parser) matchKeyword(keyword string) bool {
@@ -76,6 +105,26 @@ func (p *Parser) matchN(n int, kinds ...scanner.TokenKind) bool {
 	return ok
 }
 
+// typeLen returns the number of tokens spanned by the type name at the
+// current position, which is either a lone identifier or one qualified by
+// a module name.
+func (p *Parser) typeLen() int {
+	if p.matchN(1, scanner.Dot) && p.matchN(2, scanner.Ident) && p.matchN(3, scanner.Ident) {
+		return 3
+	}
+	return 1
+}
+
+func (p *Parser) consumeTypeName(msg string) scanner.Token {
+	qualified := p.typeLen() == 3
+	t := p.consume(scanner.Ident, msg)
+	if qualified {
+		p.consumeOne()
+		t.Lexeme += "." + p.consumeOne().Lexeme
+	}
+	return t
+}
+
 func (p *Parser) previous() scanner.Token {
 	if p.i == 0 {
 		panic("There is no previous token")
@@ -83,24 +132,95 @@ func (p *Parser) previous() scanner.Token {
 	return p.Tokens[p.i-1]
 }
 
+// consumeComments appends the comments before the next token to stmts,
+// along with the blank lines around them. Comments inside the statement
+// that stmts ends with, other than those kept with the members of an enum
+// or the arguments of a call, are inserted before that statement instead.
+func (p *Parser) consumeComments(stmts []Stmt) []Stmt {
+	if p.Comments == nil {
+		return stmts
+	}
+	next := p.peek()
+	line := -1
+	if p.i > 0 {
+		prev := p.previous()
+		line = prev.Row
+		var inner []Stmt
+		for ; p.c < len(p.Comments) && before(p.Comments[p.c], prev); p.c++ {
+			inner = append(inner, Comment{p.Comments[p.c], false})
+		}
+		if n := len(stmts); n > 0 && len(inner) > 0 {
+			stmt := stmts[n-1]
+			stmts = append(append(stmts[:n-1:n-1], inner...), stmt)
+		} else {
+			stmts = append(stmts, inner...)
+		}
+	}
+	for p.c < len(p.Comments) && before(p.Comments[p.c], next) {
+		c := p.Comments[p.c]
+		p.c++
+		if line >= 0 && c.Row > line+1 {
+			stmts = append(stmts, BlankLine{})
+		}
+		stmts = append(stmts, Comment{c, p.i > 0 && c.Row == p.previous().Row})
+		line = c.Row
+	}
+	if line >= 0 && next.Row > line+1 {
+		stmts = append(stmts, BlankLine{})
+	}
+	return stmts
+}
+
+// before reports whether the comment c comes before the token t.
+func before(c, t scanner.Token) bool {
+	return c.Row < t.Row || c.Row == t.Row && c.Col < t.Col
+}
+
+// consumeListComments returns the comments before the next token, which is
+// an element of a list or the end of it.
+func (p *Parser) consumeListComments() []Comment {
+	var comments []Comment
+	for p.Comments != nil && p.c < len(p.Comments) && before(p.Comments[p.c], p.peek()) {
+		c := p.Comments[p.c]
+		p.c++
+		comments = append(comments, Comment{c, c.Row == p.previous().Row})
+	}
+	return comments
+}
+
+// listComments appends comments, the comments before an element of a list
+// with n elements before it, to list, which is returned as nil if there
+// are no comments in the list at all.
+func listComments(list [][]Comment, n int, comments []Comment) [][]Comment {
+	if list == nil && comments == nil {
+		return nil
+	}
+	for len(list) < n {
+		list = append(list, nil)
+	}
+	return append(list, comments)
+}
+
 func (p *Parser) consumeAtomExpr() Expr {
+	t := p.peek()
 	if p.matchKeyword("true") {
 		p.consumeOne()
-		return LiteralBool{true}
+		return LiteralBool{true, t}
 	} else if p.matchKeyword("false") {
 		p.consumeOne()
-		return LiteralBool{false}
+		return LiteralBool{false, t}
 	} else if p.matchKeyword("null") {
 		p.consumeOne()
-		return LiteralNull{}
+		return LiteralNull{t}
 	}
-	t := p.peek()
 	if t.Kind == scanner.Str {
 		p.consumeOne()
-		return LiteralStr{t.Lexeme}
+		return LiteralStr{t.Lexeme, t}
+	} else if t.Kind == scanner.StrHead {
+		return p.consumeInterpolatedStr()
 	} else if t.Kind == scanner.Num {
 		p.consumeOne()
-		return LiteralNum{t.Lexeme}
+		return LiteralNum{t.Lexeme, t}
 	} else if t.Kind == scanner.LParen {
 		return p.consumeGroupExpr()
 	} else if t.Kind == scanner.Ident {
@@ -111,6 +231,22 @@ func (p *Parser) consumeAtomExpr() Expr {
 	}
 }
 
+func (p *Parser) consumeInterpolatedStr() Expr {
+	head := p.consume(scanner.StrHead, "Expected interpolated string")
+	parts := []Expr{LiteralStr{head.Lexeme, head}}
+	for {
+		parts = append(parts, p.consumeExpr())
+		if p.match(scanner.StrMid) {
+			mid := p.consumeOne()
+			parts = append(parts, LiteralStr{mid.Lexeme, mid})
+		} else {
+			tail := p.consume(scanner.StrTail, "Expected '}' after string interpolation")
+			parts = append(parts, LiteralStr{tail.Lexeme, tail})
+			return InterpolatedStr{parts}
+		}
+	}
+}
+
 func (p *Parser) consumeCallExpr() Expr {
 	e := p.consumeAtomExpr()
 	for {
@@ -120,7 +256,12 @@ func (p *Parser) consumeCallExpr() Expr {
 		} else if p.match(scanner.LParen) {
 			p.consumeOne()
 			var args []Expr
-			for !p.match(scanner.RParen) {
+			var comments [][]Comment
+			for {
+				comments = listComments(comments, len(args), p.consumeListComments())
+				if p.match(scanner.RParen) {
+					break
+				}
 				arg := p.consumeExpr()
 				args = append(args, arg)
 				if p.match(scanner.Comma) {
@@ -129,8 +270,7 @@ func (p *Parser) consumeCallExpr() Expr {
 					panic("Expected ')' or ',' after function call argument")
 				}
 			}
-			p.consumeOne()
-			e = FunctionCall{e, args}
+			e = FunctionCall{e, args, p.consumeOne(), comments}
 		} else {
 			break
 		}
@@ -139,7 +279,7 @@ func (p *Parser) consumeCallExpr() Expr {
 }
 
 func (p *Parser) consumeUnaryExpr() Expr {
-	if p.match(scanner.Minus, scanner.LNot) {
+	if p.match(scanner.Minus, scanner.LNot, scanner.BNot) {
 		op := p.consumeOne()
 		e := p.consumeUnaryExpr()
 		return UnaryOp{op, e}
@@ -150,7 +290,7 @@ func (p *Parser) consumeUnaryExpr() Expr {
 
 func (p *Parser) consumeFactorExpr() Expr {
 	e := p.consumeUnaryExpr()
-	for p.match(scanner.Star, scanner.Slash) {
+	for p.match(scanner.Star, scanner.Slash, scanner.Percent) {
 		op := p.consumeOne()
 		right := p.consumeUnaryExpr()
 		e = BinaryOp{op, e, right}
@@ -168,9 +308,9 @@ func (p *Parser) consumeTermExpr() Expr {
 	return e
 }
 
-func (p *Parser) consumeComparisonExpr() Expr {
+func (p *Parser) consumeShiftExpr() Expr {
 	e := p.consumeTermExpr()
-	for p.match(scanner.Gt, scanner.Gte, scanner.Lt, scanner.Lte) {
+	for p.match(scanner.Shl, scanner.Shr) {
 		op := p.consumeOne()
 		right := p.consumeTermExpr()
 		e = BinaryOp{op, e, right}
@@ -178,6 +318,16 @@ func (p *Parser) consumeComparisonExpr() Expr {
 	return e
 }
 
+func (p *Parser) consumeComparisonExpr() Expr {
+	e := p.consumeShiftExpr()
+	for p.match(scanner.Gt, scanner.Gte, scanner.Lt, scanner.Lte) {
+		op := p.consumeOne()
+		right := p.consumeShiftExpr()
+		e = BinaryOp{op, e, right}
+	}
+	return e
+}
+
 func (p *Parser) consumeEqualityExpr() Expr {
 	e := p.consumeComparisonExpr()
 	for p.match(scanner.EqEq, scanner.Ne) {
@@ -188,9 +338,9 @@ func (p *Parser) consumeEqualityExpr() Expr {
 	return e
 }
 
-func (p *Parser) consumeLAndExpr() Expr {
+func (p *Parser) consumeBAndExpr() Expr {
 	e := p.consumeEqualityExpr()
-	for p.match(scanner.LAnd) {
+	for p.match(scanner.BAnd) {
 		op := p.consumeOne()
 		right := p.consumeEqualityExpr()
 		e = BinaryOp{op, e, right}
@@ -198,6 +348,36 @@ func (p *Parser) consumeLAndExpr() Expr {
 	return e
 }
 
+func (p *Parser) consumeBXorExpr() Expr {
+	e := p.consumeBAndExpr()
+	for p.match(scanner.BXor) {
+		op := p.consumeOne()
+		right := p.consumeBAndExpr()
+		e = BinaryOp{op, e, right}
+	}
+	return e
+}
+
+func (p *Parser) consumeBOrExpr() Expr {
+	e := p.consumeBXorExpr()
+	for p.match(scanner.BOr) {
+		op := p.consumeOne()
+		right := p.consumeBXorExpr()
+		e = BinaryOp{op, e, right}
+	}
+	return e
+}
+
+func (p *Parser) consumeLAndExpr() Expr {
+	e := p.consumeBOrExpr()
+	for p.match(scanner.LAnd) {
+		op := p.consumeOne()
+		right := p.consumeBOrExpr()
+		e = BinaryOp{op, e, right}
+	}
+	return e
+}
+
 func (p *Parser) consumeLOrExpr() Expr {
 	e := p.consumeLAndExpr()
 	for p.match(scanner.LOr) {
@@ -208,8 +388,20 @@ func (p *Parser) consumeLOrExpr() Expr {
 	return e
 }
 
+func (p *Parser) consumeTernaryExpr() Expr {
+	cond := p.consumeLOrExpr()
+	if p.match(scanner.Question) {
+		p.consumeOne()
+		then := p.consumeExpr()
+		p.consume(scanner.Colon, "Expected ':' in ternary expression")
+		els := p.consumeTernaryExpr()
+		return TernaryExpr{cond, then, els}
+	}
+	return cond
+}
+
 func (p *Parser) consumeExpr() Expr {
-	return p.consumeLOrExpr()
+	return p.consumeTernaryExpr()
 }
 
 func (p *Parser) consumeGroupExpr() Expr {
@@ -220,46 +412,134 @@ func (p *Parser) consumeGroupExpr() Expr {
 }
 
 func (p *Parser) consumeIfStmt() Stmt {
-	p.consumeKeyword("if", "Expected 'if' statement")
+	keyword := p.consumeKeyword("if", "Expected 'if' statement")
 	cond := p.consumeGroupExpr()
 	then := p.consumeBlock()
 	if p.matchKeyword("else") {
 		p.consumeOne()
 		if p.matchKeyword("if") {
-			return IfStmt{cond, then, Block{[]Stmt{p.consumeIfStmt()}}}
+			return IfStmt{keyword, cond, then, Block{Stmts: []Stmt{p.consumeIfStmt()}}}
 		} else {
-			return IfStmt{cond, then, p.consumeBlock()}
+			return IfStmt{keyword, cond, then, p.consumeBlock()}
 		}
 	} else {
-		return IfStmt{cond, then, Block{}}
+		return IfStmt{keyword, cond, then, Block{}}
 	}
 }
 
+func (p *Parser) consumeSwitchStmt() Stmt {
+	keyword := p.consumeKeyword("switch", "Expected 'switch' statement")
+	e := p.consumeGroupExpr()
+	p.consume(scanner.LBrace, "Expected '{' after switch expression")
+	var cases []SwitchCase
+	var leading []Stmt
+	if !p.match(scanner.RBrace) {
+		leading = p.consumeComments(nil)
+	}
+	for !p.match(scanner.RBrace) {
+		c := SwitchCase{Comments: leading}
+		leading = nil
+		if p.matchKeyword("default") {
+			p.consumeOne()
+			c.Default = true
+		} else {
+			p.consumeKeyword("case", "Expected 'case' or 'default' in switch statement")
+			c.Values = append(c.Values, p.consumeExpr())
+			for p.match(scanner.Comma) {
+				p.consumeOne()
+				c.Values = append(c.Values, p.consumeExpr())
+			}
+		}
+		p.consume(scanner.Colon, "Expected ':' after switch case")
+		for {
+			c.Body.Stmts = p.consumeComments(c.Body.Stmts)
+			if p.match(scanner.RBrace) {
+				break
+			}
+			if p.matchKeyword("case") || p.matchKeyword("default") {
+				c.Body.Stmts, leading = splitCaseComments(c.Body.Stmts, p.peek())
+				break
+			}
+			// Ignore lone semicolons
+			if p.match(scanner.Semicolon) {
+				p.consumeOne()
+				continue
+			}
+			c.Body.Stmts = append(c.Body.Stmts, p.consumeStmt())
+		}
+		cases = append(cases, c)
+	}
+	p.consumeOne()
+	return SwitchStmt{keyword, e, cases}
+}
+
+// splitCaseComments splits the comments at the end of the body of a case
+// from those before the next case, whose label is next: the latter start
+// at the first comment on a line of its own that is indented no further
+// than the label, along with the blank line before it.
+func splitCaseComments(body []Stmt, next scanner.Token) ([]Stmt, []Stmt) {
+	i := len(body)
+	for i > 0 {
+		switch s := body[i-1].(type) {
+		case BlankLine:
+			i--
+			continue
+		case Comment:
+			if !s.Trailing {
+				i--
+				continue
+			}
+		}
+		break
+	}
+	for ; i < len(body); i++ {
+		if c, ok := body[i].(Comment); ok && c.Text.Col <= next.Col {
+			if i > 0 {
+				if _, ok := body[i-1].(BlankLine); ok {
+					i--
+				}
+			}
+			return body[:i:i], body[i:]
+		}
+	}
+	return body, nil
+}
+
+func (p *Parser) consumeFallthroughStmt() Stmt {
+	keyword := p.consumeKeyword("fallthrough", "Expected 'fallthrough' statement")
+	p.consume(scanner.Semicolon, "Expected ';' after fallthrough statement")
+	return FallthroughStmt{keyword}
+}
+
 func (p *Parser) consumeWhileStmt() Stmt {
-	p.consumeKeyword("while", "Expected 'while' statement")
+	keyword := p.consumeKeyword("while", "Expected 'while' statement")
 	cond := p.consumeGroupExpr()
 	body := p.consumeBlock()
-	return WhileStmt{cond, body}
+	return WhileStmt{keyword, cond, body}
 }
 
 func (p *Parser) consumeReturnStmt() Stmt {
-	p.consumeKeyword("return", "Expected 'return' statement")
+	keyword := p.consumeKeyword("return", "Expected 'return' statement")
+	if p.match(scanner.Semicolon) {
+		p.consumeOne()
+		return ReturnStmt{keyword, nil}
+	}
 	e := p.consumeExpr()
 	p.consume(scanner.Semicolon, "Expected ';' after return statement")
-	return ReturnStmt{e}
+	return ReturnStmt{keyword, e}
 }
 
 func (p *Parser) consumeVarStmt() Stmt {
-	kind := p.consume(scanner.Ident, "Expected variable declaration type")
+	kind := p.consumeTypeName("Expected variable declaration type")
 	name := p.consume(scanner.Ident, "Expected variable declaration name")
 	if p.match(scanner.Semicolon) {
 		p.consumeOne()
-		return VarStmt{kind, name, nil}
+		return VarStmt{kind, name, nil, false}
 	} else {
 		p.consume(scanner.Eq, "Expected ';' or '=' after variable declaration")
 		e := p.consumeExpr()
 		p.consume(scanner.Semicolon, "Expected ';' after variable initialization")
-		return VarStmt{kind, name, e}
+		return VarStmt{kind, name, e, false}
 	}
 }
 
@@ -271,13 +551,41 @@ func (p *Parser) consumeAssignStmt() Stmt {
 	return AssignStmt{target, e}
 }
 
-func (p *Parser) consumeFunctionStmt() Stmt {
-	returnKind := p.consume(scanner.Ident, "Expected function return type")
-	name := p.consume(scanner.Ident, "Expected function name")
+func (p *Parser) consumeCompoundAssignStmt() Stmt {
+	target := p.consume(scanner.Ident, "Expected variable assignment target")
+	if _, ok := CompoundAssignOps[p.peek().Kind]; !ok {
+		panic("Expected compound assignment operator after variable assignment target")
+	}
+	op := p.consumeOne()
+	e := p.consumeExpr()
+	p.consume(scanner.Semicolon, "Expected ';' after variable assignment")
+	return CompoundAssignStmt{op, target, e}
+}
+
+func (p *Parser) consumeIncDecStmt() Stmt {
+	target := p.consume(scanner.Ident, "Expected increment or decrement target")
+	if !p.match(scanner.Inc, scanner.Dec) {
+		panic("Expected '++' or '--' after increment or decrement target")
+	}
+	op := p.consumeOne()
+	p.consume(scanner.Semicolon, "Expected ';' after increment or decrement")
+	return IncDecStmt{op, target}
+}
+
+func (p *Parser) consumeFunctionParams() ([]FunctionParam, bool) {
 	p.consume(scanner.LParen, "Expected function parameters")
 	var params []FunctionParam
+	variadic := false
 	for !p.match(scanner.RParen) {
-		pkind := p.consume(scanner.Ident, "Expected function parameter type")
+		if p.match(scanner.Ellipsis) {
+			p.consumeOne()
+			variadic = true
+			if !p.match(scanner.RParen) {
+				panic("Expected ')' after '...'")
+			}
+			break
+		}
+		pkind := p.consumeTypeName("Expected function parameter type")
 		pname := p.consume(scanner.Ident, "Expected function parameter name")
 		params = append(params, FunctionParam{pkind, pname})
 		if p.match(scanner.Comma) {
@@ -287,8 +595,49 @@ func (p *Parser) consumeFunctionStmt() Stmt {
 		}
 	}
 	p.consumeOne()
+	return params, variadic
+}
+
+func (p *Parser) consumeFunctionStmt() Stmt {
+	returnKind := p.consumeTypeName("Expected function return type")
+	name := p.consume(scanner.Ident, "Expected function name")
+	params, variadic := p.consumeFunctionParams()
+	if variadic {
+		panic("Only extern functions can be variadic")
+	}
 	body := p.consumeBlock()
-	return FunctionStmt{returnKind, name, params, body}
+	return FunctionStmt{ReturnKind: returnKind, Name: name, Params: params, Body: body}
+}
+
+func (p *Parser) consumeExternStmt() Stmt {
+	p.consumeKeyword("extern", "Expected 'extern' declaration")
+	returnKind := p.consumeTypeName("Expected function return type")
+	name := p.consume(scanner.Ident, "Expected function name")
+	params, variadic := p.consumeFunctionParams()
+	p.consume(scanner.Semicolon, "Expected ';' after extern function declaration")
+	return FunctionStmt{ReturnKind: returnKind, Name: name, Params: params, Variadic: variadic, Extern: true}
+}
+
+func (p *Parser) consumeEnumStmt() Stmt {
+	p.consumeKeyword("enum", "Expected 'enum' declaration")
+	name := p.consume(scanner.Ident, "Expected enum name")
+	p.consume(scanner.LBrace, "Expected '{' after enum name")
+	var members []scanner.Token
+	var comments [][]Comment
+	for {
+		comments = listComments(comments, len(members), p.consumeListComments())
+		if p.match(scanner.RBrace) {
+			break
+		}
+		members = append(members, p.consume(scanner.Ident, "Expected enum member name"))
+		if p.match(scanner.Comma) {
+			p.consumeOne()
+		} else if !p.match(scanner.RBrace) {
+			panic("Expected '}' or ',' after enum member")
+		}
+	}
+	p.consumeOne()
+	return EnumStmt{name, members, false, comments}
 }
 
 func (p *Parser) consumeStmt() Stmt {
@@ -298,11 +647,19 @@ func (p *Parser) consumeStmt() Stmt {
 		return p.consumeWhileStmt()
 	} else if p.matchKeyword("if") {
 		return p.consumeIfStmt()
+	} else if p.matchKeyword("switch") {
+		return p.consumeSwitchStmt()
+	} else if p.matchKeyword("fallthrough") {
+		return p.consumeFallthroughStmt()
 	} else if p.matchN(1, scanner.Eq) {
 		return p.consumeAssignStmt()
-	} else if p.matchN(2, scanner.LParen) {
+	} else if _, ok := CompoundAssignOps[p.peekN(1).Kind]; ok {
+		return p.consumeCompoundAssignStmt()
+	} else if p.matchN(1, scanner.Inc, scanner.Dec) {
+		return p.consumeIncDecStmt()
+	} else if p.matchN(p.typeLen()+1, scanner.LParen) {
 		return p.consumeFunctionStmt()
-	} else if p.matchN(2, scanner.Eq, scanner.Semicolon) {
+	} else if p.matchN(p.typeLen()+1, scanner.Eq, scanner.Semicolon) {
 		return p.consumeVarStmt()
 	} else {
 		e := p.consumeCallExpr()
@@ -311,10 +668,66 @@ func (p *Parser) consumeStmt() Stmt {
 	}
 }
 
+func (p *Parser) consumeModuleStmt() Stmt {
+	p.consumeKeyword("module", "Expected 'module' declaration")
+	name := p.consume(scanner.Ident, "Expected module name")
+	p.consume(scanner.Semicolon, "Expected ';' after module declaration")
+	return ModuleStmt{name}
+}
+
+func (p *Parser) consumeImportStmt() Stmt {
+	p.consumeKeyword("import", "Expected 'import' declaration")
+	path := p.consume(scanner.Str, "Expected import path")
+	p.consume(scanner.Semicolon, "Expected ';' after import declaration")
+	return ImportStmt{path}
+}
+
+func (p *Parser) consumeExportedStmt() Stmt {
+	p.consumeKeyword("export", "Expected 'export' declaration")
+	switch s := p.consumeTopLevelStmt().(type) {
+	case FunctionStmt:
+		s.Exported = true
+		return s
+	case VarStmt:
+		s.Exported = true
+		return s
+	case EnumStmt:
+		s.Exported = true
+		return s
+	default:
+		panic("Expected function, variable or enum declaration after 'export'")
+	}
+}
+
+func (p *Parser) consumeAnnotatedStmt() Stmt {
+	var annotations []scanner.Token
+	for p.match(scanner.Annotation) {
+		annotations = append(annotations, p.consumeOne())
+	}
+	s, ok := p.consumeTopLevelStmt().(FunctionStmt)
+	if !ok {
+		panic("Expected function declaration after annotation")
+	}
+	s.Annotations = annotations
+	return s
+}
+
 func (p *Parser) consumeTopLevelStmt() Stmt {
-	if p.matchN(2, scanner.LParen) {
+	if p.match(scanner.Annotation) {
+		return p.consumeAnnotatedStmt()
+	} else if p.matchKeyword("module") {
+		return p.consumeModuleStmt()
+	} else if p.matchKeyword("import") {
+		return p.consumeImportStmt()
+	} else if p.matchKeyword("export") {
+		return p.consumeExportedStmt()
+	} else if p.matchKeyword("extern") {
+		return p.consumeExternStmt()
+	} else if p.matchKeyword("enum") {
+		return p.consumeEnumStmt()
+	} else if p.matchN(p.typeLen()+1, scanner.LParen) {
 		return p.consumeFunctionStmt()
-	} else if p.matchN(2, scanner.Eq, scanner.Semicolon) {
+	} else if p.matchN(p.typeLen()+1, scanner.Eq, scanner.Semicolon) {
 		return p.consumeVarStmt()
 	} else {
 		panic("Unknown top-level statement")
@@ -323,16 +736,50 @@ func (p *Parser) consumeTopLevelStmt() Stmt {
 
 func (p *Parser) ConsumeTopLevelStmts() []Stmt {
 	var stmts []Stmt
-	for !p.match(scanner.Eof) {
+	for {
+		stmts = p.consumeComments(stmts)
+		if p.match(scanner.Eof) {
+			return stmts
+		}
 		stmts = append(stmts, p.consumeTopLevelStmt())
 	}
+}
+
+// ConsumeExpr parses the remaining tokens as a single expression.
+func (p *Parser) ConsumeExpr() Expr {
+	e := p.consumeExpr()
+	p.consume(scanner.Eof, "Expected end of expression")
+	return e
+}
+
+// ConsumeStmts parses the remaining tokens as a sequence of statements, each
+// of which may be either a top-level declaration or a block statement.
+func (p *Parser) ConsumeStmts() []Stmt {
+	var stmts []Stmt
+	for !p.match(scanner.Eof) {
+		// Ignore lone semicolons
+		if p.match(scanner.Semicolon) {
+			p.consumeOne()
+			continue
+		}
+		if p.matchKeyword("module") || p.matchKeyword("import") || p.matchKeyword("export") ||
+			p.matchKeyword("extern") || p.matchKeyword("enum") || p.match(scanner.Annotation) {
+			stmts = append(stmts, p.consumeTopLevelStmt())
+		} else {
+			stmts = append(stmts, p.consumeStmt())
+		}
+	}
 	return stmts
 }
 
 func (p *Parser) consumeBlock() Block {
 	var blk Block
-	p.consume(scanner.LBrace, "Required block")
-	for !p.match(scanner.RBrace) {
+	blk.Open = p.consume(scanner.LBrace, "Required block")
+	for {
+		blk.Stmts = p.consumeComments(blk.Stmts)
+		if p.match(scanner.RBrace) {
+			break
+		}
 		// Ignore lone semicolons
 		if p.match(scanner.Semicolon) {
 			p.consumeOne()
@@ -340,6 +787,6 @@ func (p *Parser) consumeBlock() Block {
 		}
 		blk.Stmts = append(blk.Stmts, p.consumeStmt())
 	}
-	p.consumeOne()
+	blk.Close = p.consumeOne()
 	return blk
 }
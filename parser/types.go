@@ -6,8 +6,20 @@ type Expr interface{}
 
 type Stmt interface{}
 
+type ModuleStmt struct {
+	Name scanner.Token
+}
+
+type ImportStmt struct {
+	Path scanner.Token
+}
+
 type Block struct {
 	Stmts []Stmt
+	// The braces around the statements, which are missing from the block
+	// of an else if.
+	Open  scanner.Token
+	Close scanner.Token
 }
 
 type FunctionParam struct {
@@ -19,31 +31,80 @@ type FunctionStmt struct {
 	ReturnKind scanner.Token
 	Name       scanner.Token
 	Params     []FunctionParam
+	Variadic   bool
 	Body       Block
+	Exported   bool
+	Extern     bool
+	// Annotations are the names written with an '@' before the function,
+	// such as inline.
+	Annotations []scanner.Token
 }
 
-type ReturnStmt struct{ Expr }
+type EnumStmt struct {
+	Name     scanner.Token
+	Members  []scanner.Token
+	Exported bool
+	// Comments are the comments before each member, and then before the
+	// closing brace, when the parser keeps comments and there are any.
+	Comments [][]Comment
+}
+
+type ReturnStmt struct {
+	Keyword scanner.Token
+	Expr
+}
 
 type AssignStmt struct {
 	Target Expr
 	Expr
 }
 
+type CompoundAssignStmt struct {
+	Op     scanner.Token
+	Target scanner.Token
+	Expr
+}
+
+type IncDecStmt struct {
+	Op     scanner.Token
+	Target scanner.Token
+}
+
 type VarStmt struct {
 	Kind scanner.Token
 	Name scanner.Token
 	Expr
+	Exported bool
 }
 
 type IfStmt struct {
-	Cond Expr
-	Then Block
-	Els  Block
+	Keyword scanner.Token
+	Cond    Expr
+	Then    Block
+	Els     Block
 }
 
+type SwitchCase struct {
+	Values  []Expr
+	Default bool
+	Body    Block
+	// Comments are the comments and blank lines before the case, when the
+	// parser keeps comments.
+	Comments []Stmt
+}
+
+type SwitchStmt struct {
+	Keyword scanner.Token
+	Expr
+	Cases []SwitchCase
+}
+
+type FallthroughStmt struct{ Keyword scanner.Token }
+
 type WhileStmt struct {
-	Cond Expr
-	Body Block
+	Keyword scanner.Token
+	Cond    Expr
+	Body    Block
 }
 
 type MemberAccess struct {
@@ -54,6 +115,17 @@ type MemberAccess struct {
 type FunctionCall struct {
 	Callee Expr
 	Args   []Expr
+	Close  scanner.Token
+	// Comments are the comments before each argument, and then before the
+	// closing parenthesis, when the parser keeps comments and there are
+	// any.
+	Comments [][]Comment
+}
+
+type TernaryExpr struct {
+	Cond Expr
+	Then Expr
+	Els  Expr
 }
 
 type UnaryOp struct {
@@ -67,12 +139,170 @@ type BinaryOp struct {
 	Right Expr
 }
 
-type LiteralStr struct{ Value string }
+type LiteralStr struct {
+	Value string
+	Token scanner.Token
+}
+
+type InterpolatedStr struct {
+	Parts []Expr
+}
 
-type LiteralNum struct{ Value string }
+type LiteralNum struct {
+	Value string
+	Token scanner.Token
+}
 
-type LiteralBool struct{ Value bool }
+type LiteralBool struct {
+	Value bool
+	Token scanner.Token
+}
 
-type LiteralNull struct{}
+type LiteralNull struct{ Token scanner.Token }
 
 type IdentExpr struct{ Name scanner.Token }
+
+// Comment and BlankLine only appear in statement lists parsed with
+// comments. A trailing comment follows code on the same line.
+type Comment struct {
+	Text     scanner.Token
+	Trailing bool
+}
+
+type BlankLine struct{}
+
+// Pos returns the first token of a statement or expression.
+func Pos(node interface{}) scanner.Token {
+	switch n := node.(type) {
+	case ModuleStmt:
+		return n.Name
+	case ImportStmt:
+		return n.Path
+	case Block:
+		return n.Open
+	case FunctionStmt:
+		return n.ReturnKind
+	case EnumStmt:
+		return n.Name
+	case ReturnStmt:
+		return n.Keyword
+	case AssignStmt:
+		return n.Target.(scanner.Token)
+	case CompoundAssignStmt:
+		return n.Target
+	case IncDecStmt:
+		return n.Target
+	case VarStmt:
+		return n.Kind
+	case IfStmt:
+		return n.Keyword
+	case SwitchStmt:
+		return n.Keyword
+	case FallthroughStmt:
+		return n.Keyword
+	case WhileStmt:
+		return n.Keyword
+	case MemberAccess:
+		return Pos(n.Parent)
+	case FunctionCall:
+		return Pos(n.Callee)
+	case TernaryExpr:
+		return Pos(n.Cond)
+	case UnaryOp:
+		return n.Op
+	case BinaryOp:
+		return Pos(n.Left)
+	case LiteralStr:
+		return n.Token
+	case InterpolatedStr:
+		return Pos(n.Parts[0])
+	case LiteralNum:
+		return n.Token
+	case LiteralBool:
+		return n.Token
+	case LiteralNull:
+		return n.Token
+	case IdentExpr:
+		return n.Name
+	case Comment:
+		return n.Text
+	default:
+		return scanner.Token{}
+	}
+}
+
+// End returns the last token of a statement or expression.
+func End(node interface{}) scanner.Token {
+	switch n := node.(type) {
+	case ModuleStmt:
+		return n.Name
+	case ImportStmt:
+		return n.Path
+	case Block:
+		if n.Close == (scanner.Token{}) && len(n.Stmts) > 0 {
+			return End(n.Stmts[len(n.Stmts)-1])
+		}
+		return n.Close
+	case FunctionStmt:
+		if !n.Extern {
+			return n.Body.Close
+		} else if len(n.Params) > 0 {
+			return n.Params[len(n.Params)-1].Name
+		}
+		return n.Name
+	case EnumStmt:
+		if len(n.Members) > 0 {
+			return n.Members[len(n.Members)-1]
+		}
+		return n.Name
+	case ReturnStmt:
+		if n.Expr != nil {
+			return End(n.Expr)
+		}
+		return n.Keyword
+	case AssignStmt:
+		return End(n.Expr)
+	case CompoundAssignStmt:
+		return End(n.Expr)
+	case IncDecStmt:
+		return n.Op
+	case VarStmt:
+		if n.Expr != nil {
+			return End(n.Expr)
+		}
+		return n.Name
+	case IfStmt:
+		if len(n.Els.Stmts) > 0 || n.Els.Open != (scanner.Token{}) {
+			return End(n.Els)
+		}
+		return End(n.Then)
+	case SwitchStmt:
+		for i := len(n.Cases) - 1; i >= 0; i-- {
+			c := n.Cases[i]
+			if len(c.Body.Stmts) > 0 {
+				return End(c.Body)
+			} else if len(c.Values) > 0 {
+				return End(c.Values[len(c.Values)-1])
+			}
+		}
+		return End(n.Expr)
+	case FallthroughStmt:
+		return n.Keyword
+	case WhileStmt:
+		return End(n.Body)
+	case MemberAccess:
+		return n.Name
+	case FunctionCall:
+		return n.Close
+	case TernaryExpr:
+		return End(n.Els)
+	case UnaryOp:
+		return End(n.Expr)
+	case BinaryOp:
+		return End(n.Right)
+	case InterpolatedStr:
+		return End(n.Parts[len(n.Parts)-1])
+	default:
+		return Pos(node)
+	}
+}
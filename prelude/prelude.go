@@ -0,0 +1,16 @@
+package prelude
+
+import (
+	_ "embed"
+	"lang/parser"
+	"lang/scanner"
+)
+
+//go:embed src/prelude.c
+var source string
+
+// Stmts returns the extern declarations of the builtin functions.
+func Stmts() []parser.Stmt {
+	p := parser.Parser{Tokens: scanner.Scan(source)}
+	return p.ConsumeTopLevelStmts()
+}
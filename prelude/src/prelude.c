@@ -0,0 +1,23 @@
+// Declarations of the builtin functions visible to every module. Their
+// implementations are provided by the runtime.
+
+// Output
+extern void print(string s);
+extern void println(string s);
+extern void printf(string format, ...);
+
+// Strings
+extern int len(string s);
+extern string substr(string s, int start, int end);
+extern int indexOf(string s, string sub);
+extern int splitCount(string s, string sep);
+extern string split(string s, string sep, int i);
+
+// Math
+extern int abs(int n);
+extern float sqrt(float x);
+extern float pow(float x, float y);
+
+// Conversions
+extern int parseInt(string s);
+extern float parseFloat(string s);
@@ -0,0 +1,221 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/kr/pretty"
+	"io"
+	"lang/analysis"
+	"lang/interp"
+	"lang/parser"
+	"lang/scanner"
+	"strings"
+)
+
+const (
+	prompt     = "> "
+	contPrompt = "... "
+)
+
+const help = `Enter declarations, statements or expressions. The value and type of
+each expression is printed. Blocks may span several lines.
+
+:type <expr>    print the type of an expression
+:ast <input>    print the syntax tree of an expression or statements
+:tokens <input> print the tokens of any input
+:help           print this message
+:quit           exit
+`
+
+type repl struct {
+	out io.Writer
+	env analysis.Env
+	in  *interp.Interpreter
+}
+
+type snapshot struct {
+	vars  map[analysis.Symbol]analysis.Type
+	types map[analysis.Symbol]analysis.Type
+}
+
+// Run reads input from r line by line until EOF or ":quit", writing
+// results and errors to w. Everything declared persists across inputs.
+func Run(r io.Reader, w io.Writer) error {
+	in, err := interp.NewSession("repl", nil)
+	if err != nil {
+		return err
+	}
+	in.Stdout = w
+	rp := &repl{
+		out: w,
+		env: analysis.NewModuleEnv(analysis.NewUniverse(w)),
+		in:  in,
+	}
+
+	lines := bufio.NewScanner(r)
+	var buf strings.Builder
+	fmt.Fprint(w, prompt)
+	for lines.Scan() {
+		buf.WriteString(lines.Text())
+		buf.WriteString("\n")
+		input := strings.TrimSpace(buf.String())
+		if !strings.HasPrefix(input, ":") && unfinished(input) {
+			fmt.Fprint(w, contPrompt)
+			continue
+		}
+		buf.Reset()
+		if input == ":quit" || input == ":q" {
+			return nil
+		}
+		rp.protect(func() {
+			rp.handle(input)
+		})
+		fmt.Fprint(w, prompt)
+	}
+	return lines.Err()
+}
+
+// unfinished reports whether input has more opening than closing brackets,
+// meaning that it continues on the next line.
+func unfinished(input string) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	depth := 0
+	for _, t := range scanner.Scan(input) {
+		switch t.Kind {
+		case scanner.LBrace, scanner.LParen:
+			depth++
+		case scanner.RBrace, scanner.RParen:
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// protect runs f, reporting the panics that the scanner, parser and
+// checker raise on malformed input as errors.
+func (rp *repl) protect(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			fmt.Fprintln(rp.out, "error:", msg)
+		}
+	}()
+	f()
+}
+
+func (rp *repl) handle(input string) {
+	cmd, arg := input, ""
+	if i := strings.IndexAny(input, " \t"); i >= 0 {
+		cmd, arg = input[:i], strings.TrimSpace(input[i:])
+	}
+	switch cmd {
+	case "":
+	case ":help":
+		fmt.Fprint(rp.out, help)
+	case ":type":
+		p := parser.Parser{Tokens: scanner.Scan(arg)}
+		if t := analysis.TypeOf(rp.env, p.ConsumeExpr()); t != nil {
+			fmt.Fprintln(rp.out, t)
+		} else {
+			fmt.Fprintln(rp.out, "error: expression does not type check")
+		}
+	case ":ast":
+		tokens := scanner.Scan(arg)
+		if e, ok := parseExpr(tokens); ok {
+			pretty.Fprintf(rp.out, "%# v\n", e)
+			return
+		}
+		p := parser.Parser{Tokens: scanner.Scan(terminate(arg))}
+		for _, stmt := range p.ConsumeStmts() {
+			pretty.Fprintf(rp.out, "%# v\n", stmt)
+		}
+	case ":tokens":
+		for _, t := range scanner.Scan(arg) {
+			fmt.Fprintf(rp.out, "%s\t%q\n", t.Kind, t.Lexeme)
+		}
+	default:
+		if strings.HasPrefix(cmd, ":") {
+			fmt.Fprintf(rp.out, "error: unknown command %s, see :help\n", cmd)
+			return
+		}
+		rp.run(input)
+	}
+}
+
+func (rp *repl) run(input string) {
+	if e, ok := parseExpr(scanner.Scan(input)); ok {
+		t := analysis.TypeOf(rp.env, e)
+		if t == nil {
+			fmt.Fprintln(rp.out, "error: expression does not type check")
+			return
+		}
+		v, err := rp.in.Eval(e)
+		if err != nil {
+			fmt.Fprintln(rp.out, "error:", err)
+		} else if t != analysis.Void {
+			fmt.Fprintf(rp.out, "%s : %v\n", interp.Format(v), t)
+		}
+		return
+	}
+
+	p := parser.Parser{Tokens: scanner.Scan(terminate(input))}
+	for _, stmt := range p.ConsumeStmts() {
+		saved := rp.save()
+		if !analysis.CheckStmt(rp.env, stmt) {
+			rp.restore(saved)
+			return
+		}
+		if err := rp.in.Exec(stmt); err != nil {
+			rp.restore(saved)
+			fmt.Fprintln(rp.out, "error:", err)
+			return
+		}
+	}
+}
+
+func parseExpr(tokens []scanner.Token) (e parser.Expr, ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	p := parser.Parser{Tokens: tokens}
+	return p.ConsumeExpr(), true
+}
+
+// terminate adds the semicolon that is optional after the last statement of
+// an input.
+func terminate(input string) string {
+	if strings.HasSuffix(input, ";") || strings.HasSuffix(input, "}") {
+		return input
+	}
+	return input + ";"
+}
+
+func (rp *repl) save() snapshot {
+	s := snapshot{
+		vars:  map[analysis.Symbol]analysis.Type{},
+		types: map[analysis.Symbol]analysis.Type{},
+	}
+	for sym, t := range rp.env.Vars.Symbols {
+		s.vars[sym] = t
+	}
+	for sym, t := range rp.env.Types.Symbols {
+		s.types[sym] = t
+	}
+	return s
+}
+
+// restore undoes the declarations of a statement that failed to check or
+// run, so that the checker only knows about what exists at runtime.
+func (rp *repl) restore(s snapshot) {
+	rp.env.Vars.Symbols = s.vars
+	rp.env.Types.Symbols = s.types
+}
@@ -0,0 +1,57 @@
+package repl
+
+import (
+	"bytes"
+	"lang/format"
+	"strings"
+	"testing"
+)
+
+// TestRun drives the REPL with scripted input and compares everything it
+// writes, prompts included.
+func TestRun(t *testing.T) {
+	for _, tt := range []struct {
+		name, input, want string
+	}{
+		{
+			name:  "echo",
+			input: "1 + 2\n\"${1}b\"\n2.5 > 1.0\n",
+			want:  "> 3 : int\n> \"1b\" : string\n> true : bool\n> ",
+		},
+		{
+			name:  "state",
+			input: "int x = 1;\nx += 2;\nint twice(int n) {\n  return n * 2;\n}\ntwice(x)\nx\n",
+			want:  "> > > ... ... > 6 : int\n> 3 : int\n> ",
+		},
+		{
+			name:  "type error",
+			input: "int x = 1;\nx = \"a\";\nstring y = x;\nx + 1\n",
+			want:  "> > 1:5: cannot use string value as int in assignment to x\n> 1:12: cannot use int value as string in declaration of y\n> 2 : int\n> ",
+		},
+		{
+			name:  "runtime error",
+			input: "int zero = 0;\nint y = 1 / zero;\ny\n1 / zero\nint y = 2;\ny\n",
+			want:  "> > error: runtime error: integer division by zero\n> error: expression does not type check\n> error: runtime error: integer division by zero\n> > 2 : int\n> ",
+		},
+		{
+			name:  "redeclaration",
+			input: "void f(int a) {\n  int a = 2;\n}\nf(1)\nif (true) { int b = 1; int b = 2; }\nint b = 3;\nb\n",
+			want:  "> ... ... 2:7: a redeclared in this block\n1:12: other declaration of a\n> error: expression does not type check\n> 1:28: b redeclared in this block\n1:17: other declaration of b\n> > 3 : int\n> ",
+		},
+		{
+			name:  "quit",
+			input: "1\n:quit\n2\n",
+			want:  "> 1 : int\n> ",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Run(strings.NewReader(tt.input), &out); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("output differs:\n%s", format.Diff("want", "got", []byte(tt.want), []byte(got)))
+			}
+		})
+	}
+}
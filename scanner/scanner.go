@@ -1,7 +1,6 @@
 package scanner
 
 import (
-	"io/ioutil"
 	"strconv"
 	"strings"
 	"unicode"
@@ -18,13 +17,24 @@ const (
 	LBrace
 	RBrace
 	Dot
+	Ellipsis
 	Comma
 	Semicolon
+	Question
+	Colon
 
 	Plus
 	Minus
 	Star
 	Slash
+	Percent
+
+	BAnd
+	BOr
+	BXor
+	BNot
+	Shl
+	Shr
 
 	EqEq
 	Ne
@@ -38,12 +48,66 @@ const (
 	LOr
 
 	Eq
+	PlusEq
+	MinusEq
+	StarEq
+	SlashEq
+	PercentEq
+	BAndEq
+	BOrEq
+	BXorEq
+	ShlEq
+	ShrEq
+	Inc
+	Dec
 
 	Str
+	StrHead
+	StrMid
+	StrTail
 	Num
+	Comment
+	Annotation
 	Eof
 )
 
+// Operators maps the kinds of operator tokens, whose lexemes are empty, to
+// their spelling.
+var Operators = map[TokenKind]string{
+	Plus:      "+",
+	Minus:     "-",
+	Star:      "*",
+	Slash:     "/",
+	Percent:   "%",
+	BAnd:      "&",
+	BOr:       "|",
+	BXor:      "^",
+	BNot:      "~",
+	Shl:       "<<",
+	Shr:       ">>",
+	EqEq:      "==",
+	Ne:        "!=",
+	Gt:        ">",
+	Gte:       ">=",
+	Lt:        "<",
+	Lte:       "<=",
+	LNot:      "!",
+	LAnd:      "&&",
+	LOr:       "||",
+	PlusEq:    "+=",
+	MinusEq:   "-=",
+	StarEq:    "*=",
+	SlashEq:   "/=",
+	PercentEq: "%=",
+	BAndEq:    "&=",
+	BOrEq:     "|=",
+	BXorEq:    "^=",
+	ShlEq:     "<<=",
+	ShrEq:     ">>=",
+	Inc:       "++",
+	Dec:       "--",
+}
+
 type Token struct {
 	Kind   TokenKind
 	Lexeme string
@@ -51,6 +115,46 @@ type Token struct {
 	Col    int
 }
 
+// Len returns the number of bytes t takes up in the source. Strings are
+// assumed to be written with the fewest escapes.
+func (t Token) Len() int {
+	switch t.Kind {
+	case Ident, Num:
+		return len(t.Lexeme)
+	case Str, StrTail:
+		return len(t.Lexeme) + escapes(t.Lexeme) + 2
+	case StrHead, StrMid:
+		return len(t.Lexeme) + escapes(t.Lexeme) + 3
+	case Comment:
+		return len(t.Lexeme) + 2
+	case Annotation:
+		return len(t.Lexeme) + 1
+	case Ellipsis:
+		return 3
+	case Eof:
+		return 0
+	}
+	if op, ok := Operators[t.Kind]; ok {
+		return len(op)
+	}
+	return 1
+}
+
+func escapes(s string) int {
+	n := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"', '\r', '\t', '\n':
+			n++
+		case '$':
+			if i+1 < len(s) && s[i+1] == '{' {
+				n++
+			}
+		}
+	}
+	return n
+}
+
 type consumeState int
 
 const (
@@ -63,10 +167,21 @@ const (
 	consumingIdent
 )
 
-func Scan() []Token {
+func Scan(src string) []Token {
+	tokens, _ := ScanComments(src)
+	return tokens
+}
+
+// ScanComments is like Scan, but also returns the comments in src as
+// Comment tokens holding the text after the "//". The comments are never
+// nil, so that they can be handed to a parser even if there are none.
+func ScanComments(src string) ([]Token, []Token) {
+	// The first newline ends whatever token the source ends with, and the
+	// second lets the loop below always look one character ahead.
+	src += "\n\n"
 	var (
-		src       = getSource() + "\n"
 		tokens    []Token
+		comments  = []Token{}
 		row       = 0
 		col       = -1
 		addLexeme = func(kind TokenKind, lexeme string) {
@@ -77,6 +192,17 @@ func Scan() []Token {
 		}
 		state      = none
 		literalBuf = strings.Builder{}
+		// Column where the current string, or the part of it after an
+		// interpolation, starts.
+		strCol    int
+		addString = func(kind TokenKind) {
+			tokens = append(tokens, Token{kind, literalBuf.String(), row, strCol})
+		}
+		// Brace depth of each open string interpolation, innermost last.
+		interps    []int
+		strResumed bool
+		comment    Token
+		commentPos int
 	)
 
 	for i := 0; i < len(src)-1; i++ {
@@ -86,17 +212,33 @@ func Scan() []Token {
 		switch state {
 		case consumingComment:
 			if ch == '\n' {
-				row++
-				col = -1
+				comment.Lexeme = strings.TrimRight(src[commentPos:i], " \t\r")
+				comments = append(comments, comment)
 				state = none
 			} else {
 				continue
 			}
 		case consumingStr:
 			if ch == '"' {
-				addLexeme(Str, literalBuf.String())
+				if strResumed {
+					addString(StrTail)
+				} else {
+					addString(Str)
+				}
+				literalBuf.Reset()
+				state = none
+				continue
+			} else if ch == '$' && src[i+1] == '{' {
+				if strResumed {
+					addString(StrMid)
+				} else {
+					addString(StrHead)
+				}
 				literalBuf.Reset()
+				interps = append(interps, 0)
 				state = none
+				i++
+				col++
 				continue
 			} else if ch == '\n' {
 				panic("Unclosed string")
@@ -104,13 +246,15 @@ func Scan() []Token {
 				state = consumingStrEscape
 				continue
 			} else {
-				literalBuf.WriteRune(ch)
+				literalBuf.WriteByte(src[i])
 				continue
 			}
 		case consumingStrEscape:
 			switch ch {
 			case '"':
 				literalBuf.WriteRune('"')
+			case '$':
+				literalBuf.WriteRune('$')
 			case 'r':
 				literalBuf.WriteRune('\r')
 			case 't':
@@ -174,29 +318,92 @@ func Scan() []Token {
 		case ')':
 			addToken(RParen)
 		case '{':
+			if len(interps) > 0 {
+				interps[len(interps)-1]++
+			}
 			addToken(LBrace)
 		case '}':
+			if len(interps) > 0 {
+				if interps[len(interps)-1] == 0 {
+					interps = interps[:len(interps)-1]
+					strResumed = true
+					strCol = col
+					state = consumingStr
+					continue
+				}
+				interps[len(interps)-1]--
+			}
 			addToken(RBrace)
 		case '.':
-			addToken(Dot)
+			if src[i+1] == '.' && i+2 < len(src) && src[i+2] == '.' {
+				addToken(Ellipsis)
+				i += 2
+				col += 2
+			} else {
+				addToken(Dot)
+			}
 		case ',':
 			addToken(Comma)
 		case ';':
 			addToken(Semicolon)
+		case '?':
+			addToken(Question)
+		case ':':
+			addToken(Colon)
 		case '+':
-			addToken(Plus)
+			if src[i+1] == '+' {
+				addToken(Inc)
+				i++
+				col++
+			} else if src[i+1] == '=' {
+				addToken(PlusEq)
+				i++
+				col++
+			} else {
+				addToken(Plus)
+			}
 		case '-':
-			addToken(Minus)
+			if src[i+1] == '-' {
+				addToken(Dec)
+				i++
+				col++
+			} else if src[i+1] == '=' {
+				addToken(MinusEq)
+				i++
+				col++
+			} else {
+				addToken(Minus)
+			}
 		case '*':
-			addToken(Star)
+			if src[i+1] == '=' {
+				addToken(StarEq)
+				i++
+				col++
+			} else {
+				addToken(Star)
+			}
 		case '/':
 			if src[i+1] == '/' {
 				state = consumingComment
+				comment = Token{Comment, "", row, col}
+				commentPos = i + 2
+				i++
+				col++
+			} else if src[i+1] == '=' {
+				addToken(SlashEq)
 				i++
 				col++
 			} else {
 				addToken(Slash)
 			}
+		case '%':
+			if src[i+1] == '=' {
+				addToken(PercentEq)
+				i++
+				col++
+			} else {
+				addToken(Percent)
+			}
 		case '=':
 			if src[i+1] == '=' {
 				addToken(EqEq)
@@ -214,7 +421,15 @@ func Scan() []Token {
 				addToken(LNot)
 			}
 		case '>':
-			if src[i+1] == '=' {
+			if src[i+1] == '>' && i+2 < len(src) && src[i+2] == '=' {
+				addToken(ShrEq)
+				i += 2
+				col += 2
+			} else if src[i+1] == '>' {
+				addToken(Shr)
+				i++
+				col++
+			} else if src[i+1] == '=' {
 				addToken(Gte)
 				i++
 				col++
@@ -222,7 +437,15 @@ func Scan() []Token {
 				addToken(Gt)
 			}
 		case '<':
-			if src[i+1] == '=' {
+			if src[i+1] == '<' && i+2 < len(src) && src[i+2] == '=' {
+				addToken(ShlEq)
+				i += 2
+				col += 2
+			} else if src[i+1] == '<' {
+				addToken(Shl)
+				i++
+				col++
+			} else if src[i+1] == '=' {
 				addToken(Lte)
 				i++
 				col++
@@ -234,18 +457,49 @@ func Scan() []Token {
 				addToken(LAnd)
 				i++
 				col++
+			} else if src[i+1] == '=' {
+				addToken(BAndEq)
+				i++
+				col++
 			} else {
-				panic("Bitwise LAnd is NYI")
+				addToken(BAnd)
 			}
 		case '|':
 			if src[i+1] == '|' {
 				addToken(LOr)
 				i++
 				col++
+			} else if src[i+1] == '=' {
+				addToken(BOrEq)
+				i++
+				col++
+			} else {
+				addToken(BOr)
+			}
+		case '^':
+			if src[i+1] == '=' {
+				addToken(BXorEq)
+				i++
+				col++
 			} else {
-				panic("Bitwise LOr is NYI")
+				addToken(BXor)
+			}
+		case '~':
+			addToken(BNot)
+		case '@':
+			j := i + 1
+			for 'a' <= src[j] && src[j] <= 'z' || 'A' <= src[j] && src[j] <= 'Z' || src[j] == '_' || '0' <= src[j] && src[j] <= '9' {
+				j++
 			}
+			if j == i+1 {
+				panic("expected annotation name after '@' at row " + strconv.Itoa(row) + ", col " + strconv.Itoa(col))
+			}
+			tokens = append(tokens, Token{Annotation, src[i+1 : j], row, col})
+			col += j - i - 1
+			i = j - 1
 		case '"':
+			strResumed = false
+			strCol = col
 			state = consumingStr
 		default:
 			if unicode.IsDigit(ch) {
@@ -259,15 +513,10 @@ func Scan() []Token {
 			}
 		}
 	}
+	if len(interps) > 0 {
+		panic("Unclosed string interpolation")
+	}
 	addToken(Eof)
 
-	return tokens
-}
-
-func getSource() string {
-	b, err := ioutil.ReadFile("test.c")
-	if err != nil {
-		panic(err)
-	}
-	return string(b)
+	return tokens, comments
 }
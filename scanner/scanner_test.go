@@ -0,0 +1,31 @@
+package scanner
+
+import "testing"
+
+func TestScanWithoutTrailingNewline(t *testing.T) {
+	for _, tt := range []struct {
+		src  string
+		want []TokenKind
+	}{
+		{src: "x", want: []TokenKind{Ident, Eof}},
+		{src: "x + 1", want: []TokenKind{Ident, Plus, Num, Eof}},
+		{src: "x++", want: []TokenKind{Ident, Inc, Eof}},
+		{src: "\"s\"", want: []TokenKind{Str, Eof}},
+		{src: "x\n", want: []TokenKind{Ident, Eof}},
+	} {
+		var got []TokenKind
+		for _, tok := range Scan(tt.src) {
+			got = append(got, tok.Kind)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Scan(%q) = %v, want %v", tt.src, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Scan(%q) = %v, want %v", tt.src, got, tt.want)
+				break
+			}
+		}
+	}
+}
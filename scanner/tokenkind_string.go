@@ -14,30 +14,57 @@ func _() {
 	_ = x[LBrace-3]
 	_ = x[RBrace-4]
 	_ = x[Dot-5]
-	_ = x[Comma-6]
-	_ = x[Semicolon-7]
-	_ = x[Plus-8]
-	_ = x[Minus-9]
-	_ = x[Star-10]
-	_ = x[Slash-11]
-	_ = x[EqEq-12]
-	_ = x[Ne-13]
-	_ = x[Gt-14]
-	_ = x[Gte-15]
-	_ = x[Lt-16]
-	_ = x[Lte-17]
-	_ = x[LNot-18]
-	_ = x[LAnd-19]
-	_ = x[LOr-20]
-	_ = x[Eq-21]
-	_ = x[Str-22]
-	_ = x[Num-23]
-	_ = x[Eof-24]
+	_ = x[Ellipsis-6]
+	_ = x[Comma-7]
+	_ = x[Semicolon-8]
+	_ = x[Question-9]
+	_ = x[Colon-10]
+	_ = x[Plus-11]
+	_ = x[Minus-12]
+	_ = x[Star-13]
+	_ = x[Slash-14]
+	_ = x[Percent-15]
+	_ = x[BAnd-16]
+	_ = x[BOr-17]
+	_ = x[BXor-18]
+	_ = x[BNot-19]
+	_ = x[Shl-20]
+	_ = x[Shr-21]
+	_ = x[EqEq-22]
+	_ = x[Ne-23]
+	_ = x[Gt-24]
+	_ = x[Gte-25]
+	_ = x[Lt-26]
+	_ = x[Lte-27]
+	_ = x[LNot-28]
+	_ = x[LAnd-29]
+	_ = x[LOr-30]
+	_ = x[Eq-31]
+	_ = x[PlusEq-32]
+	_ = x[MinusEq-33]
+	_ = x[StarEq-34]
+	_ = x[SlashEq-35]
+	_ = x[PercentEq-36]
+	_ = x[BAndEq-37]
+	_ = x[BOrEq-38]
+	_ = x[BXorEq-39]
+	_ = x[ShlEq-40]
+	_ = x[ShrEq-41]
+	_ = x[Inc-42]
+	_ = x[Dec-43]
+	_ = x[Str-44]
+	_ = x[StrHead-45]
+	_ = x[StrMid-46]
+	_ = x[StrTail-47]
+	_ = x[Num-48]
+	_ = x[Comment-49]
+	_ = x[Annotation-50]
+	_ = x[Eof-51]
 }
 
-const _TokenKind_name = "IdentLParenRParenLBraceRBraceDotCommaSemicolonPlusMinusStarSlashEqEqNeGtGteLtLteLNotLAndLOrEqStrNumEof"
+const _TokenKind_name = "IdentLParenRParenLBraceRBraceDotEllipsisCommaSemicolonQuestionColonPlusMinusStarSlashPercentBAndBOrBXorBNotShlShrEqEqNeGtGteLtLteLNotLAndLOrEqPlusEqMinusEqStarEqSlashEqPercentEqBAndEqBOrEqBXorEqShlEqShrEqIncDecStrStrHeadStrMidStrTailNumCommentAnnotationEof"
 
-var _TokenKind_index = [...]uint8{0, 5, 11, 17, 23, 29, 32, 37, 46, 50, 55, 59, 64, 68, 70, 72, 75, 77, 80, 84, 88, 91, 93, 96, 99, 102}
+var _TokenKind_index = [...]uint16{0, 5, 11, 17, 23, 29, 32, 40, 45, 54, 62, 67, 71, 76, 80, 85, 92, 96, 99, 103, 107, 110, 113, 117, 119, 121, 124, 126, 129, 133, 137, 140, 142, 148, 155, 161, 168, 177, 183, 188, 194, 199, 204, 207, 210, 213, 220, 226, 233, 236, 243, 253, 256}
 
 func (i TokenKind) String() string {
 	if i < 0 || i >= TokenKind(len(_TokenKind_index)-1) {
@@ -0,0 +1,213 @@
+package script
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"lang/analysis"
+	"lang/interp"
+	"lang/loader"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Engine loads scripts and holds the host functions and values that are
+// predeclared in every script it loads. It is safe for concurrent use.
+type Engine struct {
+	mu     sync.RWMutex
+	types  map[analysis.Symbol]analysis.Type
+	values map[string]interp.Value
+	out    io.Writer
+}
+
+func New() *Engine {
+	return &Engine{
+		types:  map[analysis.Symbol]analysis.Type{},
+		values: map[string]interp.Value{},
+		out:    os.Stdout,
+	}
+}
+
+// SetOutput sets where scripts loaded afterwards print to.
+func (e *Engine) SetOutput(w io.Writer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.out = w
+}
+
+// DefineFunc predeclares a function implemented by fn in scripts loaded
+// afterwards. Scripts are checked against t, so fn only receives
+// arguments of the declared types, and must return a value of the
+// declared return type.
+func (e *Engine) DefineFunc(name string, t analysis.FunctionType, fn interp.Builtin) error {
+	wrapped := interp.Builtin(func(args []interp.Value) (interp.Value, error) {
+		v, err := fn(args)
+		if err != nil {
+			return nil, err
+		}
+		if !hasType(v, t.Return) {
+			return nil, fmt.Errorf("host function %s returned %T, want %s", name, v, typeName(t.Return))
+		}
+		return v, nil
+	})
+	return e.define(name, t, wrapped)
+}
+
+// DefineValue predeclares a global variable of type t holding v in scripts
+// loaded afterwards.
+func (e *Engine) DefineValue(name string, t analysis.Type, v interface{}) error {
+	if _, isFunction := t.(analysis.FunctionType); isFunction {
+		return fmt.Errorf("use DefineFunc to define function %s", name)
+	}
+	value, err := toValue(v)
+	if err != nil {
+		return err
+	}
+	if !hasType(value, t) {
+		return fmt.Errorf("value %v of %s is not a %s", v, name, typeName(t))
+	}
+	return e.define(name, t, value)
+}
+
+func (e *Engine) define(name string, t analysis.Type, v interp.Value) error {
+	sym := analysis.Symbol(name)
+	if analysis.NewUniverse(ioutil.Discard).Vars.Symbols[sym] != nil {
+		return fmt.Errorf("%s is already a builtin", name)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.types[sym] = t
+	e.values[name] = v
+	return nil
+}
+
+// Load type checks src, along with any modules it imports, and prepares
+// it to be called into. Imports are resolved relative to the directory of
+// path, which otherwise only names the script in errors.
+func (e *Engine) Load(path string, src string) (*Script, error) {
+	mods, err := loader.LoadSource(path, src)
+	if err != nil {
+		return nil, err
+	}
+	return e.load(mods)
+}
+
+// LoadFile is like Load, but reads the script from the file at path.
+func (e *Engine) LoadFile(path string) (*Script, error) {
+	mods, err := loader.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return e.load(mods)
+}
+
+func (e *Engine) load(mods []*loader.Module) (*Script, error) {
+	var diags bytes.Buffer
+	universe := analysis.NewUniverse(&diags)
+	host := map[string]interp.Value{}
+	e.mu.RLock()
+	for sym, t := range e.types {
+		universe.Vars.Symbols[sym] = t
+	}
+	for name, v := range e.values {
+		host[name] = v
+	}
+	out := e.out
+	e.mu.RUnlock()
+
+	if !analysis.CheckIn(universe, mods) {
+		if msg := strings.TrimSpace(diags.String()); msg != "" {
+			return nil, fmt.Errorf("type check failed:\n%s", msg)
+		}
+		return nil, fmt.Errorf("type check failed")
+	}
+	in, err := interp.New(mods, host)
+	if err != nil {
+		return nil, err
+	}
+	in.Stdout = out
+	return &Script{in: in}, nil
+}
+
+// Script is a loaded script. It is safe for concurrent use, but calls into
+// the same script are serialized, so host functions must not call back
+// into the script that called them.
+type Script struct {
+	mu sync.Mutex
+	in *interp.Interpreter
+}
+
+// Call runs the script's top-level function called name. Go ints and
+// float32s among args are converted to the script's int and float types.
+// Errors raised while running the script are returned as
+// *interp.RuntimeError, and a panic in a host function or in the
+// interpreter itself is recovered and returned as an error.
+func (s *Script) Call(name string, args ...interface{}) (result interp.Value, err error) {
+	values := make([]interp.Value, len(args))
+	for i, arg := range args {
+		v, err := toValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("panic in call to %s: %v", name, r)
+		}
+	}()
+	return s.in.Call(name, values...)
+}
+
+func toValue(v interface{}) (interp.Value, error) {
+	switch v := v.(type) {
+	case int:
+		return int64(v), nil
+	case int32:
+		return int64(v), nil
+	case float32:
+		return float64(v), nil
+	case int64, float64, bool, string, interp.EnumValue:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func hasType(v interp.Value, t analysis.Type) bool {
+	switch v.(type) {
+	case nil:
+		return t == analysis.Void
+	case bool:
+		return t == analysis.Bool
+	case float64:
+		return t == analysis.Float
+	case int64:
+		return t == analysis.Int
+	case string:
+		return t == analysis.String
+	default:
+		return false
+	}
+}
+
+func typeName(t analysis.Type) string {
+	switch t {
+	case analysis.Bool:
+		return "bool"
+	case analysis.Float:
+		return "float"
+	case analysis.Int:
+		return "int"
+	case analysis.String:
+		return "string"
+	case analysis.Void:
+		return "void"
+	default:
+		return fmt.Sprint(t)
+	}
+}
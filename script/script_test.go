@@ -0,0 +1,163 @@
+package script
+
+import (
+	"bytes"
+	"errors"
+	"lang/analysis"
+	"lang/interp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const src = `int add(int a, int b) {
+  return a + b;
+}
+
+int fib(int n) {
+  return n < 2 ? n : fib(n - 1) + fib(n - 2);
+}
+
+string greet(string name) {
+  return "hello, ${name}";
+}
+
+int divide(int a, int b) {
+  return a / b;
+}
+`
+
+func load(t *testing.T, e *Engine, src string) *Script {
+	t.Helper()
+	s, err := e.Load("script.c", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCall(t *testing.T) {
+	s := load(t, New(), src)
+	for _, tt := range []struct {
+		name string
+		args []interface{}
+		want interp.Value
+	}{
+		{name: "add", args: []interface{}{1, int64(2)}, want: int64(3)},
+		{name: "fib", args: []interface{}{10}, want: int64(55)},
+		{name: "greet", args: []interface{}{"world"}, want: "hello, world"},
+	} {
+		got, err := s.Call(tt.name, tt.args...)
+		if err != nil {
+			t.Errorf("Call(%q, %v): %v", tt.name, tt.args, err)
+		} else if got != tt.want {
+			t.Errorf("Call(%q, %v) = %#v, want %#v", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	s := load(t, New(), src)
+	for _, tt := range []struct {
+		name string
+		args []interface{}
+		want string
+	}{
+		{name: "missing", want: `no function "missing" in module "script"`},
+		{name: "add", args: []interface{}{1}, want: "add expects 2 arguments, got 1"},
+		{name: "add", args: []interface{}{1, "2"}, want: "argument 2 of add must be int"},
+		{name: "add", args: []interface{}{1, uint(2)}, want: "unsupported value type uint"},
+	} {
+		if _, err := s.Call(tt.name, tt.args...); err == nil || err.Error() != tt.want {
+			t.Errorf("Call(%q, %v) returned error %v, want %s", tt.name, tt.args, err, tt.want)
+		}
+	}
+
+	_, err := s.Call("divide", 1, 0)
+	var rerr *interp.RuntimeError
+	if !errors.As(err, &rerr) || rerr.Msg != "integer division by zero" {
+		t.Errorf("Call(divide, 1, 0) returned error %v, want a division by zero *interp.RuntimeError", err)
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	_, err := New().Load("script.c", "int f() {\n  return \"s\";\n}\n")
+	if err == nil || !strings.HasPrefix(err.Error(), "type check failed:\nscript.c:2:") {
+		t.Errorf("got error %v, want a type check failure on line 2", err)
+	}
+}
+
+func TestHost(t *testing.T) {
+	e := New()
+	var out bytes.Buffer
+	e.SetOutput(&out)
+	double := analysis.FunctionType{Return: analysis.Int, Params: []analysis.Type{analysis.Int}}
+	if err := e.DefineFunc("double", double, func(args []interp.Value) (interp.Value, error) {
+		return args[0].(int64) * 2, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.DefineValue("greeting", analysis.String, "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.DefineValue("len", analysis.Int, 1); err == nil {
+		t.Error("redefining the builtin len succeeded")
+	}
+	s := load(t, e, "int run(int n) {\n  println(\"${greeting} ${double(n)}\");\n  return double(double(n));\n}\n")
+	got, err := s.Call("run", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != int64(20) {
+		t.Errorf("run returned %#v, want 20", got)
+	}
+	if out.String() != "hi 10\n" {
+		t.Errorf("run printed %q, want %q", out.String(), "hi 10\n")
+	}
+}
+
+func TestHostPanic(t *testing.T) {
+	e := New()
+	fail := analysis.FunctionType{Return: analysis.Int, Params: []analysis.Type{analysis.Int}}
+	if err := e.DefineFunc("fail", fail, func(args []interp.Value) (interp.Value, error) {
+		if args[0].(int64) > 0 {
+			panic("host failure")
+		}
+		return args[0], nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s := load(t, e, "int run(int n) {\n  return fail(n);\n}\n")
+	if _, err := s.Call("run", 1); err == nil || !strings.Contains(err.Error(), "host failure") {
+		t.Errorf("got error %v, want the host function's panic", err)
+	}
+	// The script is still usable after a panic.
+	if got, err := s.Call("run", 0); err != nil || got != int64(0) {
+		t.Errorf("after a panic, run returned %#v, %v, want 0", got, err)
+	}
+}
+
+// TestConcurrentCall calls into one script from many goroutines, and is
+// meant to be run with -race.
+func TestConcurrentCall(t *testing.T) {
+	s := load(t, New(), src)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if got, err := s.Call("add", n, j); err != nil || got != int64(n+j) {
+					t.Errorf("add(%d, %d) returned %#v, %v", n, j, got, err)
+				}
+				if _, err := s.Call("divide", n, 0); err == nil {
+					t.Errorf("divide(%d, 0) succeeded", n)
+				}
+				if got, err := s.Call("fib", 15); err != nil || got != int64(610) {
+					t.Errorf("fib(15) returned %#v, %v", got, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -1,19 +0,0 @@
-int main(int argc, int argv) {
-  string s = "world";
-  int a = 5;
-  bool z = true;
-  if (testVars.someBool) {
-  } else {
-    main(5, 5);
-  }
-  // hello world!!!!!!
-  //sys.printf("Hello, %s\n", s);
-  if (isTrue()) {
-    return 5;
-  }
-  return main(5, 3);
-}
-
-bool isTrue() {
-  return true;
-}
@@ -0,0 +1,32 @@
+@inline
+int one() {
+  return 1;
+}
+
+@noinline
+export int two() {
+  return 2;
+}
+
+@fast // ERROR "unknown annotation @fast"
+int three() {
+  return 3;
+}
+
+@inline @inline // ERROR "duplicate annotation @inline"
+int four() {
+  return 4;
+}
+
+@inline
+@noinline // ERROR "function five cannot be both @inline and @noinline"
+int five() {
+  return 5;
+}
+
+@noinline // ERROR "extern function getpid cannot be annotated @noinline"
+extern int getpid();
+
+int main() {
+  return one() + two() + three() + four() + five() + getpid();
+}
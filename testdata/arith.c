@@ -0,0 +1,16 @@
+int fib(int n) {
+  return n < 2 ? n : fib(n - 1) + fib(n - 2);
+}
+
+int main() {
+  int x = 1 + 2 * 3 - 4 / 2;
+  x += 10 % 4;
+  x <<= 1;
+  x++;
+  println("${x} ${-x} ${~x} ${x & 6} ${x | 1} ${x ^ 3} ${x >> 2}");
+  float f = float(7) / 2.0;
+  f *= 3.0;
+  printf("%v %.2f %g\n", f, sqrt(2.0), pow(2.0, 8.0));
+  println("${1 < 2 && 2 <= 2} ${!(3 == 3) || 1 != 1} ${5 > 6}");
+  return fib(10);
+}
@@ -0,0 +1,72 @@
+int count;
+
+int both(bool b) {
+  int x;
+  if (b) {
+    x = 1;
+  } else {
+    x = 2;
+  }
+  return x + count;
+}
+
+int partial(bool b) {
+  int x;
+  if (b) {
+    x = 1;
+  }
+  return x; // ERROR "variable x is used before it is assigned"
+}
+
+int early(bool b) {
+  int x;
+  if (b) {
+    x = 1;
+  } else {
+    return 0;
+  }
+  return x;
+}
+
+int loop(int n) {
+  int x;
+  while (n > 0) {
+    x = n;
+    n--;
+  }
+  return x; // ERROR "variable x is used before it is assigned"
+}
+
+int cases(int n) {
+  int x;
+  int y;
+  switch (n) {
+  case 1:
+    y = 1;
+    fallthrough;
+  case 2:
+    x = 2;
+  default:
+    x = 3;
+  }
+  switch (n) {
+  case 1:
+    y = 1;
+  }
+  return x + y; // ERROR "variable y is used before it is assigned"
+}
+
+int shadow(int n) {
+  int x = n;
+  if (n > 0) {
+    int x; // ERROR "warning: variable x is unused"
+    x++; // ERROR "variable x is used before it is assigned"
+  }
+  return x;
+}
+
+int main() {
+  int x;
+  x += 1; // ERROR "variable x is used before it is assigned"
+  return x + both(true) + partial(true) + early(true) + loop(1) + cases(1) + shadow(1);
+}
@@ -0,0 +1,39 @@
+enum Color { Red, Green, Blue }
+
+string describe(Color c) {
+  switch (c) {
+  case Color.Red:
+    return "red";
+  case Color.Green, Color.Blue:
+    return "not red";
+  }
+  return "unreachable";
+}
+
+int main() {
+  int i = 0;
+  int sum = 0;
+  while (i < 5) {
+    if (i % 2 == 0) {
+      sum += i;
+    } else if (i == 3) {
+      sum -= 1;
+    } else {
+      sum++;
+    }
+    i++;
+  }
+  println("sum ${sum}");
+  switch (sum) {
+  case 0:
+    println("zero");
+  case 6:
+    println("six");
+    fallthrough;
+  default:
+    println("default");
+  }
+  println(describe(Color.Green));
+  println("${Color(2)} ${int(Color.Blue)}");
+  return 0;
+}
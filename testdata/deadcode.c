@@ -0,0 +1,42 @@
+int twice(int n) {
+  return n * 2;
+  println("never"); // ERROR "warning: unreachable code"
+}
+
+int helper() { // ERROR "warning: function helper is unused"
+  return helper();
+}
+
+int used = twice(2);
+
+string sign(int n) {
+  if (n < 0) {
+    return "negative";
+  } else {
+    return "positive";
+  }
+  return "zero"; // ERROR "warning: unreachable code"
+}
+
+int spin() { // ERROR "warning: function spin is unused"
+  while (true) {
+  }
+  return 0; // ERROR "warning: unreachable code"
+}
+
+int main() {
+  if (false) {
+    println("off"); // ERROR "warning: unreachable code"
+  }
+  while (1 > 2) {
+    println("never"); // ERROR "warning: unreachable code"
+  }
+  switch (used) {
+  case 4:
+    println("four");
+    fallthrough;
+  default:
+    return twice(used);
+  }
+  println(sign(used)); // ERROR "warning: unreachable code"
+}
@@ -0,0 +1,19 @@
+int main(int argc, int argv) { // ERROR "warning: parameter argc is unused" "warning: parameter argv is unused"
+  string s = "world";
+  int a = 5; // ERROR "warning: variable a is unused"
+  bool z = true; // ERROR "warning: variable z is unused"
+  if (len(s) > 0) {
+  } else {
+    main(5, 5);
+  }
+  // hello world!!!!!!
+  printf("Hello, %s\n", s);
+  if (isTrue()) {
+    return 5;
+  }
+  return main(5, 3);
+}
+
+bool isTrue() {
+  return true;
+}
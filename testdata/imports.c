@@ -0,0 +1,9 @@
+module main;
+
+import "modules/geo";
+
+int main() {
+  geo.Shape s = geo.Shape.Triangle;
+  println("${geo.area(s, 4)} ${geo.sides}");
+  return geo.area(geo.Shape.Square, 3);
+}
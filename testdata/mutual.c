@@ -0,0 +1,21 @@
+// Mutually recursive tail calls, deep enough to overflow the stack unless
+// they run in constant space.
+
+bool isEven(int n) {
+  if (n == 0) {
+    return true;
+  }
+  return isOdd(n - 1);
+}
+
+bool isOdd(int n) {
+  if (n == 0) {
+    return false;
+  }
+  return isEven(n - 1);
+}
+
+int main() {
+  println("${isEven(1000001)} ${isOdd(1000001)}");
+  return 0;
+}
@@ -0,0 +1,34 @@
+// shadow
+
+enum Size { Small, Large }
+
+int total = 0; // ERROR "other declaration of total"
+int total = 1; // ERROR "total redeclared in this module"
+
+// Types and values are named apart.
+int Size() {
+  return 0;
+}
+
+int scale(int n, int n) { // ERROR "other declaration of n" "n redeclared in this block" "warning: parameter n is unused"
+  return n;
+}
+
+int twice(int n) { // ERROR "other declaration of n" "warning: parameter n is unused"
+  int n = 2; // ERROR "n redeclared in this block"
+  return n * 2;
+}
+
+int main() { // ERROR "other declaration of main"
+  int a = 1; // ERROR "other declaration of a" "warning: variable a is unused"
+  int a = 2; // ERROR "a redeclared in this block"
+  if (a > 0) {
+    int a = 3; // ERROR "warning: a shadows the variable declared at 24:7"
+    println("${a}");
+  }
+  int total = a; // ERROR "warning: total shadows the variable declared at 6:5"
+  return total + scale(1, 2) + twice(3) + Size();
+}
+
+void main() { // ERROR "main redeclared in this module"
+}
@@ -0,0 +1,52 @@
+enum Color { Red, Green }
+
+int sign(bool b) {
+  if (b) {
+    return 1;
+  }
+} // ERROR "missing return"
+
+int loop(int n) {
+  while (n > 0) {
+    return n;
+  }
+} // ERROR "missing return"
+
+string name(Color c) {
+  switch (c) {
+  case Color.Red:
+    return "red";
+  case Color.Green:
+    return "green";
+  }
+} // ERROR "missing return"
+
+int either(bool b) {
+  if (b) {
+    return 1;
+  } else {
+    return 2;
+  }
+}
+
+int forever() {
+  while (true) {
+  }
+}
+
+int pick(int n) {
+  switch (n) {
+  case 1:
+    fallthrough;
+  default:
+    return n;
+  }
+}
+
+void nothing() {
+}
+
+int main() {
+  nothing();
+  return sign(true) + loop(1) + either(false) + forever() + pick(2) + len(name(Color.Red));
+}
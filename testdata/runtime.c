@@ -0,0 +1,8 @@
+int divide(int a, int b) {
+  return a / b;
+}
+
+int main() {
+  println("before");
+  return divide(1, 0);
+}
@@ -0,0 +1,18 @@
+int main() {
+  string name = "world";
+  println("hello, ${name}!");
+  println("tab\tquote\" dollar\${not}\n");
+  println("nested ${"<${len(name)}>"} and ${1 + 2}");
+  string s = "hello, world";
+  println(substr(s, 7, len(s)));
+  println("${indexOf(s, "world")} ${parseInt("41") + 1} ${string(1.5)}");
+  string csv = "a,b,,c";
+  int i = 0;
+  while (i < splitCount(csv, ",")) {
+    print("[${split(csv, ",", i)}]");
+    i++;
+  }
+  println("");
+  println("${splitCount("", ",")} ${splitCount("héllo", "")} ${split("héllo", "", 1)}");
+  return len(s);
+}
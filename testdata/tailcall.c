@@ -0,0 +1,39 @@
+int count(int n, int acc) {
+  if (n == 0) {
+    return acc;
+  }
+  return count(n - 1, acc + 1);
+}
+
+bool isEven(int n) {
+  if (n == 0) {
+    return true;
+  }
+  return isOdd(n - 1);
+}
+
+bool isOdd(int n) {
+  if (n == 0) {
+    return false;
+  }
+  return isEven(n - 1);
+}
+
+float halve(float x, int times) {
+  if (times == 0) {
+    return x;
+  }
+  return halve(x / 2.0, times - 1);
+}
+
+int depth(int n) {
+  if (n == 0) {
+    return 0;
+  }
+  return 1 + depth(n - 1);
+}
+
+int main(int n) {
+  println("${count(1000000, n)} ${isEven(100001)} ${halve(1024.0, 20)} ${depth(100)}");
+  return count(3, n);
+}
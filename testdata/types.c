@@ -0,0 +1,25 @@
+enum Color { Red, Green }
+
+int twice(int n) {
+  return n * 2;
+}
+
+int main() {
+  int x = "one"; // ERROR "cannot use string value as int in declaration of x"
+  string s = twice(1); // ERROR "cannot use int value as string" "warning: variable s is unused"
+  bool b = 1 + 2.0; // ERROR "invalid operation: int value \\+ float value" "warning: variable b is unused"
+  int q = 1 / (2 - 2); // ERROR "invalid operation: division by zero" "warning: variable q is unused"
+  q %= 0; // ERROR "invalid operation: division by zero"
+  q = 9223372036854775808; // ERROR "constant 9223372036854775808 overflows int"
+  float inf = 1.0 / 0.0; // ERROR "warning: variable inf is unused"
+  y = 3; // ERROR "undefined: y"
+  twice(1, 2); // ERROR "wrong number of arguments in call: have 2, want 1"
+  Color c = Color.Blue; // ERROR "Color has no member Blue"
+  if (x) { // ERROR "cannot use int value as bool as if condition"
+  }
+  switch (c) { // ERROR "warning: switch on Color is missing cases Green"
+  case Color.Red:
+    return 1;
+  }
+  return; // ERROR "missing return value"
+}
@@ -0,0 +1,23 @@
+module main;
+
+import "modules/geo"; // ERROR "warning: import geo is unused"
+
+int scale(int n, int _factor) {
+  return n * 2;
+}
+
+int main(int argc) { // ERROR "warning: parameter argc is unused"
+  int a = 1; // ERROR "warning: variable a is unused"
+  int b = 2; // ERROR "warning: variable b is unused"
+  b = 3;
+  b++;
+  int c;
+  c = scale(4, 0);
+  int _ = scale(c, 1);
+  int debug = c; // nowarn: kept for debugging
+  if (c > 0) {
+    int d = c * 2; // ERROR "warning: variable d is unused"
+    println("${c}");
+  }
+  return c;
+}